@@ -0,0 +1,138 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// acceptance is an entry of the -accepted file.
+type acceptance struct {
+	name   string // benchmark name, with or without -GOMAXPROCS suffix
+	metric string // canonical metric name
+	reason string
+}
+
+// acceptances lists the known regressions exempt from -errdelta.
+type acceptances []acceptance
+
+// accepted holds the entries read from -accepted.
+var accepted acceptances
+
+// readAccepted reads the -accepted file at path.
+func readAccepted(path string) (acceptances, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	a, err := parseAccepted(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%v", path, err)
+	}
+	return a, nil
+}
+
+// parseAccepted parses lines holding a benchmark name, a metric and an
+// optional reason, separated by spaces, as in
+// "BenchmarkHot allocs cache the parsed config". Blank lines and lines
+// starting with '#' are ignored.
+func parseAccepted(r io.Reader) (acceptances, error) {
+	var a acceptances
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%d: missing metric after %s", line, fields[0])
+		}
+		canonical, ok := metricAliases[fields[1]]
+		if !ok {
+			return nil, fmt.Errorf("%d: unknown metric %q", line, fields[1])
+		}
+		a = append(a, acceptance{name: fields[0], metric: canonical, reason: strings.Join(fields[2:], " ")})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// find returns the entry accepting the regressions of metric for the
+// benchmark name, preferring an entry with the package, then with the
+// -GOMAXPROCS suffix.
+func (a acceptances) find(name, metric string) (acceptance, bool) {
+	for _, n := range nameForms(name) {
+		if e, ok := a.findName(n, metric); ok {
+			return e, true
+		}
+	}
+	return acceptance{}, false
+}
+
+// findName is find for name as given.
+func (a acceptances) findName(name, metric string) (acceptance, bool) {
+	base, _ := splitProcs(name)
+	var found acceptance
+	ok := false
+	for _, e := range a {
+		if e.metric != metric {
+			continue
+		}
+		if e.name == name {
+			return e, true
+		}
+		if e.name == base && !ok {
+			found, ok = e, true
+		}
+	}
+	return found, ok
+}
+
+// covers reports whether a accepts the regressions of metric for the
+// benchmark name.
+func (a acceptances) covers(name, metric string) bool {
+	_, ok := a.find(name, metric)
+	return ok
+}
+
+// acceptedReport returns a note for every accepted regression of diffs
+// and a warning for every entry of accepted no longer needed.
+func acceptedReport(diffs []benchdiff.BenchDiff) (notes, stale []string) {
+	used := make(map[acceptance]bool)
+	for _, m := range activeMetrics() {
+		for _, diff := range diffs {
+			if !m.measuredIn(diff) || !m.overTolerance(diff) {
+				continue
+			}
+			e, ok := accepted.find(diff.Name(), m.name)
+			if !ok {
+				continue
+			}
+			used[e] = true
+			note := fmt.Sprintf("benchdiff: accepted %s %s delta for %s", m.delta(diff).PercentAsStr(), m.unit, diff.Name())
+			if e.reason != "" {
+				note += ": " + e.reason
+			}
+			notes = append(notes, note)
+		}
+	}
+	for _, e := range accepted {
+		if !used[e] {
+			stale = append(stale, fmt.Sprintf("benchdiff: warning: accepted %s %s no longer exceeds its tolerance", e.name, e.metric))
+		}
+	}
+	sort.Strings(stale)
+	return notes, stale
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+func TestParseAccepted(t *testing.T) {
+	in := `# known tradeoffs
+BenchmarkHot allocs cache the parsed config
+BenchmarkCold-8 ns/op
+`
+	want := acceptances{
+		{name: "BenchmarkHot", metric: "allocs", reason: "cache the parsed config"},
+		{name: "BenchmarkCold-8", metric: "ns"},
+	}
+	have, err := parseAccepted(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("parseAccepted: want %v have %v", want, have)
+	}
+	for _, bad := range []string{"BenchmarkHot", "BenchmarkHot cpu"} {
+		if _, err := parseAccepted(strings.NewReader(bad)); err == nil {
+			t.Errorf("parseAccepted(%q): want error", bad)
+		}
+	}
+}
+
+func TestAcceptedReport(t *testing.T) {
+	defer func(a acceptances, f bool) { accepted, *failOnDelta = a, f }(accepted, *failOnDelta)
+	*failOnDelta = true
+	accepted = acceptances{
+		{name: "BenchmarkSlower", metric: "ns", reason: "correctness fix"},
+		{name: "BenchmarkFaster", metric: "ns"},
+	}
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("BenchmarkSlower-4", 0, 10, 12),
+		nsDiff("BenchmarkFaster-4", 1, 10, 8),
+	}
+	ns, _ := lookupMetric("ns")
+	if ns.exceeded(diffs[0]) {
+		t.Error("exceeded: want accepted regression to pass")
+	}
+	notes, stale := acceptedReport(diffs)
+	wantNotes := []string{"benchdiff: accepted +20.00% ns/op delta for BenchmarkSlower-4: correctness fix"}
+	wantStale := []string{"benchdiff: warning: accepted BenchmarkFaster ns no longer exceeds its tolerance"}
+	if !reflect.DeepEqual(wantNotes, notes) {
+		t.Errorf("acceptedReport notes: want %q have %q", wantNotes, notes)
+	}
+	if !reflect.DeepEqual(wantStale, stale) {
+		t.Errorf("acceptedReport stale: want %q have %q", wantStale, stale)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// Aggregations of the repeated results selected with -agg.
+const (
+	aggMean   = "mean"
+	aggMedian = "median"
+	aggMin    = "min"
+	aggMax    = "max"
+)
+
+// aggPercentile returns the percentile of the -agg value pN, as 90 for
+// p90. It reports false if s is not of that form with N in (0, 100].
+func aggPercentile(s string) (float64, bool) {
+	if !strings.HasPrefix(s, "p") {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(s[1:], 64)
+	if err != nil || p <= 0 || p > 100 {
+		return 0, false
+	}
+	return p, true
+}
+
+// validAgg reports whether s is a valid -agg value.
+func validAgg(s string) bool {
+	switch s {
+	case aggMean, aggMedian, aggMin, aggMax:
+		return true
+	}
+	_, ok := aggPercentile(s)
+	return ok
+}
+
+// logRepeated logs with -v the ns/op of the results of each benchmark
+// of bs having several, labeled label, before one is selected or they
+// are aggregated.
+func logRepeated(bs parse.Set, label string) {
+	if !*verbose {
+		return
+	}
+	var names []string
+	for name, bb := range bs {
+		if len(bb) > 1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var xs []string
+		for _, b := range bs[name] {
+			xs = append(xs, strconv.FormatFloat(b.NsPerOp, 'g', -1, 64))
+		}
+		logf("%s: %d %s results, ns/op %s", name, len(xs), label, strings.Join(xs, " "))
+	}
+}
+
+// aggregate collapses the results of each benchmark of bs as -agg
+// selects: min, max and median keep one result as -best, -worst and
+// -median do, pN the one at percentile N as -pctile, and mean replaces
+// them with their mean (see selectMean).
+func aggregate(bs parse.Set, agg string) {
+	if p, ok := aggPercentile(agg); ok {
+		benchdiff.SelectPercentile(bs, p)
+		return
+	}
+	switch agg {
+	case aggMean:
+		selectMean(bs)
+	case aggMedian:
+		benchdiff.SelectMedian(bs)
+	case aggMin:
+		benchdiff.SelectBest(bs)
+	case aggMax:
+		benchdiff.SelectWorst(bs)
+	}
+}
+
+// selectMean replaces the results of each benchmark of bs by a single
+// one, with the parse order of the first, the smallest iteration count,
+// and the mean of each metric, standard and custom, allocs/op and B/op
+// rounded to integers. A standard metric
+// is measured only if all the results measured it; a custom metric is
+// the mean of the results reporting it.
+func selectMean(bs parse.Set) {
+	for name, bb := range bs {
+		if len(bb) < 2 {
+			continue
+		}
+		n := float64(len(bb))
+		m := &parse.Benchmark{Name: name, Ord: bb[0].Ord, N: bb[0].N, Measured: -1}
+		custom := make(map[string]float64)
+		reports := make(map[string]float64)
+		for _, b := range bb {
+			if b.N < m.N {
+				m.N = b.N
+			}
+			m.Measured &= b.Measured
+			m.NsPerOp += b.NsPerOp / n
+			m.MBPerS += b.MBPerS / n
+			m.AllocsPerOp += b.AllocsPerOp
+			m.AllocedBytesPerOp += b.AllocedBytesPerOp
+			for unit, v := range customValues[b] {
+				custom[unit] += v
+				reports[unit]++
+			}
+		}
+		// allocs/op and B/op are integers: round their means.
+		m.AllocsPerOp = (m.AllocsPerOp + uint64(len(bb))/2) / uint64(len(bb))
+		m.AllocedBytesPerOp = (m.AllocedBytesPerOp + uint64(len(bb))/2) / uint64(len(bb))
+		if len(custom) > 0 {
+			for unit := range custom {
+				custom[unit] /= reports[unit]
+			}
+			customValues[m] = custom
+		}
+		bs[name] = []*parse.Benchmark{m}
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestSelectMean(t *testing.T) {
+	defer func(v map[*parse.Benchmark]map[string]float64) { customValues = v }(customValues)
+	customValues = make(map[*parse.Benchmark]map[string]float64)
+	all := parse.NsPerOp | parse.AllocsPerOp | parse.AllocedBytesPerOp
+	bb := []*parse.Benchmark{
+		{Name: "BenchmarkA", N: 200, NsPerOp: 10, AllocsPerOp: 1, AllocedBytesPerOp: 10, Measured: all, Ord: 2},
+		{Name: "BenchmarkA", N: 100, NsPerOp: 20, AllocsPerOp: 2, AllocedBytesPerOp: 10, Measured: all, Ord: 5},
+		{Name: "BenchmarkA", N: 300, NsPerOp: 60, AllocsPerOp: 2, AllocedBytesPerOp: 11, Measured: parse.NsPerOp | parse.AllocsPerOp, Ord: 7},
+	}
+	customValues[bb[0]] = map[string]float64{"hits": 3}
+	customValues[bb[2]] = map[string]float64{"hits": 5}
+	bs := parse.Set{"BenchmarkA": bb, "BenchmarkOne": {{Name: "BenchmarkOne", NsPerOp: 5, Ord: 0}}}
+	selectMean(bs)
+
+	m := bs["BenchmarkA"]
+	if len(m) != 1 {
+		t.Fatalf("selectMean BenchmarkA: want 1 result have %v", m)
+	}
+	b := m[0]
+	if b.NsPerOp != 30 || b.AllocsPerOp != 2 || b.AllocedBytesPerOp != 10 || b.N != 100 || b.Ord != 2 {
+		t.Errorf("selectMean BenchmarkA: want 30 ns/op, 2 allocs/op, 10 B/op, N 100 at 2 have %+v", *b)
+	}
+	if b.Measured != parse.NsPerOp|parse.AllocsPerOp {
+		t.Errorf("selectMean BenchmarkA: want measured %d have %d", parse.NsPerOp|parse.AllocsPerOp, b.Measured)
+	}
+	if hits := customValues[b]["hits"]; hits != 4 {
+		t.Errorf("selectMean BenchmarkA: want 4 hits have %f", hits)
+	}
+	if bb := bs["BenchmarkOne"]; len(bb) != 1 || bb[0].NsPerOp != 5 {
+		t.Errorf("selectMean BenchmarkOne: want it unchanged have %v", bb)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	cases := map[string]float64{aggMean: 20, aggMedian: 10, aggMin: 10, aggMax: 40, "p50": 10, "p90": 40, "p99.9": 40, "p10": 10}
+	for agg, want := range cases {
+		bs := parse.Set{"BenchmarkA": nsRuns("BenchmarkA", 10, 40, 10)}
+		aggregate(bs, agg)
+		if bb := bs["BenchmarkA"]; len(bb) != 1 || bb[0].NsPerOp != want {
+			t.Errorf("aggregate %s: want %v ns/op have %v", agg, want, bb)
+		}
+	}
+	if validAgg("avg") || !validAgg(aggMean) {
+		t.Error("validAgg: want only mean, median, min, max and percentiles")
+	}
+	for _, bad := range []string{"p", "p0", "p101", "90", "px"} {
+		if validAgg(bad) {
+			t.Errorf("validAgg(%q): want invalid", bad)
+		}
+	}
+	if !validAgg("p99") {
+		t.Error("validAgg(p99): want valid")
+	}
+}
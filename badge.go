@@ -0,0 +1,82 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"text/template"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// Badge colors.
+const (
+	badgeGreen = "#4c1"
+	badgeRed   = "#e05d44"
+	badgeGray  = "#9f9f9f"
+)
+
+var badgeTemplate = template.Must(template.New("badge").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{.Label}}: {{.Message}}">
+<title>{{.Label}}: {{.Message}}</title>
+<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="{{.Width}}" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)">
+<rect width="{{.LabelWidth}}" height="20" fill="#555"/>
+<rect x="{{.LabelWidth}}" width="{{.MessageWidth}}" height="20" fill="{{.Color}}"/>
+<rect width="{{.Width}}" height="20" fill="url(#s)"/>
+</g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="{{.LabelX}}" y="14">{{.Label}}</text>
+<text x="{{.MessageX}}" y="14">{{.Message}}</text>
+</g>
+</svg>
+`))
+
+// badgeSVG renders a shields.io style badge. Text widths are
+// estimated from the number of characters.
+func badgeSVG(label, message, color string) ([]byte, error) {
+	const charWidth, padding = 7, 10
+	lw := len(label)*charWidth + padding
+	mw := len(message)*charWidth + padding
+	var buf bytes.Buffer
+	err := badgeTemplate.Execute(&buf, map[string]interface{}{
+		"Label":        label,
+		"Message":      message,
+		"Color":        color,
+		"Width":        lw + mw,
+		"LabelWidth":   lw,
+		"MessageWidth": mw,
+		"LabelX":       lw / 2,
+		"MessageX":     lw + mw/2,
+	})
+	return buf.Bytes(), err
+}
+
+// badgeMessage returns the message and color of the perf badge for
+// the geomean of the ns/op ratios: red if the benchmarks got slower,
+// green otherwise, and gray if no ns/op was compared.
+func badgeMessage(ratio float64, ok bool) (message, color string) {
+	if !ok {
+		return "n/a", badgeGray
+	}
+	message = fmt.Sprintf("%+.1f%% geomean", 100*ratio-100)
+	if ratio > 1 {
+		return message, badgeRed
+	}
+	return message, badgeGreen
+}
+
+// writeBadge writes the perf badge summarizing diffs to path.
+func writeBadge(path string, diffs []benchdiff.BenchDiff) error {
+	ns, _ := lookupMetric("ns")
+	message, color := badgeMessage(ratioGeomean(diffs, ns))
+	svg, err := badgeSVG("perf", message, color)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, svg, 0644)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBadgeMessage(t *testing.T) {
+	cases := []struct {
+		ratio   float64
+		ok      bool
+		message string
+		color   string
+	}{
+		{ratio: 1.012, ok: true, message: "+1.2% geomean", color: badgeRed},
+		{ratio: 0.9, ok: true, message: "-10.0% geomean", color: badgeGreen},
+		{ratio: 1, ok: true, message: "+0.0% geomean", color: badgeGreen},
+		{ok: false, message: "n/a", color: badgeGray},
+	}
+	for _, tt := range cases {
+		message, color := badgeMessage(tt.ratio, tt.ok)
+		if message != tt.message || color != tt.color {
+			t.Errorf("badgeMessage(%f, %t): want %q %s have %q %s", tt.ratio, tt.ok, tt.message, tt.color, message, color)
+		}
+	}
+}
+
+func TestBadgeSVG(t *testing.T) {
+	svg, err := badgeSVG("perf", "+1.2% geomean", badgeRed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"<svg", "perf", "+1.2% geomean", badgeRed} {
+		if !bytes.Contains(svg, []byte(want)) {
+			t.Errorf("badgeSVG: missing %q in %s", want, svg)
+		}
+	}
+}
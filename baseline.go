@@ -0,0 +1,108 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// baselineStamp is the layout of the time prefixing the names of the
+// baseline files, so that they sort in time order.
+const baselineStamp = "20060102T150405.000000000Z"
+
+// gitKey returns the current git branch and short commit, the branch
+// being "detached" for a detached HEAD. Outside of a git checkout it
+// returns "default" and "nocommit".
+func gitKey() (branch, commit string) {
+	branch, err := git(".", "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "default", "nocommit"
+	}
+	if branch == "HEAD" {
+		branch = "detached"
+	}
+	commit, err = git(".", "rev-parse", "--short", "HEAD")
+	if err != nil {
+		commit = "nocommit"
+	}
+	return branch, commit
+}
+
+// baselineDir returns the directory of the baselines of branch in the
+// store dir. The slashes of branch names are replaced with '_'.
+func baselineDir(dir, branch string) string {
+	return filepath.Join(dir, strings.Replace(branch, "/", "_", -1))
+}
+
+// saveBaseline writes data, the results of a run of commit on branch at
+// time t, to the store dir and returns the path of the new file.
+func saveBaseline(dir, branch, commit string, data []byte, t time.Time) (string, error) {
+	bdir := baselineDir(dir, branch)
+	if err := os.MkdirAll(bdir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(bdir, t.UTC().Format(baselineStamp)+"-"+commit+".txt")
+	return path, ioutil.WriteFile(path, data, 0644)
+}
+
+// loadBaselines returns the concatenated content of the last n
+// baselines of branch in the store dir, along with their paths, oldest
+// first. It fails if there is none.
+func loadBaselines(dir, branch string, n int) ([]byte, []string, error) {
+	paths, err := filepath.Glob(filepath.Join(baselineDir(dir, branch), "*.txt"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(paths) == 0 {
+		return nil, nil, fmt.Errorf("no baseline of branch %s in %s", branch, dir)
+	}
+	sort.Strings(paths)
+	if len(paths) > n {
+		paths = paths[len(paths)-n:]
+	}
+	var buf bytes.Buffer
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		buf.Write(data)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes(), paths, nil
+}
+
+// baselineMean is set when the old results are several baselines to
+// average, as prepare does after the preprocessing flags saw every run.
+var baselineMean bool
+
+// parseBaselines parses the last n baselines of branch in the store
+// dir as the old results, the runs of every baseline being repeated
+// results of their benchmarks. Unless a flag selects one result per
+// benchmark, prepare averages the results of several baselines, making
+// the old side a rolling average of the history.
+func parseBaselines(dir, branch string, n int) (parse.Set, benchHeader) {
+	data, paths, err := loadBaselines(dir, branch, n)
+	if err != nil {
+		fatal(err)
+	}
+	for _, path := range paths {
+		logf("reading the baseline %s", path)
+	}
+	bs, h := parseDataAs("old", paths[len(paths)-1], data, inputText)
+	baselineMean = len(paths) > 1 && selections() == 0
+	return bs, h
+}
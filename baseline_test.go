@@ -0,0 +1,71 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestBaselineStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchdiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, _, err := loadBaselines(dir, "main", 1); err == nil {
+		t.Error("loadBaselines of an empty store: no error")
+	}
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, data := range []string{"first\n", "second", "third\n"} {
+		path, err := saveBaseline(dir, "feature/x", "abc123", []byte(data), t0.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			if want := filepath.Join(dir, "feature_x", "20200102T030405.000000000Z-abc123.txt"); path != want {
+				t.Errorf("saveBaseline path = %s, want %s", path, want)
+			}
+		}
+	}
+
+	data, paths, err := loadBaselines(dir, "feature/x", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "second\nthird\n"; string(data) != want {
+		t.Errorf("loadBaselines(2) = %q, want %q", data, want)
+	}
+	if len(paths) != 2 || filepath.Base(paths[1]) != "20200102T050405.000000000Z-abc123.txt" {
+		t.Errorf("loadBaselines(2) paths = %v", paths)
+	}
+	data, _, err = loadBaselines(dir, "feature/x", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "first\nsecond\nthird\n"; string(data) != want {
+		t.Errorf("loadBaselines(10) = %q, want %q", data, want)
+	}
+}
+
+func TestBaselineMean(t *testing.T) {
+	defer func(v bool) { baselineMean = v }(baselineMean)
+	baselineMean = true
+	before := parse.Set{"BenchmarkA": nsRuns("BenchmarkA", 10, 20, 30)}
+	after := parse.Set{"BenchmarkA": nsRuns("BenchmarkA", 25)}
+	diffs, oldSamples, _ := prepare(before, after, benchHeader{}, benchHeader{})
+	if len(diffs) != 1 || diffs[0].Before.NsPerOp != 20 {
+		t.Errorf("prepare of several baselines: want the mean 20 ns/op compared, have %v", diffs)
+	}
+	if len(oldSamples["BenchmarkA"]) != 3 {
+		t.Errorf("prepare of several baselines: want the 3 runs as samples, have %d", len(oldSamples["BenchmarkA"]))
+	}
+}
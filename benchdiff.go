@@ -7,157 +7,1817 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
 	"os"
+	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
+	"strings"
 	"text/tabwriter"
+	"time"
 
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
 	"golang.org/x/tools/benchmark/parse"
 )
 
 var (
+	minChange   = toleranceFlag("threshold", nil, nil, "report the changes below this percent as ~, unchanged for -changed and -errdelta")
+	fuzzyMin    = flag.Float64("fuzzy", 0, "compare the benchmarks found on one side only with the most similar name on the other, at this similarity (0-1] at least")
+	noiseFloor  = flag.Float64("noise", 0, "hide the rows whose change is below this percent, as if unchanged")
 	changedOnly = flag.Bool("changed", false, "show only benchmarks that have changed")
-	magSort     = flag.Bool("mag", false, "sort benchmarks by magnitude of change")
+	epsilon     = flag.Float64("epsilon", 0.005, "with -changed, count the changes below this percent as unchanged")
+	changedList = flag.String("changed-metric", "", "comma-separated metrics (ns, mbs, allocs, bytes) whose blocks show only the benchmarks that have changed")
+	magSort     = flag.Bool("mag", false, "sort benchmarks by magnitude of change, same as -sort=delta")
+	sortKey     = flag.String("sort", "", "sort benchmarks by name, delta, old or new value, or by the change of ns, mbs, allocs or bytes, then :asc or :desc")
+	clamp       = flag.Float64("clamp", 0, "display percent changes above this value as >+value%")
+	clampSort   = flag.Bool("clamp-sort", false, "with -clamp, sort the changes above the clamp as equal")
+	reverse     = flag.Bool("reverse", false, "reverse the sort order")
 	best        = flag.Bool("best", false, "compare best times from old and new")
+	worst       = flag.Bool("worst", false, "compare worst times from old and new")
+	medianMode  = flag.Bool("median", false, "compare median times from old and new")
+	aggMode     = flag.String("agg", "", "collapse the repeated results of each benchmark of old and new with mean, median, min, max or a percentile as p90")
+	pctile      = flag.Float64("pctile", 0, "compare the times at this percentile (0-100] of the repeated results from old and new")
+	alpha       = flag.Float64("alpha", 0, "mark with ~ the changes not significant at this level of the -stats test over repeated results")
+	statsTest   = flag.String("stats", statsWelch, "significance test of -alpha: welch (Welch's t-test) or utest (Mann-Whitney U-test)")
+	relative    = flag.Bool("relative", false, "display new values as ratios of the old ones")
+	deltaPrec   = flag.String("delta-prec", "2", "decimals of percent changes, or auto to adapt them to the size of each change")
+	nsPrec      = flag.Int("prec", -1, "decimals of the ns/op values, -1 to adapt them to their size")
+	scaleNs     = flag.Bool("scale", false, "display ns/op in the time unit, from ns to s, fitting each column")
+	multPrec    = flag.Int("multiple-prec", 2, "decimals of the MB/s speedup, -relative and -ratio multiples")
+	ratioCol    = flag.Bool("ratio", false, "add to the ns/op tables a ratio column with the speedup as a multiple, as 1.35x faster")
+	showAbs     = flag.Bool("show-abs", false, "show the absolute change, as +134ns, next to the relative one in the delta columns")
+	templateF   = flag.String("template", "", "render the comparison through the text/template in this file instead of the tables")
+	tuiMode     = flag.Bool("tui", false, "explore the tables interactively, sorting, filtering and hiding metrics with commands read from stdin")
+	selfNoise   = flag.Bool("self-noise", false, "audit the noise between two runs of the same code instead of comparing")
+	noiseBudget = flag.Float64("noise-budget", 0, "with -self-noise, fail if a change is above this percent")
+	width       = flag.Int("width", 0, "fit the tables in this many columns, 0 to use the terminal width, -1 for no limit")
+	rollup      = flag.Bool("rollup-mismatch", false, "compare a benchmark with the aggregate of its sub-benchmarks found only on the other side")
+	trimK       = flag.Float64("trim-outliers", 0, "discard results more than this many median absolute deviations away from the median")
+	outliersF   = flag.String("outliers", "", "discard the outlier results of repeated benchmarks before aggregating them: iqr[:k], stddev:k or mad:k")
+	maxAge      = flag.Duration("max-age", 0, "warn if the old file was modified longer ago than this duration")
+	strictAge   = flag.Bool("strict-age", false, "with -max-age, fail instead of warning")
+	strictEnv   = flag.Bool("strict-env", false, "fail instead of warning when the goos, goarch, pkg or cpu of old and new differ")
+	outFormat   = flag.String("format", formatText, "output format: text, json, markdown, html, csv, benchstat or junit")
+	unified     = flag.Bool("unified", false, "display all the metrics of each benchmark as columns of a single table")
+	groupBy     = flag.String("groupby", "", "pivot the sub-benchmarks on this parameter, as size for BenchmarkX/size=1K, one row per value")
+	interleave  = flag.Bool("interleave", false, "display the old values, the new values and the changes of each benchmark on stacked lines")
+	trendMode   = flag.Bool("trend", false, "show the ns/op trend of each benchmark across the files, in order")
+	treeMode    = flag.Bool("tree", false, "display ns/op as a tree of the '/' separated benchmark names")
+	bothModes   = flag.Bool("both", false, "also compare both the best and the mean ns/op of repeated benchmarks")
+	stability   = flag.Float64("stability", 0, "also compare the ns/op variation of repeated benchmarks, marking as unstable those rising by more than this many points, any rise if 0")
 	failOnDelta = flag.Bool("errdelta", false, "return error if there are delta")
-	tNsPerOp    = flag.Float64("tnsop", 0.0, "tolerance for deltas of ns/op")
-	tMbPerS     = flag.Float64("tmbs", 0.0, "tolerance for deltas of Mb/s")
-	tAllPerOp   = flag.Float64("tallocop", 0.0, "tolerance for deltas of allocs/op")
-	tBPerOp     = flag.Float64("tbop", 0.0, "tolerance for deltas of bytes/op")
+	exitCode    = flag.Bool("exitcode", false, "exit with status 3 if a metric regressed beyond its tolerance, without failing")
+	failOn      = flag.String("fail-on", "", "with -errdelta, fail on the changes beyond tolerance that are a regression, an improvement or any")
+	newAllocs   = flag.Bool("fail-on-new-allocs", false, "with -errdelta, fail when an allocation-free benchmark allocates, whatever the tolerances")
+	regressOnly = flag.Bool("regressonly", false, "with -errdelta, fail only on changes for the worse: higher ns/op, allocs/op or bytes/op, lower MB/s")
+	tNsPerOp    = toleranceFlag("tnsop", absNsPerOp, []string{"ns"}, "tolerance for deltas of ns/op, in percent or as a duration such as 200ns")
+	tMbPerS     = toleranceFlag("tmbs", absMbPerS, []string{"MB/s"}, "tolerance for deltas of Mb/s, in percent or in MB/s")
+	tAllPerOp   = toleranceFlag("tallocop", absAllPerOp, []string{"allocs/op", "allocs"}, "tolerance for deltas of allocs/op, in percent or in allocs")
+	tBPerOp     = toleranceFlag("tbop", absBPerOp, []string{"B/op", "B"}, "tolerance for deltas of bytes/op, in percent or in B")
+	absNsPerOp  = flag.Float64("absnsop", 0.0, "absolute tolerance for deltas of ns/op")
+	absMbPerS   = flag.Float64("absmbs", 0.0, "absolute tolerance for deltas of Mb/s")
+	absAllPerOp = flag.Float64("absallocop", 0.0, "absolute tolerance for deltas of allocs/op")
+	absBPerOp   = flag.Float64("absbop", 0.0, "absolute tolerance for deltas of bytes/op")
+	tUnits      = flag.String("tunit", "", "comma-separated unit:tolerance pairs gating the custom metrics of b.ReportMetric, as req/s:5")
+	oldProfile  = flag.String("old-profile", "", "with -errdelta, CPU profile of the old benchmark run, diffed with -new-profile for the benchmarks beyond tolerance")
+	newProfile  = flag.String("new-profile", "", "with -errdelta, CPU profile of the new benchmark run, diffed with -old-profile for the benchmarks beyond tolerance")
+	policyExpr  = flag.String("policy", "", "with -errdelta, fail the benchmarks not satisfying this expression, as 'ns/op < +5% && allocs/op <= 0', instead of the tolerances")
+	minEffect   = flag.Float64("min-effect-size", 0, "with -errdelta, gate the ns/op of repeated benchmarks on Cohen's d above this value instead of on -tnsop")
+	acceptedF   = flag.String("accepted", "", "with -errdelta, read the accepted regressions, never failing, from this file")
+	configF     = flag.String("config", defaultConfig, "read default flags and per benchmark tolerances from this file, if it exists")
+	thresholdsF = flag.String("thresholds", "", "with -errdelta, read per benchmark tolerances from this file")
+	maxRegress  = flag.Int("max-regressions", -1, "with -errdelta, fail only if more than this number of benchmarks regress beyond a tolerance")
+	colorMode   = flag.String("color", colorNever, "color the changes for the worse in red and for the better in green: auto (if stdout is a terminal), always or never")
+	budgetColor = flag.Bool("budget-color", false, "with -errdelta, color the gated changes by the share of their tolerance they use")
+	headroom    = flag.Bool("show-headroom", false, "with -errdelta, show how much of its tolerance each change uses")
+	gateBoth    = flag.Bool("gate-both-measured", false, "with -errdelta, gate only metrics measured on both sides and fail if there are none")
+	gateMetric  = flag.String("gate-metric", "", "with -errdelta, gate only this metric (ns, mbs, allocs, bytes)")
+	ungatedList = flag.String("ungated", "", "comma-separated metrics (ns, mbs, allocs, bytes) never gated by -errdelta")
+	oldLabel    = flag.String("old-label", "old", "label of the old benchmarks in headers")
+	newLabel    = flag.String("new-label", "new", "label of the new benchmarks in headers")
+	since       = flag.String("since", "", "compare the working tree against this git ref by running -benchcmd on both")
+	baseline    = flag.String("baseline", "", "read the old results from the latest baseline of the branch in this store directory")
+	baseLast    = flag.Int("baseline-last", 1, "with -baseline, compare against the mean of the last this many baselines")
+	baseBranch  = flag.String("baseline-branch", "", "branch of -baseline and -save-baseline, the current git branch by default")
+	saveBase    = flag.String("save-baseline", "", "save the new results as a baseline of the current git commit in this store directory")
+	until       = flag.String("until", "", "with -since, get the new results by running -benchcmd on this git ref instead of the working tree")
+	runNew      = flag.Bool("run-new", false, "get the new results by running -benchcmd in the current directory")
+	confirmRuns = flag.Int("confirm-runs", 0, "with -run-new or -since, rerun -benchcmd this many times on failure and fail only if most reruns fail")
+	watchMode   = flag.Bool("watch", false, "run -benchcmd on every change of the files of the current directory and compare with the first run")
+	benchCmd    = flag.String("benchcmd", "go test -run=NONE -bench=. ./...", "benchmark command run by -since, -run-new and -watch")
+	improveOut  = flag.String("improvements-out", "", "also write the tables of the improvements only to this file")
+	regressOut  = flag.String("regressions-out", "", "also write the tables of the regressions only to this file")
+	weightBy    = flag.String("geomean-weight", "", "weight of each benchmark in geometric means: empty for none, iters for its iteration count")
+	echo        = flag.Bool("echo", false, "write the new benchmark lines to stdout and the comparison to stderr")
+	correlate   = flag.String("correlate", "name", "key matching old and new benchmarks: name, base (name without -GOMAXPROCS suffix) or fold (case-insensitive name)")
+	stripCPU    = flag.Bool("stripcpu", false, "ignore the -GOMAXPROCS suffix of the names when matching benchmarks, as -correlate=base")
+	noMerge     = flag.Bool("no-merge", false, "compare separately the groups of results of a benchmark recurring in an input")
+	archList    = flag.String("normalize-arch", "", "comma-separated goarch=factor pairs multiplying the ns/op of the results of each goarch")
+	summaryOnly = flag.Bool("summary", false, "print per metric counts of regressed, improved and unchanged benchmarks instead of the tables")
+	summaryFoot = flag.Bool("summary-footer", false, "print the lines of -summary after the tables")
+	githubMode  = flag.Bool("github", false, "in GitHub Actions, write the -step-summary, annotate the violations and comment the pull request")
+	stepSummary = flag.Bool("step-summary", false, "append a markdown summary to the file named by $GITHUB_STEP_SUMMARY")
+	manifestF   = flag.String("manifest", "", "write the inputs, their hashes and the settings to this file as JSON")
+	verdictF    = flag.String("out-summary", "", "write the verdict, pass or fail, and the tolerance violations to this file as JSON")
+	pushURL     = flag.String("push-url", "", "POST the comparison to this URL, with the bearer token of $BENCHDIFF_PUSH_TOKEN")
+	pushFormat  = flag.String("push-format", "json", "format of -push-url: json, influx (InfluxDB line protocol) or prometheus (pushgateway text format)")
+	chartPath   = flag.String("chart", "", "write an SVG bar chart of the largest ns/op changes to this file")
+	chartTop    = flag.Int("chart-top", 10, "number of benchmarks in the -chart")
+	chartWidth  = flag.Int("chart-width", 800, "width of the -chart in pixels")
+	badgePath   = flag.String("badge", "", "write an SVG badge with the geomean of the ns/op changes to this file")
+	verbose     = flag.Bool("v", false, "print the effective settings, the parsed inputs, the skipped benchmarks and the repeated results to stderr")
+	quiet       = flag.Bool("q", false, "with -errdelta or -exitcode, print only the rows beyond their tolerance and no warning")
+	autoLabel   = flag.Bool("auto-label", false, "derive the labels from the input file names")
+	nsPerAllocs = flag.Bool("ns-per-alloc", false, "also compare ns/op divided by allocs/op")
+	comparatorF = flag.String("comparator", "", "comma-separated metric:name comparators computing the changes, as ns:logratio")
+	lockFile    = flag.String("lock", "", "check the ranges of the values of new.txt locked in this file")
+	updateLock  = flag.Bool("update-lock", false, "with -lock, rewrite the lock file from new.txt instead of checking it")
+	lockSlack   = flag.Float64("lock-slack", 10, "with -update-lock, the percent each locked range allows either way")
+	metricsList = flag.String("metrics", "", "comma-separated metrics (ns, mbs, allocs, bytes, nsalloc or custom units) to compare, all by default")
+	filterF     = flag.String("filter", "", "after correlation, show and gate only the rows whose name, the old one, matches this regexp; the unmatched benchmarks are reported whatever their name")
+	matchF      = flag.String("match", "", "before correlation, keep only the results whose input name matches this regexp, on both sides; the others are neither correlated nor reported as unmatched")
+	excludeF    = flag.String("exclude", "", "leave out of the correlation and comparison the benchmarks whose name matches this regexp")
+	showUnmatch = flag.Bool("showunmatched", false, "list the benchmarks found only in the old or the new file")
+	strictNames = flag.Bool("strict", false, "fail if a benchmark of the old file is missing from the new one")
+	inputFormat = flag.String("input-format", inputAuto, "format of the input files: auto, text, gzip, json, markdown or ansi")
 )
 
+func init() {
+	flag.StringVar(oldLabel, "oldname", "old", "same as -old-label")
+	flag.StringVar(newLabel, "newname", "new", "same as -new-label")
+	flag.BoolVar(stripCPU, "ignoreprocs", false, "same as -stripcpu")
+	flag.BoolVar(stripCPU, "normalize-procs", false, "same as -stripcpu")
+	flag.Var(renameFlag, "rename", "compare the old benchmark named old with the new one named new, as old=new; may be repeated")
+	flag.Var(&ruleTolerances, "tolerance", "tolerance of a metric for the benchmarks matching a regexp, as BenchmarkBatch.*:ns=15%; may be repeated")
+}
+
+// inputs describes the inputs parsed so far, old first.
+var inputs []inputRecord
+
+// archFactors holds the factors given by -normalize-arch.
+var archFactors map[string]float64
+
+// percentPrec is the number of decimals set by -delta-prec, -1 for auto.
+var percentPrec = 2
+
+// changedMetrics holds the canonical names of the metrics listed by
+// -changed-metric, nil if it is not set.
+var changedMetrics map[string]bool
+
+// gateOnly is the canonical name of the metric given by -gate-metric.
+var gateOnly string
+
+// ungated holds the canonical names of the metrics listed by -ungated.
+var ungated map[string]bool
+
+// selectedMetrics holds the canonical names of the metrics listed by
+// -metrics, nil if it is not set.
+var selectedMetrics map[string]bool
+
 const usageFooter = `
 Each input file should be from:
 	go test -run=NONE -bench=. > [old,new].txt
+Either file, but not both, may be - to read it from stdin.
+Either may also be a comma-separated list of files, as in
+old1.txt,old2.txt, whose results are merged as if they were run one
+after the other, for the runs sharded across machines.
 
-benchdiff compares old and new for each benchmark.
+benchdiff compares old and new for each benchmark. Each table ends with a
+[geomean] line: the geometric means of the old and new values and of their
+ratios, over the benchmarks measuring the metric with nonzero values.
 
 If -test.benchmem=true is added to the "go test" command
 benchdiff will also compare memory allocations.
+
+Custom metrics reported with b.ReportMetric, as "12 items/op", get one
+more table per unit reported on both sides, after the standard ones, and
+a "custom" object by unit with -format=json. Units reported on one side
+only are ignored with a warning. Custom metrics are not gated by
+-errdelta, and not being known as better higher or lower, neither
+improve nor regress, unless given a tolerance with -tunit=unit:tolerance,
+as -tunit=req/s:5,p99-ns:10 (percentages, as the tolerance flags). Gated
+units are taken as better higher when they end in /s, as req/s, and as
+better lower otherwise, for -regressonly, -exitcode and the colors.
+
+Input files may also be gzip compressed, 'go test -json' event streams,
+markdown documents with the output in fenced code blocks, or ANSI-colored
+text. By default (-input-format=auto) the format is detected from the
+first bytes of each file, checking in this order: gzip magic number,
+leading '{' or '[' (JSON events), a line starting with backticks
+(markdown), any ESC byte (ANSI colors), and otherwise plain text.
+Files named *.gz are always decompressed, and gzip content is detected
+again once decompressed. The output of the JSON events is read package
+by package, so that go test -json ./... running packages in parallel
+needs no conversion to text first.
+
+An input may also be an http:// or https:// URL, downloaded with the
+bearer token of $BENCHDIFF_FETCH_TOKEN if set, sent over https only, or
+a file in a .zip, .tar.gz, .tgz or .tar archive, local or downloaded,
+named after a '#'. Downloads and archive files are read up to 1 GiB:
+	benchdiff https://ci.example.com/artifacts/old.txt new.txt
+	benchdiff artifacts.zip#bench/old.txt new.txt
+
+When the results span several packages, as those of go test -bench=.
+./..., the benchmarks are told apart by the "pkg:" line before them:
+their names are qualified with their package, as
+example.com/a.BenchmarkX-8, so that they are only matched, aggregated
+and gated within their package, and the tables are printed package by
+package after a "pkg:" heading, without the package in the names. The
+other output formats use the qualified names. The -match, -exclude,
+-filter, -rename and -tolerance entries and the lines of the -thresholds
+and -accepted files match a name with or without its package, a line
+naming the package taking precedence.
+
+With -since=ref no file is given: benchdiff checks out ref in a temporary
+git worktree, runs -benchcmd there to get the old results, then runs it
+in the current directory to get the new ones. Uncommitted changes are
+part of the new side and the working tree is never modified. With
+-until=ref2 the new results come from ref2, checked out in another
+temporary worktree, so that two commits are compared without touching
+the working tree:
+
+	benchdiff -since=v1.2.0 -until=HEAD -benchcmd='go test -run=NONE -bench=. -count=5 ./...'
+
+-run-new reads the old results from old.txt and gets the new ones by
+running -benchcmd in the current directory. With -run-new or -since,
+-confirm-runs=n guards against noise: when the comparison fails, the new
+side is run n more times and benchdiff fails only if the comparison of
+the old results with a majority of the reruns fails too.
+
+-watch is for iterating on an optimization: it runs -benchcmd in the
+current directory, keeps the results as the old side, and runs it again
+whenever a file below the directory changes, hidden ones excepted,
+redrawing the tables comparing the new results with the first run. A
+failing run, as when the code does not build, is reported and the files
+watched again. Narrow -benchcmd to the benchmarks of interest:
+
+	benchdiff -watch -benchcmd='go test -run=NONE -bench=Parse -count=3 .'
+
+-save-baseline=dir keeps the new results in the store dir, as
+dir/branch/time-commit.txt for the current git branch and commit, and
+-baseline=dir reads the old results from the latest baseline of the
+branch instead of old.txt. With -baseline-last=n the old side is the
+mean of the last n baselines, a rolling average of the history, unless
+-best, -worst, -median, -pctile or -agg selects otherwise.
+-baseline-branch picks the branch of both, for instance to compare a
+feature branch against the baselines of main:
+
+	go test -bench=. -count=5 | benchdiff -baseline=.bench -baseline-branch=main -
+
+-format=json writes an array with one object per benchmark result instead
+of the tables, in the order of the ns/op table. Each object has the name
+of the benchmark and, for ns_per_op, mb_per_s, allocs_per_op and
+bytes_per_op, the before and after values and the delta_pct percent
+change. Metrics not measured on both sides are null, as is delta_pct for
+a change from zero. With labels other than old and new, each object also
+has them as old_label and new_label, the labels of before and after.
+
+-format=markdown writes the tables as GitHub flavored markdown tables,
+with the regressions in bold and the improvements in italics.
+
+-format=csv writes one record per benchmark and metric, after a
+"benchmark,metric,old,new,delta_pct" header, in the order of the ns/op
+table. Metrics are named ns, mbs, allocs, bytes, or by the unit of a
+custom metric; values are unrounded and delta_pct is empty for a change
+from zero. The records hidden from the tables by -changed or -noise are
+left out.
+
+-format=benchstat writes the tables in the layout of benchstat, for the
+tools reading its output: per metric (time/op, speed, alloc/op,
+allocs/op), the mean of the results of each side with their largest
+deviation from it, as "148ns ± 2%", the change of the means, and the
+p-value of the Mann-Whitney U-test with the number of results on each
+side. The change is "~" when not significant at -alpha, 0.05 by
+default, or when a side has a single result. Unless a flag selects
+another, the tolerances are checked against the means.
+
+-format=junit writes a JUnit XML report, which Jenkins and GitLab show
+per test: each benchmark is a test case, failing when one of its
+metrics exceeds its tolerance as -errdelta gates it, the comparison of
+its metrics in its output. Without -errdelta every case passes.
+
+-format=html writes a standalone HTML report instead, readable in any
+browser: one table per metric, sorted by clicking on a column header,
+with the regressions in red, the improvements in green and a bar
+proportional to each change. The rows are those of the text tables.
+
+-template=file renders the comparison through the text/template in file
+instead, for the formats benchdiff has no built-in support for, as chat
+messages or wiki markup. The template is executed with:
+
+	.Old, .New      the labels of the sides
+	.Metrics        one entry per metric measured, in table order, with
+	                .Name, .Unit, the counts .Regressed, .Improved and
+	                .Unchanged, the .Rows of the table and the .Geomean
+	                row, nil if none
+	.Violations     the messages of the tolerance violations
+	.Pass           whether -errdelta passes
+
+where a row has the .Name of the benchmark, the .Before, .After and
+.Percent numbers, the .BeforeText, .AfterText and .Change cells of the
+table, and the .Regressed, .Improved and .Exceeded booleans:
+
+	{{range .Metrics}}{{.Unit}}: {{.Regressed}} regressed
+	{{range .Rows}}{{if .Exceeded}}- {{.Name}} {{.Change}}
+	{{end}}{{end}}{{end}}
+
+-unified prints a single table instead of one block per metric: each
+benchmark has one row with the old value, the new value and the change
+of every metric, so that its time and allocation changes read side by
+side. The rows are sorted like the ns/op table, and the metrics a
+benchmark did not measure are n/a.
+
+-groupby=param pivots the table-driven benchmarks on the sub-benchmark
+parameter param: the benchmarks differing only by the value of param,
+as BenchmarkEncode/size=1K and BenchmarkEncode/size=1M for -groupby=size,
+have a heading line with the geomean of their changes followed by one
+row per value, so that how the changes scale with the parameter reads
+at a glance. The other benchmarks are listed after the groups:
+
+	benchmark                    old ns/op     new ns/op     delta
+	BenchmarkEncode-8 by size                                +12.50%
+	  size=1K                    1200          1210          +0.83%
+	  size=1M                    980000        1230000       +25.51%
+
+-tree displays ns/op as a tree of the '/' separated benchmark names.
+Rows ending with '/' are branches; their delta is the geometric mean of
+the changes of all the benchmarks below them.
+
+-tui explores large comparisons: it prints the tables, reads commands
+from stdin, one per line, and redraws them after each one. "sort key"
+sorts the rows as -sort=key, "filter regexp" keeps the matching
+benchmarks ("filter" alone all of them), "metric name" hides or shows
+the block of a metric, "show name" lists the individual samples of a
+benchmark on both sides, "help" lists the commands and "quit" leaves.
+
+-self-noise compares two runs of the same code and reports, per metric,
+the median, 90th percentile and maximum absolute change, which is the
+noise of the benchmarks. With -noise-budget=pct it fails if any change
+is above pct percent.
+
+-stability=points adds, after the tables, a block comparing the ns/op
+coefficient of variation (standard deviation over mean) of the
+benchmarks repeated on both sides of the old and the new results, with
+their number of samples. Those whose variation rose by more than points
+percentage points, as from 1% to 8% with -stability=5, are marked
+"(unstable)" and listed in a warning: a benchmark becoming noisy with an
+unchanged mean is often the first sign of contention. -stability=0
+marks any rise. With -changed only the unstable benchmarks are listed.
+
+-rollup-mismatch handles benchmarks split into sub-benchmarks on one side
+only: BenchmarkX is compared with the aggregate of BenchmarkX/a,
+BenchmarkX/b... (mean ns/op and MB/s, summed allocs/op and bytes/op).
+Such rows are marked with "(rollup)".
+
+-trim-outliers=k discards, before any comparison, the results of repeated
+benchmarks more than k median absolute deviations away from their median
+in any metric. When a benchmark has as many results on both sides, the
+results of the same rank in parse order are discarded together, so that
+the sides keep the same size; otherwise each side is trimmed on its own.
+Without a selection flag the first result left is then compared.
+-outliers=method chooses how: with iqr:k a result is an outlier beyond
+k interquartile ranges below the first or above the third quartile (k
+is 1.5 for iqr alone, Tukey's fences), with stddev:k more than k
+standard deviations away from the mean, and with mad:k as with
+-trim-outliers=k. The outliers are discarded before -best, -worst,
+-median, -pctile or -agg select or aggregate the results, so that one
+GC pause or noisy neighbor in ten runs does not skew the comparison.
+
+The absolute tolerances -absnsop, -absmbs, -absallocop and -absbop set a
+floor under the percent tolerances: with -absnsop=2, a change of ns/op
+fails only if it exceeds -tnsop and is larger than 2 ns/op. The tolerance
+flags also take them with a unit: -tnsop=200ns (or 1.5µs, 2ms) is
+-absnsop=200, and likewise -tmbs=5MB/s, -tallocop=2allocs and -tbop=64B,
+while -tnsop=5 and -tnsop=5% are percentages.
+
+With -errdelta the whole comparison is printed before benchdiff exits
+with status 1, listing on stderr every benchmark and metric whose change
+exceeded its tolerance.
+
+By default -errdelta fails on the increases beyond tolerance, an increase
+of MB/s included. -fail-on picks the direction instead: regression (the
+same as -regressonly) fails only on changes for the worse, higher ns/op,
+allocs/op or bytes/op and lower MB/s; improvement only on changes for the
+better, to catch suspicious speedups; any on changes beyond tolerance
+either way.
+
+The changes from zero have no meaningful percent: they show as "+∞",
+or as "new alloc" for the allocs/op and B/op of a benchmark that did
+not allocate. -fail-on-new-allocs makes -errdelta fail on such
+allocs/op changes whatever the tolerances, -ungated, -gate-metric and
+-fail-on say, to keep the allocation-free benchmarks free of
+allocations while gating the others loosely:
+
+	benchdiff -errdelta -gate-metric=ns -tnsop=5 -fail-on-new-allocs old.txt new.txt
+
+-old-profile and -new-profile, the CPU profiles captured along the two
+runs with go test -cpuprofile, tell where the time went: for each
+benchmark beyond its tolerance, benchdiff prints after the tables the
+top functions diverging between the profiles under the benchmark
+function, as listed by go tool pprof -top -diff_base. They need the
+text output, without -format or -template:
+
+	go test -run=NONE -bench=. -cpuprofile=old.prof > old.txt
+	go test -run=NONE -bench=. -cpuprofile=new.prof > new.txt
+	benchdiff -errdelta -tnsop=5 -old-profile=old.prof -new-profile=new.prof old.txt new.txt
+
+-exitcode tells the result in the exit status instead: benchdiff exits
+with status 0 if nothing regressed, and 3 if some metric changed for the
+worse (higher ns/op, allocs/op or bytes/op, lower MB/s) by more than its
+tolerance, 0 by default. The tolerance flags apply as with -errdelta, but
+improvements never count. Status 1 is left to the errors, including the
+-errdelta failures, and 2 to the usage errors.
+
+-out-summary=file writes the verdict to file as JSON whatever the
+output format, for the CI steps acting on it: "pass" is false when
+-errdelta or -strict fails or -exitcode finds a regression, and
+"violations" lists the benchmark, metric, before and after values and
+delta_pct of each change beyond its tolerance:
+
+	{"pass": false, "violations": [{"benchmark": "BenchmarkA",
+	 "metric": "ns/op", "before": 100, "after": 110, "delta_pct": 10}]}
+
+-q keeps the CI logs short: with -errdelta or -exitcode, the tables
+show only the rows beyond their tolerance, and no warning is printed.
+-v prints more instead: the effective settings, the parsed inputs, the
+benchmarks skipped by -match, -exclude and -filter, and the ns/op of
+the repeated results of each benchmark before one is selected or they
+are aggregated.
+
+-old-label and -new-label, or their synonyms -oldname and -newname, replace
+"old" and "new" in the headers of every block, as in -oldname main
+-newname feature-x.
+
+Given more than two files, benchdiff compares the first one with each of
+the others: every block has the old column followed, for each new file,
+by its values and their change against the old ones, labeled new1, new2...
+(the file names with -auto-label). Only the results found the same number
+of times in every file are compared, the others are reported as with
+-showunmatched. The tables honor -best, -worst, -median, -pctile, -agg,
+-changed, -filter, -match, -exclude and -stripcpu; gating, sorting, the
+other output modes and the preparation of the results by -rename,
+-fuzzy, -no-merge, -normalize-arch, -trim-outliers, -outliers,
+-rollup-mismatch and -noise need two files.
+
+-trend shows how the ns/op of each benchmark evolved across the files,
+given in order, as a series of nightly runs: one row per benchmark found
+in every file, with its ns/op in the first and the last file, the change
+between them and a sparkline of all its values, to spot the slow drifts
+no pairwise comparison shows. It honors the same flags as the comparison
+of more than two files, -changed keeping the benchmarks changing in some
+file:
+
+	benchmark     first ns/op     last ns/op     delta      trend
+	BenchmarkA    200             260            +30.00%    ▁▃▆█
+
+-scale displays each ns/op column in the largest unit, among ns, µs, ms
+and s, in which its smallest value is at least 1, as in "old ms/op", with
+three significant digits. -prec=n shows the ns/op values with n decimals
+instead. The changes are always computed on the unrounded values.
+
+-ratio adds to the ns/op tables a ratio column telling the speedup as
+a multiple, the old time by the new one, which reads better than the
+percentages for the large wins: a -75% change is "4.00x faster", and a
++20% one "1.20x slower". -multiple-prec sets its decimals.
+
+-show-abs follows each change with the absolute difference, in the unit
+of the values, for the capacity planning the percentages do not tell:
+
+	benchmark      old ns/op     new ns/op     delta
+	BenchmarkA     400           534           +33.50% (+134ns)
+
+-summary replaces the tables with one line per metric counting the
+benchmarks that regressed, improved or did not change (within -epsilon),
+followed by the worst regression, the best improvement and the geomean
+of the changes, as in
+
+	ns/op     1 regressed     2 improved     0 unchanged     worst: BenchmarkB +1.48%     best: BenchmarkA -3.38%     geomean: -0.77%
+
+-summary-footer prints these lines after the tables instead, for the
+headline numbers under the details.
+
+-sort=key orders the rows of every block by key instead of parse order:
+name sorts them alphabetically, delta by magnitude of change (as -mag does,
+largest first), old and new by decreasing old or new value of the metric of
+the block. -reverse reverses any of these orders. The key may also be a
+metric, ns, mbs, allocs or bytes, to sort every block by the magnitude
+of the change of that metric, as the ns/op block by the allocs/op
+changes with -sort=allocs. A ":asc" or ":desc" suffix sets the direction,
+as -sort=name:desc or -sort=allocs:asc.
+
+-showunmatched lists after the tables the benchmarks found only in the old
+file (removed) and only in the new file (added), instead of warning about
+them on stderr. -format=json and -format=csv always include the
+benchmarks that could not be compared: in JSON as objects whose metrics
+are null and whose "warning" holds the message and the numbers of
+results of both sides, in CSV as records of the "unmatched" metric with
+the numbers of results as the old and new values. -strict fails, after
+printing the comparison, if a benchmark of the old file is missing from
+the new one, so that deleting a benchmark by accident does not go
+unnoticed.
+
+-changed hides the benchmarks whose changes are all within -epsilon
+percent, 0.005 by default: those showing as +0.00% or -0.00% with the
+default -delta-prec. Changes from or to zero always count. -epsilon=0
+hides only the exact ties.
+
+-metrics=list compares only the listed metrics, as -metrics=allocs,bytes
+for the allocation tables without the timing ones: the other metrics are
+neither printed, in any format, nor gated. The list takes ns, mbs,
+allocs, bytes, nsalloc, which implies -ns-per-alloc, and the units of
+custom metrics. The ns/op geomeans of -summary, -both, -badge and -trend
+are unaffected.
+
+-changed-metric=list scopes -changed to the blocks of the listed metrics:
+with -changed-metric=allocs, the allocs/op block shows only the benchmarks
+whose allocs/op changed while the other blocks show all of them.
+
+-noise=pct hides the rows of the tables whose change is below pct percent
+either way, as -changed hides the unchanged ones. Hidden rows are still
+gated by -errdelta and counted in the [geomean] lines.
+
+-threshold=pct, as -threshold=3%, takes the changes below pct percent
+either way for noise: they are displayed as "~" and count as unchanged
+everywhere, for -changed, -summary and the colors, and are never gated
+by -errdelta or -exitcode. Changes from or to zero always count.
+
+Without -best, -worst, -median, -pctile or -agg, a benchmark having
+several results in a file, as with go test -count, is compared on the
+first one, with a warning naming it.
+
+-worst compares the slowest result of each benchmark, to bound the worst
+case latency as -best gives the optimistic view.
+
+-median compares the result with the median ns/op of each benchmark
+instead of its best one. With an even number of results, the lower of
+the two middle results is compared.
+
+-pctile=p compares instead the result at percentile p of the ns/op of each
+benchmark, by nearest rank: of n results, the one of rank ceil(p/100*n)
+once sorted by ns/op, with its other metrics. -pctile 90 compares the
+tail latencies, -pctile 50 is -median and -pctile 100 the slowest result.
+
+-agg=mode collapses the results of each benchmark before comparing them:
+min and max are -best and -worst, median is -median, pN as p50, p90 or
+p99 is -pctile=N, to gate on the tail latencies, and mean compares
+the mean of each metric over the results, rounded to integers for
+allocs/op and B/op. A metric is compared on the mean only if every result
+measured it.
+
+-alpha=level runs a significance test on each metric of the benchmarks
+having at least two results on each side, and marks with "~" the changes
+whose p-value is above level, which are not significant. It needs all
+the results, so it cannot be combined with -best, -worst, -median,
+-pctile or -agg. -stats selects the test: welch, the default, for
+Welch's t-test on the means, or utest for the Mann-Whitney U-test on the
+ranks, which does not assume normally distributed results and copes
+better with outliers. The U-test is exact without ties, and uses the normal
+approximation with a tie correction otherwise.
+
+-min-effect-size=d gates the ns/op of the benchmarks having at least two
+results on each side on their effect size, Cohen's d: the difference of
+the mean ns/op divided by the pooled standard deviation. A benchmark fails
+when it got slower by more than d deviations, whatever its percent
+change. Benchmarks with a single result are still gated by -tnsop.
+
+-thresholds=file sets tolerances per benchmark and per metric, one
+benchmark per line, as in "BenchmarkHot: ns=3%, allocs=0". A name without
+its -GOMAXPROCS suffix applies to every suffix, and a line with the suffix
+takes precedence. Metrics not listed for a benchmark use the tolerance
+flags. Lines starting with '#' are comments.
+
+-tolerance=regexp:metric=tol sets the tolerance of metric for the
+benchmarks whose whole name, with or without its -GOMAXPROCS suffix,
+matches regexp. It may be repeated, the last matching rule winning, and
+takes precedence over -thresholds and the tolerance flags:
+
+	benchdiff -errdelta -tnsop=5 -tolerance='BenchmarkHotPath:ns/op=2%' \
+		-tolerance='BenchmarkBatch.*:ns/op=15%' old.txt new.txt
+
+-policy=expr gates each benchmark on an expression combining the changes
+of its metrics, for the rules no single tolerance expresses, instead of
+the tolerance flags, -thresholds and -tolerance. A comparison "metric op
+value" holds the percent change of the metric against value when it ends
+with '%', and its absolute change, in the unit of the metric, otherwise;
+op is one of <, <=, >, >=, == and !=. Comparisons combine with &&, ||,
+! and parentheses, && binding tighter than ||, and a comparison of a
+metric not measured on both sides holds. With -metrics, the expression
+may only name the metrics it selects. The metrics of the failing
+comparisons of a benchmark are its violations:
+
+	benchdiff -errdelta -policy='ns/op < +5% && allocs/op <= 0 && bytes/op < +10%' old.txt new.txt
+
+-comparator=metric:name,... computes the changes of the listed metrics
+with the comparators registered under name with the RegisterComparator
+function of github.com/chavacava/benchdiff/pkg/benchdiff, in place of
+the percent change: percent, ratio (new/old), logratio (its natural
+logarithm) and diff (new - old) are built in, as -comparator=ns:logratio.
+A comparator that is also a benchdiff.Judge decides the -errdelta gate
+of its metrics instead of their tolerances, and cannot be used with
+-policy. Other comparators are built into benchdiff by a file of its
+main package, under a build tag, blank importing the package
+registering them:
+
+	// +build mycomparators
+
+	package main
+
+	import _ "example.com/perf/comparators"
+
+and built with go build -tags mycomparators.
+
+-lock=file checks a single run against the values locked in file, as a
+lock file committed with the code, rather than against another run:
+	benchdiff -lock=bench.lock new.txt
+Each line "name: metric=min..max, ..." allows a benchmark the values of
+its metrics in [min, max], naming them as -ungated or by the unit of a
+custom metric. A table lists every locked value with its status, ok,
+below, above or missing, and benchdiff fails if any is not ok; the
+benchmarks not in the file are listed in a warning. Repeated results
+are averaged, unless -best, -worst, -median, -pctile or -agg selects
+another. -update-lock rewrites the file from the run instead, every
+value allowed -lock-slack percent, 10 by default, either way, allocs/op
+and bytes/op rounded out to whole numbers; edit the ranges to tighten
+or loosen them. With -metrics, only the values of the metrics it
+selects are checked and written. With -run-new no file is given, the
+run being -benchcmd in the current directory.
+
+-config=file reads default settings from file, .benchdiff.conf by
+default, ignored if it does not exist. Each line "name = value" sets the
+flag -name, unless it is given on the command line, which takes
+precedence; the other lines are per benchmark tolerances in the format
+of -thresholds, whose file overrides them. Blank lines and lines
+starting with '#' are ignored:
+
+	# .benchdiff.conf
+	errdelta = true
+	tnsop = 5
+	exclude = /large
+	sort = delta
+	BenchmarkHot: ns=2%, allocs=0
+
+Old and new benchmarks are matched by name. With -correlate=base the
+-GOMAXPROCS suffix is ignored, so BenchmarkX-4 is compared with
+BenchmarkX-8, and with -correlate=fold the case of the names is ignored.
+-stripcpu, or its synonyms -ignoreprocs and -normalize-procs, is a
+shorthand for -correlate=base, to compare runs made with different
+GOMAXPROCS, as BenchmarkX/size=1024-4 with BenchmarkX/size=1024-8. When
+the suffixes of the old and new names differ, benchdiff warns that they
+were run with a different parallelism, suggesting -normalize-procs if
+the names are matched whole. A trailing number is
+only taken for the suffix when it follows a character other than '=',
+'/' or '-', so BenchmarkX/size=8 keeps its name.
+Rows show the old names. -filter=regexp then keeps only the matched
+benchmarks whose name matches regexp, in every table and for -errdelta.
+
+-match=regexp and -exclude=regexp select the benchmarks before they are
+matched: only the benchmarks whose name matches -match and does not
+match -exclude are correlated, compared, gated by -errdelta and reported
+as unmatched, on either side. -exclude takes precedence, so -match=Encode
+-exclude=/large compares the Encode benchmarks except their large cases. The
+names are the ones of the inputs, with their -GOMAXPROCS suffix.
+
+-rename=old=new compares the old benchmark named old with the new one
+named new, for the benchmarks renamed between the runs; it may be
+repeated. A name without its -GOMAXPROCS suffix renames every suffix,
+and the rows show the new names. -fuzzy=s goes further and compares
+each benchmark found on one side only with the one of the other side
+whose name is the most similar, if their similarity, one minus their
+edit distance over the length of the longest name, is at least s, as
+-fuzzy=0.8. Every such guess is reported on stderr. Both apply after
+-match and -exclude.
+
+The results of a benchmark appearing several times in an input, as when
+concatenating the outputs of several runs, are merged as repeated results
+of that benchmark. With -no-merge, each run of consecutive results is
+kept apart instead: the second one of BenchmarkX is compared as
+BenchmarkX#2 with the second one of the other side, and so on.
+
+benchdiff warns when the goos, goarch, pkg or cpu lines printed by go
+test before the results differ between old and new, as the changes of
+results from different machines or packages mean little; -strict-env
+fails instead. The lines missing from a side are not compared, and
+goarch is not with -normalize-arch.
+
+-normalize-arch=amd64=1,arm64=1.3 allows rough comparisons across
+architectures: the ns/op of each side is multiplied by the factor of the
+goarch reported in its header before comparing. Such comparisons are only
+approximate and benchdiff warns when the architectures differ.
+
+-echo writes the new benchmark results to stdout as canonical benchmark
+lines, in their original order, so that benchdiff can sit in the middle
+of a pipeline. The comparison then goes to stderr; files written by
+-badge, -improvements-out and -regressions-out are not affected.
+
+-color=always colors the delta of each table row: red for changes for the
+worse, green for changes for the better, lower values being better except
+for MB/s. The unchanged rows, within -epsilon, are dimmed. -color=auto
+does so only when the output is a terminal, on the platforms where
+benchdiff can tell. -budget-color takes precedence for the metrics it
+colors.
+
+-budget-color colors the delta of the gated changes by the share of their
+tolerance they use: green below 80%, yellow from 80%, red above the
+tolerance.
+
+-accepted=file lists known regressions that -errdelta must not fail on,
+one per line: a benchmark name, with or without its -GOMAXPROCS suffix,
+a metric and an optional reason, as in "BenchmarkHot allocs caching".
+Accepted regressions are still displayed and reported on stderr with
+their reason; entries that no longer exceed their tolerance are reported
+as stale.
+
+-improvements-out=file and -regressions-out=file write the tables again,
+keeping only the changes for the better or for the worse: lower values
+are better except for MB/s. The main output is not affected.
+
+-chart=file.svg writes a horizontal bar chart of the -chart-top largest
+ns/op changes, labeled with the benchmark names: regressions go right in
+red, improvements go left in green. The chart is -chart-width pixels
+wide, which must leave room for the longest benchmark name. Only SVG is
+supported.
+
+-github is for the workflows of GitHub Actions. It appends the summary
+of -step-summary to $GITHUB_STEP_SUMMARY, writes an error annotation per
+tolerance violation of -errdelta to stderr and, on a pull request event
+with $GITHUB_TOKEN set, posts the summary as a comment of the pull
+request, updating the comment of the previous runs instead of adding
+one. Failing to post the comment is only a warning.
+
+-push-url=url POSTs the comparison to url, for the dashboards keeping
+the history of the benchmarks, with the token of $BENCHDIFF_PUSH_TOKEN,
+if set, as a bearer token. -push-format picks the encoding: json, the
+array of -format=json; influx, the InfluxDB line protocol, one
+"benchdiff" point per benchmark and metric tagged with both, with the
+old, new and delta_pct fields; or prometheus, the text format of a
+pushgateway, with the benchdiff_old, benchdiff_new and
+benchdiff_delta_pct gauges labeled likewise:
+
+	benchdiff -push-url=http://pushgateway:9091/metrics/job/benchdiff \
+		-push-format=prometheus old.txt new.txt
+
+Failing to push is only a warning.
+
+-badge=file writes an SVG badge reading "perf: <delta> geomean", where
+delta is the geometric mean of the new/old ns/op ratios as a percent
+change. The badge is red when that mean is above 1 (slower), green
+otherwise, and gray with "n/a" when no ns/op was compared.
 `
 
 func main() {
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "usage: %s old.txt new.txt\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "usage: %s old.txt new.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s old.txt new1.txt new2.txt...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -run-new old.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -since=ref [-until=ref]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -baseline=dir new.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -watch\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -lock=file [-update-lock] new.txt\n\n", os.Args[0])
 		flag.PrintDefaults()
 		fmt.Fprint(os.Stderr, usageFooter)
 		os.Exit(2)
 	}
 	flag.Parse()
-	if flag.NArg() != 2 {
+	configTolerances, err := readConfig(*configF)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-config: %v\n", err)
+		os.Exit(2)
+	}
+	switch {
+	case *lockFile != "" && (*since != "" || *baseline != "" || *watchMode || *trendMode):
+		fmt.Fprint(os.Stderr, "-lock cannot be used with -since, -baseline, -watch or -trend\n")
+		os.Exit(2)
+	case *lockFile != "" && *runNew && flag.NArg() == 0, *lockFile != "" && !*runNew && flag.NArg() == 1:
+		// the results checked against the lock file
+	case *lockFile != "":
+		flag.Usage()
+	case *since != "" && *runNew:
+		fmt.Fprint(os.Stderr, "-since and -run-new cannot be used together\n")
+		os.Exit(2)
+	case *since != "" && *baseline != "":
+		fmt.Fprint(os.Stderr, "-since and -baseline cannot be used together\n")
+		os.Exit(2)
+	case *since != "" && flag.NArg() != 0,
+		*baseline != "" && *runNew && flag.NArg() != 0,
+		*baseline != "" && !*runNew && flag.NArg() != 1,
+		*baseline == "" && *runNew && flag.NArg() != 1,
+		*watchMode && flag.NArg() != 0,
+		*baseline == "" && *since == "" && !*runNew && !*watchMode && flag.NArg() < 2:
 		flag.Usage()
 	}
-
-	if !*failOnDelta && (*tAllPerOp+*tBPerOp+*tMbPerS+*tNsPerOp) > 0 {
-		fmt.Fprint(os.Stderr, "tolerances flags are only valid when -errdelta is true\n")
+	if *watchMode {
+		rejectExclusions("-watch", []exclusion{
+			{"-since", *since != ""},
+			{"-run-new", *runNew},
+			{"-baseline", *baseline != ""},
+			{"-save-baseline", *saveBase != ""},
+			{"-errdelta", *failOnDelta},
+			{"-exitcode", *exitCode},
+			{"-format", *outFormat != formatText},
+			{"-confirm-runs", *confirmRuns > 0},
+			{"-echo", *echo},
+			{"-out-summary", *verdictF != ""},
+		})
+	}
+	if *quiet && *verbose {
+		fmt.Fprint(os.Stderr, "-q and -v cannot be used together\n")
+		os.Exit(2)
+	}
+	if *quiet && !*failOnDelta && !*exitCode {
+		fmt.Fprint(os.Stderr, "-q is only valid when -errdelta or -exitcode is true\n")
+		os.Exit(2)
+	}
+	if (*oldProfile == "") != (*newProfile == "") {
+		fmt.Fprint(os.Stderr, "-old-profile and -new-profile must be given together\n")
+		os.Exit(2)
+	}
+	if *oldProfile != "" && !*failOnDelta {
+		fmt.Fprint(os.Stderr, "-old-profile and -new-profile are only valid when -errdelta is true\n")
+		os.Exit(2)
+	}
+	if *oldProfile != "" && (*outFormat != formatText || *templateF != "") {
+		fmt.Fprint(os.Stderr, "-old-profile and -new-profile print text after the tables and cannot be used with -format or -template\n")
+		os.Exit(2)
+	}
+	if *baseLast < 1 {
+		fmt.Fprint(os.Stderr, "-baseline-last must be at least 1\n")
+		os.Exit(2)
+	}
+	stdins := 0
+	for _, arg := range flag.Args() {
+		for _, path := range splitShards(arg) {
+			if path == stdinPath {
+				stdins++
+			}
+		}
+	}
+	if stdins > 1 {
+		fmt.Fprint(os.Stderr, "only one of old.txt and new.txt can be - (stdin)\n")
+		os.Exit(2)
+	}
+	if *summaryOnly && (*outFormat != formatText || *treeMode || *interleave || *unified || *selfNoise) {
+		fmt.Fprint(os.Stderr, "-summary cannot be used with -format, -tree, -interleave, -unified or -self-noise\n")
+		os.Exit(2)
+	}
+	if _, ok := pushEncoders[*pushFormat]; !ok {
+		fmt.Fprintf(os.Stderr, "-push-format: unknown format %q, want one of %s\n", *pushFormat, strings.Join(pushEncoderNames(), ", "))
+		os.Exit(2)
+	}
+	if *tuiMode && (*failOnDelta || *exitCode || *outFormat != formatText || *summaryOnly || *summaryFoot || *treeMode || *interleave || *unified || *groupBy != "" || *selfNoise || *watchMode || *echo || stdins > 0) {
+		fmt.Fprint(os.Stderr, "-tui cannot be used with -errdelta, -exitcode, -format, -summary, -summary-footer, -tree, -interleave, -unified, -groupby, -self-noise, -watch, -echo or a - (stdin) input\n")
+		os.Exit(2)
+	}
+	if *templateF != "" {
+		rejectExclusions("-template", []exclusion{
+			{"-format", *outFormat != formatText},
+			{"-summary", *summaryOnly},
+			{"-summary-footer", *summaryFoot},
+			{"-tree", *treeMode},
+			{"-interleave", *interleave},
+			{"-unified", *unified},
+			{"-groupby", *groupBy != ""},
+			{"-both", *bothModes},
+			{"-stability", stabilityShown()},
+			{"-tui", *tuiMode},
+		})
+		if outTemplate, err = readTemplate(*templateF); err != nil {
+			fmt.Fprintf(os.Stderr, "-template: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *groupBy != "" && (*summaryOnly || *outFormat != formatText || *treeMode || *interleave || *unified) {
+		fmt.Fprint(os.Stderr, "-groupby cannot be used with -summary, -format, -tree, -interleave or -unified\n")
+		os.Exit(2)
+	}
+	if *summaryFoot && (*summaryOnly || *outFormat != formatText) {
+		fmt.Fprint(os.Stderr, "-summary-footer cannot be used with -summary or -format\n")
+		os.Exit(2)
+	}
+	if *trendMode && (flag.NArg() < 2 || *since != "" || *baseline != "" || *runNew) {
+		fmt.Fprint(os.Stderr, "-trend needs at least two files\n")
+		os.Exit(2)
+	}
+	if flag.NArg() > 2 || *trendMode {
+		rejectExclusions("comparing more than two files or -trend", []exclusion{
+			{"-errdelta", *failOnDelta},
+			{"-exitcode", *exitCode},
+			{"-format", *outFormat != formatText},
+			{"-tree", *treeMode},
+			{"-interleave", *interleave},
+			{"-unified", *unified},
+			{"-groupby", *groupBy != ""},
+			{"-tui", *tuiMode},
+			{"-template", *templateF != ""},
+			{"-both", *bothModes},
+			{"-stability", stabilityShown()},
+			{"-self-noise", *selfNoise},
+			{"-strict", *strictNames},
+			{"-summary", *summaryOnly},
+			{"-summary-footer", *summaryFoot},
+			{"-out-summary", *verdictF != ""},
+			{"-push-url", *pushURL != ""},
+			{"-mag", *magSort},
+			{"-sort", *sortKey != sortParse},
+			{"-rename", len(renameFlag) > 0},
+			{"-fuzzy", *fuzzyMin > 0},
+			{"-no-merge", *noMerge},
+			{"-normalize-arch", *archList != ""},
+			{"-trim-outliers", *trimK > 0},
+			{"-outliers", *outliersF != ""},
+			{"-rollup-mismatch", *rollup},
+			{"-noise", *noiseFloor > 0},
+		})
+	}
+	if *confirmRuns > 0 && *since == "" && !*runNew {
+		fmt.Fprint(os.Stderr, "-confirm-runs is only valid with -run-new or -since\n")
+		os.Exit(2)
+	}
+	if *until != "" && *since == "" {
+		fmt.Fprint(os.Stderr, "-until is only valid with -since\n")
+		os.Exit(2)
+	}
+	if *until != "" && *confirmRuns > 0 {
+		fmt.Fprint(os.Stderr, "-confirm-runs reruns the working tree and cannot be used with -until\n")
 		os.Exit(2)
 	}
-	before := parseFile(flag.Arg(0))
-	after := parseFile(flag.Arg(1))
-
-	diffs, warnings := Correlate(before, after)
 
-	for _, warn := range warnings {
-		fmt.Fprintln(os.Stderr, warn)
+	if !*failOnDelta && !*exitCode && ((*tAllPerOp+*tBPerOp+*tMbPerS+*tNsPerOp+*absAllPerOp+*absBPerOp+*absMbPerS+*absNsPerOp) > 0 || *tUnits != "" || len(ruleTolerances) > 0) {
+		fmt.Fprint(os.Stderr, "tolerances flags are only valid when -errdelta or -exitcode is true\n")
+		os.Exit(2)
+	}
+	if *epsilon < 0 {
+		fmt.Fprint(os.Stderr, "-epsilon must not be negative\n")
+		os.Exit(2)
+	}
+	if *noiseFloor < 0 {
+		fmt.Fprint(os.Stderr, "-noise must not be negative\n")
+		os.Exit(2)
+	}
+	if *stability < 0 {
+		fmt.Fprint(os.Stderr, "-stability must not be negative\n")
+		os.Exit(2)
+	}
+	if *pctile < 0 || *pctile > 100 {
+		fmt.Fprint(os.Stderr, "-pctile must be between 0 and 100\n")
+		os.Exit(2)
 	}
+	if *aggMode != "" && !validAgg(*aggMode) {
+		fmt.Fprintf(os.Stderr, "-agg must be %s, %s, %s, %s or a percentile as p90\n", aggMean, aggMedian, aggMin, aggMax)
+		os.Exit(2)
+	}
+	if selections() > 1 {
+		fmt.Fprint(os.Stderr, "-best, -worst, -median, -pctile and -agg are mutually exclusive\n")
+		os.Exit(2)
+	}
+	if *alpha != 0 && selections() > 0 {
+		fmt.Fprint(os.Stderr, "-alpha needs all the results and cannot be used with -best, -worst, -median, -pctile or -agg\n")
+		os.Exit(2)
+	}
+	if *alpha < 0 || *alpha >= 1 {
+		fmt.Fprint(os.Stderr, "-alpha must be between 0 and 1\n")
+		os.Exit(2)
+	}
+	if *statsTest != statsWelch && *statsTest != statsUTest {
+		fmt.Fprintf(os.Stderr, "-stats must be %s or %s\n", statsWelch, statsUTest)
+		os.Exit(2)
+	}
+	if !*failOnDelta && *regressOnly {
+		fmt.Fprint(os.Stderr, "-regressonly is only valid when -errdelta is true\n")
+		os.Exit(2)
+	}
+	switch *failOn {
+	case "", failOnRegression, failOnImprovement, failOnAny:
+	default:
+		fmt.Fprintf(os.Stderr, "-fail-on must be %s, %s or %s\n", failOnRegression, failOnImprovement, failOnAny)
+		os.Exit(2)
+	}
+	if !*failOnDelta && *newAllocs {
+		fmt.Fprint(os.Stderr, "-fail-on-new-allocs is only valid when -errdelta is true\n")
+		os.Exit(2)
+	}
+	if !*failOnDelta && *failOn != "" {
+		fmt.Fprint(os.Stderr, "-fail-on is only valid when -errdelta is true\n")
+		os.Exit(2)
+	}
+	if *regressOnly && *failOn != "" && *failOn != failOnRegression {
+		fmt.Fprintf(os.Stderr, "-regressonly cannot be used with -fail-on=%s\n", *failOn)
+		os.Exit(2)
+	}
+	if !*failOnDelta && *maxRegress >= 0 {
+		fmt.Fprint(os.Stderr, "-max-regressions is only valid when -errdelta is true\n")
+		os.Exit(2)
+	}
+	if *deltaPrec == "auto" {
+		percentPrec = -1
+	} else if p, err := strconv.Atoi(*deltaPrec); err == nil && p >= 0 {
+		percentPrec = p
+	} else {
+		fmt.Fprintf(os.Stderr, "-delta-prec: invalid precision %q\n", *deltaPrec)
+		os.Exit(2)
+	}
+	name, err := correlateName(*correlate, *stripCPU)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	*correlate = name
+	if key, ok := correlateKeys[*correlate]; ok {
+		correlateKey = key
+	} else {
+		fmt.Fprintf(os.Stderr, "-correlate: unknown key %q, want one of %s\n", *correlate, strings.Join(correlateKeyNames(), ", "))
+		os.Exit(2)
+	}
+	if *chartTop <= 0 {
+		fmt.Fprint(os.Stderr, "-chart-top must be positive\n")
+		os.Exit(2)
+	}
+	if *nsPrec < -1 {
+		fmt.Fprint(os.Stderr, "-prec must be a number of decimals, or -1 to adapt them to the values\n")
+		os.Exit(2)
+	}
+	if *multPrec < 0 {
+		fmt.Fprint(os.Stderr, "-multiple-prec must not be negative\n")
+		os.Exit(2)
+	}
+	if o, err := parseSortOrder(*sortKey); err != nil {
+		fmt.Fprintf(os.Stderr, "-sort: %v\n", err)
+		os.Exit(2)
+	} else if *magSort && (o.key != sortParse && o.key != sortDelta || o.metric != "") {
+		fmt.Fprintf(os.Stderr, "-mag cannot be used with -sort=%s\n", *sortKey)
+		os.Exit(2)
+	}
+	if *showUnmatch && (*outFormat == formatJSON || *outFormat == formatHTML || *outFormat == formatCSV || *outFormat == formatBenchstat || *outFormat == formatJUnit) {
+		fmt.Fprintf(os.Stderr, "-showunmatched cannot be used with -format=%s\n", *outFormat)
+		os.Exit(2)
+	}
+	switch *outFormat {
+	case formatText:
+	case formatJSON, formatMarkdown, formatHTML, formatCSV, formatBenchstat, formatJUnit:
+		rejectExclusions("-format="+*outFormat, []exclusion{
+			{"-tree", *treeMode},
+			{"-interleave", *interleave},
+			{"-unified", *unified},
+			{"-both", *bothModes},
+			{"-stability", stabilityShown()},
+			{"-self-noise", *selfNoise},
+		})
+	default:
+		fmt.Fprintf(os.Stderr, "unknown output format %q\n", *outFormat)
+		os.Exit(2)
+	}
+	if *interleave && *treeMode {
+		fmt.Fprint(os.Stderr, "-interleave and -tree cannot be used together\n")
+		os.Exit(2)
+	}
+	if *unified && (*interleave || *treeMode) {
+		fmt.Fprint(os.Stderr, "-unified cannot be used with -interleave or -tree\n")
+		os.Exit(2)
+	}
+	if *fuzzyMin < 0 || *fuzzyMin > 1 {
+		fmt.Fprint(os.Stderr, "-fuzzy must be between 0 and 1\n")
+		os.Exit(2)
+	}
+	if *clamp < 0 {
+		fmt.Fprint(os.Stderr, "-clamp must not be negative\n")
+		os.Exit(2)
+	}
+	if *clamp == 0 && *clampSort {
+		fmt.Fprint(os.Stderr, "-clamp-sort is only valid with -clamp\n")
+		os.Exit(2)
+	}
+	if *trimK < 0 {
+		fmt.Fprint(os.Stderr, "-trim-outliers must not be negative\n")
+		os.Exit(2)
+	}
+	if *trimK > 0 && *outliersF != "" {
+		fmt.Fprint(os.Stderr, "-trim-outliers and -outliers cannot be used together\n")
+		os.Exit(2)
+	}
+	if *trimK > 0 {
+		trimRule = outlierRule{outlierMAD, *trimK}
+	}
+	if *outliersF != "" {
+		if trimRule, err = parseOutlierRule(*outliersF); err != nil {
+			fmt.Fprintf(os.Stderr, "-outliers: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if !*failOnDelta && *minEffect > 0 {
+		fmt.Fprint(os.Stderr, "-min-effect-size is only valid when -errdelta is true\n")
+		os.Exit(2)
+	}
+	if !*failOnDelta && *acceptedF != "" {
+		fmt.Fprint(os.Stderr, "-accepted is only valid when -errdelta is true\n")
+		os.Exit(2)
+	}
+	if !*failOnDelta && *thresholdsF != "" {
+		fmt.Fprint(os.Stderr, "-thresholds is only valid when -errdelta is true\n")
+		os.Exit(2)
+	}
+	switch *colorMode {
+	case colorAuto, colorNever:
+	case colorAlways:
+		colorDeltas = true
+	default:
+		fmt.Fprintf(os.Stderr, "unknown -color %q\n", *colorMode)
+		os.Exit(2)
+	}
+	if !*failOnDelta && *budgetColor {
+		fmt.Fprint(os.Stderr, "-budget-color is only valid when -errdelta is true\n")
+		os.Exit(2)
+	}
+	if !*failOnDelta && *headroom {
+		fmt.Fprint(os.Stderr, "-show-headroom is only valid when -errdelta is true\n")
+		os.Exit(2)
+	}
+	if !*failOnDelta && *gateMetric != "" {
+		fmt.Fprint(os.Stderr, "-gate-metric is only valid when -errdelta is true\n")
+		os.Exit(2)
+	}
+	if !*failOnDelta && *policyExpr != "" {
+		fmt.Fprint(os.Stderr, "-policy is only valid when -errdelta is true\n")
+		os.Exit(2)
+	}
+	if !*failOnDelta && *gateBoth {
+		fmt.Fprint(os.Stderr, "-gate-both-measured is only valid when -errdelta is true\n")
+		os.Exit(2)
+	}
+	if *lockFile == "" && *updateLock {
+		fmt.Fprint(os.Stderr, "-update-lock is only valid with -lock\n")
+		os.Exit(2)
+	}
+	if *lockSlack < 0 {
+		fmt.Fprint(os.Stderr, "-lock-slack must not be negative\n")
+		os.Exit(2)
+	}
+	if *maxAge == 0 && *strictAge {
+		fmt.Fprint(os.Stderr, "-strict-age is only valid with -max-age\n")
+		os.Exit(2)
+	}
+	if !*selfNoise && *noiseBudget > 0 {
+		fmt.Fprint(os.Stderr, "-noise-budget is only valid when -self-noise is true\n")
+		os.Exit(2)
+	}
+	ungated, err = parseMetricList(*ungatedList)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-ungated: %v\n", err)
+		os.Exit(2)
+	}
+	if unitTolerances, err = parseUnitTolerances(*tUnits); err != nil {
+		fmt.Fprintf(os.Stderr, "-tunit: %v\n", err)
+		os.Exit(2)
+	}
+	if *changedList != "" {
+		if changedMetrics, err = parseMetricList(*changedList); err != nil {
+			fmt.Fprintf(os.Stderr, "-changed-metric: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *metricsList != "" {
+		if selectedMetrics, err = parseMetricSelection(*metricsList); err != nil {
+			fmt.Fprintf(os.Stderr, "-metrics: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *comparatorF != "" {
+		if metricComparators, err = parseComparators(*comparatorF); err != nil {
+			fmt.Fprintf(os.Stderr, "-comparator: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *policyExpr != "" {
+		if gatePolicy, err = parsePolicy(*policyExpr); err != nil {
+			fmt.Fprintf(os.Stderr, "-policy: %v\n", err)
+			os.Exit(2)
+		}
+		for _, name := range gatePolicy.metrics() {
+			if selectedMetrics != nil && !selectedMetrics[name] {
+				fmt.Fprintf(os.Stderr, "-policy: %s is not selected by -metrics\n", name)
+				os.Exit(2)
+			}
+		}
+		if judged := judgedMetrics(); len(judged) > 0 {
+			fmt.Fprintf(os.Stderr, "-policy cannot be used with the benchdiff.Judge comparators of %s, which gate in its place\n", strings.Join(judged, ", "))
+			os.Exit(2)
+		}
+	}
+	if *gateMetric != "" {
+		var ok bool
+		if gateOnly, ok = metricAliases[*gateMetric]; !ok {
+			fmt.Fprintf(os.Stderr, "-gate-metric: unknown metric %q\n", *gateMetric)
+			os.Exit(2)
+		}
+		if selectedMetrics != nil && !selectedMetrics[gateOnly] {
+			fmt.Fprintf(os.Stderr, "-gate-metric: %s is not selected by -metrics\n", *gateMetric)
+			os.Exit(2)
+		}
+	}
+	tolerances = configTolerances
+	if *thresholdsF != "" {
+		t, err := readThresholds(*thresholdsF)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-thresholds: %v\n", err)
+			os.Exit(2)
+		}
+		tolerances = tolerances.merge(t)
+	}
+	if *acceptedF != "" {
+		accepted, err = readAccepted(*acceptedF)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-accepted: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *archList != "" {
+		archFactors, err = parseArchFactors(*archList)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-normalize-arch: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *filterF != "" {
+		nameFilter, err = regexp.Compile(*filterF)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-filter: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *matchF != "" {
+		matchRe, err = regexp.Compile(*matchF)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-match: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *excludeF != "" {
+		excludeRe, err = regexp.Compile(*excludeF)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-exclude: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *weightBy != "" && *weightBy != weightIters {
+		fmt.Fprintf(os.Stderr, "unknown -geomean-weight %q\n", *weightBy)
+		os.Exit(2)
+	}
+	switch *inputFormat {
+	case inputAuto, inputText, inputGzip, inputJSON, inputMarkdown, inputANSI:
+	default:
+		fmt.Fprintf(os.Stderr, "unknown input format %q\n", *inputFormat)
+		os.Exit(2)
+	}
+	if *autoLabel && *since == "" && *baseline == "" {
+		deriveLabels(flag.Arg(0), flag.Arg(1))
+	}
+	if *verbose {
+		flag.VisitAll(func(f *flag.Flag) { logf("flag -%s=%s", f.Name, f.Value) })
+	}
+	if *lockFile != "" {
+		runLock(os.Stdout)
+		return
+	}
+	if *trendMode {
+		compareTrend(os.Stdout, flag.Args())
+		return
+	}
+	if flag.NArg() > 2 {
+		compareMany(os.Stdout, flag.Args())
+		return
+	}
+	if *watchMode {
+		watch(os.Stdout)
+	}
+	var before, after parse.Set
+	var oldHeader, newHeader benchHeader
+	var branch, commit string
+	if *baseline != "" || *saveBase != "" {
+		branch, commit = gitKey()
+		if *baseBranch != "" {
+			branch = *baseBranch
+		}
+	}
+	if *since != "" {
+		oldOut, newOut, err := benchSince(*since, *until, *benchCmd)
+		if err != nil {
+			fatal(err)
+		}
+		newName := "working tree"
+		if *until != "" {
+			newName = *until
+		}
+		before, oldHeader = parseData("old", *since, oldOut)
+		after, newHeader = parseData("new", newName, newOut)
+	} else {
+		newArg := 1
+		if *baseline != "" {
+			before, oldHeader = parseBaselines(*baseline, branch, *baseLast)
+			newArg = 0
+		} else {
+			for _, path := range splitShards(flag.Arg(0)) {
+				if *maxAge > 0 && path != stdinPath && !isURL(path) {
+					checkAge(path, *maxAge)
+				}
+			}
+			before, oldHeader = parseShards("old", flag.Arg(0))
+		}
+		if *runNew {
+			newOut, err := runBench(".", *benchCmd)
+			if err != nil {
+				fatal(err)
+			}
+			after, newHeader = parseData("new", "working tree", newOut)
+		} else {
+			after, newHeader = parseShards("new", flag.Arg(newArg))
+		}
+	}
+	if *saveBase != "" {
+		path, err := saveBaseline(*saveBase, branch, commit, sideText(inputs, "new"), time.Now())
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Fprintf(os.Stderr, "benchdiff: saved the new results as %s\n", path)
+	}
+	if *manifestF != "" {
+		if err := writeManifest(*manifestF, inputs); err != nil {
+			fatal(err)
+		}
+	}
+	before, after = qualifyPackages(before, after)
+	if msgs := envMismatches(oldHeader, newHeader, archFactors != nil); len(msgs) > 0 {
+		msg := "the old and new results come from different environments: " + strings.Join(msgs, ", ")
+		if *strictEnv {
+			fatal("benchdiff: -strict-env: " + msg)
+		}
+		warn("benchdiff: warning: " + msg)
+	}
+	if msg := procsMismatch(before, after); msg != "" {
+		if *correlate != "base" {
+			msg += "; -normalize-procs matches them without the -GOMAXPROCS suffix"
+		}
+		warn("benchdiff: warning: " + msg)
+	}
+	var unitWarnings []string
+	customUnits, unitWarnings = commonUnits(before, after)
+	for _, msg := range unitWarnings {
+		warn(msg)
+	}
+	if unknown := unknownSelectedMetrics(); len(unknown) > 0 {
+		fatal("benchdiff: -metrics: unknown metrics " + strings.Join(unknown, ", "))
+	}
+	if unknown := unknownComparatorMetrics(); len(unknown) > 0 {
+		fatal("benchdiff: -comparator: unknown metrics " + strings.Join(unknown, ", "))
+	}
+	if gatePolicy != nil {
+		if unknown := unknownPolicyMetrics(gatePolicy); len(unknown) > 0 {
+			fatal("benchdiff: -policy: unknown metrics " + strings.Join(unknown, ", "))
+		}
+	}
+	for _, unit := range unreportedUnits() {
+		warn(fmt.Sprintf("benchdiff: warning: -tunit %s is not reported on both sides", unit))
+	}
+	out := os.Stdout
+	if *echo {
+		echoSet(out, after)
+		out = os.Stderr
+	}
+	var rawBefore parse.Set
+	if *confirmRuns > 0 {
+		rawBefore = deepCopySet(before)
+	}
+	diffs, oldSamples, newSamples := prepare(before, after, oldHeader, newHeader)
 
+	if len(diffs) == 0 && (matchRe != nil || excludeRe != nil) {
+		fatal("benchdiff: no benchmark left by -match and -exclude")
+	}
 	if len(diffs) == 0 {
 		fatal("benchdiff: no repeated benchmarks")
 	}
+	if nameFilter != nil {
+		diffs = filterDiffs(diffs, nameFilter)
+		if len(diffs) == 0 {
+			fatal("benchdiff: no benchmark matches -filter")
+		}
+		filterSet(oldSamples, nameFilter)
+		filterSet(newSamples, nameFilter)
+	}
+
+	if *badgePath != "" {
+		if err := writeBadge(*badgePath, diffs); err != nil {
+			fatal(err)
+		}
+	}
 
+	if *chartPath != "" {
+		if err := writeChart(*chartPath, diffs); err != nil {
+			fatal(err)
+		}
+	}
+	if *stepSummary || *githubMode {
+		if err := appendStepSummary(diffs); err != nil {
+			fatal(err)
+		}
+	}
+	if *pushURL != "" {
+		if err := pushResults(*pushURL, pushEncoders[*pushFormat], diffs); err != nil {
+			warn(fmt.Sprintf("benchdiff: cannot push the results: %v", err))
+		}
+	}
+	if *improveOut != "" {
+		if err := writeSplit(*improveOut, diffs, metric.improved); err != nil {
+			fatal(err)
+		}
+	}
+	if *regressOut != "" {
+		if err := writeSplit(*regressOut, diffs, metric.regressed); err != nil {
+			fatal(err)
+		}
+	}
+
+	if *colorMode == colorAuto {
+		_, colorDeltas = terminalWidth(out)
+	}
+	limit := outputWidth(out)
+	if *outFormat != formatText {
+		limit = 0
+	}
+	padding, names := fitLayout(diffs, limit)
+	nameWidth = names
 	w := new(tabwriter.Writer)
-	w.Init(os.Stdout, 0, 0, 5, ' ', 0)
+	w.Init(out, 0, 0, padding, ' ', 0)
 	defer w.Flush()
 
-	var header bool // Has the header has been displayed yet for a given block?
+	if *selfNoise {
+		profiles, over := noiseProfiles(diffs, *noiseBudget)
+		printNoise(w, profiles)
+		if msg := noiseFailure(over, *noiseBudget); msg != "" {
+			w.Flush()
+			fatal(msg)
+		}
+		return
+	}
 
-	if *magSort {
-		sort.Sort(ByDeltaNsPerOp(diffs))
-	} else {
-		sort.Sort(ByParseOrder(diffs))
+	if *tuiMode {
+		w.Flush()
+		_, term := terminalWidth(out)
+		explore(os.Stdin, out, diffs, oldSamples, newSamples, padding, term)
+		return
 	}
-	for _, diff := range diffs {
-		if !diff.Measured(parse.NsPerOp) {
-			continue
+
+	var violations []violation
+	if *outFormat == formatJSON {
+		ns, _ := lookupMetric("ns")
+		sortDiffs(diffs, ns)
+		data, err := marshalDiffs(diffs)
+		if err != nil {
+			fatal(err)
 		}
-		if delta := diff.DeltaNsPerOp(); !*changedOnly || delta.Changed() {
-			if !header {
-				fmt.Fprint(w, "benchmark\told ns/op\tnew ns/op\tdelta\n")
-				header = true
-			}
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", diff.Name(), formatNs(diff.Before.NsPerOp), formatNs(diff.After.NsPerOp), delta.PercentAsStr())
+		out.Write(data)
+		violations = collectViolations(diffs)
+	} else if *outFormat == formatBenchstat {
+		writeBenchstat(w, diffs, oldSamples, newSamples)
+		violations = collectViolations(diffs)
+	} else if *outFormat == formatCSV {
+		ns, _ := lookupMetric("ns")
+		sortDiffs(diffs, ns)
+		if err := writeCSV(out, diffs); err != nil {
+			fatal(err)
+		}
+		violations = collectViolations(diffs)
+	} else if *outFormat == formatJUnit {
+		ns, _ := lookupMetric("ns")
+		sortDiffs(diffs, ns)
+		if err := writeJUnit(out, diffs); err != nil {
+			fatal(err)
+		}
+		violations = collectViolations(diffs)
+	} else if *outFormat == formatHTML {
+		if err := writeHTML(out, diffs); err != nil {
+			fatal(err)
+		}
+		violations = collectViolations(diffs)
+	} else if outTemplate != nil {
+		var err error
+		if violations, err = writeTemplate(out, outTemplate, diffs); err != nil {
+			w.Flush()
+			fatal(err)
+		}
+	} else if *summaryOnly {
+		printSummary(w, diffs)
+		violations = collectViolations(diffs)
+	} else if *treeMode {
+		ns, _ := lookupMetric("ns")
+		printTree(w, buildTree(diffs), ns.scaledFor(diffs))
+		violations = collectViolations(diffs)
+	} else if *interleave {
+		printInterleaved(w, diffs)
+		violations = collectViolations(diffs)
+	} else if *unified {
+		printUnified(w, diffs)
+		violations = collectViolations(diffs)
+	} else if *groupBy != "" {
+		printGrouped(w, diffs, *groupBy)
+		violations = collectViolations(diffs)
+	} else {
+		style := terminalStyle
+		if *outFormat == formatMarkdown {
+			style = markdownStyle
+		}
+		if sectionPackages != nil {
+			violations = printSections(w, diffs, style)
+		} else {
+			violations = printTables(w, diffs, style)
+		}
+	}
 
-			if *failOnDelta && delta.Percent() > *tNsPerOp {
-				w.Flush()
-				fatal(fmt.Sprintf("benchdiff: %s ns/op delta between benchmarks", delta.PercentAsStr()))
-			}
+	if *bothModes {
+		printBoth(w, bothRows(oldSamples, newSamples, orderKey(), reversed()))
+	}
+	if stabilityShown() {
+		rows := stabilityRows(oldSamples, newSamples, *stability, orderKey(), reversed())
+		printStability(w, rows)
+		if msg := unstableWarning(rows, *stability); msg != "" {
+			w.Flush()
+			warn(msg)
 		}
 	}
+	if *summaryFoot {
+		fmt.Fprint(w, "\n")
+		printSummary(w, diffs)
+	}
+	if *showUnmatch {
+		w.Flush()
+		printUnmatched(out, removed, added)
+	}
 
-	header = false
-	if *magSort {
-		sort.Sort(ByDeltaMBPerS(diffs))
+	if len(accepted) > 0 {
+		w.Flush()
+		notes, stale := acceptedReport(diffs)
+		for _, msg := range append(notes, stale...) {
+			warn(msg)
+		}
 	}
-	for _, diff := range diffs {
-		if !diff.Measured(parse.MBPerS) {
-			continue
+	if *gateBoth && gatedCount(diffs) == 0 {
+		w.Flush()
+		fatal("benchdiff: no gated metric was measured on both sides")
+	}
+	if *githubMode {
+		w.Flush()
+		writeAnnotations(os.Stderr, violations)
+		if err := postComment(diffs); err != nil {
+			warn(fmt.Sprintf("benchdiff: cannot comment the pull request: %v", err))
 		}
-		if delta := diff.DeltaMBPerS(); !*changedOnly || delta.Changed() {
-			if !header {
-				fmt.Fprint(w, "\nbenchmark\told MB/s\tnew MB/s\tspeedup\n")
-				header = true
-			}
-			fmt.Fprintf(w, "%s\t%.2f\t%.2f\t%s\n", diff.Name(), diff.Before.MBPerS, diff.After.MBPerS, delta.Multiple())
+	}
+	if *oldProfile != "" && len(violations) > 0 {
+		w.Flush()
+		checkProfiles(*oldProfile, *newProfile)
+		printProfileDiffs(out, violations, *oldProfile, *newProfile)
+	}
+	msg := gateFailure(violations)
+	regressed := *exitCode && regressionCount(diffs) > 0
+	failed := verdictViolations(diffs, violations)
+	if (msg != "" || regressed) && *confirmRuns > 0 {
+		w.Flush()
+		if !confirmRegression(rawBefore, oldHeader, *confirmRuns) {
+			fmt.Fprintln(os.Stderr, "benchdiff: regression not confirmed by the reruns, ignoring it")
+			msg, regressed, failed = "", false, nil
+		}
+	}
+	if msg == "" && *strictNames {
+		msg = strictFailure(correlateWarnings)
+	}
+	if *verdictF != "" {
+		if err := writeVerdict(*verdictF, msg == "" && !regressed, failed); err != nil {
+			w.Flush()
+			fatal(err)
+		}
+	}
+	if msg != "" {
+		w.Flush()
+		fatal(msg)
+	}
+	if regressed {
+		w.Flush()
+		os.Exit(exitRegressed)
+	}
+}
 
-			if *failOnDelta && delta.Percent() > *tMbPerS {
-				w.Flush()
-				fatal(fmt.Sprintf("benchdiff: %s Mb/s delta between benchmarks", delta.PercentAsStr()))
-			}
+// prepare applies the preprocessing flags to the results of both sides
+// and correlates them, printing the warnings to stderr. It returns the
+// diffs along with the results of both sides before -best, -worst,
+// -median, -pctile or -agg, or the mean of several -baseline runs.
+func prepare(before, after parse.Set, oldHeader, newHeader benchHeader) (diffs []benchdiff.BenchDiff, oldSamples, newSamples parse.Set) {
+	selectNames(before, *oldLabel)
+	selectNames(after, *newLabel)
+	for _, msg := range applyRenames(before) {
+		warn("benchdiff: -rename: " + msg)
+	}
+	if *fuzzyMin > 0 {
+		for _, msg := range applyFuzzy(before, after, *fuzzyMin) {
+			warn(msg)
+		}
+	}
+	if *noMerge {
+		splitGroups(before)
+		splitGroups(after)
+	}
+	if archFactors != nil {
+		warning, err := normalizeArch(before, after, oldHeader, newHeader, archFactors)
+		if err != nil {
+			fatal("benchdiff: -normalize-arch: " + err.Error())
+		}
+		if warning != "" {
+			warn(warning)
+		}
+	}
+	if trimRule.method != "" {
+		n := trimOutliers(before, after, trimRule)
+		warn(fmt.Sprintf("benchdiff: trimmed %d outlier results", n))
+	}
+	oldSamples, newSamples = copySet(before), copySet(after)
+	if *minEffect > 0 {
+		effectSizes = nsEffectSizes(oldSamples, newSamples)
+	}
+	if *alpha > 0 {
+		pValues = metricPValues(oldSamples, newSamples)
+	}
+	if baselineMean {
+		selectMean(before)
+	} else {
+		selectResults(before, *oldLabel)
+	}
+	selectResults(after, *newLabel)
+	if *rollup {
+		for _, name := range rollupMismatch(before, after) {
+			logf("rolled up the sub-benchmarks of %s", name)
 		}
 	}
 
-	header = false
-	if *magSort {
-		sort.Sort(ByDeltaAllocsPerOp(diffs))
+	diffs, warnings := benchdiff.Compare(before, after, benchdiff.Options{Key: correlateKey, Added: true, Copied: copyCustom})
+	correlateWarnings = warnings
+
+	if *showUnmatch {
+		removed, added = benchdiff.Unmatched(warnings)
 	}
-	for _, diff := range diffs {
-		if !diff.Measured(parse.AllocsPerOp) {
-			continue
+	for _, w := range warnings {
+		if w.Before == 0 || *showUnmatch && w.After == 0 {
+			continue // added benchmarks are not compared, removed ones listed on stdout
 		}
-		if delta := diff.DeltaAllocsPerOp(); !*changedOnly || delta.Changed() {
-			if !header {
-				fmt.Fprint(w, "\nbenchmark\told allocs\tnew allocs\tdelta\n")
-				header = true
-			}
-			fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", diff.Name(), diff.Before.AllocsPerOp, diff.After.AllocsPerOp, delta.PercentAsStr())
+		warn(w.String())
+	}
+	return diffs, oldSamples, newSamples
+}
 
-			if *failOnDelta && delta.Percent() > *tAllPerOp {
-				w.Flush()
-				fatal(fmt.Sprintf("benchdiff: %s allocs/op delta between benchmarks", delta.PercentAsStr()))
-			}
+// selections returns the number of the flags selecting one result per
+// benchmark that are set.
+func selections() int {
+	n := 0
+	for _, set := range []bool{*best, *worst, *medianMode, *pctile > 0, *aggMode != ""} {
+		if set {
+			n++
 		}
 	}
+	return n
+}
 
-	header = false
-	if *magSort {
-		sort.Sort(ByDeltaAllocedBytesPerOp(diffs))
+// selectResults keeps in bs the result of each benchmark selected by
+// -best, -worst, -median, -pctile or -agg, logging the results with -v.
+// Without any of them the mean is kept with -format=benchstat,
+// otherwise the first parsed result, warning about the benchmarks of
+// the results labeled label that had several.
+func selectResults(bs parse.Set, label string) {
+	logRepeated(bs, label)
+	switch {
+	case *best:
+		benchdiff.SelectBest(bs)
+	case *worst:
+		benchdiff.SelectWorst(bs)
+	case *medianMode:
+		benchdiff.SelectMedian(bs)
+	case *pctile > 0:
+		benchdiff.SelectPercentile(bs, *pctile)
+	case *aggMode != "":
+		aggregate(bs, *aggMode)
+	case *outFormat == formatBenchstat:
+		selectMean(bs)
+	default:
+		if msg := duplicatesWarning(bs, label); msg != "" {
+			warn(msg)
+		}
+		benchdiff.SelectFirst(bs)
 	}
-	for _, diff := range diffs {
-		if !diff.Measured(parse.AllocedBytesPerOp) {
-			continue
+}
+
+// duplicatesWarning returns the warning about the benchmarks having
+// several results in bs, naming the first few, or "" if there are none.
+func duplicatesWarning(bs parse.Set, label string) string {
+	var names []string
+	for name, bb := range bs {
+		if len(bb) > 1 {
+			names = append(names, name)
 		}
-		if delta := diff.DeltaAllocedBytesPerOp(); !*changedOnly || delta.Changed() {
-			if !header {
-				fmt.Fprint(w, "\nbenchmark\told bytes\tnew bytes\tdelta\n")
-				header = true
-			}
-			fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", diff.Name(), diff.Before.AllocedBytesPerOp, diff.After.AllocedBytesPerOp, diff.DeltaAllocedBytesPerOp().PercentAsStr())
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	list := strings.Join(names, ", ")
+	if len(names) > 3 {
+		list = strings.Join(names[:3], ", ") + ", ..."
+	}
+	return fmt.Sprintf("benchdiff: warning: %d benchmarks have several %s results (%s), comparing the first one of each; select another with -best, -worst, -median, -pctile or -agg", len(names), label, list)
+}
 
-			if *failOnDelta && delta.Percent() > *tBPerOp {
-				w.Flush()
-				fatal(fmt.Sprintf("benchdiff: %s bytes/op delta between benchmarks", delta.PercentAsStr()))
-			}
+// printTables writes in style one comparison block per metric and
+// returns the tolerance violations found, in display order.
+func printTables(w *tabwriter.Writer, diffs []benchdiff.BenchDiff, style tableStyle) []violation {
+	var violations []violation
+	// Hidden rows are still gated.
+	keep := func(m metric, diff benchdiff.BenchDiff) bool {
+		if m.exceeded(diff) {
+			violations = append(violations, violation{diff.Name(), m.unit, m.delta(diff), m.regressed(diff)})
 		}
+		return shown(m, diff)
+	}
+	printBlocks(w, diffs, style, keep, nil)
+	return violations
+}
+
+// shown reports whether the row of diff is displayed in the block of m:
+// with -changed or -changed-metric only if m changed, with -noise only
+// if m changed by at least -noise percent, and with -q only if m is
+// beyond its tolerance.
+func shown(m metric, diff benchdiff.BenchDiff) bool {
+	if *quiet && !m.exceeded(diff) && !(*exitCode && m.regressedBeyond(diff)) {
+		return false
+	}
+	d := m.delta(diff)
+	if m.hidesUnchanged() && !hasChanged(d) {
+		return false
+	}
+	return *noiseFloor <= 0 || math.Abs(d.Percent()) >= *noiseFloor
+}
+
+// sortDiffs sorts diffs for the block of m by the key of -sort or -mag,
+// in parse order by default, and the other way round with -reverse or
+// the opposite direction. With -sort=metric, the change of that metric
+// sorts the block instead of the one of m.
+func sortDiffs(diffs []benchdiff.BenchDiff, m metric) {
+	o := order()
+	if sm, ok := lookupMetric(o.metric); ok {
+		m = sm
+	}
+	s := sorter(diffs, m, o.key)
+	if reversed() {
+		s = sort.Reverse(s)
+	}
+	sort.Sort(s)
+}
+
+// deriveLabels sets the labels not given on the command line
+// from the names of the old and new files.
+func deriveLabels(oldPath, newPath string) {
+	oldName, newName := labelFromPath(oldPath), labelFromPath(newPath)
+	if oldName == newName {
+		return
+	}
+	if !flagGiven("old-label") && !flagGiven("oldname") {
+		*oldLabel = oldName
+	}
+	if !flagGiven("new-label") && !flagGiven("newname") {
+		*newLabel = newName
+	}
+}
+
+// labelFromPath returns the base name of path, or of its first file if
+// it lists several, or of the file it names in an archive, without its
+// extension. A trailing .gz is stripped first.
+func labelFromPath(path string) string {
+	path = splitShards(path)[0]
+	if _, member := splitArchive(path); member != "" {
+		path = member
+	}
+	base := strings.TrimSuffix(filepath.Base(path), ".gz")
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// logf prints a diagnostic message to stderr when -v is set.
+func logf(format string, args ...interface{}) {
+	if *verbose && !muted {
+		fmt.Fprintf(os.Stderr, "benchdiff: "+format+"\n", args...)
+	}
+}
+
+// muted silences warn and logf while the -confirm-runs reruns repeat
+// the preprocessing whose messages the first comparison printed.
+var muted bool
+
+// warn writes the warning msg to stderr, unless -q is set.
+func warn(msg string) {
+	if !*quiet && !muted {
+		fmt.Fprintln(os.Stderr, msg)
 	}
 }
 
@@ -166,48 +1826,119 @@ func fatal(msg interface{}) {
 	os.Exit(1)
 }
 
-func parseFile(path string) parse.Set {
-	f, err := os.Open(path)
+func parseFile(side, path string) (parse.Set, benchHeader) {
+	data, err := readInput(path, os.Stdin)
 	if err != nil {
 		fatal(err)
 	}
-	defer f.Close()
-	bb, err := parse.ParseSet(f)
+	bs, h := parseData(side, path, data)
+	if path == stdinPath {
+		return bs, h
+	}
+	archive, _ := splitArchive(path)
+	if isURL(archive) {
+		return bs, h
+	}
+	if fi, err := os.Stat(archive); err == nil {
+		mtime := fi.ModTime()
+		inputs[len(inputs)-1].ModTime = &mtime
+	}
+	return bs, h
+}
+
+// stdinPath is the input path standing for the standard input.
+const stdinPath = "-"
+
+// readInput returns the content of the file, URL or archive member at
+// path, or of stdin if path is stdinPath.
+func readInput(path string, stdin io.Reader) ([]byte, error) {
+	if path == stdinPath {
+		return ioutil.ReadAll(stdin)
+	}
+	return readSource(path)
+}
+
+// parseData parses the benchmark results of side, old or new, read
+// from the named source.
+func parseData(side, name string, data []byte) (parse.Set, benchHeader) {
+	return parseDataAs(side, name, data, formatFor(name, *inputFormat))
+}
+
+// parseDataAs parses the benchmark results of side, old or new, read
+// from the named source in the given input format.
+func parseDataAs(side, name string, data []byte, format string) (parse.Set, benchHeader) {
+	inputs = append(inputs, newInputRecord(side, name, data))
+	logf("%s: %d bytes, sha256 %s", name, len(data), inputs[len(inputs)-1].SHA256)
+	data, err := decodeInput(data, format)
+	if err != nil {
+		fatal(fmt.Sprintf("%s: %v", name, err))
+	}
+	inputs[len(inputs)-1].text = data
+	scan := scanResults(data)
+	bb := scan.set()
+	logf("%s: %d results for %d benchmarks", name, len(scan.results), len(bb))
+	return bb, scan.header
+}
+
+// checkAge warns, or fails with -strict-age, if the file at path was
+// last modified longer than max ago.
+func checkAge(path string, max time.Duration) {
+	archive, _ := splitArchive(path)
+	fi, err := os.Stat(archive)
 	if err != nil {
 		fatal(err)
 	}
-	if *best {
-		selectBest(bb)
+	age, stale := staleness(fi.ModTime(), time.Now(), max)
+	if !stale {
+		return
 	}
-	return bb
+	msg := fmt.Sprintf("%s was modified %s ago, more than -max-age %s", path, age, max)
+	if *strictAge {
+		fatal("benchdiff: " + msg)
+	}
+	warn("benchdiff: warning: " + msg)
+}
+
+// staleness returns the age at now of a file modified at mod, rounded
+// to the second, and whether it is older than max.
+func staleness(mod, now time.Time, max time.Duration) (time.Duration, bool) {
+	age := now.Sub(mod)
+	return age.Round(time.Second), age > max
 }
 
-func selectBest(bs parse.Set) {
+// copySet returns a shallow copy of bs.
+func copySet(bs parse.Set) parse.Set {
+	cp := make(parse.Set, len(bs))
 	for name, bb := range bs {
-		if len(bb) < 2 {
-			continue
-		}
-		ord := bb[0].Ord
-		best := bb[0]
-		for _, b := range bb {
-			if b.NsPerOp < best.NsPerOp {
-				b.Ord = ord
-				best = b
-			}
-		}
-		bs[name] = []*parse.Benchmark{best}
+		cp[name] = bb
 	}
+	return cp
+}
+
+// deepCopySet returns a copy of bs and of its results.
+func deepCopySet(bs parse.Set) parse.Set {
+	cp := make(parse.Set, len(bs))
+	for name, bb := range bs {
+		cp[name] = renamed(bb, name)
+	}
+	return cp
+}
+
+// countResults returns the number of benchmark results in bs.
+func countResults(bs parse.Set) int {
+	n := 0
+	for _, bb := range bs {
+		n += len(bb)
+	}
+	return n
 }
 
 // formatNs formats ns measurements to expose a useful amount of
-// precision. It mirrors the ns precision logic of testing.B.
+// precision. It mirrors the ns precision logic of testing.B, unless
+// -prec sets the number of decimals.
 func formatNs(ns float64) string {
-	prec := 0
-	switch {
-	case ns < 10:
-		prec = 2
-	case ns < 100:
-		prec = 1
+	if *nsPrec >= 0 {
+		return strconv.FormatFloat(ns, 'f', *nsPrec, 64)
 	}
-	return strconv.FormatFloat(ns, 'f', prec, 64)
+	return benchdiff.FormatNs(ns)
 }
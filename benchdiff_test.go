@@ -1,9 +1,15 @@
 package main
 
 import (
+	"bytes"
+	"flag"
 	"reflect"
+	"strings"
 	"testing"
+	"text/tabwriter"
+	"time"
 
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
 	"golang.org/x/tools/benchmark/parse"
 )
 
@@ -52,8 +58,249 @@ func TestSelectBest(t *testing.T) {
 		},
 	}
 
-	selectBest(have)
+	benchdiff.SelectBest(have)
 	if !reflect.DeepEqual(want, have) {
 		t.Errorf("filtered bench set incorrectly, want %v have %v", want, have)
 	}
 }
+
+func TestLabelFromPath(t *testing.T) {
+	cases := []struct {
+		path string
+		want string
+	}{
+		{path: "v1.2.0.txt", want: "v1.2.0"},
+		{path: "results/main.txt", want: "main"},
+		{path: "ci/feature-x.txt.gz", want: "feature-x"},
+		{path: "old", want: "old"},
+	}
+	for _, tt := range cases {
+		if have := labelFromPath(tt.path); have != tt.want {
+			t.Errorf("labelFromPath(%q): want %q have %q", tt.path, tt.want, have)
+		}
+	}
+}
+
+func TestCountResults(t *testing.T) {
+	bs := parse.Set{
+		"Benchmark1": []*parse.Benchmark{{Name: "Benchmark1"}, {Name: "Benchmark1"}},
+		"Benchmark2": []*parse.Benchmark{{Name: "Benchmark2"}},
+	}
+	if want, have := 3, countResults(bs); want != have {
+		t.Errorf("countResults: want %d have %d", want, have)
+	}
+}
+
+func TestStaleness(t *testing.T) {
+	now := time.Date(2019, 4, 12, 10, 0, 0, 0, time.UTC)
+	cases := []struct {
+		mod   time.Time
+		age   time.Duration
+		stale bool
+	}{
+		{mod: now.Add(-time.Hour), age: time.Hour, stale: false},
+		{mod: now.Add(-48*time.Hour - 300*time.Millisecond), age: 48 * time.Hour, stale: true},
+		{mod: now.Add(-24 * time.Hour), age: 24 * time.Hour, stale: false},
+	}
+	for _, tt := range cases {
+		age, stale := staleness(tt.mod, now, 24*time.Hour)
+		if age != tt.age || stale != tt.stale {
+			t.Errorf("staleness(%s): want %s, %t have %s, %t", tt.mod, tt.age, tt.stale, age, stale)
+		}
+	}
+}
+
+func TestSortDiffsReverse(t *testing.T) {
+	defer func(m, r bool) { *magSort, *reverse = m, r }(*magSort, *reverse)
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("BenchmarkSmall", 0, 100, 101),
+		nsDiff("BenchmarkLarge", 1, 100, 200),
+		nsDiff("BenchmarkMedium", 2, 100, 120),
+	}
+	ns, _ := lookupMetric("ns")
+	tests := []struct {
+		mag, reverse bool
+		want         []string
+	}{
+		{false, false, []string{"BenchmarkSmall", "BenchmarkLarge", "BenchmarkMedium"}},
+		{false, true, []string{"BenchmarkMedium", "BenchmarkLarge", "BenchmarkSmall"}},
+		{true, false, []string{"BenchmarkLarge", "BenchmarkMedium", "BenchmarkSmall"}},
+		{true, true, []string{"BenchmarkSmall", "BenchmarkMedium", "BenchmarkLarge"}},
+	}
+	for _, tt := range tests {
+		*magSort, *reverse = tt.mag, tt.reverse
+		sortDiffs(diffs, ns)
+		var have []string
+		for _, diff := range diffs {
+			have = append(have, diff.Name())
+		}
+		if !reflect.DeepEqual(tt.want, have) {
+			t.Errorf("sortDiffs -mag=%t -reverse=%t: want %v have %v", tt.mag, tt.reverse, tt.want, have)
+		}
+	}
+}
+
+func TestReadInputStdin(t *testing.T) {
+	in := "BenchmarkA-4 1000 120 ns/op\nBenchmarkA-4 1000 100 ns/op\n"
+	data, err := readInput(stdinPath, bytes.NewReader([]byte(in)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, _ := parseData("new", "stdin", data)
+	if n := countResults(bs); n != 2 {
+		t.Fatalf("parsed %d results from stdin, want 2", n)
+	}
+	benchdiff.SelectBest(bs)
+	if bb := bs["BenchmarkA-4"]; len(bb) != 1 || bb[0].NsPerOp != 100 {
+		t.Errorf("selectBest on stdin results: want one 100 ns/op result have %v", bb)
+	}
+}
+
+func TestNoiseFloor(t *testing.T) {
+	defer func(n float64, c, f bool, tol float64) {
+		*noiseFloor, *changedOnly, *failOnDelta, *tNsPerOp = n, c, f, tol
+	}(*noiseFloor, *changedOnly, *failOnDelta, *tNsPerOp)
+	*noiseFloor = 2
+	ns, _ := lookupMetric("ns")
+	cases := []struct {
+		after   float64
+		changed bool
+		want    bool
+	}{
+		{101, false, false}, // 1% is noise
+		{97, false, true},   // 3% is not
+		{100, false, false},
+		{103, true, true},
+		{100.5, true, false},
+	}
+	for _, tt := range cases {
+		*changedOnly = tt.changed
+		if have := shown(ns, nsDiff("BenchmarkA", 0, 100, tt.after)); have != tt.want {
+			t.Errorf("shown 100 -> %v with -noise 2 -changed=%t: want %t have %t", tt.after, tt.changed, tt.want, have)
+		}
+	}
+
+	// Rows hidden as noise are still gated.
+	*changedOnly, *failOnDelta, *tNsPerOp = false, true, 0.5
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 5, ' ', 0)
+	violations := printTables(w, []benchdiff.BenchDiff{nsDiff("BenchmarkA", 0, 100, 101)}, plainStyle)
+	w.Flush()
+	if len(violations) != 1 {
+		t.Errorf("printTables: want the hidden 1%% change to violate -tnsop 0.5, have %v", violations)
+	}
+	if strings.Contains(buf.String(), "BenchmarkA") {
+		t.Errorf("printTables: want BenchmarkA hidden, have\n%s", buf.String())
+	}
+}
+
+func TestQuiet(t *testing.T) {
+	defer func(q, f, e bool, tol float64) {
+		*quiet, *failOnDelta, *exitCode, *tNsPerOp = q, f, e, tol
+	}(*quiet, *failOnDelta, *exitCode, *tNsPerOp)
+	*quiet, *failOnDelta, *tNsPerOp = true, true, 5
+	ns, _ := lookupMetric("ns")
+	if shown(ns, nsDiff("BenchmarkA", 0, 100, 103)) {
+		t.Error("shown with -q: want a change within tolerance hidden")
+	}
+	if !shown(ns, nsDiff("BenchmarkA", 0, 100, 110)) {
+		t.Error("shown with -q: want a change beyond tolerance shown")
+	}
+
+	// With -exitcode only, the regressions beyond tolerance are shown.
+	*failOnDelta, *exitCode = false, true
+	if !shown(ns, nsDiff("BenchmarkA", 0, 100, 110)) {
+		t.Error("shown with -q -exitcode: want a regression beyond tolerance shown")
+	}
+	if shown(ns, nsDiff("BenchmarkA", 0, 100, 90)) {
+		t.Error("shown with -q -exitcode: want an improvement hidden")
+	}
+}
+
+func TestLabelSynonyms(t *testing.T) {
+	defer func(o, n string) { *oldLabel, *newLabel = o, n }(*oldLabel, *newLabel)
+	if err := flag.Set("oldname", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := flag.Set("newname", "feature-x"); err != nil {
+		t.Fatal(err)
+	}
+	ns, _ := lookupMetric("ns")
+	if want, have := "benchmark\tmain ns/op\tfeature-x ns/op\tdelta", ns.header(); !strings.HasPrefix(have, want) {
+		t.Errorf("header with -oldname main -newname feature-x: want %q have %q", want, have)
+	}
+}
+
+func TestIgnoreProcs(t *testing.T) {
+	defer func(v bool) { *stripCPU = v }(*stripCPU)
+	if err := flag.Set("ignoreprocs", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if !*stripCPU {
+		t.Error("-ignoreprocs: want -stripcpu set")
+	}
+	if name, err := correlateName("name", *stripCPU); err != nil || name != "base" {
+		t.Errorf("correlateName with -ignoreprocs: want base have %q, %v", name, err)
+	}
+}
+
+func TestWorstFixture(t *testing.T) {
+	defer func(w bool) { *worst = w }(*worst)
+	*worst = true
+	sets := make([]parse.Set, 2)
+	for i, path := range []string{"fixtures/strconcat-count.old", "fixtures/strconcat-count.new"} {
+		data, err := readInput(path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sets[i], err = parse.ParseSet(bytes.NewReader(data)); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		selectResults(sets[i], "")
+	}
+	diffs, warnings := benchdiff.Compare(sets[0], sets[1], benchdiff.Options{})
+	if len(warnings) != 0 {
+		t.Fatalf("Compare: unexpected warnings %v", warnings)
+	}
+	want := []struct {
+		name          string
+		before, after float64
+	}{
+		{"BenchmarkConcatString-4", 163, 171},
+		{"BenchmarkConcatBuffer-4", 9.41, 8.93},
+		{"BenchmarkConcatBuilder-4", 2.90, 2.84},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("Compare with -worst: want %d diffs have %v", len(want), diffs)
+	}
+	for i, tt := range want {
+		if d := diffs[i]; d.Name() != tt.name || d.Before.NsPerOp != tt.before || d.After.NsPerOp != tt.after {
+			t.Errorf("diff %d with -worst: want %s %v -> %v have %s %v -> %v", i, tt.name, tt.before, tt.after, d.Name(), d.Before.NsPerOp, d.After.NsPerOp)
+		}
+	}
+}
+
+func TestDuplicatesWarning(t *testing.T) {
+	bs := parse.Set{
+		"BenchmarkA": {{Name: "BenchmarkA", Ord: 0}, {Name: "BenchmarkA", Ord: 2}},
+		"BenchmarkB": {{Name: "BenchmarkB", Ord: 1}},
+	}
+	if msg := duplicatesWarning(parse.Set{"BenchmarkB": bs["BenchmarkB"]}, "old"); msg != "" {
+		t.Errorf("duplicatesWarning without duplicates: want \"\" have %q", msg)
+	}
+	want := "benchdiff: warning: 1 benchmarks have several old results (BenchmarkA), comparing the first one of each; select another with -best, -worst, -median, -pctile or -agg"
+	if msg := duplicatesWarning(bs, "old"); msg != want {
+		t.Errorf("duplicatesWarning:\nwant %q\nhave %q", want, msg)
+	}
+	for _, name := range []string{"BenchmarkD", "BenchmarkC"} {
+		bs[name] = []*parse.Benchmark{{Name: name}, {Name: name}}
+	}
+	want = "benchdiff: warning: 3 benchmarks have several new results (BenchmarkA, BenchmarkC, BenchmarkD), comparing the first one of each; select another with -best, -worst, -median, -pctile or -agg"
+	if msg := duplicatesWarning(bs, "new"); msg != want {
+		t.Errorf("duplicatesWarning:\nwant %q\nhave %q", want, msg)
+	}
+	bs["BenchmarkE"] = []*parse.Benchmark{{Name: "BenchmarkE"}, {Name: "BenchmarkE"}}
+	if msg := duplicatesWarning(bs, "new"); !strings.Contains(msg, "(BenchmarkA, BenchmarkC, BenchmarkD, ...)") {
+		t.Errorf("duplicatesWarning with 4 benchmarks: want the first 3 names have %q", msg)
+	}
+}
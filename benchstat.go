@@ -0,0 +1,139 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// formatBenchstat is the -format value writing the tables in the layout
+// of benchstat.
+const formatBenchstat = "benchstat"
+
+// benchstatAlpha is the significance level of -format=benchstat when
+// -alpha is not set, the one of benchstat.
+const benchstatAlpha = 0.05
+
+// benchstatTitles are the names benchstat gives to the standard
+// metrics.
+var benchstatTitles = map[string]string{
+	"ns":     "time/op",
+	"mbs":    "speed",
+	"allocs": "allocs/op",
+	"bytes":  "alloc/op",
+}
+
+// siPrefixes are the prefixes of the scaled benchstat values, largest
+// first.
+var siPrefixes = []struct {
+	name  string
+	scale float64
+}{{"G", 1e9}, {"M", 1e6}, {"k", 1e3}}
+
+// benchstatValue formats v, a value of m, as benchstat does: scaled to
+// the largest fitting time unit for ns/op, with an SI prefix otherwise,
+// followed by the unit.
+func benchstatValue(m metric, v float64) string {
+	switch m.name {
+	case "ns":
+		for _, u := range timeUnits {
+			if v >= u.ns {
+				return benchdiff.FormatNs(v/u.ns) + u.name
+			}
+		}
+		return benchdiff.FormatNs(v) + "ns"
+	case "mbs":
+		return benchdiff.FormatNs(v) + "MB/s"
+	}
+	unit := ""
+	if m.name == "bytes" {
+		unit = "B"
+	}
+	for _, p := range siPrefixes {
+		if math.Abs(v) >= p.scale {
+			return benchdiff.FormatNs(v/p.scale) + p.name + unit
+		}
+	}
+	return benchdiff.FormatNs(v) + unit
+}
+
+// benchstatCell formats the mean of xs, the values of m, with their
+// largest deviation from the mean, in percent of it.
+func benchstatCell(m metric, xs []float64) string {
+	mu := mean(xs)
+	var dev float64
+	for _, x := range xs {
+		dev = math.Max(dev, math.Abs(x-mu))
+	}
+	pct := 0.0
+	if mu != 0 {
+		pct = 100 * dev / math.Abs(mu)
+	}
+	return fmt.Sprintf("%s ± %.0f%%", benchstatValue(m, mu), pct)
+}
+
+// writeBenchstat writes in the layout of benchstat the comparison of
+// the results of diffs, oldSet and newSet holding all their repeated
+// results: per metric, a table with the mean and variation of each
+// side, the change of the means when the U-test finds it significant,
+// "~" otherwise, and the p-value and number of results. Tables of more
+// than one row end with the geomean of the means.
+func writeBenchstat(w io.Writer, diffs []benchdiff.BenchDiff, oldSet, newSet parse.Set) {
+	level := benchstatAlpha
+	if *alpha > 0 {
+		level = *alpha
+	}
+	first := true
+	for _, m := range activeMetrics() {
+		title := benchstatTitles[m.name]
+		if title == "" {
+			title = m.unit
+		}
+		sortDiffs(diffs, m)
+		var rows [][]string
+		var befores, afters []float64
+		for _, diff := range diffs {
+			if !m.measuredIn(diff) || !shown(m, diff) {
+				continue
+			}
+			bx, ax := m.samples(oldSet[diff.Before.Name]), m.samples(newSet[diff.After.Name])
+			if len(bx) == 0 || len(ax) == 0 {
+				continue
+			}
+			d := benchdiff.Delta{Before: mean(bx), After: mean(ax)}
+			delta, note := "~", fmt.Sprintf("(n=%d+%d)", len(bx), len(ax))
+			if len(bx) >= 2 && len(ax) >= 2 {
+				p := mannWhitneyU(bx, ax)
+				note = fmt.Sprintf("(p=%.3f n=%d+%d)", p, len(bx), len(ax))
+				if p < level {
+					delta = d.PercentAsStr()
+				}
+			}
+			rows = append(rows, []string{strings.TrimPrefix(diff.Name(), "Benchmark"), benchstatCell(m, bx), benchstatCell(m, ax), delta, note})
+			befores, afters = append(befores, d.Before), append(afters, d.After)
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		if !first {
+			fmt.Fprint(w, "\n")
+		}
+		first = false
+		fmt.Fprintf(w, "name\t%s %s\t%s %s\tdelta\n", *oldLabel, title, *newLabel, title)
+		for _, cells := range rows {
+			fmt.Fprint(w, strings.Join(cells, "\t")+"\n")
+		}
+		if len(rows) > 1 && minimum(befores) > 0 && minimum(afters) > 0 {
+			g := benchdiff.Delta{Before: geomean(befores), After: geomean(afters)}
+			fmt.Fprintf(w, "[Geo mean]\t%s\t%s\t%s\n", benchstatValue(m, g.Before), benchstatValue(m, g.After), g.PercentAsStr())
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestBenchstatValue(t *testing.T) {
+	ns, _ := lookupMetric("ns")
+	bytes, _ := lookupMetric("bytes")
+	allocs, _ := lookupMetric("allocs")
+	for _, tt := range []struct {
+		m    metric
+		v    float64
+		want string
+	}{
+		{ns, 148, "148ns"},
+		{ns, 8.96, "8.96ns"},
+		{ns, 12500, "12.5µs"},
+		{ns, 2.5e9, "2.50s"},
+		{bytes, 530, "530B"},
+		{bytes, 2048, "2.05kB"},
+		{allocs, 3, "3.00"},
+		{allocs, 4e6, "4.00M"},
+	} {
+		if have := benchstatValue(tt.m, tt.v); have != tt.want {
+			t.Errorf("benchstatValue(%s, %v): want %q, have %q", tt.m.name, tt.v, tt.want, have)
+		}
+	}
+}
+
+func TestBenchstatCell(t *testing.T) {
+	ns, _ := lookupMetric("ns")
+	if have, want := benchstatCell(ns, []float64{90, 100, 110}), "100ns ± 10%"; have != want {
+		t.Errorf("benchstatCell: want %q, have %q", want, have)
+	}
+	if have, want := benchstatCell(ns, []float64{0, 0}), "0.00ns ± 0%"; have != want {
+		t.Errorf("benchstatCell of zeros: want %q, have %q", want, have)
+	}
+}
+
+func TestWriteBenchstat(t *testing.T) {
+	samples := func(name string, xs ...float64) []*parse.Benchmark {
+		var bb []*parse.Benchmark
+		for _, x := range xs {
+			bb = append(bb, &parse.Benchmark{Name: name, NsPerOp: x, Measured: parse.NsPerOp})
+		}
+		return bb
+	}
+	oldSet := parse.Set{
+		"BenchmarkA": samples("BenchmarkA", 100, 101, 99, 100),
+		"BenchmarkB": samples("BenchmarkB", 10, 10.5),
+	}
+	newSet := parse.Set{
+		"BenchmarkA": samples("BenchmarkA", 50, 51, 49, 50),
+		"BenchmarkB": samples("BenchmarkB", 10.2, 10.3),
+	}
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("BenchmarkA", 0, 100, 50),
+		nsDiff("BenchmarkB", 1, 10.25, 10.25),
+	}
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 5, ' ', 0)
+	writeBenchstat(w, diffs, oldSet, newSet)
+	w.Flush()
+	want := `name           old time/op     new time/op     delta
+A              100ns ± 1%      50.0ns ± 2%     -50.00%     (p=0.028 n=4+4)
+B              10.2ns ± 2%     10.2ns ± 0%     ~           (p=1.000 n=2+2)
+[Geo mean]     32.0ns          22.6ns          -29.29%
+`
+	if have := buf.String(); have != want {
+		t.Errorf("writeBenchstat: want\n%s\nhave\n%s", want, have)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// bothRow compares the best and the mean ns/op of one benchmark.
+type bothRow struct {
+	name string
+	ord  int // parse order of the first old sample
+	best benchdiff.Delta
+	mean benchdiff.Delta
+}
+
+// nsSamples returns the ns/op of the samples in bb that measured it.
+func nsSamples(bb []*parse.Benchmark) []float64 {
+	var xs []float64
+	for _, b := range bb {
+		if b.Measured&parse.NsPerOp != 0 {
+			xs = append(xs, b.NsPerOp)
+		}
+	}
+	return xs
+}
+
+// bothRows computes the best and mean comparisons of the benchmarks
+// measuring ns/op in both before and after, sorted like the other
+// blocks by key (see sorter), the mean comparison standing for the
+// benchmark. The order is reversed if reverse is set.
+func bothRows(before, after parse.Set, key string, reverse bool) []bothRow {
+	var rows []bothRow
+	for name, beforebb := range before {
+		bx, ax := nsSamples(beforebb), nsSamples(after[name])
+		if len(bx) == 0 || len(ax) == 0 {
+			continue
+		}
+		rows = append(rows, bothRow{
+			name: name,
+			ord:  beforebb[0].Ord,
+			best: benchdiff.Delta{Before: minimum(bx), After: minimum(ax)},
+			mean: benchdiff.Delta{Before: mean(bx), After: mean(ax)},
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if reverse {
+			i, j = j, i
+		}
+		ri, rj := rows[i], rows[j]
+		switch key {
+		case sortName:
+		case sortDelta:
+			if mi, mj := sortMag(ri.mean), sortMag(rj.mean); mi != mj {
+				return mi < mj
+			}
+		case sortOld:
+			if ri.mean.Before != rj.mean.Before {
+				return ri.mean.Before > rj.mean.Before
+			}
+		case sortNew:
+			if ri.mean.After != rj.mean.After {
+				return ri.mean.After > rj.mean.After
+			}
+		default:
+			return ri.ord < rj.ord
+		}
+		return ri.name < rj.name
+	})
+	return rows
+}
+
+// printBoth writes the block comparing the best and the mean ns/op.
+func printBoth(w io.Writer, rows []bothRow) {
+	var header bool
+	ns, _ := lookupMetric("ns")
+	for _, r := range rows {
+		if ns.hidesUnchanged() && !hasChanged(r.best) && !hasChanged(r.mean) {
+			continue
+		}
+		if *noiseFloor > 0 && math.Abs(r.best.Percent()) < *noiseFloor && math.Abs(r.mean.Percent()) < *noiseFloor {
+			continue
+		}
+		if !header {
+			fmt.Fprintf(w, "\nbenchmark\t%s best\t%s best\tdelta\t%s mean\t%s mean\tdelta\n", *oldLabel, *newLabel, *oldLabel, *newLabel)
+			header = true
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", truncateName(r.name, nameWidth),
+			formatNs(r.best.Before), formatNs(r.best.After), formatPercent(r.best),
+			formatNs(r.mean.Before), formatNs(r.mean.After), formatPercent(r.mean))
+	}
+}
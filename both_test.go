@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestBothRows(t *testing.T) {
+	before := parse.Set{
+		"BenchmarkA": []*parse.Benchmark{
+			{Name: "BenchmarkA", NsPerOp: 100, Measured: parse.NsPerOp, Ord: 1},
+			{Name: "BenchmarkA", NsPerOp: 80, Measured: parse.NsPerOp, Ord: 2},
+		},
+		"BenchmarkB":    []*parse.Benchmark{{Name: "BenchmarkB", NsPerOp: 50, Measured: parse.NsPerOp, Ord: 0}},
+		"BenchmarkGone": []*parse.Benchmark{{Name: "BenchmarkGone", NsPerOp: 50, Measured: parse.NsPerOp, Ord: 3}},
+	}
+	after := parse.Set{
+		"BenchmarkA": []*parse.Benchmark{
+			{Name: "BenchmarkA", NsPerOp: 90, Measured: parse.NsPerOp},
+			{Name: "BenchmarkA", NsPerOp: 70, Measured: parse.NsPerOp},
+			{Name: "BenchmarkA", NsPerOp: 80, Measured: parse.NsPerOp},
+		},
+		"BenchmarkB": []*parse.Benchmark{{Name: "BenchmarkB", NsPerOp: 50, Measured: parse.NsPerOp}},
+	}
+
+	want := []bothRow{
+		{name: "BenchmarkB", ord: 0, best: benchdiff.Delta{Before: 50, After: 50}, mean: benchdiff.Delta{Before: 50, After: 50}},
+		{name: "BenchmarkA", ord: 1, best: benchdiff.Delta{Before: 80, After: 70}, mean: benchdiff.Delta{Before: 90, After: 80}},
+	}
+	if have := bothRows(before, after, sortParse, false); !reflect.DeepEqual(want, have) {
+		t.Errorf("bothRows: want %v have %v", want, have)
+	}
+
+	want[0], want[1] = want[1], want[0]
+	if have := bothRows(before, after, sortDelta, false); !reflect.DeepEqual(want, have) {
+		t.Errorf("bothRows by magnitude: want %v have %v", want, have)
+	}
+	if have := bothRows(before, after, sortParse, true); !reflect.DeepEqual(want, have) {
+		t.Errorf("bothRows reversed: want %v have %v", want, have)
+	}
+	if have := bothRows(before, after, sortName, false); !reflect.DeepEqual(want, have) {
+		t.Errorf("bothRows by name: want %v have %v", want, have)
+	}
+}
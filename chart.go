@@ -0,0 +1,122 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// Chart layout, in pixels.
+const (
+	chartRowHeight = 20
+	chartCharWidth = 7
+	chartPadding   = 10
+	chartValueRoom = 8 * chartCharWidth // room for the value next to a bar
+)
+
+var chartTemplate = template.Must(template.New("chart").Funcs(template.FuncMap{"xml": xmlEscape}).Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="{{.Height}}" role="img" aria-label="ns/op changes">
+<title>ns/op changes</title>
+<rect width="{{.Width}}" height="{{.Height}}" fill="#fff"/>
+<g font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+{{- range .Bars}}
+<text x="{{$.LabelEnd}}" y="{{.TextY}}" text-anchor="end">{{xml .Label}}</text>
+<rect x="{{.X}}" y="{{.Y}}" width="{{.Length}}" height="{{$.BarHeight}}" fill="{{.Color}}"/>
+<text x="{{.ValueX}}" y="{{.TextY}}" text-anchor="{{.Anchor}}">{{.Value}}</text>
+{{- end}}
+</g>
+<line x1="{{.Axis}}" y1="0" x2="{{.Axis}}" y2="{{.Height}}" stroke="#555"/>
+</svg>
+`))
+
+// chartBar is one bar of the chart.
+type chartBar struct {
+	Label, Value, Color, Anchor string
+	X, Y, Length, TextY, ValueX int
+}
+
+// xmlEscape escapes s for use in XML text.
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// chartSVG renders a horizontal bar chart of the ns/op changes of the
+// top n changes of diffs, largest first, width pixels wide. Bars of
+// regressions go right in red, bars of improvements go left in green.
+func chartSVG(diffs []benchdiff.BenchDiff, n, width int) ([]byte, error) {
+	cc := topChanges(diffs, n, func(m metric, diff benchdiff.BenchDiff) bool {
+		return m.name == "ns" && m.direction(diff) != 0
+	})
+	labelEnd := chartPadding
+	for _, c := range cc {
+		if w := len(c.diff.Name())*chartCharWidth + chartPadding; w > labelEnd {
+			labelEnd = w
+		}
+	}
+	half := (width - labelEnd - 2*chartPadding) / 2
+	if half < chartValueRoom+chartPadding {
+		return nil, fmt.Errorf("chart width %d is too small for the benchmark names", width)
+	}
+	scale := 0.0
+	for _, c := range cc {
+		scale = math.Max(scale, math.Abs(c.m.delta(c.diff).Percent()))
+	}
+	axis := labelEnd + chartPadding + half
+	bars := make([]chartBar, len(cc))
+	for i, c := range cc {
+		pct := c.m.delta(c.diff).Percent()
+		length := half - chartValueRoom
+		if !math.IsInf(scale, 1) {
+			length = int(math.Abs(pct) / scale * float64(half-chartValueRoom))
+		}
+		b := chartBar{
+			Label:  c.diff.Name(),
+			Value:  formatPercent(c.m.delta(c.diff)),
+			X:      axis,
+			Y:      i*chartRowHeight + 3,
+			Length: length,
+			TextY:  i*chartRowHeight + 14,
+			Color:  badgeRed,
+			Anchor: "start",
+			ValueX: axis + length + 3,
+		}
+		if c.m.improved(c.diff) {
+			b.X, b.Color, b.Anchor, b.ValueX = axis-length, badgeGreen, "end", axis-length-3
+		}
+		bars[i] = b
+	}
+	var buf bytes.Buffer
+	err := chartTemplate.Execute(&buf, map[string]interface{}{
+		"Width":     width,
+		"Height":    len(bars)*chartRowHeight + 6,
+		"LabelEnd":  labelEnd,
+		"Axis":      axis,
+		"BarHeight": chartRowHeight - 6,
+		"Bars":      bars,
+	})
+	return buf.Bytes(), err
+}
+
+// writeChart writes to path the chart of the top ns/op changes of diffs.
+func writeChart(path string, diffs []benchdiff.BenchDiff) error {
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".svg" {
+		return fmt.Errorf("-chart: only SVG charts are supported, not %q", ext)
+	}
+	svg, err := chartSVG(diffs, *chartTop, *chartWidth)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, svg, 0644)
+}
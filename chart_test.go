@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+func TestChartSVG(t *testing.T) {
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("BenchmarkSlower<1>", 0, 10, 15),
+		nsDiff("BenchmarkFaster", 1, 10, 8),
+		nsDiff("BenchmarkSame", 2, 10, 10),
+	}
+	svg, err := chartSVG(diffs, 10, 600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := xml.Unmarshal(svg, new(interface{})); err != nil {
+		t.Errorf("chartSVG: invalid XML: %v", err)
+	}
+	s := string(svg)
+	for _, want := range []string{"BenchmarkSlower&lt;1&gt;", "+50.00%", "-20.00%", badgeRed, badgeGreen} {
+		if !strings.Contains(s, want) {
+			t.Errorf("chartSVG: missing %q", want)
+		}
+	}
+	if strings.Contains(s, "BenchmarkSame") {
+		t.Error("chartSVG: unchanged benchmarks must not be charted")
+	}
+	if _, err := chartSVG(diffs, 10, 100); err == nil {
+		t.Error("chartSVG: want error for a chart too narrow")
+	}
+}
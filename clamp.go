@@ -0,0 +1,91 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// formatPercent formats d as a percent change for display, with the
+// decimals of -delta-prec. With -clamp, changes above the clamp are
+// shown as ">+clamp%".
+func formatPercent(d benchdiff.Delta) string {
+	if *clamp > 0 && d.Percent() > *clamp {
+		return fmt.Sprintf(">%+g%%", *clamp)
+	}
+	if percentPrec < 0 {
+		return d.PercentPrec(autoPercentPrec(d.Percent()))
+	}
+	return d.PercentPrec(percentPrec)
+}
+
+// autoPercentPrec returns the decimals showing a percent change of pct
+// with three significant digits, between 0 and 4 decimals. It mirrors
+// the adaptive precision of formatNs.
+func autoPercentPrec(pct float64) int {
+	pct = math.Abs(pct)
+	switch {
+	case pct == 0:
+		return 2
+	case pct < 0.01:
+		return 4
+	case pct < 0.1:
+		return 3
+	case pct < 10:
+		return 2
+	case pct < 100:
+		return 1
+	}
+	return 0
+}
+
+// clampedMag returns the magnitude of d for sorting, changes beyond
+// -clamp counting as changes of exactly the clamp.
+func clampedMag(d benchdiff.Delta) float64 {
+	limit := 1 / (1 + *clamp/100)
+	if mag := d.Mag(); mag > limit && !math.IsInf(mag, 1) {
+		return mag
+	}
+	return limit
+}
+
+// sortMag returns the magnitude of d used by magnitude sorts, clamped
+// with -clamp-sort.
+func sortMag(d benchdiff.Delta) float64 {
+	if *clampSort {
+		return clampedMag(d)
+	}
+	return d.Mag()
+}
+
+// byClampedDelta sorts diffs by clamped magnitude of change of m, then
+// by benchmark name.
+type byClampedDelta struct {
+	diffs []benchdiff.BenchDiff
+	m     metric
+}
+
+func (x byClampedDelta) Len() int      { return len(x.diffs) }
+func (x byClampedDelta) Swap(i, j int) { x.diffs[i], x.diffs[j] = x.diffs[j], x.diffs[i] }
+func (x byClampedDelta) Less(i, j int) bool {
+	mi, mj := clampedMag(x.m.delta(x.diffs[i])), clampedMag(x.m.delta(x.diffs[j]))
+	if mi != mj {
+		return mi < mj
+	}
+	return x.diffs[i].Name() < x.diffs[j].Name()
+}
+
+// magSorter returns the magnitude sort of diffs for m, clamped with
+// -clamp-sort.
+func magSorter(diffs []benchdiff.BenchDiff, m metric) sort.Interface {
+	if *clampSort {
+		return byClampedDelta{diffs, m}
+	}
+	return m.sorter(diffs)
+}
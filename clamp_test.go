@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+func TestFormatPercent(t *testing.T) {
+	defer func(v float64) { *clamp = v }(*clamp)
+	*clamp = 1000
+	cases := []struct {
+		d    benchdiff.Delta
+		want string
+	}{
+		{benchdiff.Delta{Before: 1000, After: 1}, "-99.90%"},
+		{benchdiff.Delta{Before: 1, After: 5}, "+400.00%"},
+		{benchdiff.Delta{Before: 1, After: 1000}, ">+1000%"},
+		{benchdiff.Delta{Before: 0, After: 1}, ">+1000%"},
+	}
+	for _, tt := range cases {
+		if have := formatPercent(tt.d); have != tt.want {
+			t.Errorf("formatPercent(%s): want %q have %q", tt.d, tt.want, have)
+		}
+	}
+}
+
+func TestClampedSort(t *testing.T) {
+	defer func(c float64, s bool) { *clamp, *clampSort = c, s }(*clamp, *clampSort)
+	*clamp, *clampSort = 100, true
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("BenchmarkHuge", 0, 1, 1000),
+		nsDiff("BenchmarkBig", 1, 1, 300),
+		nsDiff("BenchmarkSmall", 2, 10, 11),
+		nsDiff("BenchmarkMedium", 3, 10, 15),
+	}
+	ns, _ := lookupMetric("ns")
+	sort.Sort(magSorter(diffs, ns))
+	var have []string
+	for _, diff := range diffs {
+		have = append(have, diff.Name())
+	}
+	// Both changes above +100% sort as equal, by name.
+	want := []string{"BenchmarkBig", "BenchmarkHuge", "BenchmarkMedium", "BenchmarkSmall"}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("clamped sort: want %v have %v", want, have)
+	}
+}
+
+func TestDeltaPrecAuto(t *testing.T) {
+	defer func(p int) { percentPrec = p }(percentPrec)
+	percentPrec = -1
+	cases := []struct {
+		d    benchdiff.Delta
+		want string
+	}{
+		{benchdiff.Delta{Before: 100000, After: 100004}, "+0.0040%"},
+		{benchdiff.Delta{Before: 1000, After: 1000.5}, "+0.050%"},
+		{benchdiff.Delta{Before: 100, After: 101.5}, "+1.50%"},
+		{benchdiff.Delta{Before: 100, After: 100}, "+0.00%"},
+		{benchdiff.Delta{Before: 100, After: 125}, "+25.0%"},
+		{benchdiff.Delta{Before: 100, After: 250}, "+150%"},
+		{benchdiff.Delta{Before: 100, After: 50}, "-50.0%"},
+	}
+	for _, tt := range cases {
+		if have := formatPercent(tt.d); have != tt.want {
+			t.Errorf("formatPercent(%s): want %q have %q", tt.d, tt.want, have)
+		}
+	}
+	percentPrec = 1
+	if have := formatPercent(benchdiff.Delta{Before: 100, After: 101.5}); have != "+1.5%" {
+		t.Errorf("formatPercent with 1 decimal: want %q have %q", "+1.5%", have)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// SGR escape sequences setting the foreground color. They all have the
+// same length, so that colored columns stay aligned by tabwriter.
+const (
+	sgrRed     = "\x1b[31m"
+	sgrGreen   = "\x1b[32m"
+	sgrYellow  = "\x1b[33m"
+	sgrDefault = "\x1b[39m"
+	sgrReset   = "\x1b[0m"
+)
+
+// SGR escape sequences setting the intensity, of the same length for
+// the same reason.
+const (
+	sgrDim    = "\x1b[02m"
+	sgrNormal = "\x1b[22m"
+)
+
+// The -color values.
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// colorDeltas is set when -color colors the changes by direction.
+var colorDeltas bool
+
+// budgetWarning is the share of its tolerance above which a change is
+// shown in yellow by -budget-color.
+const budgetWarning = 0.8
+
+// colorize wraps s in the escape sequence sgr.
+func colorize(s, sgr string) string {
+	return sgr + s + sgrReset
+}
+
+// colorCell wraps the i-th tab separated cell of line in sgr.
+func colorCell(line string, i int, sgr string) string {
+	cells := strings.Split(strings.TrimSuffix(line, "\n"), "\t")
+	cells[i] = colorize(cells[i], sgr)
+	return strings.Join(cells, "\t") + "\n"
+}
+
+// dimLine wraps every tab separated cell of line in sgrDim if dim is
+// set, or else in sgrNormal, so that the lines of a table have the same
+// escape sequences in every column.
+func dimLine(line string, dim bool) string {
+	sgr := sgrNormal
+	if dim {
+		sgr = sgrDim
+	}
+	cells := strings.Split(strings.TrimSuffix(line, "\n"), "\t")
+	for i, c := range cells {
+		cells[i] = sgr + c + sgrNormal
+	}
+	return strings.Join(cells, "\t") + "\n"
+}
+
+// colored reports whether the changes of m are colored, by -budget-color
+// or -color.
+func (m metric) colored() bool {
+	return m.budgetColored() || colorDeltas
+}
+
+// changeSGR returns the color of the change of m in diff: the one of
+// -budget-color if it colors m, otherwise red if m changed for the
+// worse, green if it changed for the better and the default color if it
+// did not change.
+func (m metric) changeSGR(diff benchdiff.BenchDiff) string {
+	if m.budgetColored() {
+		return m.budgetSGR(diff)
+	}
+	switch m.direction(diff) {
+	case -1:
+		return sgrRed
+	case 1:
+		return sgrGreen
+	}
+	return sgrDefault
+}
+
+// budgetColored reports whether -budget-color colors the changes of m.
+func (m metric) budgetColored() bool {
+	return *budgetColor && (m.tolerance != nil || tolerances.mentions(m.name) || ruleTolerances.mentions(m.name))
+}
+
+// budgetSGR returns the color of the change of m in diff: red above its
+// tolerance, yellow above budgetWarning of it, green otherwise, and the
+// default color if diff is not gated.
+func (m metric) budgetSGR(diff benchdiff.BenchDiff) string {
+	tol := m.toleranceFor(diff.Name())
+	switch {
+	case tol == nil || !m.gated(diff):
+		return sgrDefault
+	case m.overTolerance(diff):
+		return sgrRed
+	case *tol > 0 && m.gatedPercent(diff) >= budgetWarning*(*tol):
+		return sgrYellow
+	}
+	return sgrGreen
+}
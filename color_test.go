@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestBudgetSGR(t *testing.T) {
+	defer func(f bool, v float64, u map[string]bool) { *failOnDelta, *tNsPerOp, ungated = f, v, u }(*failOnDelta, *tNsPerOp, ungated)
+	*failOnDelta = true
+	*tNsPerOp = 10
+	ns, _ := lookupMetric("ns")
+	cases := []struct {
+		after float64
+		want  string
+	}{
+		{90, sgrGreen},
+		{105, sgrGreen},
+		{108, sgrYellow},
+		{109.5, sgrYellow},
+		{111, sgrRed},
+	}
+	for _, tt := range cases {
+		if have := ns.budgetSGR(nsDiff("BenchmarkA", 0, 100, tt.after)); have != tt.want {
+			t.Errorf("budgetSGR 100 -> %v: want %q have %q", tt.after, tt.want, have)
+		}
+	}
+
+	ungated = map[string]bool{"ns": true}
+	ns, _ = lookupMetric("ns")
+	if have := ns.budgetSGR(nsDiff("BenchmarkA", 0, 100, 200)); have != sgrDefault {
+		t.Errorf("budgetSGR of an ungated metric: want %q have %q", sgrDefault, have)
+	}
+}
+
+func TestColorCell(t *testing.T) {
+	want := "a\tb\t" + sgrGreen + "c" + sgrReset + "\n"
+	if have := colorCell("a\tb\tc\n", 2, sgrGreen); have != want {
+		t.Errorf("colorCell: want %q have %q", want, have)
+	}
+	for _, sgr := range []string{sgrRed, sgrGreen, sgrYellow} {
+		if len(sgr) != len(sgrDefault) {
+			t.Errorf("escape %q: want the length of %q", sgr, sgrDefault)
+		}
+	}
+}
+
+func TestChangeSGR(t *testing.T) {
+	defer func(v bool) { colorDeltas = v }(colorDeltas)
+	colorDeltas = true
+	ns, _ := lookupMetric("ns")
+	mbs, _ := lookupMetric("mbs")
+	mb := func(before, after float64) benchdiff.BenchDiff {
+		return benchdiff.BenchDiff{
+			Before: &parse.Benchmark{Name: "BenchmarkA", MBPerS: before, Measured: parse.MBPerS},
+			After:  &parse.Benchmark{Name: "BenchmarkA", MBPerS: after, Measured: parse.MBPerS},
+		}
+	}
+	cases := []struct {
+		m    metric
+		diff benchdiff.BenchDiff
+		want string
+	}{
+		{ns, nsDiff("BenchmarkA", 0, 100, 110), sgrRed},
+		{ns, nsDiff("BenchmarkA", 0, 100, 90), sgrGreen},
+		{ns, nsDiff("BenchmarkA", 0, 100, 100), sgrDefault},
+		{mbs, mb(100, 110), sgrGreen},
+		{mbs, mb(100, 90), sgrRed},
+	}
+	for _, tt := range cases {
+		if have := tt.m.changeSGR(tt.diff); have != tt.want {
+			t.Errorf("changeSGR %s %v: want %q have %q", tt.m.name, tt.m.delta(tt.diff), tt.want, have)
+		}
+	}
+}
+
+func TestColorAlignment(t *testing.T) {
+	defer func(v bool) { colorDeltas = v }(colorDeltas)
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("BenchmarkSlower", 0, 10, 12),
+		nsDiff("BenchmarkFaster", 1, 1000, 8),
+		nsDiff("BenchmarkSame", 2, 50, 50),
+	}
+	render := func() string {
+		var buf bytes.Buffer
+		w := tabwriter.NewWriter(&buf, 0, 0, 5, ' ', 0)
+		printTables(w, diffs, terminalStyle)
+		w.Flush()
+		return buf.String()
+	}
+	colorDeltas = false
+	plain := render()
+	colorDeltas = true
+	colored := render()
+	if colored == plain {
+		t.Fatal("-color=always did not color the output")
+	}
+	if !strings.Contains(colored, sgrDim+"BenchmarkSame") || strings.Contains(colored, sgrDim+"BenchmarkSlower") {
+		t.Errorf("-color=always did not dim only the unchanged row:\n%q", colored)
+	}
+	if have := regexp.MustCompile("\x1b\\[[0-9]+m").ReplaceAllString(colored, ""); have != plain {
+		t.Errorf("colored output misaligned:\n%s\nwant:\n%s", have, plain)
+	}
+}
+
+func TestDimLine(t *testing.T) {
+	if have, want := dimLine("a\tb\n", true), sgrDim+"a"+sgrNormal+"\t"+sgrDim+"b"+sgrNormal+"\n"; have != want {
+		t.Errorf("dimLine dim: want %q have %q", want, have)
+	}
+	if have, want := dimLine("a\tb\n", false), sgrNormal+"a"+sgrNormal+"\t"+sgrNormal+"b"+sgrNormal+"\n"; have != want {
+		t.Errorf("dimLine: want %q have %q", want, have)
+	}
+	if len(sgrDim) != len(sgrNormal) {
+		t.Error("sgrDim and sgrNormal differ in length, misaligning the tables")
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// metricComparators holds the comparators given by -comparator, by
+// canonical metric name.
+var metricComparators map[string]benchdiff.Comparator
+
+// parseComparators parses the comma-separated metric:name pairs of
+// -comparator, as ns:logratio,allocs:diff, naming the metrics as
+// -ungated or by their custom unit and the comparators as registered
+// with benchdiff.RegisterComparator.
+func parseComparators(list string) (map[string]benchdiff.Comparator, error) {
+	cc := make(map[string]benchdiff.Comparator)
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		i := strings.LastIndex(pair, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("want metric:comparator, have %q", pair)
+		}
+		name, cname := pair[:i], pair[i+1:]
+		if canonical, ok := metricAliases[name]; ok {
+			name = canonical
+		}
+		c, ok := benchdiff.LookupComparator(cname)
+		if !ok {
+			return nil, fmt.Errorf("unknown comparator %q, want one of %s", cname, strings.Join(benchdiff.Comparators(), ", "))
+		}
+		cc[name] = c
+	}
+	return cc, nil
+}
+
+// unknownComparatorMetrics returns the sorted metrics of -comparator
+// that no result measures.
+func unknownComparatorMetrics() []string {
+	var unknown []string
+	for name := range metricComparators {
+		if _, ok := lookupMetric(name); !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// judgedMetrics returns the sorted metrics whose -comparator is a
+// benchdiff.Judge.
+func judgedMetrics() []string {
+	var names []string
+	for name, c := range metricComparators {
+		if _, ok := c.(benchdiff.Judge); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyComparators makes the metrics of mm given a comparator by
+// -comparator format their change with it.
+func applyComparators(mm []metric) {
+	for i := range mm {
+		if c, ok := metricComparators[mm[i].name]; ok {
+			mm[i].change = func(d benchdiff.Delta) string { return c.Format(c.Change(d)) }
+		}
+	}
+}
+
+// judged reports whether the comparator of m decides its gate, and if
+// so whether the change of m in diff fails it.
+func (m metric) judged(diff benchdiff.BenchDiff) (fails, decided bool) {
+	j, ok := metricComparators[m.name].(benchdiff.Judge)
+	if !ok {
+		return false, false
+	}
+	if ungated[m.name] || gateOnly != "" && m.name != gateOnly || !m.measuredIn(diff) {
+		return false, true
+	}
+	return !j.Pass(j.Change(m.delta(diff))), true
+}
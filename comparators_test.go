@@ -0,0 +1,68 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// maxSlowdown passes the ns/op ratios up to 1.1.
+type maxSlowdown struct{}
+
+func (maxSlowdown) Change(d benchdiff.Delta) float64 { return d.Float64() }
+func (maxSlowdown) Format(change float64) string     { return "" }
+func (maxSlowdown) Pass(change float64) bool         { return change <= 1.1 }
+
+func init() {
+	benchdiff.RegisterComparator("test-max-slowdown", maxSlowdown{})
+}
+
+func TestParseComparators(t *testing.T) {
+	cc, err := parseComparators("ns/op:logratio, B/op:diff,req/s:ratio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cc) != 3 || cc["ns"] == nil || cc["bytes"] == nil || cc["req/s"] == nil {
+		t.Errorf("parseComparators: want ns, bytes and req/s have %v", cc)
+	}
+	for _, list := range []string{"ns", "ns:nope"} {
+		if _, err := parseComparators(list); err == nil {
+			t.Errorf("parseComparators(%q): want an error", list)
+		}
+	}
+}
+
+func TestComparatorGate(t *testing.T) {
+	defer func(m map[string]benchdiff.Comparator) { metricComparators = m }(metricComparators)
+	defer func(v bool) { *failOnDelta = v }(*failOnDelta)
+	*failOnDelta = true
+	var err error
+	if metricComparators, err = parseComparators("ns:test-max-slowdown,allocs:ratio"); err != nil {
+		t.Fatal(err)
+	}
+	ns, _ := lookupMetric("ns")
+	if have := ns.overTolerance(nsDiff("BenchmarkA", 0, 100, 120)); !have {
+		t.Error("overTolerance: want the judge to fail a 1.2x slowdown")
+	}
+	if have := ns.overTolerance(nsDiff("BenchmarkA", 0, 100, 105)); have {
+		t.Error("overTolerance: want the judge to pass a 1.05x slowdown")
+	}
+
+	if have := judgedMetrics(); !reflect.DeepEqual(have, []string{"ns"}) {
+		t.Errorf("judgedMetrics: want ns have %v", have)
+	}
+
+	metricComparators, _ = parseComparators("ns:logratio")
+	if have := judgedMetrics(); len(have) != 0 {
+		t.Errorf("judgedMetrics of -comparator=ns:logratio: want none have %v", have)
+	}
+	ns, _ = lookupMetric("ns")
+	if have, want := ns.changeCell(nsDiff("BenchmarkA", 0, 100, 100)), "+0.0000"; have != want {
+		t.Errorf("changeCell with -comparator=ns:logratio: want %q have %q", want, have)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// defaultConfig is the configuration file read by default, if it
+// exists.
+const defaultConfig = ".benchdiff.conf"
+
+// parseConfig parses a configuration file: lines "name = value" set the
+// flag name of fs, unless it was set in set, and the other lines are
+// per benchmark tolerances in the format of -thresholds, which are
+// returned. Blank lines and lines starting with '#' are ignored.
+func parseConfig(r io.Reader, fs *flag.FlagSet, set map[flag.Value]bool) (thresholds, error) {
+	var t thresholds
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var f *flag.Flag
+		eq := strings.Index(text, "=")
+		if eq >= 0 {
+			f = fs.Lookup(strings.TrimPrefix(strings.TrimSpace(text[:eq]), "-"))
+		}
+		switch {
+		case f == nil:
+			lt, err := parseThresholds(strings.NewReader(text))
+			if err != nil {
+				return nil, fmt.Errorf("%d:%s", line, strings.TrimPrefix(err.Error(), "1:"))
+			}
+			t = t.merge(lt)
+		case f.Name == "config":
+			return nil, fmt.Errorf("%d: -config cannot be set in a configuration file", line)
+		case set[f.Value]:
+			// The command line takes precedence.
+		default:
+			if err := fs.Set(f.Name, strings.TrimSpace(text[eq+1:])); err != nil {
+				return nil, fmt.Errorf("%d: -%s: %v", line, f.Name, err)
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// readConfig reads the configuration file at path into the command
+// line flags not set on the command line. A missing defaultConfig is
+// not an error.
+func readConfig(path string) (thresholds, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) && path == defaultConfig {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	set := make(map[flag.Value]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Value] = true })
+	t, err := parseConfig(f, flag.CommandLine, set)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%v", path, err)
+	}
+	logf("read the configuration file %s", path)
+	return t, nil
+}
+
+// merge returns the tolerances of t overridden by those of over.
+func (t thresholds) merge(over thresholds) thresholds {
+	if len(t) == 0 {
+		return over
+	}
+	merged := make(thresholds, len(t))
+	for _, src := range []thresholds{t, over} {
+		for name, tols := range src {
+			if merged[name] == nil {
+				merged[name] = make(map[string]float64)
+			}
+			for m, v := range tols {
+				merged[name][m] = v
+			}
+		}
+	}
+	return merged
+}
@@ -0,0 +1,81 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	fs := flag.NewFlagSet("benchdiff", flag.ContinueOnError)
+	errdelta := fs.Bool("errdelta", false, "")
+	tnsop := fs.Float64("tnsop", 0, "")
+	label := fs.String("old-label", "old", "")
+	fs.StringVar(label, "oldname", "old", "")
+	fs.String("config", "", "")
+	if err := fs.Parse([]string{"-oldname=main"}); err != nil {
+		t.Fatal(err)
+	}
+	set := make(map[flag.Value]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Value] = true })
+
+	tols, err := parseConfig(strings.NewReader(`# defaults
+errdelta = true
+-tnsop=5
+
+old-label = base
+BenchmarkHot: ns=2%, allocs=0
+BenchmarkX/size=8: bytes=1
+`), fs, set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !*errdelta || *tnsop != 5 {
+		t.Errorf("parseConfig: -errdelta=%t -tnsop=%g, want true and 5", *errdelta, *tnsop)
+	}
+	if *label != "main" {
+		t.Errorf("parseConfig overrode -oldname=main with %q", *label)
+	}
+	want := thresholds{
+		"BenchmarkHot":      {"ns": 2, "allocs": 0},
+		"BenchmarkX/size=8": {"bytes": 1},
+	}
+	if !reflect.DeepEqual(tols, want) {
+		t.Errorf("parseConfig tolerances: want %v have %v", want, tols)
+	}
+
+	for _, tt := range []struct {
+		config, err string
+	}{
+		{"errdelta = maybe", "1: -errdelta: "},
+		{"# comment\nconfig = other.conf", "2: -config cannot be set"},
+		{"tnsop = 5\nBenchmarkHot ns=2", "2: missing ':'"},
+		{"\nBenchmarkHot: speed=2", "2: unknown metric"},
+	} {
+		_, err := parseConfig(strings.NewReader(tt.config), fs, set)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Errorf("parseConfig(%q): want error %q..., have %v", tt.config, tt.err, err)
+		}
+	}
+}
+
+func TestMergeThresholds(t *testing.T) {
+	base := thresholds{"BenchmarkA": {"ns": 5, "allocs": 0}, "BenchmarkB": {"ns": 1}}
+	over := thresholds{"BenchmarkA": {"ns": 2}, "BenchmarkC": {"bytes": 3}}
+	want := thresholds{
+		"BenchmarkA": {"ns": 2, "allocs": 0},
+		"BenchmarkB": {"ns": 1},
+		"BenchmarkC": {"bytes": 3},
+	}
+	if have := base.merge(over); !reflect.DeepEqual(have, want) {
+		t.Errorf("merge: want %v have %v", want, have)
+	}
+	if have := thresholds(nil).merge(over); !reflect.DeepEqual(have, over) {
+		t.Errorf("merge into nil: want %v have %v", over, have)
+	}
+}
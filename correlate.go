@@ -0,0 +1,49 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// correlateKeys are the correlation keys selectable with -correlate.
+var correlateKeys = map[string]func(*parse.Benchmark) string{
+	"name": benchdiff.BenchName,
+	"base": func(b *parse.Benchmark) string {
+		base, _ := splitProcs(b.Name)
+		return base
+	},
+	"fold": func(b *parse.Benchmark) string { return strings.ToLower(b.Name) },
+}
+
+// correlateKey is the correlation key selected with -correlate.
+var correlateKey = benchdiff.BenchName
+
+// correlateKeyNames returns the sorted names of correlateKeys.
+func correlateKeyNames() []string {
+	var names []string
+	for name := range correlateKeys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// correlateName returns the -correlate key to use given the -correlate
+// value and -stripcpu, which is a shorthand for -correlate=base.
+func correlateName(name string, stripCPU bool) (string, error) {
+	if !stripCPU || name == "base" {
+		return name, nil
+	}
+	if name != "name" {
+		return "", fmt.Errorf("-stripcpu conflicts with -correlate=%s", name)
+	}
+	return "base", nil
+}
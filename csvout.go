@@ -0,0 +1,60 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// formatCSV is the -format value writing one CSV record per benchmark
+// and metric.
+const formatCSV = "csv"
+
+// csvHeader is the header record of the CSV output.
+var csvHeader = []string{"benchmark", "metric", "old", "new", "delta_pct"}
+
+// writeCSV writes to w the comparison of diffs as CSV: a header record,
+// then one record per benchmark and metric measured on both sides, in
+// the order of diffs and of the blocks. The metrics are named as in the
+// metric lists (ns, mbs, allocs, bytes, or the unit of a custom metric),
+// the values are unrounded and delta_pct is empty for a change from
+// zero. Records hidden from the tables by -changed, -changed-metric or
+// -noise are left out. The benchmarks of correlateWarnings follow, as
+// records of the "unmatched" metric whose old and new values are their
+// numbers of results.
+func writeCSV(w io.Writer, diffs []benchdiff.BenchDiff) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	metrics := activeMetrics()
+	for _, diff := range diffs {
+		for _, m := range metrics {
+			if !m.measuredIn(diff) || !shown(m, diff) {
+				continue
+			}
+			d := m.delta(diff)
+			pct := ""
+			if p := d.Percent(); !math.IsInf(p, 0) && !math.IsNaN(p) {
+				pct = strconv.FormatFloat(p, 'f', -1, 64)
+			}
+			cw.Write([]string{
+				diff.Name(),
+				m.name,
+				strconv.FormatFloat(d.Before, 'f', -1, 64),
+				strconv.FormatFloat(d.After, 'f', -1, 64),
+				pct,
+			})
+		}
+	}
+	for _, w := range correlateWarnings {
+		cw.Write([]string{w.Name, "unmatched", strconv.Itoa(w.Before), strconv.Itoa(w.After), ""})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,68 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestWriteCSV(t *testing.T) {
+	mem := parse.NsPerOp | parse.AllocsPerOp | parse.AllocedBytesPerOp
+	diffs := []benchdiff.BenchDiff{
+		{
+			Before: &parse.Benchmark{Name: "BenchmarkA", NsPerOp: 100, AllocsPerOp: 0, AllocedBytesPerOp: 64, Measured: mem},
+			After:  &parse.Benchmark{Name: "BenchmarkA", NsPerOp: 50, AllocsPerOp: 1, AllocedBytesPerOp: 64, Measured: mem},
+		},
+		nsDiff("BenchmarkB,\"quoted\"", 1, 2.5, 2.5),
+	}
+	var buf bytes.Buffer
+	if err := writeCSV(&buf, diffs); err != nil {
+		t.Fatal(err)
+	}
+	want := `benchmark,metric,old,new,delta_pct
+BenchmarkA,ns,100,50,-50
+BenchmarkA,allocs,0,1,
+BenchmarkA,bytes,64,64,0
+"BenchmarkB,""quoted""",ns,2.5,2.5,0
+`
+	if have := buf.String(); have != want {
+		t.Errorf("writeCSV: want\n%s\nhave\n%s", want, have)
+	}
+
+	defer func(v bool) { *changedOnly = v }(*changedOnly)
+	*changedOnly = true
+	buf.Reset()
+	if err := writeCSV(&buf, diffs); err != nil {
+		t.Fatal(err)
+	}
+	want = `benchmark,metric,old,new,delta_pct
+BenchmarkA,ns,100,50,-50
+BenchmarkA,allocs,0,1,
+`
+	if have := buf.String(); have != want {
+		t.Errorf("writeCSV with -changed: want\n%s\nhave\n%s", want, have)
+	}
+}
+
+func TestWriteCSVUnmatched(t *testing.T) {
+	defer func(w []benchdiff.Warning) { correlateWarnings = w }(correlateWarnings)
+	correlateWarnings = []benchdiff.Warning{{Name: "BenchmarkGone", Before: 2}, {Name: "BenchmarkNew", After: 1}}
+	var buf bytes.Buffer
+	if err := writeCSV(&buf, []benchdiff.BenchDiff{nsDiff("BenchmarkA", 0, 100, 90)}); err != nil {
+		t.Fatal(err)
+	}
+	want := `benchmark,metric,old,new,delta_pct
+BenchmarkA,ns,100,90,-10
+BenchmarkGone,unmatched,2,0,
+BenchmarkNew,unmatched,0,1,
+`
+	if have := buf.String(); have != want {
+		t.Errorf("writeCSV with unmatched benchmarks: want\n%s\nhave\n%s", want, have)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// standardUnits are the units parse.ParseLine understands.
+var standardUnits = map[string]bool{"ns/op": true, "MB/s": true, "B/op": true, "allocs/op": true}
+
+// customValues holds the values of the custom metrics, reported with
+// b.ReportMetric, of each result, by unit.
+var customValues = make(map[*parse.Benchmark]map[string]float64)
+
+// customUnits are the custom units reported on both sides, sorted. Each
+// one gets its own comparison block.
+var customUnits []string
+
+// unitTolerances holds the tolerances of the custom units given by
+// -tunit, in percent.
+var unitTolerances map[string]float64
+
+// lineCustom returns the values of the custom metrics of the result
+// line, by unit.
+func lineCustom(line string) map[string]float64 {
+	values := make(map[string]float64)
+	fields := strings.Fields(line)
+	for i := 1; i < len(fields)/2; i++ {
+		unit := fields[i*2+1]
+		if standardUnits[unit] {
+			continue
+		}
+		if v, err := strconv.ParseFloat(fields[i*2], 64); err == nil {
+			values[unit] = v
+		}
+	}
+	return values
+}
+
+// copyCustom gives to the custom metrics of from, of which to is a copy.
+func copyCustom(to, from *parse.Benchmark) {
+	if values, ok := customValues[from]; ok {
+		customValues[to] = values
+	}
+}
+
+// reportedUnits returns the custom units reported by the results of bs.
+func reportedUnits(bs parse.Set) map[string]bool {
+	units := make(map[string]bool)
+	for _, bb := range bs {
+		for _, b := range bb {
+			for unit := range customValues[b] {
+				units[unit] = true
+			}
+		}
+	}
+	return units
+}
+
+// commonUnits returns the sorted custom units reported by both before
+// and after, and a warning for each unit reported by one side only.
+func commonUnits(before, after parse.Set) (units, warnings []string) {
+	bu, au := reportedUnits(before), reportedUnits(after)
+	for unit := range bu {
+		if au[unit] {
+			units = append(units, unit)
+		} else {
+			warnings = append(warnings, fmt.Sprintf("benchdiff: ignoring %s: only reported by the old benchmarks", unit))
+		}
+	}
+	for unit := range au {
+		if !bu[unit] {
+			warnings = append(warnings, fmt.Sprintf("benchdiff: ignoring %s: only reported by the new benchmarks", unit))
+		}
+	}
+	sort.Strings(units)
+	sort.Strings(warnings)
+	return units, warnings
+}
+
+// customMetric returns the metric comparing the custom unit. Custom
+// metrics are not gated and, their direction being unknown, neither
+// improve nor regress.
+func customMetric(unit string) metric {
+	m := metric{
+		name:      unit,
+		unit:      unit,
+		column:    unit,
+		changeCol: "delta",
+		custom:    true,
+		value: func(b *parse.Benchmark) (float64, bool) {
+			v, ok := customValues[b][unit]
+			return v, ok
+		},
+		format: formatNs,
+		change: formatPercent,
+	}
+	if tol, ok := unitTolerances[unit]; ok {
+		m.tolerance = &tol
+		m.higher = strings.HasSuffix(unit, "/s")
+	}
+	m.sorter = func(d []benchdiff.BenchDiff) sort.Interface { return byMetricDelta{d, m} }
+	return m
+}
+
+// parseUnitTolerances parses the -tunit list of unit:tolerance pairs,
+// as "req/s:5,p99-ns:10%".
+func parseUnitTolerances(list string) (map[string]float64, error) {
+	tols := make(map[string]float64)
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		colon := strings.LastIndex(pair, ":")
+		if colon <= 0 {
+			return nil, fmt.Errorf("%q is not unit:tolerance", pair)
+		}
+		unit := pair[:colon]
+		if standardUnits[unit] {
+			return nil, fmt.Errorf("%s is not a custom unit, use its tolerance flag", unit)
+		}
+		tol, err := strconv.ParseFloat(strings.TrimSuffix(pair[colon+1:], "%"), 64)
+		if err != nil || tol < 0 {
+			return nil, fmt.Errorf("invalid tolerance for %s: %q", unit, pair[colon+1:])
+		}
+		tols[unit] = tol
+	}
+	return tols, nil
+}
+
+// unreportedUnits returns the units of unitTolerances missing from
+// customUnits, sorted.
+func unreportedUnits() []string {
+	reported := make(map[string]bool)
+	for _, unit := range customUnits {
+		reported[unit] = true
+	}
+	var missing []string
+	for unit := range unitTolerances {
+		if !reported[unit] {
+			missing = append(missing, unit)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// byMetricDelta sorts diffs by magnitude of change of m, then by
+// benchmark name.
+type byMetricDelta struct {
+	diffs []benchdiff.BenchDiff
+	m     metric
+}
+
+func (x byMetricDelta) Len() int      { return len(x.diffs) }
+func (x byMetricDelta) Swap(i, j int) { x.diffs[i], x.diffs[j] = x.diffs[j], x.diffs[i] }
+func (x byMetricDelta) Less(i, j int) bool {
+	mi, mj := x.m.delta(x.diffs[i]).Mag(), x.m.delta(x.diffs[j]).Mag()
+	if mi != mj {
+		return mi < mj
+	}
+	return x.diffs[i].Name() < x.diffs[j].Name()
+}
@@ -0,0 +1,115 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+func TestScanCustom(t *testing.T) {
+	data := []byte(`goos: linux
+BenchmarkA-4 1000 100 ns/op 12 items/op 64 B/op
+not a benchmark 1 ns/op
+BenchmarkB-4 1000 10 ns/op 0.5 hit-ratio bad items/op
+`)
+	want := []map[string]float64{
+		{"items/op": 12},
+		{"hit-ratio": 0.5},
+	}
+	if have := scanResults(data).custom; !reflect.DeepEqual(have, want) {
+		t.Errorf("scanResults: want custom metrics %v have %v", want, have)
+	}
+}
+
+func TestCustomBlock(t *testing.T) {
+	defer func(v []string) { customUnits = v }(customUnits)
+	oldText := "BenchmarkA 1000 100 ns/op 12 items/op 3 hits\nBenchmarkB 1000 10 ns/op\n"
+	newText := "BenchmarkA 1000 100 ns/op 15 items/op 7 misses\nBenchmarkB 1000 10 ns/op\n"
+	before := scanResults([]byte(oldText)).set()
+	after := scanResults([]byte(newText)).set()
+
+	var warnings []string
+	customUnits, warnings = commonUnits(before, after)
+	if want := []string{"items/op"}; !reflect.DeepEqual(customUnits, want) {
+		t.Errorf("commonUnits: want %v have %v", want, customUnits)
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "hits") || !strings.Contains(warnings[1], "misses") {
+		t.Errorf("commonUnits: want warnings about hits and misses have %q", warnings)
+	}
+
+	diffs, _ := benchdiff.Compare(before, after, benchdiff.Options{})
+	m, ok := lookupMetric("items/op")
+	if !ok {
+		t.Fatal("no items/op metric")
+	}
+	var buf bytes.Buffer
+	printBlocks(&buf, diffs, terminalStyle, func(n metric, _ benchdiff.BenchDiff) bool { return n.name == m.name }, nil)
+	want := "benchmark\told items/op\tnew items/op\tdelta\n" +
+		"BenchmarkA\t12.0\t15.0\t+25.00%\n" +
+		"[geomean]\t12.0\t15.0\t+25.00%\n"
+	if have := buf.String(); have != want {
+		t.Errorf("custom block: want\n%q\nhave\n%q", want, have)
+	}
+	if m.direction(diffs[0]) != 0 || m.tolerance != nil {
+		t.Error("custom metrics must neither improve nor regress, nor be gated")
+	}
+}
+
+func TestCustomCopied(t *testing.T) {
+	text := "BenchmarkA 1000 100 ns/op 12 items/op\nBenchmarkA 1000 90 ns/op 10 items/op\n"
+	before := scanResults([]byte(text)).set()
+	after := scanResults([]byte(text)).set()
+	diffs, _ := benchdiff.Compare(before, after, benchdiff.Options{Best: true, Copied: copyCustom})
+	if len(diffs) != 1 || customValues[diffs[0].Before]["items/op"] != 10 || customValues[diffs[0].After]["items/op"] != 10 {
+		t.Errorf("Compare with Best: want the items/op of the best result kept, have %v", diffs)
+	}
+}
+
+func TestParseUnitTolerances(t *testing.T) {
+	tols, err := parseUnitTolerances("req/s:5, p99-ns:10%,")
+	if want := map[string]float64{"req/s": 5, "p99-ns": 10}; err != nil || !reflect.DeepEqual(tols, want) {
+		t.Errorf("parseUnitTolerances: want %v have %v, %v", want, tols, err)
+	}
+	for _, list := range []string{"req/s", ":5", "req/s:fast", "req/s:-1", "ns/op:5"} {
+		if _, err := parseUnitTolerances(list); err == nil {
+			t.Errorf("parseUnitTolerances(%q): want an error", list)
+		}
+	}
+}
+
+func TestUnitTolerance(t *testing.T) {
+	defer func(v []string) { customUnits = v }(customUnits)
+	defer func(v map[string]float64) { unitTolerances = v }(unitTolerances)
+	defer func(v bool) { *failOnDelta = v }(*failOnDelta)
+	oldText := "BenchmarkA 1000 100 ns/op 1000 req/s 50 p99-ns\n"
+	newText := "BenchmarkA 1000 100 ns/op 900 req/s 60 p99-ns\n"
+	before := scanResults([]byte(oldText)).set()
+	after := scanResults([]byte(newText)).set()
+	customUnits, _ = commonUnits(before, after)
+	unitTolerances = map[string]float64{"req/s": 5, "p99-ns": 25, "items/op": 1}
+	*failOnDelta = true
+	diffs, _ := benchdiff.Compare(before, after, benchdiff.Options{})
+
+	reqs, _ := lookupMetric("req/s")
+	if !reqs.higher || !reqs.regressed(diffs[0]) || !reqs.regressedBeyond(diffs[0]) {
+		t.Error("req/s: want a regression beyond 5% from 1000 to 900")
+	}
+	p99, _ := lookupMetric("p99-ns")
+	if p99.higher || !p99.regressed(diffs[0]) || p99.regressedBeyond(diffs[0]) || p99.exceeded(diffs[0]) {
+		t.Error("p99-ns: want a regression within 25% from 50 to 60")
+	}
+	unitTolerances["p99-ns"] = 10
+	if p99, _ = lookupMetric("p99-ns"); !p99.exceeded(diffs[0]) {
+		t.Error("p99-ns: want +20% to exceed 10%")
+	}
+	if want, have := []string{"items/op"}, unreportedUnits(); !reflect.DeepEqual(have, want) {
+		t.Errorf("unreportedUnits: want %v have %v", want, have)
+	}
+}
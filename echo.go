@@ -0,0 +1,25 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// echoSet writes the results of bs as benchmark lines, in parse order.
+func echoSet(w io.Writer, bs parse.Set) {
+	var bb []*parse.Benchmark
+	for _, results := range bs {
+		bb = append(bb, results...)
+	}
+	sort.Slice(bb, func(i, j int) bool { return bb[i].Ord < bb[j].Ord })
+	for _, b := range bb {
+		fmt.Fprintln(w, b)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestEchoSet(t *testing.T) {
+	in := `goos: linux
+BenchmarkB-4   	 1000000	      1500 ns/op	      16 B/op	       1 allocs/op
+BenchmarkA-4   	 2000000	       800.5 ns/op
+BenchmarkB-4   	 1000000	      1400 ns/op	      16 B/op	       1 allocs/op
+PASS
+`
+	bs, err := parse.ParseSet(bytes.NewBufferString(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	echoSet(&buf, bs)
+	want := "BenchmarkB-4 1000000 1500.00 ns/op 16 B/op 1 allocs/op\n" +
+		"BenchmarkA-4 2000000 800.50 ns/op\n" +
+		"BenchmarkB-4 1000000 1400.00 ns/op 16 B/op 1 allocs/op\n"
+	if have := buf.String(); have != want {
+		t.Errorf("echoSet:\nwant:\n%s\nhave:\n%s", want, have)
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// effectSizes holds the Cohen's d of the ns/op changes of the
+// benchmarks with repeated results, for -min-effect-size.
+var effectSizes map[string]float64
+
+// nsEffectSizes returns the Cohen's d of the ns/op change of the
+// benchmarks having at least two ns/op results on both sides.
+func nsEffectSizes(before, after parse.Set) map[string]float64 {
+	sizes := make(map[string]float64)
+	for name, bb := range before {
+		if d, ok := cohensD(nsSamples(bb), nsSamples(after[name])); ok {
+			sizes[name] = d
+		}
+	}
+	return sizes
+}
+
+// effectExceeded reports whether -min-effect-size decides the ns/op
+// gate of diff, and if so whether the slowdown is too large.
+func effectExceeded(diff benchdiff.BenchDiff) (exceeded, decided bool) {
+	if *minEffect <= 0 {
+		return false, false
+	}
+	d, ok := effectSizes[diff.Name()]
+	if !ok {
+		return false, false
+	}
+	return d > *minEffect, true
+}
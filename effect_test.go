@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestEffectGate(t *testing.T) {
+	defer func(f bool, e float64, s map[string]float64) {
+		*failOnDelta, *minEffect, effectSizes = f, e, s
+	}(*failOnDelta, *minEffect, effectSizes)
+	*failOnDelta = true
+	*minEffect = 1
+
+	before := parse.Set{
+		"BenchmarkNoisy":  nsRuns("BenchmarkNoisy", 80, 100, 120),
+		"BenchmarkSteady": nsRuns("BenchmarkSteady", 100, 101, 99),
+		"BenchmarkOnce":   nsRuns("BenchmarkOnce", 100),
+	}
+	after := parse.Set{
+		"BenchmarkNoisy":  nsRuns("BenchmarkNoisy", 90, 110, 130),
+		"BenchmarkSteady": nsRuns("BenchmarkSteady", 103, 104, 102),
+		"BenchmarkOnce":   nsRuns("BenchmarkOnce", 110),
+	}
+	effectSizes = nsEffectSizes(before, after)
+	if _, ok := effectSizes["BenchmarkOnce"]; ok {
+		t.Error("nsEffectSizes: want no effect size for a single result")
+	}
+
+	ns, _ := lookupMetric("ns")
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"BenchmarkNoisy", false}, // +10% but d = 0.5
+		{"BenchmarkSteady", true}, // +3% but d = 3
+		{"BenchmarkOnce", true},   // no effect size, +10% above -tnsop
+	}
+	for _, tt := range tests {
+		diff := benchdiff.BenchDiff{Before: before[tt.name][0], After: after[tt.name][0]}
+		if have := ns.exceeded(diff); have != tt.want {
+			t.Errorf("%s: exceeded: want %t have %t", tt.name, tt.want, have)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// exclusion is a flag a mode cannot be combined with, and whether it is
+// in use.
+type exclusion struct {
+	flag string // as written in the message, "-format"
+	set  bool
+}
+
+// exclusionMessage returns the usage error of what combined with the
+// flags of ex in use, naming all of them, or "" if none is.
+func exclusionMessage(what string, ex []exclusion) string {
+	var names []string
+	used := false
+	for _, e := range ex {
+		names = append(names, e.flag)
+		used = used || e.set
+	}
+	if !used {
+		return ""
+	}
+	list := names[0]
+	if n := len(names); n > 1 {
+		list = strings.Join(names[:n-1], ", ") + " or " + names[n-1]
+	}
+	return fmt.Sprintf("%s cannot be used with %s", what, list)
+}
+
+// rejectExclusions exits with a usage error if a flag of ex is in use
+// along with what.
+func rejectExclusions(what string, ex []exclusion) {
+	if msg := exclusionMessage(what, ex); msg != "" {
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(2)
+	}
+}
+
+// flagGiven reports whether the flag name was set on the command line
+// or by the -config file.
+func flagGiven(name string) bool {
+	given := false
+	flag.Visit(func(f *flag.Flag) { given = given || f.Name == name })
+	return given
+}
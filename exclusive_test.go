@@ -0,0 +1,21 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestExclusionMessage(t *testing.T) {
+	ex := []exclusion{{"-tree", false}, {"-unified", true}, {"-both", false}}
+	if want, have := "-template cannot be used with -tree, -unified or -both", exclusionMessage("-template", ex); have != want {
+		t.Errorf("exclusionMessage: want %q have %q", want, have)
+	}
+	ex[1].set = false
+	if have := exclusionMessage("-template", ex); have != "" {
+		t.Errorf("exclusionMessage without a flag in use: want none have %q", have)
+	}
+	if want, have := "-x cannot be used with -y", exclusionMessage("-x", []exclusion{{"-y", true}}); have != want {
+		t.Errorf("exclusionMessage of one flag: want %q have %q", want, have)
+	}
+}
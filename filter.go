@@ -0,0 +1,71 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// nameFilter holds the regexp given by -filter, nil if none.
+var nameFilter *regexp.Regexp
+
+// matchRe and excludeRe hold the regexps given by -match and -exclude,
+// nil if none.
+var matchRe, excludeRe *regexp.Regexp
+
+// filterDiffs returns the diffs of diffs whose name matches re. With
+// -v the others are logged.
+func filterDiffs(diffs []benchdiff.BenchDiff, re *regexp.Regexp) []benchdiff.BenchDiff {
+	var kept []benchdiff.BenchDiff
+	for _, diff := range diffs {
+		if matchesName(re, diff.Name()) {
+			kept = append(kept, diff)
+		} else {
+			logf("skipping %s: not matched by -filter", diff.Name())
+		}
+	}
+	return kept
+}
+
+// matchesName reports whether re matches the benchmark name, with or
+// without its package.
+func matchesName(re *regexp.Regexp, name string) bool {
+	for _, n := range nameForms(name) {
+		if re.MatchString(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterSet removes from bs the benchmarks whose name does not match re.
+func filterSet(bs parse.Set, re *regexp.Regexp) {
+	for name := range bs {
+		if !matchesName(re, name) {
+			delete(bs, name)
+		}
+	}
+}
+
+// selectNames removes from bs, before correlation, the benchmarks whose
+// name does not match -match or matches -exclude. With -v they are
+// logged in name order, for the results labeled label.
+func selectNames(bs parse.Set, label string) {
+	var skipped []string
+	for name := range bs {
+		if matchRe != nil && !matchesName(matchRe, name) || excludeRe != nil && matchesName(excludeRe, name) {
+			delete(bs, name)
+			skipped = append(skipped, name)
+		}
+	}
+	sort.Strings(skipped)
+	for _, name := range skipped {
+		logf("skipping the %s results of %s: left out by -match or -exclude", label, name)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestFilterDiffs(t *testing.T) {
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("BenchmarkEncode/small", 0, 1, 1),
+		nsDiff("BenchmarkDecode/small", 1, 1, 1),
+		nsDiff("BenchmarkEncode/large", 2, 1, 1),
+	}
+	var names []string
+	for _, diff := range filterDiffs(diffs, regexp.MustCompile("^BenchmarkEncode")) {
+		names = append(names, diff.Name())
+	}
+	if want := []string{"BenchmarkEncode/small", "BenchmarkEncode/large"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("want %v have %v", want, names)
+	}
+	if kept := filterDiffs(diffs, regexp.MustCompile("Parse")); len(kept) != 0 {
+		t.Errorf("want no diff, have %v", kept)
+	}
+}
+
+func TestFilterSet(t *testing.T) {
+	bs := parse.Set{
+		"BenchmarkA": nsRuns("BenchmarkA", 1),
+		"BenchmarkB": nsRuns("BenchmarkB", 1),
+	}
+	filterSet(bs, regexp.MustCompile("A$"))
+	if _, ok := bs["BenchmarkA"]; !ok || len(bs) != 1 {
+		t.Errorf("want only BenchmarkA, have %v", bs)
+	}
+}
+
+func TestSelectNames(t *testing.T) {
+	defer func(m, e *regexp.Regexp) { matchRe, excludeRe = m, e }(matchRe, excludeRe)
+	cases := []struct {
+		match, exclude string
+		want           []string
+	}{
+		{"", "", []string{"BenchmarkDecode/small-4", "BenchmarkEncode/large-4", "BenchmarkEncode/small-4"}},
+		{"Encode", "", []string{"BenchmarkEncode/large-4", "BenchmarkEncode/small-4"}},
+		{"", "/small", []string{"BenchmarkEncode/large-4"}},
+		{"Encode", "/large", []string{"BenchmarkEncode/small-4"}},
+	}
+	for _, tt := range cases {
+		matchRe, excludeRe = nil, nil
+		if tt.match != "" {
+			matchRe = regexp.MustCompile(tt.match)
+		}
+		if tt.exclude != "" {
+			excludeRe = regexp.MustCompile(tt.exclude)
+		}
+		bs := parse.Set{}
+		for _, name := range []string{"BenchmarkEncode/small-4", "BenchmarkDecode/small-4", "BenchmarkEncode/large-4"} {
+			bs[name] = nsRuns(name, 1)
+		}
+		selectNames(bs, "old")
+		var names []string
+		for name := range bs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("-match=%q -exclude=%q: want %v have %v", tt.match, tt.exclude, tt.want, names)
+		}
+	}
+}
+
+func TestMatchBeforeCorrelation(t *testing.T) {
+	defer func(m *regexp.Regexp) { matchRe = m }(matchRe)
+	matchRe = regexp.MustCompile("Kept")
+	before := parse.Set{
+		"BenchmarkKept":    nsRuns("BenchmarkKept", 10),
+		"BenchmarkRemoved": nsRuns("BenchmarkRemoved", 10),
+	}
+	after := parse.Set{"BenchmarkKept": nsRuns("BenchmarkKept", 12)}
+	diffs, _, _ := prepare(before, after, benchHeader{}, benchHeader{})
+	if len(diffs) != 1 || diffs[0].Name() != "BenchmarkKept" {
+		t.Errorf("prepare with -match: want only BenchmarkKept have %v", diffs)
+	}
+}
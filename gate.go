@@ -0,0 +1,264 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// violation is a measurement whose delta exceeded its tolerance.
+type violation struct {
+	name      string // benchmark name
+	unit      string // unit of the metric
+	delta     benchdiff.Delta
+	regressed bool // the metric changed for the worse
+}
+
+// message describes v the way -errdelta reports it.
+func (v violation) message() string {
+	return fmt.Sprintf("%s: %s %s delta between benchmarks", v.name, v.delta.PercentAsStr(), v.unit)
+}
+
+// violationSummary describes all the violations of vv, one per line.
+func violationSummary(vv []violation) string {
+	s := "violation"
+	if len(vv) > 1 {
+		s += "s"
+	}
+	lines := []string{fmt.Sprintf("benchdiff: %d tolerance %s:", len(vv), s)}
+	for _, v := range vv {
+		lines = append(lines, "\t"+v.message())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// exceeded reports whether -errdelta is set and the change of m
+// between the two sides of diff is above its tolerance and not listed
+// in the -accepted file.
+func (m metric) exceeded(diff benchdiff.BenchDiff) bool {
+	return m.overTolerance(diff) && !accepted.covers(diff.Name(), m.name)
+}
+
+// overTolerance reports whether -errdelta is set and the change of m
+// between the two sides of diff is above its tolerance, as beyond
+// decides for the percent change of gatedPercent.
+func (m metric) overTolerance(diff benchdiff.BenchDiff) bool {
+	return *failOnDelta && m.beyond(diff, m.gatedPercent(diff))
+}
+
+// beyond reports whether the change of m in diff, of percent pct, fails
+// the gate: the -policy blaming m, else the benchdiff.Judge of m not
+// passing it, else pct above the tolerance of m, or with -min-effect-size
+// the effect size of a repeated ns/op change above its minimum. Changes
+// within the absolute tolerance or -threshold pass, allocs/op going from
+// zero fails with -fail-on-new-allocs, and with -gate-both-measured a
+// change measured on one side only passes.
+func (m metric) beyond(diff benchdiff.BenchDiff, pct float64) bool {
+	if *gateBoth && !m.gated(diff) {
+		return false
+	}
+	if gatePolicy != nil {
+		return gatePolicy.blames(diff, m.name)
+	}
+	if fails, ok := m.judged(diff); ok {
+		return fails
+	}
+	if *newAllocs && m.name == "allocs" && newAllocations(m, m.delta(diff)) {
+		return true
+	}
+	tol := m.toleranceFor(diff.Name())
+	if tol == nil {
+		return false
+	}
+	if m.absolute != nil && m.delta(diff).Abs() <= *m.absolute || belowThreshold(m.delta(diff)) {
+		return false
+	}
+	if m.name == "ns" {
+		if exceeded, ok := effectExceeded(diff); ok {
+			return exceeded
+		}
+	}
+	return pct > *tol
+}
+
+// Directions of the changes -fail-on gates.
+const (
+	failOnRegression  = "regression"
+	failOnImprovement = "improvement"
+	failOnAny         = "any"
+)
+
+// gatedPercent returns the percent change of m in diff compared with
+// its tolerance. With -regressonly or -fail-on=regression it is the
+// size of the change for regressions, whatever the direction of the
+// metric, and 0 otherwise; with -fail-on=improvement likewise for the
+// improvements, and with -fail-on=any the size of any change. By
+// default it is the signed change, increases failing.
+func (m metric) gatedPercent(diff benchdiff.BenchDiff) float64 {
+	pct := m.delta(diff).Percent()
+	switch {
+	case *regressOnly || *failOn == failOnRegression:
+		if !m.regressed(diff) {
+			return 0
+		}
+	case *failOn == failOnImprovement:
+		if !m.improved(diff) {
+			return 0
+		}
+	case *failOn != failOnAny:
+		return pct
+	}
+	return math.Abs(pct)
+}
+
+// toleranceFor returns the tolerance of m for the benchmark name, nil
+// if it is not gated. The -tolerance rules take precedence over the
+// -thresholds file, which takes precedence over the tolerance flags,
+// and -ungated and -gate-metric over all of them.
+func (m metric) toleranceFor(name string) *float64 {
+	if ungated[m.name] || gateOnly != "" && m.name != gateOnly {
+		return nil
+	}
+	if tol, ok := ruleTolerances.lookup(name, m.name); ok {
+		return &tol
+	}
+	if tol, ok := tolerances.lookup(name, m.name); ok {
+		return &tol
+	}
+	return m.tolerance
+}
+
+// showsHeadroom reports whether the block of m has a headroom column.
+func (m metric) showsHeadroom() bool {
+	return *headroom && (m.tolerance != nil || tolerances.mentions(m.name) || ruleTolerances.mentions(m.name))
+}
+
+// headroomCell formats the share of its tolerance used by the change
+// of m in diff. Improvements use none of it.
+func (m metric) headroomCell(diff benchdiff.BenchDiff) string {
+	tol := m.toleranceFor(diff.Name())
+	if tol == nil || *gateBoth && !m.gated(diff) {
+		return "n/a"
+	}
+	return budgetShare(m.gatedPercent(diff), *tol)
+}
+
+// budgetShare formats pct as a percentage of the tolerance tol.
+func budgetShare(pct, tol float64) string {
+	switch {
+	case pct <= 0:
+		return "0% of budget"
+	case tol == 0:
+		return "+Inf% of budget"
+	}
+	return fmt.Sprintf("%.0f%% of budget", 100*pct/tol)
+}
+
+// gated reports whether m has a tolerance and a value on both sides
+// of diff.
+func (m metric) gated(diff benchdiff.BenchDiff) bool {
+	if m.toleranceFor(diff.Name()) == nil || !m.measuredIn(diff) {
+		return false
+	}
+	_, bok := m.value(diff.Before)
+	_, aok := m.value(diff.After)
+	return bok && aok
+}
+
+// gatedCount returns the number of benchmark and metric pairs of diffs
+// that -errdelta checks against a tolerance.
+func gatedCount(diffs []benchdiff.BenchDiff) int {
+	n := 0
+	for _, m := range activeMetrics() {
+		for _, diff := range diffs {
+			if m.gated(diff) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// collectViolations returns the tolerance violations of diffs for
+// every metric, in display order.
+func collectViolations(diffs []benchdiff.BenchDiff) []violation {
+	var violations []violation
+	for _, m := range activeMetrics() {
+		for _, diff := range diffs {
+			if m.measuredIn(diff) && m.exceeded(diff) {
+				violations = append(violations, violation{diff.Name(), m.unit, m.delta(diff), m.regressed(diff)})
+			}
+		}
+	}
+	return violations
+}
+
+// gateFailure returns the message benchdiff must fail with given
+// violations, or "" if the comparison passes. Without -max-regressions
+// any violation fails, listing them all; with it, only the benchmarks
+// having a violation that is a regression count.
+func gateFailure(violations []violation) string {
+	if *maxRegress < 0 {
+		if len(violations) > 0 {
+			return violationSummary(violations)
+		}
+		return ""
+	}
+	if names := regressedNames(violations); len(names) > *maxRegress {
+		s := "benchmark"
+		if len(names) > 1 {
+			s += "s"
+		}
+		return fmt.Sprintf("benchdiff: %d %s regressed beyond a tolerance, more than the %d allowed: %s", len(names), s, *maxRegress, strings.Join(names, ", "))
+	}
+	return ""
+}
+
+// exitRegressed is the exit status of -exitcode when a regression was
+// found.
+const exitRegressed = 3
+
+// regressionCount returns the number of benchmark and metric pairs of
+// diffs that regressed beyond their tolerance, as -exitcode counts them.
+func regressionCount(diffs []benchdiff.BenchDiff) int {
+	n := 0
+	for _, m := range activeMetrics() {
+		for _, diff := range diffs {
+			if m.measuredIn(diff) && m.regressedBeyond(diff) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// regressedBeyond reports whether m changed for the worse in diff
+// beyond its tolerance, gated as -errdelta gates it with
+// -fail-on=regression, and the change is not listed in the -accepted
+// file. Ungated metrics never regress.
+func (m metric) regressedBeyond(diff benchdiff.BenchDiff) bool {
+	if !m.regressed(diff) || accepted.covers(diff.Name(), m.name) {
+		return false
+	}
+	return m.beyond(diff, math.Abs(m.delta(diff).Percent()))
+}
+
+// regressedNames returns the names of the benchmarks having at least
+// one violation that is a regression, in order of first appearance.
+func regressedNames(vv []violation) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, v := range vv {
+		if v.regressed && !seen[v.name] {
+			seen[v.name] = true
+			names = append(names, v.name)
+		}
+	}
+	return names
+}
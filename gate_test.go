@@ -0,0 +1,311 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestRegressedNames(t *testing.T) {
+	vv := []violation{
+		{name: "BenchmarkB", unit: "ns/op", regressed: true},
+		{name: "BenchmarkC", unit: "MB/s"}, // an improvement beyond the tolerance
+		{name: "BenchmarkA", unit: "ns/op", regressed: true},
+		{name: "BenchmarkB", unit: "allocs/op", regressed: true},
+	}
+	want := []string{"BenchmarkB", "BenchmarkA"}
+	if have := regressedNames(vv); !reflect.DeepEqual(want, have) {
+		t.Errorf("regressedNames: want %v have %v", want, have)
+	}
+	if have := regressedNames(nil); len(have) != 0 {
+		t.Errorf("regressedNames(nil): want none have %v", have)
+	}
+}
+
+func TestGatedCount(t *testing.T) {
+	mem := parse.NsPerOp | parse.AllocsPerOp | parse.AllocedBytesPerOp
+	ns := &parse.Benchmark{Name: "BenchmarkA", NsPerOp: 10, Measured: parse.NsPerOp}
+	withMem := &parse.Benchmark{Name: "BenchmarkA", NsPerOp: 10, AllocsPerOp: 1, Measured: mem}
+	none := &parse.Benchmark{Name: "BenchmarkB"}
+	tests := []struct {
+		diffs []benchdiff.BenchDiff
+		want  int
+	}{
+		{nil, 0},
+		{[]benchdiff.BenchDiff{{Before: none, After: none}}, 0},
+		{[]benchdiff.BenchDiff{{Before: ns, After: ns}}, 1},
+		{[]benchdiff.BenchDiff{{Before: withMem, After: ns}}, 1},
+		{[]benchdiff.BenchDiff{{Before: withMem, After: withMem}, {Before: ns, After: ns}}, 4},
+	}
+	for i, tt := range tests {
+		if have := gatedCount(tt.diffs); have != tt.want {
+			t.Errorf("#%d: gatedCount: want %d have %d", i, tt.want, have)
+		}
+	}
+}
+
+func TestBudgetShare(t *testing.T) {
+	cases := []struct {
+		pct, tol float64
+		want     string
+	}{
+		{-3, 5, "0% of budget"},
+		{0, 0, "0% of budget"},
+		{4, 5, "80% of budget"},
+		{7.5, 5, "150% of budget"},
+		{1, 0, "+Inf% of budget"},
+	}
+	for _, tt := range cases {
+		if have := budgetShare(tt.pct, tt.tol); have != tt.want {
+			t.Errorf("budgetShare(%v, %v): want %q have %q", tt.pct, tt.tol, tt.want, have)
+		}
+	}
+}
+
+func TestGateMetric(t *testing.T) {
+	defer func(f bool, g string) { *failOnDelta, gateOnly = f, g }(*failOnDelta, gateOnly)
+	*failOnDelta = true
+	gateOnly = "allocs"
+	mem := parse.NsPerOp | parse.AllocsPerOp
+	diff := benchdiff.BenchDiff{
+		Before: &parse.Benchmark{Name: "BenchmarkA", NsPerOp: 10, AllocsPerOp: 2, Measured: mem},
+		After:  &parse.Benchmark{Name: "BenchmarkA", NsPerOp: 20, AllocsPerOp: 3, Measured: mem},
+	}
+	var units []string
+	for _, v := range collectViolations([]benchdiff.BenchDiff{diff}) {
+		units = append(units, v.unit)
+	}
+	if want := []string{"allocs/op"}; !reflect.DeepEqual(want, units) {
+		t.Errorf("collectViolations with -gate-metric allocs: want %v have %v", want, units)
+	}
+}
+
+func TestFailOnNewAllocs(t *testing.T) {
+	defer func(f, n bool, g string) { *failOnDelta, *newAllocs, gateOnly = f, n, g }(*failOnDelta, *newAllocs, gateOnly)
+	*failOnDelta, gateOnly = true, "ns"
+	mem := parse.NsPerOp | parse.AllocsPerOp | parse.AllocedBytesPerOp
+	diff := func(allocs uint64) benchdiff.BenchDiff {
+		return benchdiff.BenchDiff{
+			Before: &parse.Benchmark{Name: "BenchmarkA", NsPerOp: 10, Measured: mem},
+			After:  &parse.Benchmark{Name: "BenchmarkA", NsPerOp: 10, AllocsPerOp: allocs, AllocedBytesPerOp: 16 * allocs, Measured: mem},
+		}
+	}
+	allocs, _ := lookupMetric("allocs")
+	if have := allocs.changeCell(diff(3)); have != newAllocMark {
+		t.Errorf("changeCell of 0 -> 3 allocs/op: want %q have %q", newAllocMark, have)
+	}
+	if v := collectViolations([]benchdiff.BenchDiff{diff(3)}); len(v) != 0 {
+		t.Errorf("collectViolations with -gate-metric ns: want none have %v", v)
+	}
+	*newAllocs = true
+	var units []string
+	for _, v := range collectViolations([]benchdiff.BenchDiff{diff(3), diff(0)}) {
+		units = append(units, v.unit)
+	}
+	if want := []string{"allocs/op"}; !reflect.DeepEqual(want, units) {
+		t.Errorf("collectViolations with -fail-on-new-allocs: want %v have %v", want, units)
+	}
+}
+
+func TestRegressOnly(t *testing.T) {
+	defer func(f, r bool, ns, mbs float64) {
+		*failOnDelta, *regressOnly, *tNsPerOp, *tMbPerS = f, r, ns, mbs
+	}(*failOnDelta, *regressOnly, *tNsPerOp, *tMbPerS)
+	*failOnDelta, *tNsPerOp, *tMbPerS = true, 10, 10
+	ns, _ := lookupMetric("ns")
+	mbs, _ := lookupMetric("mbs")
+	b := func(ns, mbs float64) *parse.Benchmark {
+		return &parse.Benchmark{Name: "BenchmarkA", NsPerOp: ns, MBPerS: mbs, Measured: parse.NsPerOp | parse.MBPerS}
+	}
+	tests := []struct {
+		name           string
+		m              metric
+		diff           benchdiff.BenchDiff
+		plain, regonly bool
+	}{
+		{"slower", ns, benchdiff.BenchDiff{Before: b(100, 0), After: b(150, 0)}, true, true},
+		{"faster", ns, benchdiff.BenchDiff{Before: b(150, 0), After: b(100, 0)}, false, false},
+		{"throughput down", mbs, benchdiff.BenchDiff{Before: b(0, 150), After: b(0, 100)}, false, true},
+		{"throughput up", mbs, benchdiff.BenchDiff{Before: b(0, 100), After: b(0, 150)}, true, false},
+	}
+	for _, tt := range tests {
+		*regressOnly = false
+		if have := tt.m.exceeded(tt.diff); have != tt.plain {
+			t.Errorf("%s: exceeded: want %t have %t", tt.name, tt.plain, have)
+		}
+		*regressOnly = true
+		if have := tt.m.exceeded(tt.diff); have != tt.regonly {
+			t.Errorf("%s: exceeded with -regressonly: want %t have %t", tt.name, tt.regonly, have)
+		}
+	}
+}
+
+func TestFailOn(t *testing.T) {
+	defer func(f bool, on string, ns, mbs float64) {
+		*failOnDelta, *failOn, *tNsPerOp, *tMbPerS = f, on, ns, mbs
+	}(*failOnDelta, *failOn, *tNsPerOp, *tMbPerS)
+	*failOnDelta, *tNsPerOp, *tMbPerS = true, 10, 10
+	ns, _ := lookupMetric("ns")
+	mbs, _ := lookupMetric("mbs")
+	b := func(ns, mbs float64) *parse.Benchmark {
+		return &parse.Benchmark{Name: "BenchmarkA", NsPerOp: ns, MBPerS: mbs, Measured: parse.NsPerOp | parse.MBPerS}
+	}
+	tests := []struct {
+		name                    string
+		m                       metric
+		diff                    benchdiff.BenchDiff
+		regression, improvement bool
+	}{
+		{"slower", ns, benchdiff.BenchDiff{Before: b(100, 0), After: b(150, 0)}, true, false},
+		{"faster", ns, benchdiff.BenchDiff{Before: b(150, 0), After: b(100, 0)}, false, true},
+		{"slightly faster", ns, benchdiff.BenchDiff{Before: b(100, 0), After: b(95, 0)}, false, false},
+		{"throughput down", mbs, benchdiff.BenchDiff{Before: b(0, 150), After: b(0, 100)}, true, false},
+		{"throughput up", mbs, benchdiff.BenchDiff{Before: b(0, 100), After: b(0, 150)}, false, true},
+	}
+	for _, tt := range tests {
+		for on, want := range map[string]bool{
+			failOnRegression:  tt.regression,
+			failOnImprovement: tt.improvement,
+			failOnAny:         tt.regression || tt.improvement,
+		} {
+			*failOn = on
+			if have := tt.m.exceeded(tt.diff); have != want {
+				t.Errorf("%s: exceeded with -fail-on=%s: want %t have %t", tt.name, on, want, have)
+			}
+		}
+	}
+}
+
+func TestViolationSummary(t *testing.T) {
+	defer func(v int) { *maxRegress = v }(*maxRegress)
+	*maxRegress = -1
+	if have := gateFailure(nil); have != "" {
+		t.Errorf("no violation: want pass, have %q", have)
+	}
+	vv := []violation{
+		{"BenchmarkA", "ns/op", benchdiff.Delta{Before: 100, After: 120}, true},
+		{"BenchmarkB", "allocs/op", benchdiff.Delta{Before: 2, After: 3}, true},
+		{"BenchmarkC", "MB/s", benchdiff.Delta{Before: 10, After: 20}, false},
+	}
+	want := "benchdiff: 3 tolerance violations:\n" +
+		"\tBenchmarkA: +20.00% ns/op delta between benchmarks\n" +
+		"\tBenchmarkB: +50.00% allocs/op delta between benchmarks\n" +
+		"\tBenchmarkC: +100.00% MB/s delta between benchmarks"
+	if have := gateFailure(vv); have != want {
+		t.Errorf("want\n%s\nhave\n%s", want, have)
+	}
+
+	*maxRegress = 1
+	want = "benchdiff: 2 benchmarks regressed beyond a tolerance, more than the 1 allowed: BenchmarkA, BenchmarkB"
+	if have := gateFailure(vv); have != want {
+		t.Errorf("-max-regressions=1: want %q have %q", want, have)
+	}
+	*maxRegress = 0
+	want = "benchdiff: 1 benchmark regressed beyond a tolerance, more than the 0 allowed: BenchmarkA"
+	if have := gateFailure(vv[:1]); have != want {
+		t.Errorf("-max-regressions=0: want %q have %q", want, have)
+	}
+	if have := gateFailure(vv[2:]); have != "" {
+		t.Errorf("-max-regressions=0 with an improvement only: want pass, have %q", have)
+	}
+}
+
+func TestAbsoluteTolerance(t *testing.T) {
+	defer func(f bool, tol, abs float64) {
+		*failOnDelta, *tNsPerOp, *absNsPerOp = f, tol, abs
+	}(*failOnDelta, *tNsPerOp, *absNsPerOp)
+	*failOnDelta, *tNsPerOp, *absNsPerOp = true, 5, 2
+	ns, _ := lookupMetric("ns")
+	tests := []struct {
+		before, after float64
+		want          bool
+	}{
+		{4, 5, false},     // +25% but only 1 ns/op
+		{4, 6.5, true},    // +62.5% and 2.5 ns/op
+		{100, 103, false}, // 3 ns/op but only +3%
+		{100, 110, true},
+	}
+	for _, tt := range tests {
+		diff := nsDiff("BenchmarkA", 0, tt.before, tt.after)
+		if have := ns.exceeded(diff); have != tt.want {
+			t.Errorf("%v -> %v: want %t have %t", tt.before, tt.after, tt.want, have)
+		}
+	}
+}
+
+func TestRegressedBeyond(t *testing.T) {
+	defer func(v float64) { *tNsPerOp = v }(*tNsPerOp)
+	*tNsPerOp = 5
+	ns, _ := lookupMetric("ns")
+	mbs, _ := lookupMetric("mbs")
+	mbDiff := func(before, after float64) benchdiff.BenchDiff {
+		return benchdiff.BenchDiff{
+			Before: &parse.Benchmark{Name: "BenchmarkA", MBPerS: before, Measured: parse.MBPerS},
+			After:  &parse.Benchmark{Name: "BenchmarkA", MBPerS: after, Measured: parse.MBPerS},
+		}
+	}
+	cases := []struct {
+		m    metric
+		diff benchdiff.BenchDiff
+		want bool
+	}{
+		{ns, nsDiff("BenchmarkA", 0, 100, 110), true},
+		{ns, nsDiff("BenchmarkA", 0, 100, 104), false}, // within -tnsop
+		{ns, nsDiff("BenchmarkA", 0, 100, 50), false},  // improvement
+		{mbs, mbDiff(100, 50), true},                   // lower MB/s, -tmbs 0
+		{mbs, mbDiff(100, 200), false},
+	}
+	for _, tt := range cases {
+		if have := tt.m.regressedBeyond(tt.diff); have != tt.want {
+			t.Errorf("%s regressedBeyond %v: want %t have %t", tt.m.name, tt.m.delta(tt.diff), tt.want, have)
+		}
+	}
+}
+
+// TestExitCode runs benchdiff in a child process, which calls main with
+// the arguments of $BENCHDIFF_ARGS, and checks its exit status.
+func TestExitCode(t *testing.T) {
+	if args := os.Getenv("BENCHDIFF_ARGS"); args != "" {
+		os.Args = append([]string{"benchdiff"}, strings.Fields(args)...)
+		main()
+		os.Exit(0)
+	}
+	cases := []struct {
+		args string
+		want int
+	}{
+		// BenchmarkConcatBuffer-4 regressed by 1.48% ns/op.
+		{"-exitcode fixtures/strconcat.old fixtures/strconcat.new", exitRegressed},
+		{"-exitcode -tnsop 2 fixtures/strconcat.old fixtures/strconcat.new", 0},
+		// Improvements do not count: BenchmarkConcatBuffer-4 improved by 1.46% ns/op.
+		{"-exitcode -filter ConcatBuffer fixtures/strconcat.new fixtures/strconcat.old", 0},
+		{"-exitcode -filter ConcatBuffer fixtures/strconcat.old fixtures/strconcat.new", exitRegressed},
+		// -exitcode gates as -errdelta does.
+		{"-exitcode -errdelta -policy ns/op<+5% fixtures/strconcat.old fixtures/strconcat.new", 0},
+		{"-exitcode -errdelta fixtures/strconcat.old fixtures/strconcat.new", 1},
+		{"-exitcode fixtures/strconcat.old fixtures/missing.new", 1},
+		{"-tnsop 2 fixtures/strconcat.old fixtures/strconcat.new", 2},
+		{"-errdelta -metrics ns -policy allocs/op<=0 fixtures/strconcat.old fixtures/strconcat.new", 2},
+		{"-errdelta -comparator ns:test-max-slowdown -policy ns/op<+5% fixtures/strconcat.old fixtures/strconcat.new", 2},
+		{"-errdelta -format json -old-profile a.prof -new-profile b.prof fixtures/strconcat.old fixtures/strconcat.new", 2},
+	}
+	for _, tt := range cases {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestExitCode$")
+		cmd.Env = append(os.Environ(), "BENCHDIFF_ARGS="+tt.args)
+		err := cmd.Run()
+		have := 0
+		if ee, ok := err.(*exec.ExitError); ok {
+			have = ee.ExitCode()
+		} else if err != nil {
+			t.Fatalf("benchdiff %s: %v", tt.args, err)
+		}
+		if have != tt.want {
+			t.Errorf("benchdiff %s: want exit status %d have %d", tt.args, tt.want, have)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// githubMarker starts the pull request comments of -github, to find
+// the comment to update on the next runs.
+const githubMarker = "<!-- benchdiff -->"
+
+// githubTimeout bounds each request to the GitHub API.
+const githubTimeout = 30 * time.Second
+
+// githubEscaper escapes the message of GitHub Actions workflow commands.
+var githubEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+
+// writeAnnotations writes one GitHub Actions error annotation per
+// violation, as the runner reads them from the output of the steps,
+// stderr included.
+func writeAnnotations(w io.Writer, violations []violation) {
+	for _, v := range violations {
+		fmt.Fprintf(w, "::error title=benchdiff::%s\n", githubEscaper.Replace(v.message()))
+	}
+}
+
+// pullRequest returns the number of the pull request of the event in
+// the file named by $GITHUB_EVENT_PATH, 0 if the event is not about a
+// pull request.
+func pullRequest() (int, error) {
+	path := os.Getenv("GITHUB_EVENT_PATH")
+	if path == "" {
+		return 0, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var event struct {
+		PullRequest struct {
+			Number int `json:"number"`
+		} `json:"pull_request"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return 0, fmt.Errorf("%s: %v", path, err)
+	}
+	return event.PullRequest.Number, nil
+}
+
+// githubClient calls the GitHub REST API of a repository.
+type githubClient struct {
+	api   string // base URL, as https://api.github.com
+	repo  string // owner/name
+	token string
+}
+
+// do sends a request with the JSON encoding of in, if not nil, and
+// decodes the JSON response into out, if not nil.
+func (c githubClient) do(method, path string, in, out interface{}) error {
+	_, err := c.doURL(method, c.api+path, in, out)
+	return err
+}
+
+// doURL is do for the absolute url. It returns the URL of the next page
+// of the response, as told by its Link header, "" if there is none.
+func (c githubClient) doURL(method, url string, in, out interface{}) (next string, err error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return "", err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	client := &http.Client{Timeout: githubTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("%s %s: %s", method, strings.TrimPrefix(url, c.api), resp.Status)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return "", err
+		}
+	}
+	return nextLink(resp.Header.Get("Link")), nil
+}
+
+// nextLink returns the URL of the rel="next" link of the Link header
+// link, "" if there is none.
+func nextLink(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		fields := strings.Split(part, ";")
+		url := strings.TrimSpace(fields[0])
+		if !strings.HasPrefix(url, "<") || !strings.HasSuffix(url, ">") {
+			continue
+		}
+		for _, param := range fields[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return url[1 : len(url)-1]
+			}
+		}
+	}
+	return ""
+}
+
+// upsertComment updates the comment of pull request pr starting with
+// githubMarker to body, or creates it if there is none. The comments
+// are listed page by page, following only links to c.api, so that the
+// token is not sent elsewhere.
+func (c githubClient) upsertComment(pr int, body string) error {
+	path := fmt.Sprintf("/repos/%s/issues/%d/comments", c.repo, pr)
+	in := map[string]string{"body": githubMarker + "\n" + body}
+	for url := c.api + path + "?per_page=100"; url != ""; {
+		var comments []struct {
+			ID   int64  `json:"id"`
+			Body string `json:"body"`
+		}
+		next, err := c.doURL("GET", url, nil, &comments)
+		if err != nil {
+			return err
+		}
+		for _, comment := range comments {
+			if strings.HasPrefix(comment.Body, githubMarker) {
+				return c.do("PATCH", fmt.Sprintf("/repos/%s/issues/comments/%d", c.repo, comment.ID), in, nil)
+			}
+		}
+		url = ""
+		if strings.HasPrefix(next, c.api+"/") {
+			url = next
+		}
+	}
+	return c.do("POST", path, in, nil)
+}
+
+// postComment posts the markdown summary of diffs as a comment of the
+// pull request of the workflow run, updating the one of a previous run.
+// It does nothing outside of a pull request or without $GITHUB_TOKEN.
+func postComment(diffs []benchdiff.BenchDiff) error {
+	c := githubClient{
+		api:   os.Getenv("GITHUB_API_URL"),
+		repo:  os.Getenv("GITHUB_REPOSITORY"),
+		token: os.Getenv("GITHUB_TOKEN"),
+	}
+	if c.token == "" || c.repo == "" {
+		logf("GITHUB_TOKEN or GITHUB_REPOSITORY is not set, skipping the pull request comment")
+		return nil
+	}
+	if c.api == "" {
+		c.api = "https://api.github.com"
+	}
+	pr, err := pullRequest()
+	if err != nil {
+		return err
+	}
+	if pr == 0 {
+		logf("not a pull request event, skipping the pull request comment")
+		return nil
+	}
+	var buf bytes.Buffer
+	writeStepSummary(&buf, diffs)
+	return c.upsertComment(pr, buf.String())
+}
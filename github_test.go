@@ -0,0 +1,155 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+func TestWriteAnnotations(t *testing.T) {
+	var buf bytes.Buffer
+	writeAnnotations(&buf, []violation{
+		{"BenchmarkA", "ns/op", benchdiff.Delta{Before: 100, After: 150}, true},
+		{"BenchmarkB%", "allocs/op", benchdiff.Delta{Before: 1, After: 2}, true},
+	})
+	want := "::error title=benchdiff::BenchmarkA: +50.00%25 ns/op delta between benchmarks\n" +
+		"::error title=benchdiff::BenchmarkB%25: +100.00%25 allocs/op delta between benchmarks\n"
+	if have := buf.String(); have != want {
+		t.Errorf("writeAnnotations: want\n%s\nhave\n%s", want, have)
+	}
+}
+
+func TestUpsertComment(t *testing.T) {
+	var comments []map[string]interface{}
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var in map[string]string
+		json.NewDecoder(r.Body).Decode(&in)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/o/r/issues/7/comments":
+			json.NewEncoder(w).Encode(comments)
+		case "POST /repos/o/r/issues/7/comments":
+			comments = append(comments, map[string]interface{}{"id": 42, "body": in["body"]})
+		case "PATCH /repos/o/r/issues/comments/42":
+			comments[1]["body"] = in["body"]
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	comments = []map[string]interface{}{{"id": 1, "body": "looks good"}}
+
+	c := githubClient{api: srv.URL, repo: "o/r", token: "secret"}
+	for _, body := range []string{"first", "second"} {
+		if err := c.upsertComment(7, body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{
+		"GET /repos/o/r/issues/7/comments", "POST /repos/o/r/issues/7/comments",
+		"GET /repos/o/r/issues/7/comments", "PATCH /repos/o/r/issues/comments/42",
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("upsertComment calls: want %v have %v", want, calls)
+	}
+	if len(comments) != 2 || comments[1]["body"] != githubMarker+"\nsecond" {
+		t.Errorf("upsertComment comments: %v", comments)
+	}
+
+	c.token = "wrong"
+	if err := c.upsertComment(7, "third"); err == nil {
+		t.Error("upsertComment with a wrong token: no error")
+	}
+}
+
+func TestUpsertCommentPages(t *testing.T) {
+	var calls []string
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.RequestURI())
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/o/r/issues/7/comments":
+			if r.URL.Query().Get("page") == "2" {
+				json.NewEncoder(w).Encode([]map[string]interface{}{{"id": 99, "body": githubMarker + "\nold"}})
+				return
+			}
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/o/r/issues/7/comments?per_page=100&page=2>; rel="next", <%[1]s/repos/o/r/issues/7/comments?per_page=100&page=2>; rel="last"`, srv.URL))
+			json.NewEncoder(w).Encode([]map[string]interface{}{{"id": 1, "body": "looks good"}})
+		case "PATCH /repos/o/r/issues/comments/99":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	c := githubClient{api: srv.URL, repo: "o/r", token: "secret"}
+	if err := c.upsertComment(7, "new"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"GET /repos/o/r/issues/7/comments?per_page=100",
+		"GET /repos/o/r/issues/7/comments?per_page=100&page=2",
+		"PATCH /repos/o/r/issues/comments/99",
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("upsertComment calls: want %v have %v", want, calls)
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	for link, want := range map[string]string{
+		"": "",
+		`<https://api.github.com/x?page=2>; rel="next", <https://api.github.com/x?page=5>; rel="last"`: "https://api.github.com/x?page=2",
+		`<https://api.github.com/x?page=1>; rel="prev"`:                                                "",
+	} {
+		if have := nextLink(link); have != want {
+			t.Errorf("nextLink(%q): want %q have %q", link, want, have)
+		}
+	}
+}
+
+func TestPullRequest(t *testing.T) {
+	defer os.Setenv("GITHUB_EVENT_PATH", os.Getenv("GITHUB_EVENT_PATH"))
+	dir, err := ioutil.TempDir("", "benchdiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, tt := range []struct {
+		event string
+		want  int
+	}{
+		{`{"pull_request": {"number": 12}}`, 12},
+		{`{"ref": "refs/heads/main"}`, 0},
+	} {
+		path := filepath.Join(dir, "event.json")
+		if err := ioutil.WriteFile(path, []byte(tt.event), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Setenv("GITHUB_EVENT_PATH", path)
+		if have, err := pullRequest(); err != nil || have != tt.want {
+			t.Errorf("pullRequest of %s = %d, %v; want %d", tt.event, have, err, tt.want)
+		}
+	}
+	os.Setenv("GITHUB_EVENT_PATH", "")
+	if have, err := pullRequest(); err != nil || have != 0 {
+		t.Errorf("pullRequest without event = %d, %v; want 0", have, err)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// splitParam splits the sub-benchmark name of the parameter param off
+// name: BenchmarkEncode/size=1K/fast-8 by size is BenchmarkEncode/fast-8
+// and 1K. It reports false if name has no such parameter.
+func splitParam(name, param string) (group, value string, ok bool) {
+	base, procs := splitProcs(name)
+	parts := strings.Split(base, "/")
+	for i := 1; i < len(parts); i++ {
+		if strings.HasPrefix(parts[i], param+"=") {
+			value = parts[i][len(param)+1:]
+			rest := append(append([]string(nil), parts[:i]...), parts[i+1:]...)
+			return strings.Join(rest, "/") + procs, value, true
+		}
+	}
+	return "", "", false
+}
+
+// paramGroup is a benchmark pivoted on a parameter: diffs are its
+// results for each value of the parameter, in values.
+type paramGroup struct {
+	name   string
+	values []string
+	diffs  []benchdiff.BenchDiff
+}
+
+// groupByParam groups the diffs having the parameter param by the rest
+// of their name, in order of first appearance, and returns the others
+// apart.
+func groupByParam(diffs []benchdiff.BenchDiff, param string) (groups []*paramGroup, rest []benchdiff.BenchDiff) {
+	byName := make(map[string]*paramGroup)
+	for _, diff := range diffs {
+		name, value, ok := splitParam(diff.Name(), param)
+		if !ok {
+			rest = append(rest, diff)
+			continue
+		}
+		g := byName[name]
+		if g == nil {
+			g = &paramGroup{name: name}
+			byName[name] = g
+			groups = append(groups, g)
+		}
+		g.values = append(g.values, value)
+		g.diffs = append(g.diffs, diff)
+	}
+	return groups, rest
+}
+
+// printGrouped writes one block per metric in which the benchmarks
+// having the parameter param are pivoted on it: a heading line per
+// group of benchmarks differing only by the value of param, with the
+// geomean of their changes, then one indented row per value. The other
+// benchmarks follow as in the tables.
+func printGrouped(w io.Writer, diffs []benchdiff.BenchDiff, param string) {
+	first := true
+	for _, m := range activeMetrics() {
+		m = m.scaledFor(diffs)
+		sortDiffs(diffs, m)
+		var shownDiffs []benchdiff.BenchDiff
+		for _, diff := range diffs {
+			if m.measuredIn(diff) && shown(m, diff) {
+				shownDiffs = append(shownDiffs, diff)
+			}
+		}
+		if len(shownDiffs) == 0 {
+			continue
+		}
+		if !first {
+			fmt.Fprint(w, "\n")
+		}
+		first = false
+		fmt.Fprintf(w, "benchmark\t%s %s\t%s %s\t%s\n", *oldLabel, m.column, *newLabel, m.column, m.changeCol)
+		groups, rest := groupByParam(shownDiffs, param)
+		for _, g := range groups {
+			change := "n/a"
+			if ratio, ok := ratioGeomean(g.diffs, m); ok {
+				change = formatPercent(benchdiff.Delta{Before: 1, After: ratio})
+			}
+			fmt.Fprintf(w, "%s by %s\t\t\t%s\n", g.name, param, change)
+			for i, diff := range g.diffs {
+				before, after := m.cells(diff)
+				fmt.Fprintf(w, "  %s=%s\t%s\t%s\t%s\n", param, g.values[i], before, after, m.changeCell(diff))
+			}
+		}
+		for _, diff := range rest {
+			before, after := m.cells(diff)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", displayName(diff), before, after, m.changeCell(diff))
+		}
+		if cells, ok := m.summaryRow(diffs); ok {
+			fmt.Fprint(w, strings.Join(cells[:4], "\t")+"\n")
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+func TestSplitParam(t *testing.T) {
+	for _, tt := range []struct {
+		name, group, value string
+		ok                 bool
+	}{
+		{"BenchmarkEncode/size=1K", "BenchmarkEncode", "1K", true},
+		{"BenchmarkEncode/size=1K-8", "BenchmarkEncode-8", "1K", true},
+		{"BenchmarkEncode/size=1M/fast-8", "BenchmarkEncode/fast-8", "1M", true},
+		{"BenchmarkEncode/mode=a/size=8", "BenchmarkEncode/mode=a", "8", true},
+		{"BenchmarkEncode/sizes=8", "", "", false},
+		{"Benchmarksize=8", "", "", false},
+	} {
+		group, value, ok := splitParam(tt.name, "size")
+		if group != tt.group || value != tt.value || ok != tt.ok {
+			t.Errorf("splitParam(%q): want %q, %q, %t have %q, %q, %t", tt.name, tt.group, tt.value, tt.ok, group, value, ok)
+		}
+	}
+}
+
+func TestPrintGrouped(t *testing.T) {
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("BenchmarkEncode/size=1K", 0, 100, 110),
+		nsDiff("BenchmarkOther", 1, 50, 40),
+		nsDiff("BenchmarkEncode/size=1M", 2, 1000, 1210),
+		nsDiff("BenchmarkDecode/size=1K", 3, 100, 100),
+	}
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 5, ' ', 0)
+	printGrouped(w, diffs, "size")
+	w.Flush()
+	want := `benchmark                   old ns/op     new ns/op     delta
+BenchmarkEncode by size                                 +15.37%
+  size=1K                   100           110           +10.00%
+  size=1M                   1000          1210          +21.00%
+BenchmarkDecode by size                                 +0.00%
+  size=1K                   100           100           +0.00%
+BenchmarkOther              50.0          40.0          -20.00%
+[geomean]                   150           152           +1.58%
+`
+	if have := buf.String(); have != want {
+		t.Errorf("printGrouped: want\n%s\nhave\n%s", want, have)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// benchHeader holds the configuration lines 'go test' prints before the
+// benchmarks, such as "goarch: amd64", keyed by name.
+type benchHeader map[string]string
+
+// headerLine matches a configuration line.
+var headerLine = regexp.MustCompile(`^([a-z][a-z0-9-]*): *(.*)$`)
+
+// envKeys are the header lines telling the environment of a run, which
+// should be the same on both sides to compare them.
+var envKeys = []string{"goos", "goarch", "pkg", "cpu"}
+
+// envMismatches describes the envKeys lines whose values differ between
+// oldHeader and newHeader, skipping goarch if ignoreArch is set. Lines
+// missing from a side are not compared.
+func envMismatches(oldHeader, newHeader benchHeader, ignoreArch bool) []string {
+	var msgs []string
+	for _, key := range envKeys {
+		if key == "goarch" && ignoreArch {
+			continue
+		}
+		o, ook := oldHeader[key]
+		n, nok := newHeader[key]
+		if ook && nok && o != n {
+			msgs = append(msgs, fmt.Sprintf("%s %q vs %q", key, o, n))
+		}
+	}
+	return msgs
+}
+
+// parseArchFactors parses a comma-separated list of goarch=factor
+// pairs, as given to -normalize-arch.
+func parseArchFactors(list string) (map[string]float64, error) {
+	factors := make(map[string]float64)
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		eq := strings.Index(pair, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("%q is not goarch=factor", pair)
+		}
+		f, err := strconv.ParseFloat(pair[eq+1:], 64)
+		if err != nil || f <= 0 {
+			return nil, fmt.Errorf("invalid factor for %s: %q", pair[:eq], pair[eq+1:])
+		}
+		factors[pair[:eq]] = f
+	}
+	return factors, nil
+}
+
+// normalizeArch multiplies the ns/op of before and after by the factor
+// of the goarch of their header. It returns a warning if the goarch of
+// the sides differ.
+func normalizeArch(before, after parse.Set, oldHeader, newHeader benchHeader, factors map[string]float64) (warning string, err error) {
+	oldArch, newArch := oldHeader["goarch"], newHeader["goarch"]
+	for _, side := range []struct {
+		name string
+		arch string
+		bs   parse.Set
+	}{{"old", oldArch, before}, {"new", newArch, after}} {
+		if side.arch == "" {
+			return "", fmt.Errorf("no goarch line in the %s results", side.name)
+		}
+		f, ok := factors[side.arch]
+		if !ok {
+			return "", fmt.Errorf("no factor for goarch %s of the %s results", side.arch, side.name)
+		}
+		for _, bb := range side.bs {
+			for _, b := range bb {
+				b.NsPerOp *= f
+			}
+		}
+	}
+	if oldArch != newArch {
+		warning = fmt.Sprintf("benchdiff: WARNING: comparing %s results with %s results; normalized ns/op comparisons are only approximate", oldArch, newArch)
+	}
+	return warning, nil
+}
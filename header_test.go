@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestScanHeader(t *testing.T) {
+	data := []byte(`goos: linux
+goarch: arm64
+pkg: example.com/a
+cpu: Neoverse-N1
+BenchmarkA-4   	 1000000	      1500 ns/op
+pkg: example.com/b
+BenchmarkB/key:value-4   	 1000000	      1500 ns/op
+PASS
+`)
+	want := benchHeader{"goos": "linux", "goarch": "arm64", "pkg": "example.com/a", "cpu": "Neoverse-N1"}
+	if have := scanResults(data).header; !reflect.DeepEqual(want, have) {
+		t.Errorf("scanResults: want header %v have %v", want, have)
+	}
+}
+
+func TestParseArchFactors(t *testing.T) {
+	want := map[string]float64{"amd64": 1, "arm64": 1.25}
+	have, err := parseArchFactors("amd64=1, arm64=1.25")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("parseArchFactors: want %v have %v", want, have)
+	}
+	for _, bad := range []string{"amd64", "amd64=0", "arm64=fast"} {
+		if _, err := parseArchFactors(bad); err == nil {
+			t.Errorf("parseArchFactors(%q): want error", bad)
+		}
+	}
+}
+
+func TestNormalizeArch(t *testing.T) {
+	before := parse.Set{"BenchmarkA": nsRuns("BenchmarkA", 100)}
+	after := parse.Set{"BenchmarkA": nsRuns("BenchmarkA", 80)}
+	factors := map[string]float64{"amd64": 1, "arm64": 1.5}
+	warning, err := normalizeArch(before, after, benchHeader{"goarch": "amd64"}, benchHeader{"goarch": "arm64"}, factors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warning == "" {
+		t.Error("normalizeArch: want a warning for different architectures")
+	}
+	if b, a := before["BenchmarkA"][0].NsPerOp, after["BenchmarkA"][0].NsPerOp; b != 100 || a != 120 {
+		t.Errorf("normalizeArch: want 100 and 120 ns/op have %v and %v", b, a)
+	}
+
+	if _, err := normalizeArch(before, after, benchHeader{}, benchHeader{"goarch": "arm64"}, factors); err == nil {
+		t.Error("normalizeArch without goarch: want error")
+	}
+	if _, err := normalizeArch(before, after, benchHeader{"goarch": "386"}, benchHeader{"goarch": "arm64"}, factors); err == nil {
+		t.Error("normalizeArch without factor: want error")
+	}
+}
+
+func TestEnvMismatches(t *testing.T) {
+	old := benchHeader{"goos": "linux", "goarch": "amd64", "pkg": "example.com/a", "cpu": "Intel(R) Xeon(R)"}
+	if msgs := envMismatches(old, old, false); len(msgs) != 0 {
+		t.Errorf("envMismatches of the same header: %v", msgs)
+	}
+	other := benchHeader{"goos": "linux", "goarch": "arm64", "pkg": "example.com/b"}
+	want := []string{`goarch "amd64" vs "arm64"`, `pkg "example.com/a" vs "example.com/b"`}
+	if have := envMismatches(old, other, false); !reflect.DeepEqual(want, have) {
+		t.Errorf("envMismatches: want %q have %q", want, have)
+	}
+	if have := envMismatches(old, other, true); !reflect.DeepEqual(want[1:], have) {
+		t.Errorf("envMismatches ignoring goarch: want %q have %q", want[1:], have)
+	}
+}
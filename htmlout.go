@@ -0,0 +1,160 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"html/template"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// formatHTML is the -format value writing a standalone HTML report.
+const formatHTML = "html"
+
+// htmlBarWidth is the width, in pixels, of the bar of the largest
+// change of a table of the HTML report.
+const htmlBarWidth = 120
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>benchdiff: {{.Old}} vs {{.New}}</title>
+<style>
+body { font-family: Verdana, Geneva, "DejaVu Sans", sans-serif; font-size: 13px; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { padding: 3px 10px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+th { cursor: pointer; border-bottom: 1px solid #555; }
+tfoot td { border-top: 1px solid #555; font-weight: bold; }
+.regressed { color: #b00; }
+.improved { color: #080; }
+.bar { display: inline-block; height: 10px; }
+.regressed .bar { background: {{.Red}}; }
+.improved .bar { background: {{.Green}}; }
+</style>
+</head>
+<body>
+<h1>benchdiff: {{.Old}} vs {{.New}}</h1>
+{{- range .Tables}}
+<table class="sortable">
+<thead><tr>{{range .Header}}<th>{{.}}</th>{{end}}<th></th></tr></thead>
+<tbody>
+{{- range .Rows}}
+<tr class="{{.Class}}"><td>{{.Name}}</td><td data-sort="{{.Before}}">{{.BeforeText}}</td><td data-sort="{{.After}}">{{.AfterText}}</td><td data-sort="{{.Percent}}">{{.Change}}</td><td data-sort="{{.Percent}}"><span class="bar" style="width: {{.Bar}}px"></span></td></tr>
+{{- end}}
+</tbody>
+{{- with .Summary}}
+<tfoot><tr>{{range .}}<td>{{.}}</td>{{end}}<td></td></tr></tfoot>
+{{- end}}
+</table>
+{{- end}}
+<script>
+document.querySelectorAll("table.sortable").forEach(function(table) {
+	table.querySelectorAll("th").forEach(function(th, col) {
+		var asc = false;
+		th.addEventListener("click", function() {
+			asc = !asc;
+			var body = table.tBodies[0];
+			var rows = Array.prototype.slice.call(body.rows);
+			rows.sort(function(a, b) {
+				var x = a.cells[col], y = b.cells[col];
+				var vx = parseFloat(x.dataset.sort), vy = parseFloat(y.dataset.sort);
+				var c = isNaN(vx) || isNaN(vy) ? x.textContent.localeCompare(y.textContent) : vx - vy;
+				return asc ? c : -c;
+			});
+			rows.forEach(function(r) { body.appendChild(r); });
+		});
+	});
+});
+</script>
+</body>
+</html>
+`))
+
+// htmlTable is the table of one metric in the HTML report.
+type htmlTable struct {
+	Header  []string
+	Rows    []htmlRow
+	Summary []string // geomean cells, nil for none
+}
+
+// htmlRow is the row of one benchmark in a table of the HTML report.
+// Before, After and Percent are the raw values the columns sort on.
+type htmlRow struct {
+	Name                          string
+	BeforeText, AfterText, Change string
+	Before, After, Percent        string
+	Class                         string // regressed, improved or empty
+	Bar                           int    // length of the bar of the change
+}
+
+// writeHTML writes to w a standalone HTML report of diffs: one sortable
+// table per metric, the changes for the worse in red and for the better
+// in green, with a bar proportional to each change. Rows are chosen and
+// ordered as in the text tables.
+func writeHTML(w io.Writer, diffs []benchdiff.BenchDiff) error {
+	var tables []htmlTable
+	for _, m := range activeMetrics() {
+		m = m.scaledFor(diffs)
+		sortDiffs(diffs, m)
+		var t htmlTable
+		var largest float64
+		for _, diff := range diffs {
+			if !m.measuredIn(diff) || !shown(m, diff) {
+				continue
+			}
+			d := m.delta(diff)
+			before, after := m.cells(diff)
+			r := htmlRow{
+				Name:       diff.Name(),
+				BeforeText: before,
+				AfterText:  after,
+				Change:     m.changeCell(diff),
+				Before:     strconv.FormatFloat(d.Before, 'g', -1, 64),
+				After:      strconv.FormatFloat(d.After, 'g', -1, 64),
+				Percent:    strconv.FormatFloat(d.Percent(), 'g', -1, 64),
+			}
+			switch m.direction(diff) {
+			case -1:
+				r.Class = "regressed"
+			case 1:
+				r.Class = "improved"
+			}
+			largest = math.Max(largest, math.Abs(d.Percent()))
+			t.Rows = append(t.Rows, r)
+		}
+		if len(t.Rows) == 0 {
+			continue
+		}
+		for i, r := range t.Rows {
+			pct, _ := strconv.ParseFloat(r.Percent, 64)
+			switch {
+			case r.Class == "" || largest == 0:
+			case math.IsInf(largest, 1):
+				if math.IsInf(pct, 1) {
+					t.Rows[i].Bar = htmlBarWidth
+				}
+			default:
+				t.Rows[i].Bar = int(math.Abs(pct) / largest * htmlBarWidth)
+			}
+		}
+		t.Header = []string{"benchmark", *oldLabel + " " + m.column, *newLabel + " " + m.column, m.changeCol}
+		if cells, ok := m.summaryRow(diffs); ok {
+			t.Summary = cells[:4]
+		}
+		tables = append(tables, t)
+	}
+	return htmlTemplate.Execute(w, map[string]interface{}{
+		"Old":    *oldLabel,
+		"New":    *newLabel,
+		"Red":    template.CSS(badgeRed),
+		"Green":  template.CSS(badgeGreen),
+		"Tables": tables,
+	})
+}
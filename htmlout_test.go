@@ -0,0 +1,56 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+func TestWriteHTML(t *testing.T) {
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("BenchmarkSlower<1>", 0, 10, 15),
+		nsDiff("BenchmarkFaster", 1, 10, 8),
+		nsDiff("BenchmarkSame", 2, 10, 10),
+	}
+	var buf bytes.Buffer
+	if err := writeHTML(&buf, diffs); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	for _, want := range []string{
+		"<title>benchdiff: old vs new</title>",
+		"<th>old ns/op</th><th>new ns/op</th><th>delta</th>",
+		`<tr class="regressed"><td>BenchmarkSlower&lt;1&gt;</td>`,
+		`<td data-sort="50">&#43;50.00%</td><td data-sort="50"><span class="bar" style="width: 120px"></span>`,
+		`<tr class="improved"><td>BenchmarkFaster</td>`,
+		`<span class="bar" style="width: 48px"></span>`,
+		`<tr class=""><td>BenchmarkSame</td>`,
+		"<tfoot><tr><td>[geomean]</td>",
+		badgeRed,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("writeHTML: missing %q in\n%s", want, s)
+		}
+	}
+	if strings.Count(s, "<table") != 1 {
+		t.Errorf("writeHTML: want one table, for ns/op only")
+	}
+}
+
+func TestWriteHTMLChanged(t *testing.T) {
+	defer func(v bool) { *changedOnly = v }(*changedOnly)
+	*changedOnly = true
+	var buf bytes.Buffer
+	if err := writeHTML(&buf, []benchdiff.BenchDiff{nsDiff("BenchmarkSame", 0, 10, 10)}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "<table") {
+		t.Error("writeHTML with -changed: want no table without changes")
+	}
+}
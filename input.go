@@ -0,0 +1,175 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// Input formats accepted by the -input-format flag.
+const (
+	inputAuto     = "auto"
+	inputText     = "text"
+	inputGzip     = "gzip"
+	inputJSON     = "json"
+	inputMarkdown = "markdown"
+	inputANSI     = "ansi"
+)
+
+// sniffLen is the number of leading bytes inspected by detectFormat.
+const sniffLen = 512
+
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;?]*[A-Za-z]")
+
+// detectFormat guesses the format of data by looking at its first bytes.
+// The checks are applied in order and the first match wins:
+//   - gzip magic number (0x1f 0x8b)
+//   - '{' or '[' as first non-blank byte: test2json event stream
+//   - a line starting with "```" in the sniffed prefix: markdown
+//   - an ESC byte anywhere in the sniffed prefix: ANSI-colored text
+//   - anything else: plain text
+func detectFormat(data []byte) string {
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		return inputGzip
+	}
+	head := data
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+	trimmed := bytes.TrimLeft(head, " \t\r\n")
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")), bytes.HasPrefix(trimmed, []byte("[")):
+		return inputJSON
+	case bytes.HasPrefix(trimmed, []byte("```")), bytes.Contains(head, []byte("\n```")):
+		return inputMarkdown
+	case bytes.IndexByte(head, 0x1b) >= 0:
+		return inputANSI
+	}
+	return inputText
+}
+
+// formatFor returns the format of the input named name for the format
+// given by -input-format: with auto, a name ending in .gz is gzip
+// compressed even if its content does not look like it, so that a
+// corrupt file fails to decompress rather than to parse. The name of a
+// file in an archive is that of the file.
+func formatFor(name, format string) string {
+	if _, member := splitArchive(name); member != "" {
+		name = member
+	}
+	if format == inputAuto && strings.HasSuffix(name, ".gz") {
+		return inputGzip
+	}
+	return format
+}
+
+// decodeInput converts data in the given format into the plain text
+// output of 'go test -bench' that parse.ParseSet understands.
+// Decompressed gzip content is detected again when format is auto.
+func decodeInput(data []byte, format string) ([]byte, error) {
+	if format == inputAuto {
+		format = detectFormat(data)
+	}
+	switch format {
+	case inputText:
+		return data, nil
+	case inputGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %v", err)
+		}
+		defer zr.Close()
+		plain, err := ioutil.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %v", err)
+		}
+		return decodeInput(plain, inputAuto)
+	case inputJSON:
+		return decodeJSON(data)
+	case inputMarkdown:
+		return decodeMarkdown(data)
+	case inputANSI:
+		return ansiEscape.ReplaceAll(data, nil), nil
+	}
+	return nil, fmt.Errorf("unknown input format %q", format)
+}
+
+// testEvent is the subset of a test2json event used by benchdiff.
+type testEvent struct {
+	Action  string
+	Package string
+	Output  string
+}
+
+// decodeJSON concatenates the output of a 'go test -json' event stream,
+// package by package in the order they first appear, so that the output
+// of packages run in parallel is not interleaved and every result
+// follows the "pkg:" line of its package. Both newline-delimited events
+// and a JSON array of events are accepted.
+func decodeJSON(data []byte) ([]byte, error) {
+	var events []testEvent
+	if trimmed := bytes.TrimLeft(data, " \t\r\n"); bytes.HasPrefix(trimmed, []byte("[")) {
+		if err := json.Unmarshal(trimmed, &events); err != nil {
+			return nil, fmt.Errorf("json: %v", err)
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		for dec.More() {
+			var ev testEvent
+			if err := dec.Decode(&ev); err != nil {
+				return nil, fmt.Errorf("json: %v", err)
+			}
+			events = append(events, ev)
+		}
+	}
+	var pkgs []string
+	outputs := make(map[string]*bytes.Buffer)
+	for _, ev := range events {
+		if ev.Action != "output" {
+			continue
+		}
+		buf := outputs[ev.Package]
+		if buf == nil {
+			buf = new(bytes.Buffer)
+			outputs[ev.Package] = buf
+			pkgs = append(pkgs, ev.Package)
+		}
+		buf.WriteString(ev.Output)
+	}
+	var buf bytes.Buffer
+	for _, pkg := range pkgs {
+		buf.Write(outputs[pkg].Bytes())
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeMarkdown keeps only the lines inside ``` fenced blocks.
+func decodeMarkdown(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	fenced := false
+	scan := bufio.NewScanner(bytes.NewReader(data))
+	for scan.Scan() {
+		line := scan.Bytes()
+		if bytes.HasPrefix(bytes.TrimSpace(line), []byte("```")) {
+			fenced = !fenced
+			continue
+		}
+		if fenced {
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+	}
+	if err := scan.Err(); err != nil {
+		return nil, fmt.Errorf("markdown: %v", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+const benchText = "BenchmarkA-4 \t 100\t 123 ns/op\n"
+
+func gzipped(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectFormat(t *testing.T) {
+	cases := []struct {
+		data string
+		want string
+	}{
+		{data: benchText, want: inputText},
+		{data: "", want: inputText},
+		{data: `{"Action":"output","Output":"ok\n"}`, want: inputJSON},
+		{data: "\n  [{\"Action\":\"output\"}]", want: inputJSON},
+		{data: "```\n" + benchText + "```\n", want: inputMarkdown},
+		{data: "\x1b[32m" + benchText + "\x1b[0m", want: inputANSI},
+		{data: string(gzipped(t, []byte(benchText))), want: inputGzip},
+	}
+	for _, tt := range cases {
+		if have := detectFormat([]byte(tt.data)); have != tt.want {
+			t.Errorf("detectFormat(%q): want %s have %s", tt.data, tt.want, have)
+		}
+	}
+}
+
+func TestDecodeInput(t *testing.T) {
+	cases := []struct {
+		name   string
+		data   []byte
+		format string
+	}{
+		{name: "text", data: []byte(benchText), format: inputAuto},
+		{name: "gzip", data: gzipped(t, []byte(benchText)), format: inputAuto},
+		{name: "gzip json", data: gzipped(t, []byte(`{"Action":"output","Output":"BenchmarkA-4 \t 100\t 123 ns/op\n"}`)), format: inputAuto},
+		{name: "json split", data: []byte(`{"Action":"run"}
+{"Action":"output","Output":"BenchmarkA-4 \t"}
+{"Action":"output","Output":" 100\t 123 ns/op\n"}`), format: inputAuto},
+		{name: "json array", data: []byte(`[{"Action":"output","Output":"BenchmarkA-4 \t 100\t 123 ns/op\n"}]`), format: inputJSON},
+		{name: "markdown", data: []byte("BenchmarkB-4 1 2 ns/op\n```\n" + benchText + "```\n"), format: inputAuto},
+		{name: "forced markdown", data: []byte("BenchmarkB-4 1 2 ns/op\n```\n" + benchText + "```\n"), format: inputMarkdown},
+		{name: "ansi", data: []byte("\x1b[1;32m" + benchText + "\x1b[0m"), format: inputAuto},
+	}
+	for _, tt := range cases {
+		have, err := decodeInput(tt.data, tt.format)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if !bytes.Contains(have, []byte(benchText)) || bytes.Contains(have, []byte("BenchmarkB")) {
+			t.Errorf("%s: want %q have %q", tt.name, benchText, have)
+		}
+	}
+}
+
+func TestDecodeJSONPackages(t *testing.T) {
+	data := []byte(`{"Action":"output","Package":"a","Output":"pkg: a\n"}
+{"Action":"output","Package":"b","Output":"pkg: b\n"}
+{"Action":"output","Package":"a","Output":"BenchmarkA \t"}
+{"Action":"output","Package":"b","Output":"BenchmarkB \t 10\t 2 ns/op\n"}
+{"Action":"output","Package":"a","Output":" 10\t 1 ns/op\n"}
+{"Action":"pass","Package":"a"}`)
+	have, err := decodeJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "pkg: a\nBenchmarkA \t 10\t 1 ns/op\npkg: b\nBenchmarkB \t 10\t 2 ns/op\n"
+	if string(have) != want {
+		t.Errorf("decodeJSON: want %q have %q", want, have)
+	}
+}
+
+func TestDecodeInputErrors(t *testing.T) {
+	if _, err := decodeInput([]byte{0x1f, 0x8b, 0, 0}, inputAuto); err == nil {
+		t.Error("corrupt gzip: expected an error")
+	}
+	if _, err := decodeInput([]byte(`{"Action":`), inputAuto); err == nil {
+		t.Error("truncated json: expected an error")
+	}
+	if _, err := decodeInput([]byte(benchText), "xml"); err == nil {
+		t.Error("unknown format: expected an error")
+	}
+}
+
+func TestGzipFixture(t *testing.T) {
+	plain, err := ioutil.ReadFile("fixtures/strconcat.old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := readInput("fixtures/strconcat.old.gz", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	have, err := decodeInput(data, formatFor("fixtures/strconcat.old.gz", inputAuto))
+	if err != nil {
+		t.Fatalf("fixtures/strconcat.old.gz: unexpected error: %v", err)
+	}
+	if !bytes.Equal(have, plain) {
+		t.Errorf("fixtures/strconcat.old.gz: want the content of fixtures/strconcat.old, have %q", have)
+	}
+}
+
+func TestFormatFor(t *testing.T) {
+	if have := formatFor("old.txt.gz", inputAuto); have != inputGzip {
+		t.Errorf("formatFor(old.txt.gz, auto): want %q have %q", inputGzip, have)
+	}
+	if have := formatFor("old.txt.gz", inputText); have != inputText {
+		t.Errorf("formatFor(old.txt.gz, text): want %q have %q", inputText, have)
+	}
+	if have := formatFor("old.txt", inputAuto); have != inputAuto {
+		t.Errorf("formatFor(old.txt, auto): want %q have %q", inputAuto, have)
+	}
+	// A .gz file without the gzip magic fails to decompress.
+	if _, err := decodeInput([]byte(benchText), formatFor("old.txt.gz", inputAuto)); err == nil || !strings.HasPrefix(err.Error(), "gzip: ") {
+		t.Errorf("corrupt old.txt.gz: want a gzip error, have %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// printInterleaved writes one block per benchmark of diffs, stacking
+// vertically a line naming the metrics, a line with the old values, a
+// line with the new ones and a line with the changes. Benchmarks are
+// sorted like the ns/op table.
+func printInterleaved(w io.Writer, diffs []benchdiff.BenchDiff) {
+	mm := activeMetrics()
+	for i, m := range mm {
+		mm[i] = m.scaledFor(diffs)
+	}
+	sortDiffs(diffs, mm[0])
+	first := true
+	for _, diff := range diffs {
+		var names, befores, afters, changes string
+		filtered, changed := false, false
+		for _, m := range mm {
+			if !m.measuredIn(diff) {
+				continue
+			}
+			before, after := m.cells(diff)
+			names += "\t" + m.column
+			befores += "\t" + before
+			afters += "\t" + after
+			changes += "\t" + m.changeCell(diff)
+			if m.hidesUnchanged() {
+				filtered = true
+				changed = changed || hasChanged(m.delta(diff))
+			}
+		}
+		if names == "" || filtered && !changed {
+			continue
+		}
+		if !first {
+			fmt.Fprint(w, "\n")
+		}
+		first = false
+		fmt.Fprintf(w, "%s%s\n", displayName(diff), names)
+		fmt.Fprintf(w, "  %s%s\n", *oldLabel, befores)
+		fmt.Fprintf(w, "  %s%s\n", *newLabel, afters)
+		fmt.Fprintf(w, "  change%s\n", changes)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestPrintInterleaved(t *testing.T) {
+	mem := parse.NsPerOp | parse.AllocsPerOp | parse.AllocedBytesPerOp
+	diffs := []benchdiff.BenchDiff{
+		{
+			Before: &parse.Benchmark{Name: "BenchmarkB", NsPerOp: 100, AllocsPerOp: 2, AllocedBytesPerOp: 64, Measured: mem, Ord: 1},
+			After:  &parse.Benchmark{Name: "BenchmarkB", NsPerOp: 90, AllocsPerOp: 1, AllocedBytesPerOp: 32, Measured: mem, Ord: 1},
+		},
+		nsDiff("BenchmarkA", 0, 10, 12),
+	}
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 1, ' ', 0)
+	printInterleaved(w, diffs)
+	w.Flush()
+	want := `BenchmarkA ns/op
+  old      10.0
+  new      12.0
+  change   +20.00%
+
+BenchmarkB ns/op   allocs  bytes
+  old      100     2       64
+  new      90.0    1       32
+  change   -10.00% -50.00% -50.00%
+`
+	if have := buf.String(); have != want {
+		t.Errorf("printInterleaved:\nwant:\n%s\nhave:\n%s", want, have)
+	}
+}
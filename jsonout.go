@@ -0,0 +1,130 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// Output formats selected with -format.
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// jsonMeasure is the comparison of one metric in the JSON output.
+type jsonMeasure struct {
+	Before   float64  `json:"before"`
+	After    float64  `json:"after"`
+	DeltaPct *float64 `json:"delta_pct"` // null if the change is infinite
+}
+
+// jsonBench is the comparison of one benchmark in the JSON output.
+// Metrics not measured on both sides are null.
+type jsonBench struct {
+	Name        string                  `json:"name"`
+	OldLabel    string                  `json:"old_label,omitempty"` // only with labels other than old and new
+	NewLabel    string                  `json:"new_label,omitempty"`
+	NsPerOp     *jsonMeasure            `json:"ns_per_op"`
+	MBPerS      *jsonMeasure            `json:"mb_per_s"`
+	AllocsPerOp *jsonMeasure            `json:"allocs_per_op"`
+	BytesPerOp  *jsonMeasure            `json:"bytes_per_op"`
+	NsPerAlloc  *jsonMeasure            `json:"ns_per_alloc,omitempty"` // only with -ns-per-alloc
+	Custom      map[string]*jsonMeasure `json:"custom,omitempty"`       // by unit, for b.ReportMetric metrics
+	Warning     *jsonWarning            `json:"warning,omitempty"`      // only for the benchmarks not compared
+}
+
+// jsonWarning tells why a benchmark of the JSON output, whose metrics
+// are all null, was not compared.
+type jsonWarning struct {
+	Message       string `json:"message"`
+	BeforeResults int    `json:"before_results"`
+	AfterResults  int    `json:"after_results"`
+}
+
+// jsonBenches converts diffs to their JSON representation, keeping
+// their order, followed by the benchmarks of correlateWarnings. With
+// -changed, benchmarks without any change are left out, and with
+// -changed-metric those for which none of its metrics changed.
+func jsonBenches(diffs []benchdiff.BenchDiff) []jsonBench {
+	benches := make([]jsonBench, 0, len(diffs))
+	oldName, newName := jsonLabels()
+	for _, diff := range diffs {
+		jb := jsonBench{Name: diff.Name(), OldLabel: oldName, NewLabel: newName}
+		filtered, changed := changedMetrics == nil && *changedOnly, false
+		for _, m := range activeMetrics() {
+			if !m.measuredIn(diff) {
+				continue
+			}
+			before, bok := m.value(diff.Before)
+			after, aok := m.value(diff.After)
+			if !bok || !aok {
+				continue
+			}
+			d := benchdiff.Delta{Before: before, After: after}
+			jm := &jsonMeasure{Before: before, After: after}
+			if pct := d.Percent(); !math.IsInf(pct, 0) && !math.IsNaN(pct) {
+				jm.DeltaPct = &pct
+			}
+			if m.hidesUnchanged() {
+				filtered = true
+				changed = changed || hasChanged(d)
+			}
+			switch m.name {
+			case "ns":
+				jb.NsPerOp = jm
+			case "mbs":
+				jb.MBPerS = jm
+			case "allocs":
+				jb.AllocsPerOp = jm
+			case "bytes":
+				jb.BytesPerOp = jm
+			case "nsalloc":
+				jb.NsPerAlloc = jm
+			default:
+				if jb.Custom == nil {
+					jb.Custom = make(map[string]*jsonMeasure)
+				}
+				jb.Custom[m.unit] = jm
+			}
+		}
+		if filtered && !changed {
+			continue
+		}
+		benches = append(benches, jb)
+	}
+	for _, w := range correlateWarnings {
+		benches = append(benches, jsonBench{
+			Name:     w.Name,
+			OldLabel: oldName,
+			NewLabel: newName,
+			Warning:  &jsonWarning{Message: w.String(), BeforeResults: w.Before, AfterResults: w.After},
+		})
+	}
+	return benches
+}
+
+// jsonLabels returns the labels of the old and new sides the JSON
+// objects carry: those of -old-label, -new-label or -auto-label, or none
+// if they are the default old and new, which before and after match.
+func jsonLabels() (oldName, newName string) {
+	if *oldLabel == "old" && *newLabel == "new" {
+		return "", ""
+	}
+	return *oldLabel, *newLabel
+}
+
+// marshalDiffs returns the JSON output for diffs: an array with one
+// object per benchmark, in the order of diffs.
+func marshalDiffs(diffs []benchdiff.BenchDiff) ([]byte, error) {
+	data, err := json.MarshalIndent(jsonBenches(diffs), "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
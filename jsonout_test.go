@@ -0,0 +1,94 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestMarshalDiffs(t *testing.T) {
+	mem := parse.NsPerOp | parse.AllocsPerOp | parse.AllocedBytesPerOp
+	diffs := []benchdiff.BenchDiff{
+		{
+			Before: &parse.Benchmark{Name: "BenchmarkA", NsPerOp: 100, AllocsPerOp: 0, AllocedBytesPerOp: 64, Measured: mem},
+			After:  &parse.Benchmark{Name: "BenchmarkA", NsPerOp: 50, AllocsPerOp: 1, AllocedBytesPerOp: 64, Measured: mem},
+		},
+		nsDiff("BenchmarkB", 1, 10, 10),
+	}
+	data, err := marshalDiffs(diffs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[
+	{
+		"name": "BenchmarkA",
+		"ns_per_op": {
+			"before": 100,
+			"after": 50,
+			"delta_pct": -50
+		},
+		"mb_per_s": null,
+		"allocs_per_op": {
+			"before": 0,
+			"after": 1,
+			"delta_pct": null
+		},
+		"bytes_per_op": {
+			"before": 64,
+			"after": 64,
+			"delta_pct": 0
+		}
+	},
+	{
+		"name": "BenchmarkB",
+		"ns_per_op": {
+			"before": 10,
+			"after": 10,
+			"delta_pct": 0
+		},
+		"mb_per_s": null,
+		"allocs_per_op": null,
+		"bytes_per_op": null
+	}
+]
+`
+	if have := string(data); have != want {
+		t.Errorf("marshalDiffs:\nwant:\n%s\nhave:\n%s", want, have)
+	}
+
+	defer func(o, n string) { *oldLabel, *newLabel = o, n }(*oldLabel, *newLabel)
+	*oldLabel, *newLabel = "main", "feature-x"
+	benches := jsonBenches(diffs[1:])
+	if len(benches) != 1 || benches[0].OldLabel != "main" || benches[0].NewLabel != "feature-x" {
+		t.Errorf("jsonBenches with -oldname main -newname feature-x: want the labels have %+v", benches)
+	}
+	if data, _ := marshalDiffs(diffs[1:]); !strings.Contains(string(data), `"old_label": "main",
+		"new_label": "feature-x",`) {
+		t.Errorf("marshalDiffs with labels: want old_label and new_label have\n%s", data)
+	}
+
+	defer func(v bool) { *changedOnly = v }(*changedOnly)
+	*changedOnly = true
+	if benches := jsonBenches(diffs); len(benches) != 1 || benches[0].Name != "BenchmarkA" {
+		t.Errorf("jsonBenches with -changed: want BenchmarkA only have %v", benches)
+	}
+}
+
+func TestJSONWarnings(t *testing.T) {
+	defer func(w []benchdiff.Warning) { correlateWarnings = w }(correlateWarnings)
+	correlateWarnings = []benchdiff.Warning{{Name: "BenchmarkGone", Before: 2}}
+	benches := jsonBenches([]benchdiff.BenchDiff{nsDiff("BenchmarkA", 0, 100, 90)})
+	if len(benches) != 2 || benches[0].Warning != nil {
+		t.Fatalf("jsonBenches: want BenchmarkA then the warning have %v", benches)
+	}
+	want := jsonBench{
+		Name:    "BenchmarkGone",
+		Warning: &jsonWarning{Message: "ignoring BenchmarkGone: before has 2 instances, after has 0", BeforeResults: 2},
+	}
+	if have := benches[1]; !reflect.DeepEqual(have, want) {
+		t.Errorf("jsonBenches warning: want %+v have %+v", want.Warning, have.Warning)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// formatJUnit is the -format value writing a JUnit XML report.
+const formatJUnit = "junit"
+
+// junitSuite is the single test suite of the JUnit report.
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+// junitCase is the test case of one benchmark.
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut *junitText    `xml:"system-out,omitempty"`
+}
+
+// junitText is text kept as written, newlines included.
+type junitText struct {
+	Text string `xml:",cdata"`
+}
+
+// junitFailure lists the tolerances exceeded by a benchmark.
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",cdata"`
+}
+
+// writeJUnit writes to w a JUnit XML report of diffs: one test case per
+// benchmark, in the order of diffs, failing when one of its metrics
+// exceeds its tolerance as -errdelta gates it. The output of a case
+// lists the comparison of each metric shown in the tables.
+func writeJUnit(w io.Writer, diffs []benchdiff.BenchDiff) error {
+	suite := junitSuite{Name: "benchdiff"}
+	metrics := activeMetrics()
+	for _, diff := range diffs {
+		c := junitCase{Name: diff.Name(), ClassName: "benchdiff"}
+		var out, failed []string
+		for _, m := range metrics {
+			if !m.measuredIn(diff) {
+				continue
+			}
+			if shown(m, diff) {
+				before, after := m.cells(diff)
+				out = append(out, fmt.Sprintf("%s: %s -> %s (%s)", m.column, before, after, m.changeCell(diff)))
+			}
+			if m.exceeded(diff) {
+				failed = append(failed, violation{diff.Name(), m.unit, m.delta(diff), m.regressed(diff)}.message())
+			}
+		}
+		if len(out) == 0 && len(failed) == 0 {
+			continue
+		}
+		if len(failed) > 0 {
+			c.Failure = &junitFailure{Message: failed[0], Text: strings.Join(failed, "\n")}
+			suite.Failures++
+		}
+		if len(out) > 0 {
+			c.SystemOut = &junitText{strings.Join(out, "\n")}
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	suite.Tests = len(suite.Cases)
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	if err := enc.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
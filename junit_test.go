@@ -0,0 +1,39 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	defer func(f bool, tol float64) { *failOnDelta, *tNsPerOp = f, tol }(*failOnDelta, *tNsPerOp)
+	*failOnDelta, *tNsPerOp = true, 5
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("BenchmarkA", 0, 100, 110),
+		nsDiff("BenchmarkB<x>", 1, 100, 101),
+	}
+	var buf bytes.Buffer
+	if err := writeJUnit(&buf, diffs); err != nil {
+		t.Fatal(err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="benchdiff" tests="2" failures="1">
+	<testcase name="BenchmarkA" classname="benchdiff">
+		<failure message="BenchmarkA: +10.00% ns/op delta between benchmarks"><![CDATA[BenchmarkA: +10.00% ns/op delta between benchmarks]]></failure>
+		<system-out><![CDATA[ns/op: 100 -> 110 (+10.00%)]]></system-out>
+	</testcase>
+	<testcase name="BenchmarkB&lt;x&gt;" classname="benchdiff">
+		<system-out><![CDATA[ns/op: 100 -> 101 (+1.00%)]]></system-out>
+	</testcase>
+</testsuite>
+`
+	if have := buf.String(); have != want {
+		t.Errorf("writeJUnit: want\n%s\nhave\n%s", want, have)
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// lockRange is the range of values a metric of a benchmark is allowed
+// by a -lock file.
+type lockRange struct {
+	min, max float64
+}
+
+func (r lockRange) String() string {
+	return lockFloat(r.min) + ".." + lockFloat(r.max)
+}
+
+// lockFloat formats v with the fewest digits representing it, without
+// an exponent.
+func lockFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// lockEntry is a locked metric of a benchmark.
+type lockEntry struct {
+	name   string // benchmark name
+	metric string // canonical metric name, or unit of a custom metric
+	lockRange
+}
+
+// readLock reads the -lock file at path.
+func readLock(path string) ([]lockEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries, err := parseLock(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%v", path, err)
+	}
+	return entries, nil
+}
+
+// parseLock parses lines such as "BenchmarkHot: ns=1080..1320,
+// allocs=3..3", the ranges of the metrics of a benchmark, in the order
+// of the lines and the metrics. Blank lines and lines starting with '#'
+// are ignored. The metrics accept the spellings of -ungated, the other
+// names being the units of custom metrics.
+func parseLock(r io.Reader) ([]lockEntry, error) {
+	var entries []lockEntry
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		colon := strings.LastIndex(text, ": ")
+		if colon < 0 {
+			return nil, fmt.Errorf("%d: missing ': ' after the benchmark name", line)
+		}
+		name := strings.TrimSpace(text[:colon])
+		if name == "" {
+			return nil, fmt.Errorf("%d: missing benchmark name", line)
+		}
+		for _, field := range strings.Split(text[colon+2:], ",") {
+			eq := strings.LastIndex(field, "=")
+			if eq < 0 {
+				return nil, fmt.Errorf("%d: %q is not metric=min..max", line, strings.TrimSpace(field))
+			}
+			m := strings.TrimSpace(field[:eq])
+			if canonical, ok := metricAliases[m]; ok {
+				m = canonical
+			}
+			value := strings.TrimSpace(field[eq+1:])
+			bounds := strings.SplitN(value, "..", 2)
+			if len(bounds) != 2 {
+				return nil, fmt.Errorf("%d: invalid range for %s: %q", line, m, value)
+			}
+			min, err1 := strconv.ParseFloat(bounds[0], 64)
+			max, err2 := strconv.ParseFloat(bounds[1], 64)
+			if err1 != nil || err2 != nil || min > max {
+				return nil, fmt.Errorf("%d: invalid range for %s: %q", line, m, value)
+			}
+			entries = append(entries, lockEntry{name, m, lockRange{min, max}})
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// lockHeader is written at the top of the -update-lock files.
+const lockHeader = `# benchdiff lock file: the allowed range of each metric of each benchmark.
+# Check with benchdiff -lock=FILE new.txt, regenerate with -update-lock.
+`
+
+// writeLock writes the lock file locking the metrics of every
+// benchmark of bs, in name order, within slack percent of their value,
+// widened to two decimals, or to whole numbers for allocs/op and
+// bytes/op.
+func writeLock(w io.Writer, bs parse.Set, slack float64) error {
+	names := make([]string, 0, len(bs))
+	for name := range bs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	bw := bufio.NewWriter(w)
+	bw.WriteString(lockHeader)
+	for _, name := range names {
+		var fields []string
+		for _, m := range activeMetrics() {
+			if v, ok := lockValue(m, bs[name][0]); ok {
+				r := lockRange{math.Floor(100*v*(1-slack/100)) / 100, math.Ceil(100*v*(1+slack/100)) / 100}
+				if m.name == "allocs" || m.name == "bytes" {
+					r.min, r.max = math.Floor(r.min), math.Ceil(r.max)
+				}
+				fields = append(fields, m.name+"="+r.String())
+			}
+		}
+		if len(fields) > 0 {
+			fmt.Fprintf(bw, "%s: %s\n", name, strings.Join(fields, ", "))
+		}
+	}
+	return bw.Flush()
+}
+
+// lockValue returns the value of m measured by b.
+func lockValue(m metric, b *parse.Benchmark) (float64, bool) {
+	if !m.measuredIn(benchdiff.BenchDiff{Before: b, After: b}) {
+		return 0, false
+	}
+	return m.value(b)
+}
+
+// lockCheck is the check of a lockEntry against the current results.
+type lockCheck struct {
+	lockEntry
+	value  float64
+	status string // "ok", "below", "above" or "missing"
+}
+
+// checkLock checks the values of bs against the ranges of entries,
+// returning the checks in the order of entries. With -metrics, the
+// entries of the metrics it leaves out are not checked.
+func checkLock(entries []lockEntry, bs parse.Set) []lockCheck {
+	var checks []lockCheck
+	for _, e := range entries {
+		if selectedMetrics != nil && !selectedMetrics[e.metric] {
+			continue
+		}
+		c := lockCheck{lockEntry: e, status: "missing"}
+		m, ok := lookupMetric(e.metric)
+		if bb := bs[e.name]; ok && len(bb) > 0 {
+			if v, ok := lockValue(m, bb[0]); ok {
+				c.value = v
+				switch {
+				case v < e.min:
+					c.status = "below"
+				case v > e.max:
+					c.status = "above"
+				default:
+					c.status = "ok"
+				}
+			}
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+// printLockChecks writes the table of checks.
+func printLockChecks(w io.Writer, checks []lockCheck) {
+	fmt.Fprint(w, "benchmark\tmetric\tlocked\tvalue\tstatus\n")
+	for _, c := range checks {
+		unit := c.metric
+		if m, ok := lookupMetric(c.metric); ok {
+			unit = m.unit
+		}
+		value := "n/a"
+		if c.status != "missing" {
+			value = lockFloat(c.value)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", truncateName(c.name, nameWidth), unit, c.lockRange, value, c.status)
+	}
+}
+
+// lockFailure returns the message -lock fails with if some checks are
+// out of range or missing, "" if none is.
+func lockFailure(checks []lockCheck) string {
+	var lines []string
+	for _, c := range checks {
+		switch c.status {
+		case "ok":
+		case "missing":
+			lines = append(lines, fmt.Sprintf("\t%s: %s not measured", c.name, c.metric))
+		default:
+			lines = append(lines, fmt.Sprintf("\t%s: %s %s %s %s", c.name, c.metric, lockFloat(c.value), c.status, c.lockRange))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("benchdiff: -lock: %d of %d locked values out of range:\n%s", len(lines), len(checks), strings.Join(lines, "\n"))
+}
+
+// unlockedNames returns the sorted benchmarks of bs not in entries.
+func unlockedNames(entries []lockEntry, bs parse.Set) []string {
+	locked := make(map[string]bool)
+	for _, e := range entries {
+		locked[e.name] = true
+	}
+	var names []string
+	for name := range bs {
+		if !locked[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runLock checks the new results against the -lock file, or rewrites it
+// from them with -update-lock.
+func runLock(out io.Writer) {
+	var bs parse.Set
+	if *runNew {
+		data, err := runBench(".", *benchCmd)
+		if err != nil {
+			fatal(err)
+		}
+		bs, _ = parseData("new", "working tree", data)
+	} else {
+		bs, _ = parseShards("new", flag.Arg(0))
+	}
+	selectNames(bs, *newLabel)
+	bs, _ = qualifyPackages(bs, parse.Set{})
+	customUnits, _ = commonUnits(bs, bs)
+	if selections() == 0 {
+		selectMean(bs)
+	} else {
+		selectResults(bs, *newLabel)
+	}
+	if *updateLock {
+		var buf bytes.Buffer
+		writeLock(&buf, bs, *lockSlack)
+		if err := ioutil.WriteFile(*lockFile, buf.Bytes(), 0644); err != nil {
+			fatal(err)
+		}
+		logf("wrote %d benchmarks to %s", len(bs), *lockFile)
+		return
+	}
+	entries, err := readLock(*lockFile)
+	if err != nil {
+		fatal(err)
+	}
+	checks := checkLock(entries, bs)
+	w := tabwriter.NewWriter(out, 0, 0, 5, ' ', 0)
+	printLockChecks(w, checks)
+	w.Flush()
+	if names := unlockedNames(entries, bs); len(names) > 0 {
+		warn(fmt.Sprintf("benchdiff: -lock: %d benchmarks are not locked, add them with -update-lock: %s", len(names), strings.Join(names, ", ")))
+	}
+	if msg := lockFailure(checks); msg != "" {
+		fatal(msg)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestParseLock(t *testing.T) {
+	in := `# locked
+BenchmarkA: ns/op=90..110, allocs=3..3
+
+BenchmarkB/x=1: frames/op=1.5..2
+`
+	entries, err := parseLock(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []lockEntry{
+		{"BenchmarkA", "ns", lockRange{90, 110}},
+		{"BenchmarkA", "allocs", lockRange{3, 3}},
+		{"BenchmarkB/x=1", "frames/op", lockRange{1.5, 2}},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("parseLock: want %v have %v", want, entries)
+	}
+	for _, bad := range []string{"BenchmarkA ns=1..2", "BenchmarkA: ns=1", "BenchmarkA: ns=2..1", "BenchmarkA: ns"} {
+		if _, err := parseLock(strings.NewReader(bad)); err == nil {
+			t.Errorf("parseLock(%q): want an error", bad)
+		}
+	}
+}
+
+func TestWriteLock(t *testing.T) {
+	mem := parse.NsPerOp | parse.AllocsPerOp | parse.AllocedBytesPerOp
+	bs := parse.Set{
+		"BenchmarkB": []*parse.Benchmark{{Name: "BenchmarkB", NsPerOp: 1234.5, AllocsPerOp: 3, AllocedBytesPerOp: 64, Measured: mem}},
+		"BenchmarkA": nsRuns("BenchmarkA", 50),
+	}
+	var buf bytes.Buffer
+	if err := writeLock(&buf, bs, 10); err != nil {
+		t.Fatal(err)
+	}
+	want := lockHeader + `BenchmarkA: ns=45..55
+BenchmarkB: ns=1111.05..1357.95, allocs=2..4, bytes=57..71
+`
+	if have := buf.String(); have != want {
+		t.Errorf("writeLock: want\n%s\nhave\n%s", want, have)
+	}
+	entries, err := parseLock(&buf)
+	if err != nil {
+		t.Fatalf("parseLock of the written lock: %v", err)
+	}
+	if msg := lockFailure(checkLock(entries, bs)); msg != "" {
+		t.Errorf("checkLock of the locked results: want none have %q", msg)
+	}
+}
+
+func TestCheckLock(t *testing.T) {
+	entries := []lockEntry{
+		{"BenchmarkA", "ns", lockRange{90, 110}},
+		{"BenchmarkB", "ns", lockRange{90, 110}},
+		{"BenchmarkC", "ns", lockRange{90, 110}},
+		{"BenchmarkGone", "ns", lockRange{90, 110}},
+	}
+	bs := parse.Set{
+		"BenchmarkA":   nsRuns("BenchmarkA", 100),
+		"BenchmarkB":   nsRuns("BenchmarkB", 80),
+		"BenchmarkC":   nsRuns("BenchmarkC", 120),
+		"BenchmarkNew": nsRuns("BenchmarkNew", 1),
+	}
+	checks := checkLock(entries, bs)
+	var statuses []string
+	for _, c := range checks {
+		statuses = append(statuses, c.status)
+	}
+	if want := []string{"ok", "below", "above", "missing"}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("checkLock: want %v have %v", want, statuses)
+	}
+	want := `benchdiff: -lock: 3 of 4 locked values out of range:
+	BenchmarkB: ns 80 below 90..110
+	BenchmarkC: ns 120 above 90..110
+	BenchmarkGone: ns not measured`
+	if have := lockFailure(checks); have != want {
+		t.Errorf("lockFailure: want\n%s\nhave\n%s", want, have)
+	}
+	if have := unlockedNames(entries, bs); !reflect.DeepEqual(have, []string{"BenchmarkNew"}) {
+		t.Errorf("unlockedNames: want BenchmarkNew have %v", have)
+	}
+
+	defer func(m map[string]bool) { selectedMetrics = m }(selectedMetrics)
+	selectedMetrics = map[string]bool{"allocs": true}
+	if checks := checkLock(entries, bs); len(checks) != 0 {
+		t.Errorf("checkLock with -metrics=allocs: want no ns/op checks, have %v", checks)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// inputRecord describes one input of the comparison for -manifest.
+type inputRecord struct {
+	Side    string     `json:"side"` // "old" or "new"
+	Path    string     `json:"path"` // file name, or git ref with -since
+	Size    int        `json:"size"`
+	SHA256  string     `json:"sha256"`
+	ModTime *time.Time `json:"mtime,omitempty"` // nil if not read from a file
+
+	text []byte // benchmark output once decoded, for -save-baseline
+}
+
+// manifest records the provenance of a comparison.
+type manifest struct {
+	Inputs []inputRecord     `json:"inputs"`
+	Labels map[string]string `json:"labels"`
+	Flags  map[string]string `json:"flags"`
+}
+
+// newInputRecord describes the data read for side from path.
+func newInputRecord(side, path string, data []byte) inputRecord {
+	return inputRecord{Side: side, Path: path, Size: len(data), SHA256: fmt.Sprintf("%x", sha256.Sum256(data))}
+}
+
+// sideText returns the decoded text of the inputs of side, in order,
+// each ending with a newline: all the shards of a side for
+// -save-baseline.
+func sideText(inputs []inputRecord, side string) []byte {
+	var buf bytes.Buffer
+	for _, in := range inputs {
+		if in.Side != side {
+			continue
+		}
+		buf.Write(in.text)
+		if len(in.text) > 0 && in.text[len(in.text)-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
+
+// writeManifest writes the manifest of inputs and of the effective
+// settings to path as JSON.
+func writeManifest(path string, inputs []inputRecord) error {
+	m := manifest{
+		Inputs: inputs,
+		Labels: map[string]string{"old": *oldLabel, "new": *newLabel},
+		Flags:  make(map[string]string),
+	}
+	flag.VisitAll(func(f *flag.Flag) { m.Flags[f.Name] = f.Value.String() })
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
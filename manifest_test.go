@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchdiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "manifest.json")
+	inputs := []inputRecord{
+		newInputRecord("old", "old.txt", []byte("abc")),
+		newInputRecord("new", "new.txt", nil),
+	}
+	if err := writeManifest(path, inputs); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Inputs) != 2 || m.Inputs[0].Size != 3 || m.Inputs[1].Side != "new" {
+		t.Errorf("manifest inputs: have %+v", m.Inputs)
+	}
+	if want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"; m.Inputs[0].SHA256 != want {
+		t.Errorf("sha256: want %s have %s", want, m.Inputs[0].SHA256)
+	}
+	if m.Labels["old"] != *oldLabel || m.Flags["mag"] != "false" {
+		t.Errorf("manifest settings: have labels %v, -mag=%q", m.Labels, m.Flags["mag"])
+	}
+}
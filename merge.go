@@ -0,0 +1,49 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// splitGroups undoes the merge parse.ParseSet does of the results of a
+// benchmark recurring in concatenated outputs. Each run of consecutive
+// results of a benchmark is a group; the n-th group after the first is
+// renamed with a "#n" suffix, so that groups are compared with the
+// groups of the same rank on the other side.
+func splitGroups(bs parse.Set) {
+	var all []*parse.Benchmark
+	for _, bb := range bs {
+		all = append(all, bb...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Ord < all[j].Ord })
+
+	groups := make(map[string]int)
+	split := make(parse.Set)
+	prev := ""
+	for _, b := range all {
+		if b.Name != prev {
+			groups[b.Name]++
+			prev = b.Name
+		}
+		name := b.Name
+		if n := groups[b.Name]; n > 1 {
+			name = fmt.Sprintf("%s#%d", b.Name, n)
+		}
+		c := *b
+		c.Name = name
+		copyCustom(&c, b)
+		split[name] = append(split[name], &c)
+	}
+	for name := range bs {
+		delete(bs, name)
+	}
+	for name, bb := range split {
+		bs[name] = bb
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// concatenated is the output of two runs catenated together.
+const concatenated = `BenchmarkA-4   	 1000000	      100 ns/op
+BenchmarkA-4   	 1000000	      101 ns/op
+BenchmarkB-4   	 1000000	      200 ns/op
+PASS
+BenchmarkA-4   	 1000000	      110 ns/op
+BenchmarkB-4   	 1000000	      210 ns/op
+PASS
+`
+
+func TestParseSetMerges(t *testing.T) {
+	bs, err := parse.ParseSet(strings.NewReader(concatenated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]float64{
+		"BenchmarkA-4": {100, 101, 110},
+		"BenchmarkB-4": {200, 210},
+	}
+	for name, ns := range want {
+		if have := nsSamples(bs[name]); !reflect.DeepEqual(ns, have) {
+			t.Errorf("%s: want %v have %v", name, ns, have)
+		}
+	}
+	if len(bs) != len(want) {
+		t.Errorf("ParseSet: want %d benchmarks have %d", len(want), len(bs))
+	}
+}
+
+func TestSplitGroups(t *testing.T) {
+	bs, err := parse.ParseSet(strings.NewReader(concatenated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	splitGroups(bs)
+	want := map[string][]float64{
+		"BenchmarkA-4":   {100, 101},
+		"BenchmarkB-4":   {200},
+		"BenchmarkA-4#2": {110},
+		"BenchmarkB-4#2": {210},
+	}
+	for name, ns := range want {
+		if have := nsSamples(bs[name]); !reflect.DeepEqual(ns, have) {
+			t.Errorf("%s: want %v have %v", name, ns, have)
+		}
+		for _, b := range bs[name] {
+			if b.Name != name {
+				t.Errorf("%s: result named %s", name, b.Name)
+			}
+		}
+	}
+	if len(bs) != len(want) {
+		t.Errorf("splitGroups: want %d benchmarks have %d", len(want), len(bs))
+	}
+}
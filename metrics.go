@@ -0,0 +1,443 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// metric describes how one measurement is compared and displayed.
+type metric struct {
+	name      string                                     // name used in metric lists
+	unit      string                                     // unit used in messages
+	column    string                                     // header of the value columns
+	changeCol string                                     // header of the delta column
+	measured  int                                        // measurements required on both sides
+	value     func(*parse.Benchmark) (float64, bool)     // false if not available
+	format    func(float64) string                       // formats before and after values
+	change    func(benchdiff.Delta) string               // formats the delta column
+	sorter    func([]benchdiff.BenchDiff) sort.Interface // sorts by magnitude of change
+	tolerance *float64                                   // nil if not gated by -errdelta
+	absolute  *float64                                   // absolute tolerance, nil if none
+	higher    bool                                       // larger values are improvements
+	custom    bool                                       // reported with b.ReportMetric
+}
+
+// header returns the header line of the block of m.
+func (m metric) header() string {
+	h := fmt.Sprintf("benchmark\t%s %s\t%s %s\t%s", *oldLabel, m.column, *newLabel, m.column, m.changeCol)
+	if m.showsRatio() {
+		h += "\tratio"
+	}
+	if m.showsHeadroom() {
+		h += "\theadroom"
+	}
+	return h + "\n"
+}
+
+// row returns the cells of the line of diff in the block of m.
+func (m metric) row(diff benchdiff.BenchDiff) []string {
+	before, after := m.cells(diff)
+	cells := []string{displayName(diff), before, after, m.changeCell(diff)}
+	if m.showsRatio() {
+		cells = append(cells, ratioCell(m.delta(diff)))
+	}
+	if m.showsHeadroom() {
+		cells = append(cells, m.headroomCell(diff))
+	}
+	return cells
+}
+
+// showsRatio reports whether the block of m has a ratio column: the
+// ns/op block with -ratio.
+func (m metric) showsRatio() bool {
+	return *ratioCol && m.name == "ns"
+}
+
+// ratioCell formats the speedup of d, a change of ns/op, as a multiple
+// of the old time by the new one, as 1.35x faster or 1.20x slower.
+func ratioCell(d benchdiff.Delta) string {
+	switch {
+	case d.Before == 0 || d.After == 0:
+		return "n/a"
+	case d.Before > d.After:
+		return fmt.Sprintf("%.*fx faster", *multPrec, d.Before/d.After)
+	case d.Before < d.After:
+		return fmt.Sprintf("%.*fx slower", *multPrec, d.After/d.Before)
+	}
+	return fmt.Sprintf("%.*fx", *multPrec, 1.0)
+}
+
+// summaryName is the name cell of the geomean summary line of a block.
+const summaryName = "[geomean]"
+
+// summaryRow returns the cells of the geomean summary line of the block
+// of m over diffs. It reports false if no diff has positive values of m
+// on both sides.
+func (m metric) summaryRow(diffs []benchdiff.BenchDiff) ([]string, bool) {
+	before, after, ratio, ok := summaryGeomeans(diffs, m)
+	if !ok {
+		return nil, false
+	}
+	cells := []string{summaryName, m.format(before), m.format(after), m.change(benchdiff.Delta{Before: 1, After: ratio})}
+	if *relative {
+		cells[1], cells[2] = benchdiff.Delta{Before: 1, After: 1}.MultiplePrec(*multPrec), benchdiff.Delta{Before: 1, After: ratio}.MultiplePrec(*multPrec)
+	}
+	if m.showsRatio() {
+		cells = append(cells, ratioCell(benchdiff.Delta{Before: 1, After: ratio}))
+	}
+	if m.showsHeadroom() {
+		cells = append(cells, "")
+	}
+	return cells, true
+}
+
+// hidesUnchanged reports whether the benchmarks for which m did not
+// change are left out of the block of m: with -changed-metric if it
+// lists m, with -changed otherwise.
+func (m metric) hidesUnchanged() bool {
+	if changedMetrics != nil {
+		return changedMetrics[m.name]
+	}
+	return *changedOnly
+}
+
+// hasChanged reports whether d counts as a change for -changed and
+// -changed-metric: by more than -epsilon percent, or from or to zero.
+func hasChanged(d benchdiff.Delta) bool {
+	return d.ChangedBeyond(*epsilon) && !belowThreshold(d)
+}
+
+// unchangedMark replaces the changes below -threshold.
+const unchangedMark = "~"
+
+// newAllocMark replaces the changes of allocs/op and B/op from zero,
+// whose percent is infinite.
+const newAllocMark = "new alloc"
+
+// newAllocations reports whether d, a change of m, is the one of an
+// allocation-free benchmark starting to allocate.
+func newAllocations(m metric, d benchdiff.Delta) bool {
+	return (m.name == "allocs" || m.name == "bytes") && d.Before == 0 && d.After > 0
+}
+
+// belowThreshold reports whether d is a change below -threshold, which
+// counts as no change. Changes from or to zero are never below it.
+func belowThreshold(d benchdiff.Delta) bool {
+	return *minChange > 0 && !d.ChangedBeyond(*minChange)
+}
+
+// measuredIn reports whether both sides of diff measured m.
+func (m metric) measuredIn(diff benchdiff.BenchDiff) bool {
+	if m.custom {
+		_, bok := m.value(diff.Before)
+		_, aok := m.value(diff.After)
+		return bok && aok
+	}
+	return diff.Before.Measured&m.measured == m.measured && diff.After.Measured&m.measured == m.measured
+}
+
+// samples returns the values of m of the results in bb that have it.
+func (m metric) samples(bb []*parse.Benchmark) []float64 {
+	var xs []float64
+	for _, b := range bb {
+		if !m.custom && b.Measured&m.measured != m.measured {
+			continue
+		}
+		if v, ok := m.value(b); ok {
+			xs = append(xs, v)
+		}
+	}
+	return xs
+}
+
+// cell formats the value of m for b.
+func (m metric) cell(b *parse.Benchmark) string {
+	v, ok := m.value(b)
+	if !ok {
+		return "n/a"
+	}
+	return m.format(v)
+}
+
+// cells formats the before and after values of m for diff. With
+// -relative the values are shown as ratios of the before value.
+func (m metric) cells(diff benchdiff.BenchDiff) (before, after string) {
+	if !*relative {
+		return m.cell(diff.Before), m.cell(diff.After)
+	}
+	_, bok := m.value(diff.Before)
+	_, aok := m.value(diff.After)
+	d := m.delta(diff)
+	if !bok || !aok || d.Before == 0 {
+		return "n/a", "n/a"
+	}
+	return benchdiff.Delta{Before: 1, After: 1}.MultiplePrec(*multPrec), d.MultiplePrec(*multPrec)
+}
+
+// delta returns the change of m between the two sides of diff.
+// Unavailable values count as zero.
+func (m metric) delta(diff benchdiff.BenchDiff) benchdiff.Delta {
+	before, _ := m.value(diff.Before)
+	after, _ := m.value(diff.After)
+	return benchdiff.Delta{Before: before, After: after}
+}
+
+// changeCell formats the change of m between the two sides of diff.
+func (m metric) changeCell(diff benchdiff.BenchDiff) string {
+	_, bok := m.value(diff.Before)
+	_, aok := m.value(diff.After)
+	if !bok || !aok {
+		return "n/a"
+	}
+	if belowThreshold(m.delta(diff)) {
+		return unchangedMark
+	}
+	var cell string
+	switch {
+	case newAllocations(m, m.delta(diff)):
+		cell = newAllocMark
+	case m.insignificant(diff):
+		cell = m.change(m.delta(diff)) + insignificantMark
+	default:
+		cell = m.change(m.delta(diff))
+	}
+	if *showAbs {
+		cell += " (" + m.absChange(m.delta(diff)) + ")"
+	}
+	return cell
+}
+
+// absChange formats the difference between the two sides of d with the
+// unit of m, as +134ns, -2 allocs or +512B, for -show-abs.
+func (m metric) absChange(d benchdiff.Delta) string {
+	v, sign := d.After-d.Before, "+"
+	if v < 0 {
+		v, sign = -v, "-"
+	}
+	var unit string
+	switch {
+	case m.name == "allocs":
+		unit = " allocs"
+	case m.name == "bytes":
+		unit = "B"
+	case strings.HasSuffix(m.column, "s/op"):
+		unit = strings.TrimSuffix(m.column, "/op")
+	default:
+		unit = " " + m.column
+	}
+	return sign + m.format(v) + unit
+}
+
+// improved reports whether m is available on both sides of diff and
+// changed for the better.
+func (m metric) improved(diff benchdiff.BenchDiff) bool {
+	return m.direction(diff) > 0
+}
+
+// regressed reports whether m is available on both sides of diff and
+// changed for the worse.
+func (m metric) regressed(diff benchdiff.BenchDiff) bool {
+	return m.direction(diff) < 0
+}
+
+// direction returns 1 if m changed for the better in diff, -1 if it
+// changed for the worse, and 0 if it did not change beyond -threshold,
+// is unavailable or is a custom metric not gated by -tunit.
+func (m metric) direction(diff benchdiff.BenchDiff) int {
+	before, bok := m.value(diff.Before)
+	after, aok := m.value(diff.After)
+	if !bok || !aok || before == after || m.custom && m.tolerance == nil || belowThreshold(m.delta(diff)) {
+		return 0
+	}
+	if (after > before) == m.higher {
+		return 1
+	}
+	return -1
+}
+
+// activeMetrics returns the metrics to compare, in display order: those
+// selected by -metrics, every one without it.
+func activeMetrics() []metric {
+	mm := allMetrics()
+	if selectedMetrics == nil {
+		return mm
+	}
+	var selected []metric
+	for _, m := range mm {
+		if selectedMetrics[m.name] {
+			selected = append(selected, m)
+		}
+	}
+	return selected
+}
+
+// allMetrics returns the metrics measured by the results, whether or
+// not -metrics selects them, in display order.
+func allMetrics() []metric {
+	mm := []metric{
+		{
+			name:      "ns",
+			unit:      "ns/op",
+			column:    "ns/op",
+			changeCol: "delta",
+			measured:  parse.NsPerOp,
+			value:     func(b *parse.Benchmark) (float64, bool) { return b.NsPerOp, true },
+			format:    formatNs,
+			change:    formatPercent,
+			sorter:    func(d []benchdiff.BenchDiff) sort.Interface { return benchdiff.ByDeltaNsPerOp(d) },
+			tolerance: tNsPerOp,
+			absolute:  absNsPerOp,
+		},
+		{
+			name:      "mbs",
+			unit:      "Mb/s",
+			column:    "MB/s",
+			changeCol: "speedup",
+			measured:  parse.MBPerS,
+			value:     func(b *parse.Benchmark) (float64, bool) { return b.MBPerS, true },
+			format:    func(v float64) string { return fmt.Sprintf("%.2f", v) },
+			change:    func(d benchdiff.Delta) string { return d.MultiplePrec(*multPrec) },
+			sorter:    func(d []benchdiff.BenchDiff) sort.Interface { return benchdiff.ByDeltaMBPerS(d) },
+			tolerance: tMbPerS,
+			absolute:  absMbPerS,
+			higher:    true,
+		},
+		{
+			name:      "allocs",
+			unit:      "allocs/op",
+			column:    "allocs",
+			changeCol: "delta",
+			measured:  parse.AllocsPerOp,
+			value:     func(b *parse.Benchmark) (float64, bool) { return float64(b.AllocsPerOp), true },
+			format:    formatCount,
+			change:    formatPercent,
+			sorter:    func(d []benchdiff.BenchDiff) sort.Interface { return benchdiff.ByDeltaAllocsPerOp(d) },
+			tolerance: tAllPerOp,
+			absolute:  absAllPerOp,
+		},
+		{
+			name:      "bytes",
+			unit:      "bytes/op",
+			column:    "bytes",
+			changeCol: "delta",
+			measured:  parse.AllocedBytesPerOp,
+			value:     func(b *parse.Benchmark) (float64, bool) { return float64(b.AllocedBytesPerOp), true },
+			format:    formatCount,
+			change:    formatPercent,
+			sorter:    func(d []benchdiff.BenchDiff) sort.Interface { return benchdiff.ByDeltaAllocedBytesPerOp(d) },
+			tolerance: tBPerOp,
+			absolute:  absBPerOp,
+		},
+	}
+	for i := range mm {
+		if ungated[mm[i].name] {
+			mm[i].tolerance = nil
+		}
+	}
+	if *nsPerAllocs || selectedMetrics["nsalloc"] {
+		mm = append(mm, metric{
+			name:      "nsalloc",
+			unit:      "ns/alloc",
+			column:    "ns/alloc",
+			changeCol: "delta",
+			measured:  parse.NsPerOp | parse.AllocsPerOp,
+			value:     benchdiff.NsPerAlloc,
+			format:    formatNs,
+			change:    formatPercent,
+			sorter:    func(d []benchdiff.BenchDiff) sort.Interface { return benchdiff.ByDeltaNsPerAlloc(d) },
+		})
+	}
+	for _, unit := range customUnits {
+		mm = append(mm, customMetric(unit))
+	}
+	applyComparators(mm)
+	return mm
+}
+
+// lookupMetric returns the metric with the given canonical name, even
+// if -metrics leaves it out: the callers gating on it or showing it
+// check selectedMetrics themselves.
+func lookupMetric(name string) (metric, bool) {
+	for _, m := range allMetrics() {
+		if m.name == name {
+			return m, true
+		}
+	}
+	return metric{}, false
+}
+
+// metricAliases maps the accepted spellings of metric names
+// to their canonical name.
+var metricAliases = map[string]string{
+	"ns": "ns", "ns/op": "ns",
+	"mbs": "mbs", "mb": "mbs", "MB/s": "mbs",
+	"allocs": "allocs", "allocs/op": "allocs",
+	"bytes": "bytes", "B/op": "bytes", "bytes/op": "bytes",
+	"nsalloc": "nsalloc", "ns/alloc": "nsalloc",
+}
+
+// parseMetricList parses a comma-separated list of metric names
+// into a set of canonical names.
+func parseMetricList(list string) (map[string]bool, error) {
+	set := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		canonical, ok := metricAliases[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown metric %q", name)
+		}
+		set[canonical] = true
+	}
+	return set, nil
+}
+
+// parseMetricSelection parses the comma-separated list of -metrics into
+// a set of canonical names. The names that are not those of a built-in
+// metric are kept as custom units, checked once the results are read.
+func parseMetricSelection(list string) (map[string]bool, error) {
+	set := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if canonical, ok := metricAliases[name]; ok {
+			name = canonical
+		}
+		set[name] = true
+	}
+	if len(set) == 0 {
+		return nil, fmt.Errorf("no metric in %q", list)
+	}
+	return set, nil
+}
+
+// unknownSelectedMetrics returns the sorted metrics of -metrics that no
+// result measures.
+func unknownSelectedMetrics() []string {
+	var unknown []string
+	for name := range selectedMetrics {
+		if _, ok := lookupMetric(name); !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// formatCount formats integral measurements such as allocs/op.
+func formatCount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 0, 64)
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func findMetric(t *testing.T, unit string) metric {
+	for _, m := range activeMetrics() {
+		if m.unit == unit {
+			return m
+		}
+	}
+	t.Fatalf("no %s metric", unit)
+	return metric{}
+}
+
+func TestNsPerAlloc(t *testing.T) {
+	defer func(v bool) { *nsPerAllocs = v }(*nsPerAllocs)
+	*nsPerAllocs = true
+	m := findMetric(t, "ns/alloc")
+
+	measured := parse.NsPerOp | parse.AllocsPerOp
+	cases := []struct {
+		before, after *parse.Benchmark
+		cells         [3]string
+		changed       bool
+	}{
+		{
+			before:  &parse.Benchmark{NsPerOp: 100, AllocsPerOp: 2, Measured: measured},
+			after:   &parse.Benchmark{NsPerOp: 120, AllocsPerOp: 3, Measured: measured},
+			cells:   [3]string{"50.0", "40.0", "-20.00%"},
+			changed: true,
+		},
+		{
+			before:  &parse.Benchmark{NsPerOp: 50, Measured: measured},
+			after:   &parse.Benchmark{NsPerOp: 55, AllocsPerOp: 1, Measured: measured},
+			cells:   [3]string{"n/a", "55.0", "n/a"},
+			changed: true,
+		},
+		{
+			before:  &parse.Benchmark{NsPerOp: 50, Measured: measured},
+			after:   &parse.Benchmark{NsPerOp: 40, Measured: measured},
+			cells:   [3]string{"n/a", "n/a", "n/a"},
+			changed: false,
+		},
+	}
+	for _, tt := range cases {
+		diff := benchdiff.BenchDiff{Before: tt.before, After: tt.after}
+		have := [3]string{m.cell(diff.Before), m.cell(diff.After), m.changeCell(diff)}
+		if have != tt.cells {
+			t.Errorf("%s: want cells %q have %q", diff, tt.cells, have)
+		}
+		if have := m.delta(diff).Changed(); have != tt.changed {
+			t.Errorf("%s: want changed %t have %t", diff, tt.changed, have)
+		}
+	}
+
+	if m.measuredIn(benchdiff.BenchDiff{Before: &parse.Benchmark{Measured: parse.NsPerOp}, After: &parse.Benchmark{Measured: measured}}) {
+		t.Error("ns/alloc must require allocs/op on both sides")
+	}
+}
+
+func TestParseMetricList(t *testing.T) {
+	have, err := parseMetricList("ns, B/op,allocs/op,,ns/op")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]bool{"ns": true, "bytes": true, "allocs": true}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("parseMetricList: want %v have %v", want, have)
+	}
+	if have, err := parseMetricList(""); err != nil || len(have) != 0 {
+		t.Errorf("parseMetricList(\"\"): want empty set have %v, %v", have, err)
+	}
+	if _, err := parseMetricList("ns,cpu"); err == nil {
+		t.Error("parseMetricList: expected an error for an unknown metric")
+	}
+}
+
+func TestRelativeCells(t *testing.T) {
+	defer func(v bool) { *relative = v }(*relative)
+	*relative = true
+	ns, _ := lookupMetric("ns")
+
+	diff := benchdiff.BenchDiff{Before: &parse.Benchmark{NsPerOp: 200, Measured: parse.NsPerOp}, After: &parse.Benchmark{NsPerOp: 170, Measured: parse.NsPerOp}}
+	if before, after := ns.cells(diff); before != "1.00x" || after != "0.85x" {
+		t.Errorf("cells: want 1.00x 0.85x have %s %s", before, after)
+	}
+	diff.Before.NsPerOp = 0
+	if before, after := ns.cells(diff); before != "n/a" || after != "n/a" {
+		t.Errorf("cells with a zero baseline: want n/a n/a have %s %s", before, after)
+	}
+}
+
+func TestChangedMetric(t *testing.T) {
+	defer func(c bool, cm map[string]bool) { *changedOnly, changedMetrics = c, cm }(*changedOnly, changedMetrics)
+	diff := func(name string, ord int, ns, allocs float64) benchdiff.BenchDiff {
+		measured := parse.NsPerOp | parse.AllocsPerOp
+		return benchdiff.BenchDiff{
+			Before: &parse.Benchmark{Name: name, NsPerOp: 100, AllocsPerOp: 10, Measured: measured, Ord: ord},
+			After:  &parse.Benchmark{Name: name, NsPerOp: ns, AllocsPerOp: uint64(allocs), Measured: measured, Ord: ord},
+		}
+	}
+	diffs := []benchdiff.BenchDiff{
+		diff("BenchmarkFaster", 0, 90, 10),
+		diff("BenchmarkAllocs", 1, 100, 12),
+	}
+	ns, _ := lookupMetric("ns")
+	allocs, _ := lookupMetric("allocs")
+	cases := []struct {
+		changed bool
+		metrics map[string]bool
+		m       metric
+		want    []bool // shown, by diff
+	}{
+		{false, nil, allocs, []bool{true, true}},
+		{true, nil, ns, []bool{true, false}},
+		{true, nil, allocs, []bool{false, true}},
+		{false, map[string]bool{"allocs": true}, ns, []bool{true, true}},
+		{false, map[string]bool{"allocs": true}, allocs, []bool{false, true}},
+		// -changed-metric scopes -changed.
+		{true, map[string]bool{"allocs": true}, ns, []bool{true, true}},
+	}
+	for _, tt := range cases {
+		*changedOnly, changedMetrics = tt.changed, tt.metrics
+		for i, d := range diffs {
+			if have := shown(tt.m, d); have != tt.want[i] {
+				t.Errorf("-changed=%t -changed-metric=%v: %s in the %s block: want shown %t have %t", tt.changed, tt.metrics, d.Name(), tt.m.name, tt.want[i], have)
+			}
+		}
+	}
+
+	changedMetrics = map[string]bool{"allocs": true}
+	var names []string
+	for _, jb := range jsonBenches(diffs) {
+		names = append(names, jb.Name)
+	}
+	if want := []string{"BenchmarkAllocs"}; !reflect.DeepEqual(want, names) {
+		t.Errorf("jsonBenches with -changed-metric=allocs: want %v have %v", want, names)
+	}
+}
+
+func TestEpsilon(t *testing.T) {
+	defer func(c bool, e float64) { *changedOnly, *epsilon = c, e }(*changedOnly, *epsilon)
+	*changedOnly = true
+	ns, _ := lookupMetric("ns")
+	cases := []struct {
+		epsilon       float64
+		before, after float64
+		want          bool
+	}{
+		{0.005, 1000, 1000.01, false}, // +0.001% shows as +0.00%
+		{0.005, 1000, 1000.1, true},
+		{0, 1000, 1000.01, true},
+		{0.005, 0, 0.001, true},
+		{0.005, 0, 0, false},
+	}
+	for _, tt := range cases {
+		*epsilon = tt.epsilon
+		if have := shown(ns, nsDiff("BenchmarkA", 0, tt.before, tt.after)); have != tt.want {
+			t.Errorf("-changed -epsilon=%v: %v -> %v: want shown %t have %t", tt.epsilon, tt.before, tt.after, tt.want, have)
+		}
+	}
+}
+
+func TestShowAbs(t *testing.T) {
+	defer func(v bool) { *showAbs = v }(*showAbs)
+	*showAbs = true
+	diff := benchdiff.BenchDiff{
+		Before: &parse.Benchmark{NsPerOp: 400, MBPerS: 12, AllocsPerOp: 5, AllocedBytesPerOp: 512, Measured: parse.NsPerOp | parse.MBPerS | parse.AllocsPerOp | parse.AllocedBytesPerOp},
+		After:  &parse.Benchmark{NsPerOp: 534, MBPerS: 9, AllocsPerOp: 3, AllocedBytesPerOp: 1024, Measured: parse.NsPerOp | parse.MBPerS | parse.AllocsPerOp | parse.AllocedBytesPerOp},
+	}
+	for name, want := range map[string]string{
+		"ns":     "+33.50% (+134ns)",
+		"mbs":    "0.75x (-3.00 MB/s)",
+		"allocs": "-40.00% (-2 allocs)",
+		"bytes":  "+100.00% (+512B)",
+	} {
+		m, _ := lookupMetric(name)
+		if have := m.changeCell(diff); have != want {
+			t.Errorf("changeCell %s: want %q have %q", name, want, have)
+		}
+	}
+}
+
+func TestMetricSelection(t *testing.T) {
+	defer func(m map[string]bool) { selectedMetrics = m }(selectedMetrics)
+	var err error
+	if selectedMetrics, err = parseMetricSelection("B/op, allocs,,nsalloc,frames/op"); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]bool{"bytes": true, "allocs": true, "nsalloc": true, "frames/op": true}; !reflect.DeepEqual(selectedMetrics, want) {
+		t.Errorf("parseMetricSelection: want %v have %v", want, selectedMetrics)
+	}
+	var names []string
+	for _, m := range activeMetrics() {
+		names = append(names, m.name)
+	}
+	if want := []string{"allocs", "bytes", "nsalloc"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("activeMetrics with -metrics: want %v have %v", want, names)
+	}
+	if _, ok := lookupMetric("ns"); !ok {
+		t.Error("lookupMetric: want ns, left out by -metrics, still found")
+	}
+	if have, want := unknownSelectedMetrics(), []string{"frames/op"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("unknownSelectedMetrics: want %v have %v", want, have)
+	}
+	if _, err := parseMetricSelection(" , "); err == nil {
+		t.Error("parseMetricSelection: want an error for an empty list")
+	}
+}
+
+func TestRatioColumn(t *testing.T) {
+	defer func(v bool) { *ratioCol = v }(*ratioCol)
+	*ratioCol = true
+	for _, tt := range []struct {
+		before, after float64
+		want          string
+	}{
+		{400, 100, "4.00x faster"},
+		{100, 120, "1.20x slower"},
+		{100, 100, "1.00x"},
+		{0, 100, "n/a"},
+	} {
+		if have := ratioCell(benchdiff.Delta{Before: tt.before, After: tt.after}); have != tt.want {
+			t.Errorf("ratioCell(%g, %g): want %q have %q", tt.before, tt.after, tt.want, have)
+		}
+	}
+	ns, _ := lookupMetric("ns")
+	allocs, _ := lookupMetric("allocs")
+	if !ns.showsRatio() || allocs.showsRatio() {
+		t.Error("showsRatio: want a ratio column for ns/op only")
+	}
+	diffs := []benchdiff.BenchDiff{nsDiff("BenchmarkA", 0, 400, 100)}
+	if have, want := ns.row(diffs[0]), []string{"BenchmarkA", "400", "100", "-75.00%", "4.00x faster"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("row: want %q have %q", want, have)
+	}
+	if have, _ := ns.summaryRow(diffs); have[4] != "4.00x faster" {
+		t.Errorf("summaryRow: want the geomean ratio 4.00x faster, have %q", have)
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	defer func(th, tol float64, f bool) { *minChange, *tNsPerOp, *failOnDelta = th, tol, f }(*minChange, *tNsPerOp, *failOnDelta)
+	*minChange, *tNsPerOp, *failOnDelta = 3, 0, true
+	ns, _ := lookupMetric("ns")
+	cases := []struct {
+		diff      benchdiff.BenchDiff
+		cell      string
+		changed   bool
+		direction int
+		exceeded  bool
+	}{
+		{nsDiff("BenchmarkA", 0, 100, 102), "~", false, 0, false},
+		{nsDiff("BenchmarkA", 0, 100, 97), "~", false, 0, false},
+		{nsDiff("BenchmarkA", 0, 100, 104), "+4.00%", true, -1, true},
+		{nsDiff("BenchmarkA", 0, 0, 1), "+∞", true, -1, true},
+	}
+	for _, tt := range cases {
+		d := ns.delta(tt.diff)
+		if have := ns.changeCell(tt.diff); have != tt.cell {
+			t.Errorf("changeCell %v: want %q have %q", d, tt.cell, have)
+		}
+		if have := hasChanged(d); have != tt.changed {
+			t.Errorf("hasChanged %v: want %t have %t", d, tt.changed, have)
+		}
+		if have := ns.direction(tt.diff); have != tt.direction {
+			t.Errorf("direction %v: want %d have %d", d, tt.direction, have)
+		}
+		if have := ns.exceeded(tt.diff); have != tt.exceeded {
+			t.Errorf("exceeded %v: want %t have %t", d, tt.exceeded, have)
+		}
+	}
+	var v toleranceValue
+	v.pct = new(float64)
+	if err := v.Set("2ms"); err == nil || err.Error() != "want a percentage" {
+		t.Errorf("-threshold=2ms: want error %q, have %v", "want a percentage", err)
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// A multiRow compares one old result with the matching result of each
+// new file, in the order of the files. All diffs share their Before.
+type multiRow []benchdiff.BenchDiff
+
+// correlateMany correlates the results of before with those of every
+// set of afters. Only the results found in all the files make a row;
+// the rows are in parse order of before. The names of the benchmarks
+// missing from some file, or not having the same number of results in
+// all of them, are returned sorted as unmatched.
+func correlateMany(before parse.Set, afters []parse.Set) (rows []multiRow, unmatched []string) {
+	byOrd := make([]map[int]benchdiff.BenchDiff, len(afters))
+	skipped := make(map[string]bool)
+	var first []benchdiff.BenchDiff
+	for i, after := range afters {
+		diffs, warnings := benchdiff.Compare(before, after, benchdiff.Options{Key: correlateKey, Added: true, Copied: copyCustom})
+		for _, w := range warnings {
+			skipped[w.Name] = true
+		}
+		byOrd[i] = make(map[int]benchdiff.BenchDiff, len(diffs))
+		for _, diff := range diffs {
+			byOrd[i][diff.Before.Ord] = diff
+		}
+		if i == 0 {
+			first = diffs
+		}
+	}
+	for _, diff := range first {
+		row := make(multiRow, len(afters))
+		for i := range afters {
+			d, ok := byOrd[i][diff.Before.Ord]
+			if !ok {
+				row = nil
+				break
+			}
+			row[i] = d
+		}
+		if row != nil {
+			rows = append(rows, row)
+		}
+	}
+	for name := range skipped {
+		unmatched = append(unmatched, name)
+	}
+	sort.Strings(unmatched)
+	return rows, unmatched
+}
+
+// newLabels returns the labels of the columns of the new files at
+// paths: the -new-label label numbered from 1, or with -auto-label the
+// names of the files.
+func newLabels(paths []string) []string {
+	labels := make([]string, len(paths))
+	for i, path := range paths {
+		if *autoLabel {
+			labels[i] = labelFromPath(path)
+		} else {
+			labels[i] = fmt.Sprintf("%s%d", *newLabel, i+1)
+		}
+	}
+	return labels
+}
+
+// printMany writes one block per metric comparing the old value of
+// each row with the value of each new file, labeled with labels. It
+// follows printBlocks: with -changed or -changed-metric only the rows
+// having a change against some new file are shown, and every non empty block ends with
+// the geomean of each column.
+func printMany(w io.Writer, rows []multiRow, labels []string) {
+	var all []benchdiff.BenchDiff
+	for _, row := range rows {
+		all = append(all, row...)
+	}
+	for i, m := range activeMetrics() {
+		var header bool
+		var cols [][]benchdiff.BenchDiff // diffs shown, by new file
+		m = m.scaledFor(all)
+		for _, row := range rows {
+			if !row.measures(m) || m.hidesUnchanged() && !row.changed(m) {
+				continue
+			}
+			if !header {
+				if i > 0 {
+					fmt.Fprint(w, "\n")
+				}
+				fmt.Fprint(w, "benchmark\t"+*oldLabel+" "+m.column)
+				for _, l := range labels {
+					fmt.Fprintf(w, "\t%s %s\t%s", l, m.column, m.changeCol)
+				}
+				fmt.Fprint(w, "\n")
+				header = true
+				cols = make([][]benchdiff.BenchDiff, len(labels))
+			}
+			before, _ := m.cells(row[0])
+			cells := []string{displayName(row[0]), before}
+			for j, diff := range row {
+				_, after := m.cells(diff)
+				cells = append(cells, after, m.changeCell(diff))
+				cols[j] = append(cols[j], diff)
+			}
+			fmt.Fprint(w, strings.Join(cells, "\t")+"\n")
+		}
+		if !header {
+			continue
+		}
+		cells := []string{summaryName, ""}
+		var ok bool
+		for j := range labels {
+			s, sok := m.summaryRow(cols[j])
+			if !sok {
+				cells = append(cells, "", "")
+				continue
+			}
+			cells[1] = s[1]
+			cells = append(cells, s[2], s[3])
+			ok = true
+		}
+		if ok {
+			fmt.Fprint(w, strings.Join(cells, "\t")+"\n")
+		}
+	}
+}
+
+// measures reports whether every diff of r measured m.
+func (r multiRow) measures(m metric) bool {
+	for _, diff := range r {
+		if !m.measuredIn(diff) {
+			return false
+		}
+	}
+	return true
+}
+
+// changed reports whether m changed against some new file.
+func (r multiRow) changed(m metric) bool {
+	for _, diff := range r {
+		if hasChanged(m.delta(diff)) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareMany compares the old file at paths[0] with each of the new
+// files at paths[1:], writing the tables to out.
+func compareMany(out io.Writer, paths []string) {
+	rows, unmatched := correlateFiles(paths)
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 0, defaultPadding, ' ', 0)
+	printMany(w, rows, newLabels(paths[1:]))
+	w.Flush()
+	if *showUnmatch {
+		printNames(out, "unmatched (not in every file)", unmatched)
+	}
+}
+
+// manyUnits returns the sorted custom units reported by before and
+// every set of afters.
+func manyUnits(before parse.Set, afters []parse.Set) []string {
+	units, _ := commonUnits(before, afters[0])
+	for _, after := range afters[1:] {
+		reported := reportedUnits(after)
+		kept := units[:0]
+		for _, unit := range units {
+			if reported[unit] {
+				kept = append(kept, unit)
+			}
+		}
+		units = kept
+	}
+	return units
+}
+
+// correlateFiles parses the files at paths and correlates the results
+// of the first one with those of the others, as correlateMany does,
+// after selecting the results and the benchmarks. Unless -showunmatched
+// is set, the unmatched benchmarks are reported on stderr.
+func correlateFiles(paths []string) ([]multiRow, []string) {
+	before, _ := parseShards("old", paths[0])
+	afters := make([]parse.Set, len(paths)-1)
+	for i, path := range paths[1:] {
+		afters[i], _ = parseShards("new", path)
+	}
+	labels := newLabels(paths[1:])
+	selectNames(before, *oldLabel)
+	selectResults(before, *oldLabel)
+	for i, after := range afters {
+		selectNames(after, labels[i])
+		selectResults(after, labels[i])
+	}
+	customUnits = manyUnits(before, afters)
+
+	rows, unmatched := correlateMany(before, afters)
+	if !*showUnmatch {
+		for _, name := range unmatched {
+			warn(fmt.Sprintf("ignoring %s: not found the same number of times in every file", name))
+		}
+	}
+	if nameFilter != nil {
+		var kept []multiRow
+		for _, row := range rows {
+			if nameFilter.MatchString(row[0].Name()) {
+				kept = append(kept, row)
+			}
+		}
+		rows = kept
+	}
+	if len(rows) == 0 {
+		fatal("benchdiff: no benchmark found in every file")
+	}
+	return rows, unmatched
+}
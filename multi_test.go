@@ -0,0 +1,90 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"text/tabwriter"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestCompareMany(t *testing.T) {
+	defer func(c bool) { *changedOnly = c }(*changedOnly)
+	ns := func(name string, ord int, v float64) []*parse.Benchmark {
+		return []*parse.Benchmark{{Name: name, NsPerOp: v, Measured: parse.NsPerOp, Ord: ord}}
+	}
+	before := parse.Set{
+		"BenchmarkA":    ns("BenchmarkA", 0, 100),
+		"BenchmarkB":    ns("BenchmarkB", 1, 10),
+		"BenchmarkGone": ns("BenchmarkGone", 2, 1),
+	}
+	afters := []parse.Set{
+		{"BenchmarkA": ns("BenchmarkA", 0, 90), "BenchmarkB": ns("BenchmarkB", 1, 10), "BenchmarkGone": ns("BenchmarkGone", 2, 1)},
+		{"BenchmarkA": ns("BenchmarkA", 0, 80), "BenchmarkB": ns("BenchmarkB", 1, 10), "BenchmarkNew": ns("BenchmarkNew", 2, 1)},
+	}
+	rows, unmatched := correlateMany(before, afters)
+	if want := []string{"BenchmarkGone", "BenchmarkNew"}; !reflect.DeepEqual(want, unmatched) {
+		t.Errorf("correlateMany unmatched: want %v have %v", want, unmatched)
+	}
+	if len(rows) != 2 || rows[0][0].Name() != "BenchmarkA" || rows[0][1].After.NsPerOp != 80 || rows[1][0].Name() != "BenchmarkB" {
+		t.Fatalf("correlateMany: want rows BenchmarkA and BenchmarkB, have %v", rows)
+	}
+
+	for _, tt := range []struct {
+		changed bool
+		want    string
+	}{
+		{false, `benchmark      old ns/op     new1 ns/op     delta       new2 ns/op     delta
+BenchmarkA     100           90.0           -10.00%     80.0           -20.00%
+BenchmarkB     10.0          10.0           +0.00%      10.0           +0.00%
+[geomean]      31.6          30.0           -5.13%      28.3           -10.56%
+`},
+		{true, `benchmark      old ns/op     new1 ns/op     delta       new2 ns/op     delta
+BenchmarkA     100           90.0           -10.00%     80.0           -20.00%
+[geomean]      100           90.0           -10.00%     80.0           -20.00%
+`},
+	} {
+		*changedOnly = tt.changed
+		var buf bytes.Buffer
+		w := tabwriter.NewWriter(&buf, 0, 0, 5, ' ', 0)
+		printMany(w, rows, newLabels([]string{"a.txt", "b.txt"}))
+		w.Flush()
+		if have := buf.String(); have != tt.want {
+			t.Errorf("printMany -changed=%t: want\n%s\nhave\n%s", tt.changed, tt.want, have)
+		}
+	}
+}
+
+func TestManyUnits(t *testing.T) {
+	set := func(units ...string) parse.Set {
+		b := &parse.Benchmark{Name: "BenchmarkA", NsPerOp: 1, Measured: parse.NsPerOp}
+		values := make(map[string]float64)
+		for _, unit := range units {
+			values[unit] = 1
+		}
+		customValues[b] = values
+		return parse.Set{"BenchmarkA": {b}}
+	}
+	before := set("hits", "items/op", "req/s")
+	afters := []parse.Set{set("hits", "items/op", "req/s"), set("items/op", "req/s"), set("items/op")}
+	if have, want := manyUnits(before, afters), []string{"items/op"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("manyUnits: want %v have %v", want, have)
+	}
+}
+
+func TestNewLabels(t *testing.T) {
+	defer func(a bool) { *autoLabel = a }(*autoLabel)
+	paths := []string{"bench/v2.txt", "bench/v3.txt.gz"}
+	if want, have := []string{"new1", "new2"}, newLabels(paths); !reflect.DeepEqual(want, have) {
+		t.Errorf("newLabels: want %v have %v", want, have)
+	}
+	*autoLabel = true
+	if want, have := []string{"v2", "v3"}, newLabels(paths); !reflect.DeepEqual(want, have) {
+		t.Errorf("newLabels with -auto-label: want %v have %v", want, have)
+	}
+}
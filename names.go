@@ -0,0 +1,69 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// procsSuffix matches the -GOMAXPROCS suffix 'go test' appends to
+// benchmark names. A number following '=', '/' or '-' is part of a
+// sub-benchmark name, as in BenchmarkFoo/delta=-8.
+var procsSuffix = regexp.MustCompile(`[^=/-](-\d+)$`)
+
+// splitProcs splits name into its base and its -GOMAXPROCS suffix,
+// which is empty if name has none.
+func splitProcs(name string) (base, suffix string) {
+	loc := procsSuffix.FindStringSubmatchIndex(name)
+	if loc == nil {
+		return name, ""
+	}
+	return name[:loc[2]], name[loc[2]:]
+}
+
+// procsOf returns the distinct GOMAXPROCS of the results of bs, in
+// increasing order, as told by the suffixes of their names. A name
+// without a suffix was run with GOMAXPROCS=1.
+func procsOf(bs parse.Set) []int {
+	seen := make(map[int]bool)
+	var procs []int
+	for name := range bs {
+		n := 1
+		if _, suffix := splitProcs(name); suffix != "" {
+			n, _ = strconv.Atoi(suffix[1:])
+		}
+		if !seen[n] {
+			seen[n] = true
+			procs = append(procs, n)
+		}
+	}
+	sort.Ints(procs)
+	return procs
+}
+
+// procsMismatch describes how the GOMAXPROCS of the results of before
+// and after differ, or returns "" if both were run with the same ones.
+func procsMismatch(before, after parse.Set) string {
+	o, n := procsOf(before), procsOf(after)
+	if joinInts(o) == joinInts(n) {
+		return ""
+	}
+	return fmt.Sprintf("the old results were run with GOMAXPROCS %s and the new ones with %s", joinInts(o), joinInts(n))
+}
+
+// joinInts formats ns separated by commas.
+func joinInts(ns []int) string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestSplitProcs(t *testing.T) {
+	cases := []struct {
+		name   string
+		base   string
+		suffix string
+	}{
+		{name: "BenchmarkFoo-8", base: "BenchmarkFoo", suffix: "-8"},
+		{name: "BenchmarkFoo", base: "BenchmarkFoo", suffix: ""},
+		{name: "BenchmarkFoo/size=8", base: "BenchmarkFoo/size=8", suffix: ""},
+		{name: "BenchmarkFoo/size=8-16", base: "BenchmarkFoo/size=8", suffix: "-16"},
+		{name: "BenchmarkFoo/delta=-8", base: "BenchmarkFoo/delta=-8", suffix: ""},
+		{name: "BenchmarkFoo/-8", base: "BenchmarkFoo/-8", suffix: ""},
+	}
+	for _, tt := range cases {
+		if base, suffix := splitProcs(tt.name); base != tt.base || suffix != tt.suffix {
+			t.Errorf("splitProcs(%q): want %q %q have %q %q", tt.name, tt.base, tt.suffix, base, suffix)
+		}
+	}
+}
+
+func TestStripCPU(t *testing.T) {
+	key := correlateKeys["base"]
+	cases := []struct {
+		before, after string
+		match         bool
+	}{
+		{"BenchmarkFoo-4", "BenchmarkFoo-8", true},
+		{"BenchmarkFoo/size=8-4", "BenchmarkFoo/size=8-8", true},
+		{"BenchmarkFoo/size=4", "BenchmarkFoo/size=8", false},
+		{"BenchmarkFoo/delta=-4", "BenchmarkFoo/delta=-8", false},
+	}
+	for _, tt := range cases {
+		bk, ak := key(&parse.Benchmark{Name: tt.before}), key(&parse.Benchmark{Name: tt.after})
+		if match := bk == ak; match != tt.match {
+			t.Errorf("-stripcpu %s and %s: want match %t have %t", tt.before, tt.after, tt.match, match)
+		}
+	}
+
+	for _, tt := range []struct {
+		correlate string
+		strip     bool
+		want      string
+	}{
+		{"name", false, "name"},
+		{"fold", false, "fold"},
+		{"name", true, "base"},
+		{"base", true, "base"},
+	} {
+		if have, err := correlateName(tt.correlate, tt.strip); err != nil || have != tt.want {
+			t.Errorf("correlateName(%q, %t): want %q have %q, %v", tt.correlate, tt.strip, tt.want, have, err)
+		}
+	}
+	if _, err := correlateName("fold", true); err == nil {
+		t.Error("correlateName: want an error with -stripcpu and -correlate=fold")
+	}
+}
+
+func TestProcsMismatch(t *testing.T) {
+	before := parse.Set{"BenchmarkA-8": nil, "BenchmarkB/n=4-8": nil}
+	if msg := procsMismatch(before, parse.Set{"BenchmarkA-8": nil}); msg != "" {
+		t.Errorf("procsMismatch: want none for the same GOMAXPROCS, have %q", msg)
+	}
+	after := parse.Set{"BenchmarkA-16": nil, "BenchmarkB": nil}
+	want := "the old results were run with GOMAXPROCS 8 and the new ones with 1,16"
+	if have := procsMismatch(before, after); have != want {
+		t.Errorf("procsMismatch: want %q have %q", want, have)
+	}
+}
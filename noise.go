@@ -0,0 +1,70 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// noiseProfile summarizes the changes of one metric between two runs
+// of the same code.
+type noiseProfile struct {
+	unit     string
+	deltas   []float64 // absolute percent changes, ascending
+	noisiest string    // benchmark with the largest change
+}
+
+// noiseProfiles returns the noise profile of every metric measured by
+// at least one of diffs, along with the benchmarks whose change is
+// above budget percent. A budget of 0 disables the check.
+func noiseProfiles(diffs []benchdiff.BenchDiff, budget float64) (profiles []noiseProfile, over []string) {
+	for _, m := range activeMetrics() {
+		p := noiseProfile{unit: m.unit}
+		worst := -1.0
+		for _, diff := range diffs {
+			if !m.measuredIn(diff) {
+				continue
+			}
+			d := math.Abs(m.delta(diff).Percent())
+			p.deltas = append(p.deltas, d)
+			if d > worst {
+				worst, p.noisiest = d, diff.Name()
+			}
+			if budget > 0 && d > budget {
+				over = append(over, fmt.Sprintf("%s (%s %.2f%%)", diff.Name(), m.unit, d))
+			}
+		}
+		if len(p.deltas) == 0 {
+			continue
+		}
+		sort.Float64s(p.deltas)
+		profiles = append(profiles, p)
+	}
+	return profiles, over
+}
+
+// printNoise writes the noise profiles.
+func printNoise(w io.Writer, profiles []noiseProfile) {
+	fmt.Fprint(w, "metric\tbenchmarks\tmedian\tp90\tmax\tnoisiest\n")
+	for _, p := range profiles {
+		fmt.Fprintf(w, "%s\t%d\t%.2f%%\t%.2f%%\t%.2f%%\t%s\n", p.unit, len(p.deltas),
+			percentile(p.deltas, 50), percentile(p.deltas, 90), p.deltas[len(p.deltas)-1], p.noisiest)
+	}
+}
+
+// noiseFailure returns the message benchdiff must fail with when some
+// benchmarks are over the noise budget, or "" if none is.
+func noiseFailure(over []string, budget float64) string {
+	if len(over) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("benchdiff: %d changes above the noise budget of %.2f%%: %s", len(over), budget, strings.Join(over, ", "))
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+func TestNoiseProfiles(t *testing.T) {
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("BenchmarkA", 0, 100, 101),
+		nsDiff("BenchmarkB", 1, 100, 97),
+		nsDiff("BenchmarkC", 2, 100, 100),
+	}
+	profiles, over := noiseProfiles(diffs, 2)
+	if len(profiles) != 1 {
+		t.Fatalf("noiseProfiles: want a single ns/op profile have %v", profiles)
+	}
+	p := profiles[0]
+	if want := []float64{0, 1, 3}; p.unit != "ns/op" || p.noisiest != "BenchmarkB" || !reflect.DeepEqual(want, p.deltas) {
+		t.Errorf("noiseProfiles: unexpected profile %+v", p)
+	}
+	if want := []string{"BenchmarkB (ns/op 3.00%)"}; !reflect.DeepEqual(want, over) {
+		t.Errorf("noiseProfiles: want over budget %v have %v", want, over)
+	}
+	if _, over := noiseProfiles(diffs, 0); over != nil {
+		t.Errorf("noiseProfiles without budget: want none over have %v", over)
+	}
+	if msg := noiseFailure(nil, 2); msg != "" {
+		t.Errorf("noiseFailure: want no failure have %q", msg)
+	}
+}
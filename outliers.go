@@ -0,0 +1,174 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// Outlier rejection methods of -outliers.
+const (
+	outlierMAD    = "mad"
+	outlierIQR    = "iqr"
+	outlierStddev = "stddev"
+)
+
+// defaultIQRFactor is the factor of -outliers=iqr given without one,
+// that of Tukey's fences.
+const defaultIQRFactor = 1.5
+
+// outlierRule is an -outliers value: the results farther than k
+// spreads of method from the center of their benchmark are outliers.
+type outlierRule struct {
+	method string // "" for no outlier rejection
+	k      float64
+}
+
+// trimRule is the outlier rejection of -outliers or -trim-outliers.
+var trimRule outlierRule
+
+// parseOutlierRule parses an -outliers value: iqr[:k], stddev:k or
+// mad:k, with a positive k.
+func parseOutlierRule(s string) (outlierRule, error) {
+	method, factor := s, ""
+	if i := strings.Index(s, ":"); i >= 0 {
+		method, factor = s[:i], s[i+1:]
+	}
+	r := outlierRule{method: method}
+	switch method {
+	case outlierIQR:
+		r.k = defaultIQRFactor
+		if factor == "" {
+			return r, nil
+		}
+	case outlierStddev, outlierMAD:
+		if factor == "" {
+			return r, fmt.Errorf("%s needs a factor, as %s:3", method, method)
+		}
+	default:
+		return r, fmt.Errorf("unknown method %q, want iqr, stddev or mad", method)
+	}
+	k, err := strconv.ParseFloat(factor, 64)
+	if err != nil || k <= 0 {
+		return r, fmt.Errorf("invalid factor %q", factor)
+	}
+	r.k = k
+	return r, nil
+}
+
+// fences returns the bounds outside of which the values xs are outliers
+// under r: k interquartile ranges beyond the quartiles for iqr, k
+// standard deviations around the mean for stddev and k median absolute
+// deviations around the median for mad. It reports false if the spread
+// of xs is zero, leaving no outlier.
+func (r outlierRule) fences(xs []float64) (lo, hi float64, ok bool) {
+	var center, spread float64
+	switch r.method {
+	case outlierIQR:
+		sorted := append([]float64(nil), xs...)
+		sort.Float64s(sorted)
+		q1, q3 := percentile(sorted, 25), percentile(sorted, 75)
+		return q1 - r.k*(q3-q1), q3 + r.k*(q3-q1), q3 > q1
+	case outlierStddev:
+		center, spread = mean(xs), math.Sqrt(variance(xs))
+	default:
+		center = median(xs)
+		devs := make([]float64, len(xs))
+		for i, x := range xs {
+			devs[i] = math.Abs(x - center)
+		}
+		spread = median(devs)
+	}
+	return center - r.k*spread, center + r.k*spread, spread > 0
+}
+
+// trimOutliers discards the results of before and after that are
+// outliers under r among the results of their benchmark in any metric.
+// When both sides have the same number of results, which Correlate
+// pairs by index, whole pairs are discarded so that the sides still
+// match. trimOutliers returns the number of results discarded.
+func trimOutliers(before, after parse.Set, r outlierRule) int {
+	trimmed := 0
+	paired := make(map[string]bool)
+	for name, beforebb := range before {
+		afterbb := after[name]
+		if len(beforebb) != len(afterbb) {
+			continue
+		}
+		paired[name] = true
+		out := outliers(beforebb, r)
+		for i, o := range outliers(afterbb, r) {
+			out[i] = out[i] || o
+		}
+		var n int
+		before[name], n = drop(beforebb, out)
+		trimmed += n
+		after[name], n = drop(afterbb, out)
+		trimmed += n
+	}
+	for _, bs := range []parse.Set{before, after} {
+		for name, bb := range bs {
+			if !paired[name] {
+				var n int
+				bs[name], n = drop(bb, outliers(bb, r))
+				trimmed += n
+			}
+		}
+	}
+	return trimmed
+}
+
+// outliers reports which results of bb are outliers under r in any
+// metric measured by all of bb. A metric whose spread is zero has no
+// outliers, nor do benchmarks with less than 3 results.
+func outliers(bb []*parse.Benchmark, r outlierRule) []bool {
+	out := make([]bool, len(bb))
+	if len(bb) < 3 {
+		return out
+	}
+	for _, m := range activeMetrics() {
+		xs := make([]float64, len(bb))
+		measured := true
+		for i, b := range bb {
+			v, ok := m.value(b)
+			if b.Measured&m.measured != m.measured || !ok {
+				measured = false
+				break
+			}
+			xs[i] = v
+		}
+		if !measured {
+			continue
+		}
+		lo, hi, ok := r.fences(xs)
+		if !ok {
+			continue
+		}
+		for i, x := range xs {
+			if x < lo || x > hi {
+				out[i] = true
+			}
+		}
+	}
+	return out
+}
+
+// drop returns the results of bb not marked in out and the number of
+// results dropped.
+func drop(bb []*parse.Benchmark, out []bool) ([]*parse.Benchmark, int) {
+	keep := make([]*parse.Benchmark, 0, len(bb))
+	for i, b := range bb {
+		if !out[i] {
+			keep = append(keep, b)
+		}
+	}
+	return keep, len(bb) - len(keep)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// nsRuns returns results of name measuring the given ns/op.
+func nsRuns(name string, ns ...float64) []*parse.Benchmark {
+	bb := make([]*parse.Benchmark, len(ns))
+	for i, v := range ns {
+		bb[i] = &parse.Benchmark{Name: name, N: 1000, NsPerOp: v, Measured: parse.NsPerOp, Ord: i}
+	}
+	return bb
+}
+
+func TestTrimOutliers(t *testing.T) {
+	before := parse.Set{
+		"BenchmarkPaired":  nsRuns("BenchmarkPaired", 100, 101, 99, 100, 500),
+		"BenchmarkSteady":  nsRuns("BenchmarkSteady", 10, 10, 10, 50),
+		"BenchmarkFew":     nsRuns("BenchmarkFew", 10, 1000),
+		"BenchmarkOldOnly": nsRuns("BenchmarkOldOnly", 7, 8, 7, 70),
+	}
+	after := parse.Set{
+		"BenchmarkPaired": nsRuns("BenchmarkPaired", 900, 90, 91, 89, 90),
+		"BenchmarkSteady": nsRuns("BenchmarkSteady", 10, 10, 10, 10),
+		"BenchmarkFew":    nsRuns("BenchmarkFew", 10, 1000),
+	}
+	if n := trimOutliers(before, after, outlierRule{outlierMAD, 3}); n != 5 {
+		t.Errorf("trimOutliers: want 5 results trimmed, have %d", n)
+	}
+	tests := []struct {
+		set  parse.Set
+		name string
+		want []float64
+	}{
+		{before, "BenchmarkPaired", []float64{101, 99, 100}},
+		{after, "BenchmarkPaired", []float64{90, 91, 89}},
+		// A zero median absolute deviation keeps every result.
+		{before, "BenchmarkSteady", []float64{10, 10, 10, 50}},
+		{before, "BenchmarkFew", []float64{10, 1000}},
+		{before, "BenchmarkOldOnly", []float64{7, 8, 7}},
+	}
+	for _, tt := range tests {
+		if have := nsSamples(tt.set[tt.name]); !reflect.DeepEqual(tt.want, have) {
+			t.Errorf("%s: want %v have %v", tt.name, tt.want, have)
+		}
+	}
+}
+
+func TestParseOutlierRule(t *testing.T) {
+	for s, want := range map[string]outlierRule{
+		"iqr":      {outlierIQR, 1.5},
+		"iqr:3":    {outlierIQR, 3},
+		"stddev:2": {outlierStddev, 2},
+		"mad:3.5":  {outlierMAD, 3.5},
+	} {
+		if have, err := parseOutlierRule(s); err != nil || have != want {
+			t.Errorf("parseOutlierRule(%q): want %v have %v, %v", s, want, have, err)
+		}
+	}
+	for _, s := range []string{"", "stddev", "mad", "iqr:0", "iqr:x", "zscore:3"} {
+		if _, err := parseOutlierRule(s); err == nil {
+			t.Errorf("parseOutlierRule(%q): want an error", s)
+		}
+	}
+}
+
+func TestOutlierMethods(t *testing.T) {
+	bb := nsRuns("BenchmarkA", 100, 102, 98, 101, 99, 100, 103, 97, 100, 160)
+	tests := []struct {
+		rule outlierRule
+		want []float64
+	}{
+		{outlierRule{outlierIQR, 1.5}, []float64{100, 102, 98, 101, 99, 100, 103, 97, 100}},
+		// The outlier inflates the standard deviation it is measured with.
+		{outlierRule{outlierStddev, 3}, []float64{100, 102, 98, 101, 99, 100, 103, 97, 100, 160}},
+		{outlierRule{outlierStddev, 2}, []float64{100, 102, 98, 101, 99, 100, 103, 97, 100}},
+		{outlierRule{outlierMAD, 3}, []float64{100, 102, 98, 101, 99, 100, 103, 97, 100}},
+	}
+	for _, tt := range tests {
+		kept, _ := drop(bb, outliers(bb, tt.rule))
+		if have := nsSamples(kept); !reflect.DeepEqual(tt.want, have) {
+			t.Errorf("%v: want %v have %v", tt.rule, tt.want, have)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// resultPackages holds the package of each result parsed after a
+// "pkg:" line, as 'go test ./...' prints one per package.
+var resultPackages = make(map[*parse.Benchmark]string)
+
+// sectionPackages are the packages the names of the results were
+// qualified with by qualifyPackages, longest first, or nil if the
+// results are from a single package.
+var sectionPackages []string
+
+// linePackage returns the package of the "pkg:" line, and whether it is
+// one.
+func linePackage(line string) (string, bool) {
+	if !strings.HasPrefix(line, "pkg:") {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, "pkg:")), true
+}
+
+// packagesOf returns the sorted distinct packages of the results of
+// sets.
+func packagesOf(sets ...parse.Set) []string {
+	seen := make(map[string]bool)
+	var pkgs []string
+	for _, bs := range sets {
+		for _, bb := range bs {
+			for _, b := range bb {
+				if pkg := resultPackages[b]; pkg != "" && !seen[pkg] {
+					seen[pkg] = true
+					pkgs = append(pkgs, pkg)
+				}
+			}
+		}
+	}
+	sort.Strings(pkgs)
+	return pkgs
+}
+
+// qualifyPackages prefixes the name of every result of before and
+// after having a package with the package and a dot, as in
+// example.com/a.BenchmarkX, when the results span more than one
+// package, so that the benchmarks are correlated, aggregated and gated
+// within their package only. It returns the new sets and records the
+// packages in sectionPackages.
+func qualifyPackages(before, after parse.Set) (parse.Set, parse.Set) {
+	pkgs := packagesOf(before, after)
+	if len(pkgs) < 2 {
+		return before, after
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return len(pkgs[i]) > len(pkgs[j]) })
+	sectionPackages = pkgs
+	return qualifySet(before), qualifySet(after)
+}
+
+// qualifySet returns the results of bs keyed by their name qualified
+// with their package, in parse order.
+func qualifySet(bs parse.Set) parse.Set {
+	qualified := make(parse.Set)
+	for _, bb := range bs {
+		for _, b := range bb {
+			if pkg := resultPackages[b]; pkg != "" {
+				b.Name = pkg + "." + b.Name
+			}
+			qualified[b.Name] = append(qualified[b.Name], b)
+		}
+	}
+	for _, bb := range qualified {
+		sort.Slice(bb, func(i, j int) bool { return bb[i].Ord < bb[j].Ord })
+	}
+	return qualified
+}
+
+// splitPackage splits the qualified name into its package among
+// sectionPackages and the benchmark name. The package is empty if name
+// is not qualified.
+func splitPackage(name string) (pkg, bench string) {
+	for _, pkg := range sectionPackages {
+		if strings.HasPrefix(name, pkg+".") {
+			return pkg, name[len(pkg)+1:]
+		}
+	}
+	return "", name
+}
+
+// nameForms returns name and, if qualifyPackages qualified it, its
+// benchmark name without the package: the names the -thresholds,
+// -tolerance, -accepted, -rename, -match, -exclude and -filter entries
+// written for a single package still match, qualified ones first.
+func nameForms(name string) []string {
+	if pkg, bench := splitPackage(name); pkg != "" {
+		return []string{name, bench}
+	}
+	return []string{name}
+}
+
+// packageSection is the comparison of the benchmarks of one package.
+type packageSection struct {
+	pkg   string
+	diffs []benchdiff.BenchDiff
+}
+
+// packageSections splits diffs by package, in the order the packages
+// first appear, the diffs of each one keeping their order.
+func packageSections(diffs []benchdiff.BenchDiff) []packageSection {
+	var sections []packageSection
+	index := make(map[string]int)
+	for _, diff := range diffs {
+		pkg, _ := splitPackage(diff.Name())
+		i, ok := index[pkg]
+		if !ok {
+			i = len(sections)
+			index[pkg] = i
+			sections = append(sections, packageSection{pkg: pkg})
+		}
+		sections[i].diffs = append(sections[i].diffs, diff)
+	}
+	return sections
+}
+
+// sectionNames is set while printSections writes the sections, whose
+// names are shown without their package.
+var sectionNames bool
+
+// printSections writes the tables of each package of diffs in style,
+// after a "pkg:" heading. It returns the tolerance violations found, as
+// printTables.
+func printSections(w *tabwriter.Writer, diffs []benchdiff.BenchDiff, style tableStyle) []violation {
+	sectionNames = true
+	defer func() { sectionNames = false }()
+	var violations []violation
+	for i, s := range packageSections(diffs) {
+		if i > 0 {
+			fmt.Fprint(w, "\n")
+		}
+		pkg := s.pkg
+		if pkg == "" {
+			pkg = "(no package)"
+		}
+		fmt.Fprintf(w, "pkg: %s\n", pkg)
+		violations = append(violations, printTables(w, s.diffs, style)...)
+	}
+	return violations
+}
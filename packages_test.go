@@ -0,0 +1,127 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// parseWithPackages parses data as an input, recording the packages of
+// its results.
+func parseWithPackages(t *testing.T, data string) parse.Set {
+	return scanResults([]byte(data)).set()
+}
+
+func TestQualifyPackages(t *testing.T) {
+	defer func(pkgs []string) { sectionPackages = pkgs }(sectionPackages)
+	sectionPackages = nil
+	single := parseWithPackages(t, "pkg: example.com/a\nBenchmarkX 1 100 ns/op\n")
+	before, _ := qualifyPackages(single, parseWithPackages(t, "pkg: example.com/a\nBenchmarkX 1 110 ns/op\n"))
+	if _, ok := before["BenchmarkX"]; !ok || sectionPackages != nil {
+		t.Errorf("qualifyPackages of a single package: want the names unchanged, have %v", before)
+	}
+
+	data := "pkg: example.com/a\nBenchmarkX 1 100 ns/op\npkg: example.com/a/b\nBenchmarkX 1 200 ns/op\nBenchmarkY 1 300 ns/op\n"
+	before, after := qualifyPackages(parseWithPackages(t, data), parseWithPackages(t, data))
+	var names []string
+	for name := range after {
+		names = append(names, name)
+	}
+	for _, name := range []string{"example.com/a.BenchmarkX", "example.com/a/b.BenchmarkX", "example.com/a/b.BenchmarkY"} {
+		if len(before[name]) != 1 || len(after[name]) != 1 {
+			t.Errorf("qualifyPackages: want one result of %s on each side, have %v", name, names)
+		}
+	}
+	if pkg, bench := splitPackage("example.com/a/b.BenchmarkY"); pkg != "example.com/a/b" || bench != "BenchmarkY" {
+		t.Errorf("splitPackage: want example.com/a/b BenchmarkY have %s %s", pkg, bench)
+	}
+}
+
+func TestPrintSections(t *testing.T) {
+	defer func(pkgs []string) { sectionPackages = pkgs }(sectionPackages)
+	sectionPackages = []string{"example.com/a"}
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("example.com/a.BenchmarkX", 0, 100, 110),
+		nsDiff("BenchmarkZ", 1, 100, 90),
+	}
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 5, ' ', 0)
+	printSections(w, diffs, terminalStyle)
+	w.Flush()
+	want := `pkg: example.com/a
+benchmark      old ns/op     new ns/op     delta
+BenchmarkX     100           110           +10.00%
+[geomean]      100           110           +10.00%
+
+pkg: (no package)
+benchmark      old ns/op     new ns/op     delta
+BenchmarkZ     100           90.0          -10.00%
+[geomean]      100           90.0          -10.00%
+`
+	if have := buf.String(); have != want {
+		t.Errorf("printSections: want\n%s\nhave\n%s", want, have)
+	}
+	if sections := packageSections(diffs); !reflect.DeepEqual([]string{sections[0].pkg, sections[1].pkg}, []string{"example.com/a", ""}) {
+		t.Errorf("packageSections: want example.com/a then none, have %v", sections)
+	}
+}
+
+func TestQualifiedNames(t *testing.T) {
+	defer func(pkgs []string, r toleranceRules, v thresholds, a acceptances) {
+		sectionPackages, ruleTolerances, tolerances, accepted = pkgs, r, v, a
+	}(sectionPackages, ruleTolerances, tolerances, accepted)
+	defer func(m, e *regexp.Regexp) { matchRe, excludeRe = m, e }(matchRe, excludeRe)
+	data := "pkg: example.com/a\nBenchmarkX-8 1 100 ns/op\nBenchmarkY-8 1 100 ns/op\npkg: example.com/b\nBenchmarkX-8 1 200 ns/op\n"
+	sectionPackages = nil
+	before, _ := qualifyPackages(parseWithPackages(t, data), parseWithPackages(t, data))
+	const name = "example.com/a.BenchmarkX-8"
+	if _, ok := before[name]; !ok {
+		t.Fatalf("qualifyPackages: no %s in %v", name, before)
+	}
+
+	ruleTolerances = nil
+	tolerances = thresholds{"BenchmarkX": {"ns": 5, "allocs": 1}, "example.com/a.BenchmarkX": {"allocs": 2}}
+	if err := ruleTolerances.Set("BenchmarkY:ns=7"); err != nil {
+		t.Fatal(err)
+	}
+	ns, _ := lookupMetric("ns")
+	allocs, _ := lookupMetric("allocs")
+	tests := []struct {
+		m    metric
+		name string
+		want float64
+	}{
+		{ns, name, 5},
+		{allocs, name, 2}, // the qualified line wins
+		{ns, "example.com/a.BenchmarkY-8", 7},
+	}
+	for _, tt := range tests {
+		if tol := tt.m.toleranceFor(tt.name); tol == nil || *tol != tt.want {
+			t.Errorf("%s tolerance of %s: want %v have %v", tt.m.name, tt.name, tt.want, tol)
+		}
+	}
+
+	accepted = acceptances{{name: "BenchmarkX", metric: "ns"}}
+	if !accepted.covers("example.com/b.BenchmarkX-8", "ns") {
+		t.Error("accepted.covers: want the unqualified entry to cover the qualified name")
+	}
+
+	bs := parse.Set{name: before[name], "example.com/a.BenchmarkY-8": before["example.com/a.BenchmarkY-8"]}
+	if w := rename(bs, "BenchmarkX", "BenchmarkZ"); len(w) > 0 || len(bs["example.com/a.BenchmarkZ-8"]) != 1 {
+		t.Errorf("rename: want example.com/a.BenchmarkZ-8 have %v (%v)", bs, w)
+	}
+	matchRe, excludeRe = nil, regexp.MustCompile("^BenchmarkY-8$")
+	selectNames(bs, "old")
+	if _, ok := bs["example.com/a.BenchmarkY-8"]; ok {
+		t.Error("selectNames: want the qualified BenchmarkY left out by -exclude")
+	}
+}
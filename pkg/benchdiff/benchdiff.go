@@ -0,0 +1,225 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchdiff
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// Options control how Compare compares two benchmark sets.
+type Options struct {
+	// Best compares only the fastest result, by ns/op, of each
+	// benchmark having several results.
+	Best bool
+	// Median compares only the result with the median ns/op of each
+	// benchmark having several results, as SelectMedian. It is ignored
+	// if Best is set.
+	Median bool
+	// ChangedOnly drops the benchmarks for which no measurement changed.
+	ChangedOnly bool
+	// Epsilon is the percent a measurement must change by, with
+	// ChangedOnly, to count as changed. See Delta.ChangedBeyond.
+	Epsilon float64
+	// Added also reports, with warnings of no results before, the
+	// benchmarks having results only after.
+	Added bool
+	// Key returns the key matching the results of both sides.
+	// It defaults to BenchName.
+	Key func(*parse.Benchmark) string
+	// Copied, if not nil, is called with each copy Compare makes of a
+	// result, for Best and Median, and the result it copies, so that
+	// callers keeping data by result can carry it over to the diffs.
+	Copied func(copy, orig *parse.Benchmark)
+}
+
+// A Warning reports a benchmark that could not be compared because it
+// does not have the same number of results on both sides.
+type Warning struct {
+	Name   string // correlation key of the benchmark
+	Before int    // number of results before
+	After  int    // number of results after
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("ignoring %s: before has %d instances, after has %d", w.Name, w.Before, w.After)
+}
+
+// Unmatched returns the names of the benchmarks of warnings having
+// results only before (removed) or only after (added), in the order of
+// warnings. The added ones are only reported with Options.Added.
+func Unmatched(warnings []Warning) (removed, added []string) {
+	for _, w := range warnings {
+		switch {
+		case w.After == 0:
+			removed = append(removed, w.Name)
+		case w.Before == 0:
+			added = append(added, w.Name)
+		}
+	}
+	return removed, added
+}
+
+// Compare correlates the benchmarks of before and after according to
+// opts. The diffs are in parse order of the before benchmarks and the
+// warnings sorted by name. before and after are not modified.
+func Compare(before, after parse.Set, opts Options) ([]BenchDiff, []Warning) {
+	switch {
+	case opts.Best:
+		before, after = copySet(before, opts.Copied), copySet(after, opts.Copied)
+		SelectBest(before)
+		SelectBest(after)
+	case opts.Median:
+		before, after = copySet(before, opts.Copied), copySet(after, opts.Copied)
+		SelectMedian(before)
+		SelectMedian(after)
+	}
+	key := opts.Key
+	if key == nil {
+		key = BenchName
+	}
+	before, after = regroup(before, key), regroup(after, key)
+	diffs, warnings := correlate(before, after)
+	if opts.Added {
+		// correlate only walks before, so report the benchmarks added after.
+		for name, afterbb := range after {
+			if len(before[name]) == 0 {
+				warnings = append(warnings, Warning{name, 0, len(afterbb)})
+			}
+		}
+	}
+	if opts.ChangedOnly {
+		diffs = changed(diffs, opts.Epsilon)
+	}
+	sort.Sort(ByParseOrder(diffs))
+	sort.Slice(warnings, func(i, j int) bool { return warnings[i].Name < warnings[j].Name })
+	return diffs, warnings
+}
+
+// SelectBest keeps in bs only the fastest result, by ns/op, of each
+// benchmark, giving it the parse order of the first result.
+func SelectBest(bs parse.Set) {
+	for name, bb := range bs {
+		if len(bb) < 2 {
+			continue
+		}
+		ord := bb[0].Ord
+		best := bb[0]
+		for _, b := range bb {
+			if b.NsPerOp < best.NsPerOp {
+				b.Ord = ord
+				best = b
+			}
+		}
+		bs[name] = []*parse.Benchmark{best}
+	}
+}
+
+// SelectFirst keeps in bs only the first parsed result, of lowest parse
+// order, of each benchmark.
+func SelectFirst(bs parse.Set) {
+	for name, bb := range bs {
+		if len(bb) < 2 {
+			continue
+		}
+		first := bb[0]
+		for _, b := range bb {
+			if b.Ord < first.Ord {
+				first = b
+			}
+		}
+		bs[name] = []*parse.Benchmark{first}
+	}
+}
+
+// SelectWorst keeps in bs only the slowest result, by ns/op, of each
+// benchmark, giving it the parse order of the first result.
+func SelectWorst(bs parse.Set) {
+	for name, bb := range bs {
+		if len(bb) < 2 {
+			continue
+		}
+		ord := bb[0].Ord
+		worst := bb[0]
+		for _, b := range bb {
+			if b.NsPerOp > worst.NsPerOp {
+				b.Ord = ord
+				worst = b
+			}
+		}
+		bs[name] = []*parse.Benchmark{worst}
+	}
+}
+
+// SelectMedian keeps in bs only the result with the median ns/op of
+// each benchmark, giving it the parse order of the first result. With an
+// even number of results, the lower median is kept: the slower of the
+// two middle results is dropped. Results with the same ns/op are ranked
+// in parse order.
+func SelectMedian(bs parse.Set) {
+	SelectPercentile(bs, 50)
+}
+
+// SelectPercentile keeps in bs only the result at percentile p, between
+// 0 and 100, of the ns/op of each benchmark, giving it the parse order of
+// the first result. The nearest-rank method is used: of n results sorted
+// by ns/op, the one of rank ceil(p/100*n) is kept, so 50 keeps the lower
+// median and 100 the slowest result. Results with the same ns/op are
+// ranked in parse order.
+func SelectPercentile(bs parse.Set, p float64) {
+	for name, bb := range bs {
+		if len(bb) < 2 {
+			continue
+		}
+		ord := bb[0].Ord
+		sorted := append([]*parse.Benchmark(nil), bb...)
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].NsPerOp < sorted[j].NsPerOp })
+		rank := int(math.Ceil(p * float64(len(sorted)) / 100))
+		if rank < 1 {
+			rank = 1
+		}
+		if rank > len(sorted) {
+			rank = len(sorted)
+		}
+		kept := sorted[rank-1]
+		kept.Ord = ord
+		bs[name] = []*parse.Benchmark{kept}
+	}
+}
+
+// copySet returns a copy of bs holding copies of its benchmarks,
+// calling copied, if not nil, with each copy and its original.
+func copySet(bs parse.Set, copied func(copy, orig *parse.Benchmark)) parse.Set {
+	cp := make(parse.Set, len(bs))
+	for name, bb := range bs {
+		cp[name] = make([]*parse.Benchmark, len(bb))
+		for i, b := range bb {
+			c := *b
+			cp[name][i] = &c
+			if copied != nil {
+				copied(&c, b)
+			}
+		}
+	}
+	return cp
+}
+
+// changed returns the diffs having a measurement that changed by more
+// than epsilon percent.
+func changed(diffs []BenchDiff, epsilon float64) []BenchDiff {
+	var kept []BenchDiff
+	for _, c := range diffs {
+		if c.Measured(parse.NsPerOp) && c.DeltaNsPerOp().ChangedBeyond(epsilon) ||
+			c.Measured(parse.MBPerS) && c.DeltaMBPerS().ChangedBeyond(epsilon) ||
+			c.Measured(parse.AllocsPerOp) && c.DeltaAllocsPerOp().ChangedBeyond(epsilon) ||
+			c.Measured(parse.AllocedBytesPerOp) && c.DeltaAllocedBytesPerOp().ChangedBeyond(epsilon) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
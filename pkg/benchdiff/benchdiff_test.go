@@ -0,0 +1,194 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchdiff
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestCompare(t *testing.T) {
+	ns := func(name string, ord int, v ...float64) []*parse.Benchmark {
+		var bb []*parse.Benchmark
+		for i, x := range v {
+			bb = append(bb, &parse.Benchmark{Name: name, NsPerOp: x, Measured: parse.NsPerOp, Ord: ord + i})
+		}
+		return bb
+	}
+	before := parse.Set{
+		"BenchmarkA": ns("BenchmarkA", 0, 12, 10),
+		"BenchmarkB": ns("BenchmarkB", 2, 5),
+		"BenchmarkC": ns("BenchmarkC", 3, 7),
+	}
+	after := parse.Set{
+		"BenchmarkA": ns("BenchmarkA", 0, 9, 11),
+		"BenchmarkB": ns("BenchmarkB", 2, 5),
+		"BenchmarkC": ns("BenchmarkC", 3, 7, 8),
+	}
+
+	diffs, warnings := Compare(before, after, Options{})
+	if len(diffs) != 3 || diffs[0].Name() != "BenchmarkA" || diffs[2].Name() != "BenchmarkB" {
+		t.Errorf("Compare: want BenchmarkA twice then BenchmarkB, have %v", diffs)
+	}
+	if want := []Warning{{"BenchmarkC", 1, 2}}; !reflect.DeepEqual(warnings, want) {
+		t.Errorf("Compare warnings: want %v have %v", want, warnings)
+	}
+	if want, have := "ignoring BenchmarkC: before has 1 instances, after has 2", warnings[0].String(); want != have {
+		t.Errorf("Warning.String: want %q have %q", want, have)
+	}
+
+	diffs, _ = Compare(before, after, Options{Best: true, ChangedOnly: true})
+	// BenchmarkC compares 7 with its best result, 7.
+	if len(diffs) != 1 || diffs[0].DeltaNsPerOp() != (Delta{10, 9}) {
+		t.Errorf("Compare with Best and ChangedOnly: want BenchmarkA 10 -> 9, have %v", diffs)
+	}
+	if len(before["BenchmarkA"]) != 2 || before["BenchmarkA"][1].Ord != 1 {
+		t.Error("Compare with Best modified its input")
+	}
+
+	origs := make(map[*parse.Benchmark]*parse.Benchmark)
+	diffs, _ = Compare(before, after, Options{Median: true, Copied: func(c, orig *parse.Benchmark) { origs[c] = orig }})
+	for _, d := range diffs {
+		if orig := origs[d.Before]; orig == nil || orig.Name != d.Name() || orig == d.Before {
+			t.Errorf("Compare with Median: no Copied call for the copy of %s", d.Name())
+		}
+	}
+}
+
+func TestUnmatched(t *testing.T) {
+	ns := func(name string) []*parse.Benchmark {
+		return []*parse.Benchmark{{Name: name, NsPerOp: 1, Measured: parse.NsPerOp}}
+	}
+	before := parse.Set{"BenchmarkA": ns("BenchmarkA"), "BenchmarkOld": ns("BenchmarkOld")}
+	after := parse.Set{"BenchmarkA": append(ns("BenchmarkA"), ns("BenchmarkA")...), "BenchmarkNew": ns("BenchmarkNew")}
+	_, warnings := Compare(before, after, Options{})
+	if want := []Warning{{"BenchmarkA", 1, 2}, {"BenchmarkOld", 1, 0}}; !reflect.DeepEqual(warnings, want) {
+		t.Fatalf("Compare warnings without Added: want %v have %v", want, warnings)
+	}
+	_, warnings = Compare(before, after, Options{Added: true})
+	want := []Warning{{"BenchmarkA", 1, 2}, {"BenchmarkNew", 0, 1}, {"BenchmarkOld", 1, 0}}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Fatalf("Compare warnings: want %v have %v", want, warnings)
+	}
+	removed, added := Unmatched(warnings)
+	if !reflect.DeepEqual(removed, []string{"BenchmarkOld"}) || !reflect.DeepEqual(added, []string{"BenchmarkNew"}) {
+		t.Errorf("Unmatched: want [BenchmarkOld] [BenchmarkNew] have %v %v", removed, added)
+	}
+}
+
+func TestSelectMedian(t *testing.T) {
+	bs := parse.Set{
+		"BenchmarkOdd": {
+			{Name: "BenchmarkOdd", NsPerOp: 30, Ord: 0},
+			{Name: "BenchmarkOdd", NsPerOp: 10, Ord: 2},
+			{Name: "BenchmarkOdd", NsPerOp: 20, Ord: 4},
+		},
+		"BenchmarkEven": {
+			{Name: "BenchmarkEven", NsPerOp: 40, Ord: 1},
+			{Name: "BenchmarkEven", NsPerOp: 10, Ord: 3},
+			{Name: "BenchmarkEven", NsPerOp: 30, Ord: 5},
+			{Name: "BenchmarkEven", NsPerOp: 20, Ord: 6},
+		},
+		"BenchmarkOne": {
+			{Name: "BenchmarkOne", NsPerOp: 5, Ord: 7},
+		},
+	}
+	SelectMedian(bs)
+	cases := []struct {
+		name string
+		ns   float64
+		ord  int
+	}{
+		{"BenchmarkOdd", 20, 0},
+		{"BenchmarkEven", 20, 1}, // lower of 20 and 30
+		{"BenchmarkOne", 5, 7},
+	}
+	for _, tt := range cases {
+		bb := bs[tt.name]
+		if len(bb) != 1 || bb[0].NsPerOp != tt.ns || bb[0].Ord != tt.ord {
+			t.Errorf("SelectMedian %s: want %v ns/op at %d have %v", tt.name, tt.ns, tt.ord, bb)
+		}
+	}
+}
+
+func TestSelectPercentile(t *testing.T) {
+	runs := func() parse.Set {
+		// Ten runs of 10 to 100 ns/op, out of order, with 1000 allocs/op
+		// on the 90 ns/op one to check the whole result is kept.
+		var bb []*parse.Benchmark
+		for i, ns := range []float64{50, 100, 10, 90, 20, 80, 30, 70, 40, 60} {
+			b := &parse.Benchmark{Name: "BenchmarkA", NsPerOp: ns, Ord: i}
+			if ns == 90 {
+				b.AllocsPerOp = 1000
+			}
+			bb = append(bb, b)
+		}
+		return parse.Set{"BenchmarkA": bb}
+	}
+	cases := []struct {
+		p  float64
+		ns float64
+	}{
+		{50, 50},
+		{90, 90},
+		{30, 30}, // not 40: 30*10/100 is exactly 3
+		{99, 100},
+		{100, 100},
+		{1, 10},
+	}
+	for _, tt := range cases {
+		bs := runs()
+		SelectPercentile(bs, tt.p)
+		bb := bs["BenchmarkA"]
+		if len(bb) != 1 || bb[0].NsPerOp != tt.ns || bb[0].Ord != 0 {
+			t.Errorf("SelectPercentile %v: want %v ns/op at 0 have %v", tt.p, tt.ns, bb)
+		}
+	}
+	bs := runs()
+	SelectPercentile(bs, 90)
+	if b := bs["BenchmarkA"][0]; b.AllocsPerOp != 1000 {
+		t.Errorf("SelectPercentile 90: want the allocs/op of the 90 ns/op run, have %v", b)
+	}
+}
+
+func TestSelectWorst(t *testing.T) {
+	bs := parse.Set{
+		"BenchmarkA": {
+			{Name: "BenchmarkA", NsPerOp: 20, Ord: 1},
+			{Name: "BenchmarkA", NsPerOp: 30, Ord: 3, AllocsPerOp: 7},
+			{Name: "BenchmarkA", NsPerOp: 10, Ord: 4},
+			{Name: "BenchmarkA", NsPerOp: 30, Ord: 5},
+		},
+		"BenchmarkOne": {{Name: "BenchmarkOne", NsPerOp: 5, Ord: 0}},
+	}
+	SelectWorst(bs)
+	// The first of the slowest results is kept, with the first parse order.
+	if bb := bs["BenchmarkA"]; len(bb) != 1 || bb[0].NsPerOp != 30 || bb[0].AllocsPerOp != 7 || bb[0].Ord != 1 {
+		t.Errorf("SelectWorst BenchmarkA: want 30 ns/op, 7 allocs/op at 1 have %v", bb)
+	}
+	if bb := bs["BenchmarkOne"]; len(bb) != 1 || bb[0].NsPerOp != 5 || bb[0].Ord != 0 {
+		t.Errorf("SelectWorst BenchmarkOne: want 5 ns/op at 0 have %v", bb)
+	}
+}
+
+func TestSelectFirst(t *testing.T) {
+	bs := parse.Set{
+		"BenchmarkA": {
+			{Name: "BenchmarkA", NsPerOp: 20, Ord: 3},
+			{Name: "BenchmarkA", NsPerOp: 30, Ord: 1, AllocsPerOp: 7},
+			{Name: "BenchmarkA", NsPerOp: 10, Ord: 4},
+		},
+		"BenchmarkOne": {{Name: "BenchmarkOne", NsPerOp: 5, Ord: 0}},
+	}
+	SelectFirst(bs)
+	if bb := bs["BenchmarkA"]; len(bb) != 1 || bb[0].NsPerOp != 30 || bb[0].AllocsPerOp != 7 || bb[0].Ord != 1 {
+		t.Errorf("SelectFirst BenchmarkA: want 30 ns/op, 7 allocs/op at 1 have %v", bb)
+	}
+	if bb := bs["BenchmarkOne"]; len(bb) != 1 || bb[0].NsPerOp != 5 || bb[0].Ord != 0 {
+		t.Errorf("SelectFirst BenchmarkOne: want 5 ns/op at 0 have %v", bb)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchdiff
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// A Comparator computes the change of a measurement between the two
+// sides of a Delta, in place of the percent change, and formats it.
+type Comparator interface {
+	// Change returns the change from d.Before to d.After.
+	Change(d Delta) float64
+	// Format formats a change returned by Change.
+	Format(change float64) string
+}
+
+// A Judge is a Comparator also deciding whether a change is acceptable,
+// in place of a tolerance on the percent change.
+type Judge interface {
+	Comparator
+	// Pass reports whether the change returned by Change is acceptable.
+	Pass(change float64) bool
+}
+
+var (
+	comparatorsMu sync.RWMutex
+	comparators   = make(map[string]Comparator)
+)
+
+// RegisterComparator makes c available by name, as to the -comparator
+// flag of the benchdiff command. It is meant to be called from the init
+// function of the package implementing c, and panics if name is already
+// registered or c is nil.
+func RegisterComparator(name string, c Comparator) {
+	comparatorsMu.Lock()
+	defer comparatorsMu.Unlock()
+	if c == nil {
+		panic("benchdiff: RegisterComparator of a nil Comparator " + name)
+	}
+	if _, dup := comparators[name]; dup {
+		panic("benchdiff: RegisterComparator called twice for " + name)
+	}
+	comparators[name] = c
+}
+
+// LookupComparator returns the Comparator registered as name, and
+// whether there is one.
+func LookupComparator(name string) (Comparator, bool) {
+	comparatorsMu.RLock()
+	defer comparatorsMu.RUnlock()
+	c, ok := comparators[name]
+	return c, ok
+}
+
+// Comparators returns the sorted names of the registered comparators.
+func Comparators() []string {
+	comparatorsMu.RLock()
+	defer comparatorsMu.RUnlock()
+	names := make([]string, 0, len(comparators))
+	for name := range comparators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// The built-in comparators: the percent change, the ratio of the after
+// to the before value, their natural logarithm, symmetric for
+// speedups and slowdowns, and the absolute difference.
+func init() {
+	RegisterComparator("percent", percentComparator{})
+	RegisterComparator("ratio", ratioComparator{})
+	RegisterComparator("logratio", logRatioComparator{})
+	RegisterComparator("diff", diffComparator{})
+}
+
+type percentComparator struct{}
+
+func (percentComparator) Change(d Delta) float64 { return d.Percent() }
+
+func (percentComparator) Format(change float64) string {
+	if math.IsInf(change, 1) {
+		return "+∞"
+	}
+	return fmt.Sprintf("%+.2f%%", change)
+}
+
+type ratioComparator struct{}
+
+func (ratioComparator) Change(d Delta) float64 { return d.Float64() }
+
+func (ratioComparator) Format(change float64) string {
+	if math.IsInf(change, 1) {
+		return "∞x"
+	}
+	return fmt.Sprintf("%.2fx", change)
+}
+
+type logRatioComparator struct{}
+
+func (logRatioComparator) Change(d Delta) float64 { return math.Log(d.Float64()) }
+
+func (logRatioComparator) Format(change float64) string {
+	if math.IsInf(change, 0) {
+		return fmt.Sprintf("%+v", change)
+	}
+	return fmt.Sprintf("%+.4f", change)
+}
+
+type diffComparator struct{}
+
+func (diffComparator) Change(d Delta) float64 { return d.After - d.Before }
+
+// Format rounds change to two decimals.
+func (diffComparator) Format(change float64) string {
+	s := strconv.FormatFloat(math.Round(100*change)/100, 'f', -1, 64)
+	if change >= 0 {
+		s = "+" + s
+	}
+	return s
+}
@@ -0,0 +1,64 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchdiff
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestBuiltinComparators(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		d    Delta
+		want string
+	}{
+		{"percent", Delta{100, 90}, "-10.00%"},
+		{"percent", Delta{0, 90}, "+∞"},
+		{"ratio", Delta{100, 150}, "1.50x"},
+		{"logratio", Delta{100, 100}, "+0.0000"},
+		{"logratio", Delta{0, 1}, "+Inf"},
+		{"diff", Delta{5, 6.5}, "+1.5"},
+		{"diff", Delta{2, 1}, "-1"},
+	} {
+		c, ok := LookupComparator(tt.name)
+		if !ok {
+			t.Fatalf("LookupComparator(%q): not registered", tt.name)
+		}
+		if have := c.Format(c.Change(tt.d)); have != tt.want {
+			t.Errorf("%s of %v: want %q have %q", tt.name, tt.d, tt.want, have)
+		}
+	}
+}
+
+type maxRatio float64
+
+func (m maxRatio) Change(d Delta) float64       { return d.Float64() }
+func (m maxRatio) Format(change float64) string { return "" }
+func (m maxRatio) Pass(change float64) bool     { return change <= float64(m) }
+
+func TestRegisterComparator(t *testing.T) {
+	RegisterComparator("test-max-ratio", maxRatio(1.1))
+	defer func() {
+		comparatorsMu.Lock()
+		delete(comparators, "test-max-ratio")
+		comparatorsMu.Unlock()
+	}()
+	c, ok := LookupComparator("test-max-ratio")
+	if _, judge := c.(Judge); !ok || !judge {
+		t.Fatalf("LookupComparator: want the registered Judge have %v, %v", c, ok)
+	}
+	names := Comparators()
+	if !sort.StringsAreSorted(names) || !reflect.DeepEqual(names[:5], []string{"diff", "logratio", "percent", "ratio", "test-max-ratio"}) {
+		t.Errorf("Comparators: want the sorted built-in and registered names have %v", names)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterComparator of a registered name: want a panic")
+		}
+	}()
+	RegisterComparator("test-max-ratio", maxRatio(2))
+}
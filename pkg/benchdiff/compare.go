@@ -0,0 +1,292 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package benchdiff compares the results of two runs of Go benchmarks,
+// as parsed by golang.org/x/tools/benchmark/parse.
+package benchdiff
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// BenchDiff is a pair of benchmarks.
+type BenchDiff struct {
+	Before *parse.Benchmark
+	After  *parse.Benchmark
+}
+
+// Correlate correlates benchmarks from two BenchSets.
+func Correlate(before, after parse.Set) (cmps []BenchDiff, warnings []string) {
+	cmps, ww := correlate(before, after)
+	for _, w := range ww {
+		warnings = append(warnings, w.String())
+	}
+	return cmps, warnings
+}
+
+// correlate is Correlate returning the warnings as Warnings.
+func correlate(before, after parse.Set) (cmps []BenchDiff, warnings []Warning) {
+	cmps = make([]BenchDiff, 0, len(after))
+	for name, beforebb := range before {
+		afterbb := after[name]
+		if len(beforebb) != len(afterbb) {
+			warnings = append(warnings, Warning{name, len(beforebb), len(afterbb)})
+			continue
+		}
+		for i, beforeb := range beforebb {
+			afterb := afterbb[i]
+			cmps = append(cmps, BenchDiff{beforeb, afterb})
+		}
+	}
+	return
+}
+
+// CorrelateWith correlates benchmarks from two BenchSets, matching the
+// benchmarks for which key returns the same string. Results with the
+// same key are gathered in parse order. With BenchName as key,
+// CorrelateWith is Correlate.
+func CorrelateWith(before, after parse.Set, key func(*parse.Benchmark) string) (cmps []BenchDiff, warnings []string) {
+	return Correlate(regroup(before, key), regroup(after, key))
+}
+
+// BenchName returns the name of b, the default correlation key.
+func BenchName(b *parse.Benchmark) string { return b.Name }
+
+// regroup returns the results of bs keyed by key, in parse order.
+func regroup(bs parse.Set, key func(*parse.Benchmark) string) parse.Set {
+	var all []*parse.Benchmark
+	for _, bb := range bs {
+		all = append(all, bb...)
+	}
+	sort.Sort(byOrd(all))
+	grouped := make(parse.Set)
+	for _, b := range all {
+		k := key(b)
+		grouped[k] = append(grouped[k], b)
+	}
+	return grouped
+}
+
+// byOrd sorts benchmark results in parse order.
+type byOrd []*parse.Benchmark
+
+func (x byOrd) Len() int           { return len(x) }
+func (x byOrd) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+func (x byOrd) Less(i, j int) bool { return x[i].Ord < x[j].Ord }
+
+// Name returns the name of the before benchmark.
+func (c BenchDiff) Name() string { return c.Before.Name }
+
+func (c BenchDiff) String() string { return fmt.Sprintf("<%s, %s>", c.Before, c.After) }
+
+// Measured reports whether both benchmarks measured flag, one of the
+// parse.NsPerOp family of constants.
+func (c BenchDiff) Measured(flag int) bool { return (c.Before.Measured & c.After.Measured & flag) != 0 }
+
+// DeltaNsPerOp compares the ns/op.
+func (c BenchDiff) DeltaNsPerOp() Delta { return Delta{c.Before.NsPerOp, c.After.NsPerOp} }
+
+// DeltaMBPerS compares the MB/s.
+func (c BenchDiff) DeltaMBPerS() Delta { return Delta{c.Before.MBPerS, c.After.MBPerS} }
+
+// DeltaAllocedBytesPerOp compares the B/op.
+func (c BenchDiff) DeltaAllocedBytesPerOp() Delta {
+	return Delta{float64(c.Before.AllocedBytesPerOp), float64(c.After.AllocedBytesPerOp)}
+}
+
+// DeltaAllocsPerOp compares the allocs/op.
+func (c BenchDiff) DeltaAllocsPerOp() Delta {
+	return Delta{float64(c.Before.AllocsPerOp), float64(c.After.AllocsPerOp)}
+}
+
+// DeltaNsPerAlloc compares the ns/op spent per allocation. A side without
+// allocations counts as zero.
+func (c BenchDiff) DeltaNsPerAlloc() Delta {
+	before, _ := NsPerAlloc(c.Before)
+	after, _ := NsPerAlloc(c.After)
+	return Delta{before, after}
+}
+
+// NsPerAlloc returns the ns/op of b divided by its allocs/op.
+// It reports false if b did not allocate.
+func NsPerAlloc(b *parse.Benchmark) (float64, bool) {
+	if b.AllocsPerOp == 0 {
+		return 0, false
+	}
+	return b.NsPerOp / float64(b.AllocsPerOp), true
+}
+
+// Delta is the before and after value for a benchmark measurement.
+// Both must be non-negative.
+type Delta struct {
+	Before float64
+	After  float64
+}
+
+// Mag calculates the magnitude of a change, regardless of the direction of
+// the change. Mag is intended for sorting and has no independent meaning.
+func (d Delta) Mag() float64 {
+	switch {
+	case d.Before != 0 && d.After != 0 && d.Before >= d.After:
+		return d.After / d.Before
+	case d.Before != 0 && d.After != 0 && d.Before < d.After:
+		return d.Before / d.After
+	case d.Before == 0 && d.After == 0:
+		return 1
+	default:
+		// 0 -> 1 or 1 -> 0
+		// These are significant changes and worth surfacing.
+		return math.Inf(1)
+	}
+}
+
+// Changed reports whether the benchmark quantities are different.
+func (d Delta) Changed() bool { return d.Before != d.After }
+
+// ChangedBeyond reports whether the benchmark quantities differ by more
+// than epsilon percent. A change from or to zero, whose percent is not
+// meaningful, is always beyond epsilon.
+func (d Delta) ChangedBeyond(epsilon float64) bool {
+	if epsilon <= 0 || d.Before == 0 || d.After == 0 {
+		return d.Changed()
+	}
+	return math.Abs(d.Percent()) > epsilon
+}
+
+// Float64 returns After / Before. If Before is 0, Float64 returns
+// 1 if After is also 0, and +Inf otherwise.
+func (d Delta) Float64() float64 {
+	switch {
+	case d.Before != 0:
+		return d.After / d.Before
+	case d.After == 0:
+		return 1
+	default:
+		return math.Inf(1)
+	}
+}
+
+// PercentAsStr formats a Delta as a percent change, ranging from -100% up.
+func (d Delta) PercentAsStr() string {
+	return d.PercentPrec(2)
+}
+
+// PercentPrec formats a Delta as a percent change with prec decimals.
+// A change from zero, whose percent is infinite, is "+∞".
+func (d Delta) PercentPrec(prec int) string {
+	if math.IsInf(d.Float64(), 1) {
+		return "+∞"
+	}
+	return fmt.Sprintf("%+.*f%%", prec, 100*d.Float64()-100)
+}
+
+// Percent returns a Delta as a percent, ranging from -100% up
+func (d Delta) Percent() float64 {
+	return 100*d.Float64() - 100
+}
+
+// Abs returns the absolute difference between the After and Before values.
+func (d Delta) Abs() float64 {
+	return math.Abs(d.After - d.Before)
+}
+
+// Multiple formats a Delta as a multiplier, ranging from 0.00x up.
+func (d Delta) Multiple() string {
+	return d.MultiplePrec(2)
+}
+
+// MultiplePrec formats a Delta as a multiplier with prec decimals.
+// A change from zero is "∞x".
+func (d Delta) MultiplePrec(prec int) string {
+	if math.IsInf(d.Float64(), 1) {
+		return "∞x"
+	}
+	return fmt.Sprintf("%.*fx", prec, d.Float64())
+}
+
+func (d Delta) String() string {
+	return fmt.Sprintf("Δ(%f, %f)", d.Before, d.After)
+}
+
+// ByParseOrder sorts BenchDiffs to match the order in
+// which the Before benchmarks were presented to Parse.
+type ByParseOrder []BenchDiff
+
+func (x ByParseOrder) Len() int           { return len(x) }
+func (x ByParseOrder) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+func (x ByParseOrder) Less(i, j int) bool { return x[i].Before.Ord < x[j].Before.Ord }
+
+// ByName sorts BenchDiffs alphabetically by benchmark name, then in
+// parse order.
+type ByName []BenchDiff
+
+func (x ByName) Len() int      { return len(x) }
+func (x ByName) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+func (x ByName) Less(i, j int) bool {
+	if x[i].Name() != x[j].Name() {
+		return x[i].Name() < x[j].Name()
+	}
+	return x[i].Before.Ord < x[j].Before.Ord
+}
+
+// lessByDelta provides lexicographic ordering:
+//   * largest delta by magnitude
+//   * alphabetic by name
+func lessByDelta(i, j BenchDiff, calcDelta func(BenchDiff) Delta) bool {
+	iDelta, jDelta := calcDelta(i).Mag(), calcDelta(j).Mag()
+	if iDelta != jDelta {
+		return iDelta < jDelta
+	}
+	return i.Name() < j.Name()
+}
+
+// ByDeltaNsPerOp sorts BenchCmps lexicographically by change
+// in ns/op, descending, then by benchmark name.
+type ByDeltaNsPerOp []BenchDiff
+
+func (x ByDeltaNsPerOp) Len() int           { return len(x) }
+func (x ByDeltaNsPerOp) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+func (x ByDeltaNsPerOp) Less(i, j int) bool { return lessByDelta(x[i], x[j], BenchDiff.DeltaNsPerOp) }
+
+// ByDeltaMBPerS sorts BenchCmps lexicographically by change
+// in MB/s, descending, then by benchmark name.
+type ByDeltaMBPerS []BenchDiff
+
+func (x ByDeltaMBPerS) Len() int           { return len(x) }
+func (x ByDeltaMBPerS) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+func (x ByDeltaMBPerS) Less(i, j int) bool { return lessByDelta(x[i], x[j], BenchDiff.DeltaMBPerS) }
+
+// ByDeltaAllocedBytesPerOp sorts BenchCmps lexicographically by change
+// in B/op, descending, then by benchmark name.
+type ByDeltaAllocedBytesPerOp []BenchDiff
+
+func (x ByDeltaAllocedBytesPerOp) Len() int      { return len(x) }
+func (x ByDeltaAllocedBytesPerOp) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+func (x ByDeltaAllocedBytesPerOp) Less(i, j int) bool {
+	return lessByDelta(x[i], x[j], BenchDiff.DeltaAllocedBytesPerOp)
+}
+
+// ByDeltaAllocsPerOp sorts BenchCmps lexicographically by change
+// in allocs/op, descending, then by benchmark name.
+type ByDeltaAllocsPerOp []BenchDiff
+
+func (x ByDeltaAllocsPerOp) Len() int      { return len(x) }
+func (x ByDeltaAllocsPerOp) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+func (x ByDeltaAllocsPerOp) Less(i, j int) bool {
+	return lessByDelta(x[i], x[j], BenchDiff.DeltaAllocsPerOp)
+}
+
+// ByDeltaNsPerAlloc sorts BenchCmps lexicographically by change
+// in ns per alloc, descending, then by benchmark name.
+type ByDeltaNsPerAlloc []BenchDiff
+
+func (x ByDeltaNsPerAlloc) Len() int      { return len(x) }
+func (x ByDeltaNsPerAlloc) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+func (x ByDeltaNsPerAlloc) Less(i, j int) bool {
+	return lessByDelta(x[i], x[j], BenchDiff.DeltaNsPerAlloc)
+}
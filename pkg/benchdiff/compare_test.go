@@ -2,12 +2,13 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package benchdiff
 
 import (
 	"math"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 
 	"golang.org/x/tools/benchmark/parse"
@@ -22,18 +23,19 @@ func TestDelta(t *testing.T) {
 		changed bool
 		pct     string
 		mult    string
+		abs     float64
 	}{
-		{before: 1, after: 1, mag: 1, f: 1, changed: false, pct: "+0.00%", mult: "1.00x"},
-		{before: 1, after: 2, mag: 0.5, f: 2, changed: true, pct: "+100.00%", mult: "2.00x"},
-		{before: 2, after: 1, mag: 0.5, f: 0.5, changed: true, pct: "-50.00%", mult: "0.50x"},
-		{before: 0, after: 0, mag: 1, f: 1, changed: false, pct: "+0.00%", mult: "1.00x"},
-		{before: 1, after: 0, mag: math.Inf(1), f: 0, changed: true, pct: "-100.00%", mult: "0.00x"},
-		{before: 0, after: 1, mag: math.Inf(1), f: math.Inf(1), changed: true, pct: "+Inf%", mult: "+Infx"},
+		{before: 1, after: 1, mag: 1, f: 1, changed: false, pct: "+0.00%", mult: "1.00x", abs: 0},
+		{before: 1, after: 2, mag: 0.5, f: 2, changed: true, pct: "+100.00%", mult: "2.00x", abs: 1},
+		{before: 2, after: 1, mag: 0.5, f: 0.5, changed: true, pct: "-50.00%", mult: "0.50x", abs: 1},
+		{before: 0, after: 0, mag: 1, f: 1, changed: false, pct: "+0.00%", mult: "1.00x", abs: 0},
+		{before: 1, after: 0, mag: math.Inf(1), f: 0, changed: true, pct: "-100.00%", mult: "0.00x", abs: 1},
+		{before: 0, after: 1, mag: math.Inf(1), f: math.Inf(1), changed: true, pct: "+∞", mult: "∞x", abs: 1},
 	}
 	for _, tt := range cases {
 		d := Delta{tt.before, tt.after}
-		if want, have := tt.mag, d.mag(); want != have {
-			t.Errorf("%s.mag(): want %f have %f", d, want, have)
+		if want, have := tt.mag, d.Mag(); want != have {
+			t.Errorf("%s.Mag(): want %f have %f", d, want, have)
 		}
 		if want, have := tt.f, d.Float64(); want != have {
 			t.Errorf("%s.Float64(): want %f have %f", d, want, have)
@@ -47,6 +49,34 @@ func TestDelta(t *testing.T) {
 		if want, have := tt.mult, d.Multiple(); want != have {
 			t.Errorf("%s.Multiple(): want %q have %q", d, want, have)
 		}
+		if want, have := tt.abs, d.Abs(); want != have {
+			t.Errorf("%s.Abs(): want %f have %f", d, want, have)
+		}
+	}
+}
+
+func TestChangedBeyond(t *testing.T) {
+	cases := []struct {
+		before, after float64
+		epsilon       float64
+		want          bool
+	}{
+		{100, 100.01, 0.005, true},
+		{100, 100.005, 0.01, false},
+		{100, 125, 25, false}, // the boundary itself is unchanged
+		{100, 99.98, 0.01, true},
+		{100, 100.001, 0, true}, // no epsilon: any difference
+		{100, 100, 0, false},
+		// A change from or to zero always counts, whatever epsilon.
+		{0, 0.001, 50, true},
+		{0.001, 0, 50, true},
+		{0, 0, 50, false},
+	}
+	for _, tt := range cases {
+		d := Delta{tt.before, tt.after}
+		if have := d.ChangedBeyond(tt.epsilon); have != tt.want {
+			t.Errorf("%s.ChangedBeyond(%v): want %t have %t", d, tt.epsilon, tt.want, have)
+		}
 	}
 }
 
@@ -130,4 +160,51 @@ func TestBenchDiffSorting(t *testing.T) {
 	if !reflect.DeepEqual(want, have) {
 		t.Errorf("ByParseOrder incorrect sorting: want %v have %v", want, have)
 	}
+
+	sort.Sort(ByName(c))
+	want = []string{"BenchmarkMuchFaster", "BenchmarkSameA", "BenchmarkSameB", "BenchmarkSlower"}
+	have = []string{c[0].Name(), c[1].Name(), c[2].Name(), c[3].Name()}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("ByName incorrect sorting: want %v have %v", want, have)
+	}
+}
+
+func TestMultiplePrec(t *testing.T) {
+	d := Delta{3, 4}
+	cases := []struct {
+		prec int
+		want string
+	}{
+		{0, "1x"},
+		{1, "1.3x"},
+		{2, "1.33x"},
+		{4, "1.3333x"},
+	}
+	for _, tt := range cases {
+		if have := d.MultiplePrec(tt.prec); have != tt.want {
+			t.Errorf("%s.MultiplePrec(%d): want %q have %q", d, tt.prec, tt.want, have)
+		}
+	}
+}
+
+func TestCorrelateWith(t *testing.T) {
+	before := parse.Set{
+		"BenchmarkA-4": []*parse.Benchmark{{Name: "BenchmarkA-4", NsPerOp: 1, Ord: 0}},
+		"BenchmarkB-4": []*parse.Benchmark{{Name: "BenchmarkB-4", NsPerOp: 2, Ord: 1}},
+	}
+	after := parse.Set{
+		"BenchmarkA-8": []*parse.Benchmark{{Name: "BenchmarkA-8", NsPerOp: 3, Ord: 0}},
+		"BenchmarkC-8": []*parse.Benchmark{{Name: "BenchmarkC-8", NsPerOp: 4, Ord: 1}},
+	}
+	if diffs, _ := CorrelateWith(before, after, BenchName); len(diffs) != 0 {
+		t.Errorf("CorrelateWith by name: want no diff have %v", diffs)
+	}
+	base := func(b *parse.Benchmark) string { return strings.TrimRight(b.Name, "-0123456789") }
+	diffs, warnings := CorrelateWith(before, after, base)
+	if len(diffs) != 1 || diffs[0].Before.NsPerOp != 1 || diffs[0].After.NsPerOp != 3 {
+		t.Errorf("CorrelateWith by base name: want BenchmarkA-4 with BenchmarkA-8 have %v", diffs)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("CorrelateWith by base name: want 1 warning have %v", warnings)
+	}
 }
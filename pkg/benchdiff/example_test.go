@@ -0,0 +1,66 @@
+package benchdiff_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func ExampleCorrelateWith() {
+	before, _ := parse.ParseSet(strings.NewReader("BenchmarkDecode/json-4 1000 1500 ns/op\n"))
+	after, _ := parse.ParseSet(strings.NewReader("BenchmarkDecode/JSON-8 1000 1200 ns/op\n"))
+
+	// Match the sub-benchmarks whatever the case of their name and
+	// the GOMAXPROCS they ran with.
+	key := func(b *parse.Benchmark) string {
+		return strings.ToLower(strings.TrimRight(b.Name, "-0123456789"))
+	}
+	diffs, _ := benchdiff.CorrelateWith(before, after, key)
+	for _, diff := range diffs {
+		fmt.Println(diff.Name(), diff.After.Name, diff.DeltaNsPerOp().PercentAsStr())
+	}
+	// Output: BenchmarkDecode/json-4 BenchmarkDecode/JSON-8 -20.00%
+}
+
+func ExampleWriteTables() {
+	before, _ := parse.ParseSet(strings.NewReader("BenchmarkEncode-4 1000 1500 ns/op 64 B/op 2 allocs/op\n"))
+	after, _ := parse.ParseSet(strings.NewReader("BenchmarkEncode-4 1000 1200 ns/op 32 B/op 1 allocs/op\n"))
+
+	diffs, _ := benchdiff.Compare(before, after, benchdiff.Options{})
+	benchdiff.WriteTables(os.Stdout, diffs, "old", "new")
+	// Output:
+	// benchmark             old ns/op     new ns/op     delta
+	// BenchmarkEncode-4     1500          1200          -20.00%
+	//
+	// benchmark             old allocs     new allocs     delta
+	// BenchmarkEncode-4     2              1              -50.00%
+	//
+	// benchmark             old bytes     new bytes     delta
+	// BenchmarkEncode-4     64            32            -50.00%
+}
+
+// throughput compares the ns/op of benchmarks processing 4096 bytes as
+// their throughput, failing when it drops by more than 0.5 B/ns.
+type throughput struct{}
+
+func (throughput) Change(d benchdiff.Delta) float64 { return 4096/d.After - 4096/d.Before }
+func (throughput) Format(change float64) string     { return fmt.Sprintf("%+.2f B/ns", change) }
+func (throughput) Pass(change float64) bool         { return change >= -0.5 }
+
+func init() {
+	benchdiff.RegisterComparator("throughput", throughput{})
+}
+
+func ExampleRegisterComparator() {
+	c, _ := benchdiff.LookupComparator("throughput")
+	for _, d := range []benchdiff.Delta{{Before: 1000, After: 800}, {Before: 1000, After: 2000}} {
+		change := c.Change(d)
+		fmt.Println(c.Format(change), c.(benchdiff.Judge).Pass(change))
+	}
+	// Output:
+	// +1.02 B/ns true
+	// -2.05 B/ns false
+}
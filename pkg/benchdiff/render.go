@@ -0,0 +1,85 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchdiff
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// A column describes one block of the tables WriteTables renders.
+type column struct {
+	measured int
+	header   string
+	delta    string
+	format   func(*parse.Benchmark) string
+	change   func(BenchDiff) string
+}
+
+var columns = []column{
+	{parse.NsPerOp, "ns/op", "delta",
+		func(b *parse.Benchmark) string { return FormatNs(b.NsPerOp) },
+		func(c BenchDiff) string { return c.DeltaNsPerOp().PercentAsStr() }},
+	{parse.MBPerS, "MB/s", "speedup",
+		func(b *parse.Benchmark) string { return fmt.Sprintf("%.2f", b.MBPerS) },
+		func(c BenchDiff) string { return c.DeltaMBPerS().Multiple() }},
+	{parse.AllocsPerOp, "allocs", "delta",
+		func(b *parse.Benchmark) string { return fmt.Sprintf("%d", b.AllocsPerOp) },
+		func(c BenchDiff) string { return c.DeltaAllocsPerOp().PercentAsStr() }},
+	{parse.AllocedBytesPerOp, "bytes", "delta",
+		func(b *parse.Benchmark) string { return fmt.Sprintf("%d", b.AllocedBytesPerOp) },
+		func(c BenchDiff) string { return c.DeltaAllocedBytesPerOp().PercentAsStr() }},
+}
+
+// WriteTables writes to w the tables comparing diffs, one block per
+// metric measured on both sides by some of them (ns/op, MB/s, allocs/op
+// and B/op), in the order of diffs. The value columns are labeled with
+// oldLabel and newLabel, as "old ns/op" and "new ns/op".
+//
+// These are the plain tables of the benchdiff command without its
+// options, which it renders itself: WriteTables writes no [geomean]
+// row, no block for the custom metrics, and every row of every block,
+// without the -metrics selection, the -changed, -noise and -q hiding,
+// the -sort order, the scaling of the units, the styles of -format and
+// -color, and the extra columns such as -show-headroom.
+func WriteTables(w io.Writer, diffs []BenchDiff, oldLabel, newLabel string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 5, ' ', 0)
+	var blocks int
+	for _, col := range columns {
+		var header bool
+		for _, c := range diffs {
+			if !c.Measured(col.measured) {
+				continue
+			}
+			if !header {
+				if blocks > 0 {
+					fmt.Fprint(tw, "\n")
+				}
+				fmt.Fprintf(tw, "benchmark\t%s %s\t%s %s\t%s\n", oldLabel, col.header, newLabel, col.header, col.delta)
+				header = true
+				blocks++
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.Name(), col.format(c.Before), col.format(c.After), col.change(c))
+		}
+	}
+	return tw.Flush()
+}
+
+// FormatNs formats ns measurements to expose a useful amount of
+// precision: two decimals below 10 ns, one below 100 ns and none above.
+func FormatNs(ns float64) string {
+	prec := 0
+	switch {
+	case ns < 10:
+		prec = 2
+	case ns < 100:
+		prec = 1
+	}
+	return strconv.FormatFloat(ns, 'f', prec, 64)
+}
@@ -0,0 +1,273 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// A policyNode is a node of a -policy expression: a comparison of the
+// change of a metric with a value, or the combination of other nodes
+// with &&, || or !.
+type policyNode struct {
+	op          string      // "&&", "||", "!" or a comparison operator
+	left, right *policyNode // operands of && and ||, left only for !
+	metric      string      // canonical name of the compared metric
+	value       float64
+	percent     bool // value is a percent change, else an absolute one
+}
+
+// gatePolicy is the expression given by -policy, nil for none.
+var gatePolicy *policyNode
+
+// policyOps are the comparison operators of -policy, longest first.
+var policyOps = []string{"<=", ">=", "==", "!=", "<", ">"}
+
+// parsePolicy parses a -policy expression such as
+// "ns/op < +5% && allocs/op <= 0". A comparison holds the change of a
+// metric, named as in -ungated or by its custom unit, against a percent
+// change when its value ends with '%' and against the absolute change,
+// in the unit of the metric, otherwise. && binds tighter than ||, ! is
+// a negation and parentheses group.
+func parsePolicy(s string) (*policyNode, error) {
+	p := &policyParser{tokens: policyTokens(s)}
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if tok, ok := p.peek(); ok {
+		return nil, fmt.Errorf("unexpected %q", tok)
+	}
+	return n, nil
+}
+
+// policyTokens splits s into the operators, parentheses and words of a
+// -policy expression.
+func policyTokens(s string) []string {
+	var tokens []string
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		n := 0
+		switch {
+		case strings.HasPrefix(s, "&&"), strings.HasPrefix(s, "||"):
+			n = 2
+		case s[0] == '(' || s[0] == ')':
+			n = 1
+		default:
+			for _, op := range policyOps {
+				if strings.HasPrefix(s, op) {
+					n = len(op)
+					break
+				}
+			}
+		}
+		if n == 0 && s[0] == '!' {
+			n = 1
+		}
+		if n == 0 {
+			n = strings.IndexAny(s, " \t()<>=!&|")
+			if n < 0 {
+				n = len(s)
+			}
+		}
+		tokens = append(tokens, s[:n])
+		s = s[n:]
+	}
+	return tokens
+}
+
+// policyParser is a recursive descent parser of -policy expressions.
+type policyParser struct {
+	tokens []string
+}
+
+func (p *policyParser) peek() (string, bool) {
+	if len(p.tokens) == 0 {
+		return "", false
+	}
+	return p.tokens[0], true
+}
+
+func (p *policyParser) next() (string, bool) {
+	tok, ok := p.peek()
+	if ok {
+		p.tokens = p.tokens[1:]
+	}
+	return tok, ok
+}
+
+// or parses a || list of and operands.
+func (p *policyParser) or() (*policyNode, error) {
+	n, err := p.and()
+	for err == nil {
+		if tok, _ := p.peek(); tok != "||" {
+			break
+		}
+		p.next()
+		var right *policyNode
+		if right, err = p.and(); err == nil {
+			n = &policyNode{op: "||", left: n, right: right}
+		}
+	}
+	return n, err
+}
+
+// and parses a && list of unary operands.
+func (p *policyParser) and() (*policyNode, error) {
+	n, err := p.unary()
+	for err == nil {
+		if tok, _ := p.peek(); tok != "&&" {
+			break
+		}
+		p.next()
+		var right *policyNode
+		if right, err = p.unary(); err == nil {
+			n = &policyNode{op: "&&", left: n, right: right}
+		}
+	}
+	return n, err
+}
+
+// unary parses a negation, a parenthesized expression or a comparison.
+func (p *policyParser) unary() (*policyNode, error) {
+	tok, ok := p.next()
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("unexpected end of the expression")
+	case tok == "!":
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &policyNode{op: "!", left: n}, nil
+	case tok == "(":
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if tok, _ := p.next(); tok != ")" {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		return n, nil
+	}
+	n := &policyNode{metric: tok}
+	if canonical, ok := metricAliases[tok]; ok {
+		n.metric = canonical
+	}
+	op, _ := p.next()
+	for _, o := range policyOps {
+		if op == o {
+			n.op = op
+		}
+	}
+	if n.op == "" {
+		return nil, fmt.Errorf("want a comparison operator after %s, have %q", tok, op)
+	}
+	value, _ := p.next()
+	n.percent = strings.HasSuffix(value, "%")
+	v, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q after %s %s", value, tok, op)
+	}
+	n.value = v
+	return n, nil
+}
+
+// metrics returns the canonical names of the metrics compared by n.
+func (n *policyNode) metrics() []string {
+	switch n.op {
+	case "&&", "||":
+		return append(n.left.metrics(), n.right.metrics()...)
+	case "!":
+		return n.left.metrics()
+	}
+	return []string{n.metric}
+}
+
+// eval reports whether diff satisfies n, along with the names of the
+// metrics whose comparisons make it fail. A comparison of a metric not
+// measured on both sides of diff holds.
+func (n *policyNode) eval(diff benchdiff.BenchDiff) (bool, []string) {
+	switch n.op {
+	case "&&", "||":
+		lok, lblame := n.left.eval(diff)
+		rok, rblame := n.right.eval(diff)
+		if n.op == "&&" {
+			if !lok && !rok {
+				return false, append(lblame, rblame...)
+			}
+			if !lok {
+				return false, lblame
+			}
+			return rok, rblame
+		}
+		if lok || rok {
+			return true, nil
+		}
+		return false, append(lblame, rblame...)
+	case "!":
+		if ok, _ := n.left.eval(diff); ok {
+			return false, n.left.metrics()
+		}
+		return true, nil
+	}
+	m, ok := lookupMetric(n.metric)
+	if !ok || !m.measuredIn(diff) {
+		return true, nil
+	}
+	d := m.delta(diff)
+	change := d.After - d.Before
+	if n.percent {
+		change = d.Percent()
+	}
+	var holds bool
+	switch n.op {
+	case "<":
+		holds = change < n.value
+	case "<=":
+		holds = change <= n.value
+	case ">":
+		holds = change > n.value
+	case ">=":
+		holds = change >= n.value
+	case "==":
+		holds = change == n.value
+	case "!=":
+		holds = change != n.value
+	}
+	if holds {
+		return true, nil
+	}
+	return false, []string{n.metric}
+}
+
+// blames reports whether diff fails n because of its comparisons of
+// the metric name.
+func (n *policyNode) blames(diff benchdiff.BenchDiff, name string) bool {
+	ok, blamed := n.eval(diff)
+	if ok {
+		return false
+	}
+	for _, b := range blamed {
+		if b == name {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownPolicyMetrics returns the metrics of n that are not active.
+func unknownPolicyMetrics(n *policyNode) []string {
+	var unknown []string
+	for _, name := range n.metrics() {
+		if _, ok := lookupMetric(name); !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
@@ -0,0 +1,79 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestPolicyTokens(t *testing.T) {
+	want := []string{"!", "(", "ns/op", "<", "+5%", "||", "allocs/op", "!=", "-1", ")", "&&", "MB/s", ">=", "0"}
+	if have := policyTokens("!(ns/op<+5% || allocs/op != -1)&&MB/s >= 0"); !reflect.DeepEqual(have, want) {
+		t.Errorf("policyTokens: want %q have %q", want, have)
+	}
+}
+
+func TestParsePolicyErrors(t *testing.T) {
+	for _, s := range []string{"", "ns/op", "ns/op <", "ns/op < x", "ns/op ~ 5", "(ns/op < 5", "ns/op < 5 )", "ns/op < 5 &&"} {
+		if _, err := parsePolicy(s); err == nil {
+			t.Errorf("parsePolicy(%q): want an error", s)
+		}
+	}
+}
+
+func TestPolicyEval(t *testing.T) {
+	diff := benchdiff.BenchDiff{
+		Before: &parse.Benchmark{NsPerOp: 100, AllocsPerOp: 5, Measured: parse.NsPerOp | parse.AllocsPerOp},
+		After:  &parse.Benchmark{NsPerOp: 103, AllocsPerOp: 6, Measured: parse.NsPerOp | parse.AllocsPerOp},
+	}
+	cases := []struct {
+		expr   string
+		ok     bool
+		blamed []string
+	}{
+		{"ns/op < +5%", true, nil},
+		{"ns/op < +2%", false, []string{"ns"}},
+		{"ns < 3", false, []string{"ns"}},
+		{"ns <= 3", true, nil},
+		{"ns/op < +5% && allocs/op <= 0", false, []string{"allocs"}},
+		{"ns/op < +2% && allocs/op <= 0", false, []string{"ns", "allocs"}},
+		{"ns/op < +2% || allocs/op <= 1", true, nil},
+		{"ns/op < +2% || allocs/op <= 0", false, []string{"ns", "allocs"}},
+		{"!(allocs > 0)", false, []string{"allocs"}},
+		{"ns < 0 || allocs == 1 && ns < 5%", true, nil},
+		{"bytes/op <= 0", true, nil}, // not measured
+	}
+	for _, tt := range cases {
+		p, err := parsePolicy(tt.expr)
+		if err != nil {
+			t.Errorf("parsePolicy(%q): %v", tt.expr, err)
+			continue
+		}
+		ok, blamed := p.eval(diff)
+		if ok != tt.ok || !reflect.DeepEqual(blamed, tt.blamed) {
+			t.Errorf("%s: want %t %v have %t %v", tt.expr, tt.ok, tt.blamed, ok, blamed)
+		}
+	}
+}
+
+func TestPolicyGate(t *testing.T) {
+	defer func(f bool, p *policyNode, tol float64) { *failOnDelta, gatePolicy, *tNsPerOp = f, p, tol }(*failOnDelta, gatePolicy, *tNsPerOp)
+	*failOnDelta, *tNsPerOp = true, 1
+	var err error
+	if gatePolicy, err = parsePolicy("ns/op < +50%"); err != nil {
+		t.Fatal(err)
+	}
+	ns, _ := lookupMetric("ns")
+	if ns.exceeded(nsDiff("BenchmarkA", 0, 100, 120)) {
+		t.Error("exceeded: want the policy to replace -tnsop")
+	}
+	if !ns.exceeded(nsDiff("BenchmarkA", 0, 100, 160)) {
+		t.Error("exceeded: want a change failing the policy to exceed it")
+	}
+}
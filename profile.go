@@ -0,0 +1,82 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// profileTop is the number of functions listed for each benchmark by
+// -old-profile and -new-profile.
+const profileTop = 10
+
+// pprofCommand is the command diffing two profiles, to which
+// profileArgs are appended.
+var pprofCommand = []string{"go", "tool", "pprof"}
+
+// benchFunc returns the function of the benchmark name: the name
+// without its sub-benchmarks and -GOMAXPROCS suffix.
+func benchFunc(name string) string {
+	if i := strings.IndexByte(name, '/'); i >= 0 {
+		return name[:i]
+	}
+	base, _ := splitProcs(name)
+	return base
+}
+
+// profileArgs returns the pprof arguments listing the top functions
+// whose samples under the benchmark function fn diverge the most from
+// the old profile to the new one. The sub-benchmarks of fn are its
+// closures, fn.func1 and so on.
+func profileArgs(fn, oldPath, newPath string) []string {
+	return []string{
+		"-top",
+		fmt.Sprintf("-nodecount=%d", profileTop),
+		`-focus=(^|\.)` + regexp.QuoteMeta(fn) + `(\.|$)`,
+		"-diff_base=" + oldPath,
+		newPath,
+	}
+}
+
+// printProfileDiffs writes to w, for the function of each benchmark of
+// violations, the top functions of the diff of the CPU profiles at
+// oldPath and newPath, as listed by pprof -top. The benchmarks pprof
+// fails on are reported as warnings.
+func printProfileDiffs(w io.Writer, violations []violation, oldPath, newPath string) {
+	seen := make(map[string]bool)
+	for _, v := range violations {
+		fn := benchFunc(v.name)
+		if seen[fn] {
+			continue
+		}
+		seen[fn] = true
+		args := append(append([]string(nil), pprofCommand[1:]...), profileArgs(fn, oldPath, newPath)...)
+		cmd := exec.Command(pprofCommand[0], args...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			warn(fmt.Sprintf("benchdiff: cannot diff the profiles of %s: %v: %s", fn, err, strings.TrimSpace(stderr.String())))
+			continue
+		}
+		fmt.Fprintf(w, "\nprofile diff of %s:\n%s", fn, out)
+	}
+}
+
+// checkProfiles fails unless the -old-profile and -new-profile files
+// exist.
+func checkProfiles(oldPath, newPath string) {
+	for _, path := range []string{oldPath, newPath} {
+		if _, err := os.Stat(path); err != nil {
+			fatal(err)
+		}
+	}
+}
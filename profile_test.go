@@ -0,0 +1,47 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"testing"
+)
+
+func TestBenchFunc(t *testing.T) {
+	for name, want := range map[string]string{
+		"BenchmarkA":          "BenchmarkA",
+		"BenchmarkA-8":        "BenchmarkA",
+		"BenchmarkA/size=1K":  "BenchmarkA",
+		"BenchmarkA/x/y-8":    "BenchmarkA",
+		"BenchmarkA_Parallel": "BenchmarkA_Parallel",
+	} {
+		if have := benchFunc(name); have != want {
+			t.Errorf("benchFunc(%q): want %q have %q", name, want, have)
+		}
+	}
+}
+
+func TestPrintProfileDiffs(t *testing.T) {
+	if _, err := exec.LookPath("echo"); err != nil {
+		t.Skip("echo not available")
+	}
+	defer func(cmd []string) { pprofCommand = cmd }(pprofCommand)
+	pprofCommand = []string{"echo", "pprof"}
+
+	violations := []violation{{name: "BenchmarkA/x-8"}, {name: "BenchmarkA/y-8"}, {name: "BenchmarkB.C"}}
+	var buf bytes.Buffer
+	printProfileDiffs(&buf, violations, "old.prof", "new.prof")
+	want := `
+profile diff of BenchmarkA:
+pprof -top -nodecount=10 -focus=(^|\.)BenchmarkA(\.|$) -diff_base=old.prof new.prof
+
+profile diff of BenchmarkB.C:
+pprof -top -nodecount=10 -focus=(^|\.)BenchmarkB\.C(\.|$) -diff_base=old.prof new.prof
+`
+	if have := buf.String(); have != want {
+		t.Errorf("printProfileDiffs: want\n%s\nhave\n%s", want, have)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// pushTokenEnv is the environment variable holding the bearer token of
+// -push-url.
+const pushTokenEnv = "BENCHDIFF_PUSH_TOKEN"
+
+// pushTimeout bounds the request of -push-url.
+const pushTimeout = 30 * time.Second
+
+// A pushEncoder writes diffs, compared at t, in the format of an
+// endpoint of -push-url, and has the content type of that format.
+type pushEncoder struct {
+	contentType string
+	encode      func(w io.Writer, diffs []benchdiff.BenchDiff, t time.Time) error
+}
+
+// pushEncoders are the formats selectable with -push-format.
+var pushEncoders = map[string]pushEncoder{
+	"json": {"application/json", func(w io.Writer, diffs []benchdiff.BenchDiff, t time.Time) error {
+		data, err := marshalDiffs(diffs)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}},
+	"influx":     {"text/plain; charset=utf-8", writeInflux},
+	"prometheus": {"text/plain; version=0.0.4", writePrometheus},
+}
+
+// pushEncoderNames returns the sorted names of pushEncoders.
+func pushEncoderNames() []string {
+	var names []string
+	for name := range pushEncoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// pushPoint is a metric of a benchmark compared on both sides.
+type pushPoint struct {
+	name, metric  string
+	before, after float64
+	pct           float64 // NaN if infinite
+}
+
+// pushPoints returns the metrics of diffs measured on both sides, by
+// benchmark then in the order of the blocks.
+func pushPoints(diffs []benchdiff.BenchDiff) []pushPoint {
+	var pp []pushPoint
+	metrics := activeMetrics()
+	for _, diff := range diffs {
+		for _, m := range metrics {
+			if !m.measuredIn(diff) {
+				continue
+			}
+			d := m.delta(diff)
+			pct := d.Percent()
+			if math.IsInf(pct, 0) {
+				pct = math.NaN()
+			}
+			pp = append(pp, pushPoint{diff.Name(), m.name, d.Before, d.After, pct})
+		}
+	}
+	return pp
+}
+
+// influxEscaper escapes the tag values of the InfluxDB line protocol.
+var influxEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// writeInflux writes diffs in the InfluxDB line protocol: one
+// "benchdiff" point per benchmark and metric, tagged with both, whose
+// fields are the old and new values and the percent change, left out
+// for a change from zero, at t.
+func writeInflux(w io.Writer, diffs []benchdiff.BenchDiff, t time.Time) error {
+	for _, p := range pushPoints(diffs) {
+		fields := "old=" + formatFloat(p.before) + ",new=" + formatFloat(p.after)
+		if !math.IsNaN(p.pct) {
+			fields += ",delta_pct=" + formatFloat(p.pct)
+		}
+		if _, err := fmt.Fprintf(w, "benchdiff,benchmark=%s,metric=%s %s %d\n", influxEscaper.Replace(p.name), influxEscaper.Replace(p.metric), fields, t.UnixNano()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prometheusEscaper escapes the label values of the Prometheus text
+// format.
+var prometheusEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writePrometheus writes diffs in the Prometheus text format, as a
+// pushgateway takes it: the gauges benchdiff_old, benchdiff_new and
+// benchdiff_delta_pct labeled with the benchmark and the metric, the
+// changes from zero being left out of the last one.
+func writePrometheus(w io.Writer, diffs []benchdiff.BenchDiff, t time.Time) error {
+	pp := pushPoints(diffs)
+	var buf bytes.Buffer
+	for _, g := range []struct {
+		name, help string
+		value      func(pushPoint) float64
+	}{
+		{"benchdiff_old", "Old value of the metric of the benchmark.", func(p pushPoint) float64 { return p.before }},
+		{"benchdiff_new", "New value of the metric of the benchmark.", func(p pushPoint) float64 { return p.after }},
+		{"benchdiff_delta_pct", "Percent change of the metric of the benchmark.", func(p pushPoint) float64 { return p.pct }},
+	} {
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+		for _, p := range pp {
+			v := g.value(p)
+			if math.IsNaN(v) {
+				continue
+			}
+			fmt.Fprintf(&buf, "%s{benchmark=\"%s\",metric=\"%s\"} %s\n", g.name, prometheusEscaper.Replace(p.name), prometheusEscaper.Replace(p.metric), formatFloat(v))
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// formatFloat formats v with the fewest digits representing it.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// pushResults POSTs diffs encoded with the -push-format encoder enc to
+// url, with the bearer token of $BENCHDIFF_PUSH_TOKEN if set.
+func pushResults(url string, enc pushEncoder, diffs []benchdiff.BenchDiff) error {
+	var body bytes.Buffer
+	if err := enc.encode(&body, diffs, time.Now()); err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", enc.contentType)
+	if token := os.Getenv(pushTokenEnv); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	client := &http.Client{Timeout: pushTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("POST %s: %s", url, resp.Status)
+	}
+	logf("pushed the results to %s", url)
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// pushDiffs are a benchmark getting slower and one starting to
+// allocate.
+var pushDiffs = []benchdiff.BenchDiff{
+	nsDiff("BenchmarkA/k=v 1", 0, 100, 150),
+	{
+		Before: &parse.Benchmark{Name: "BenchmarkB", AllocsPerOp: 0, Measured: parse.AllocsPerOp},
+		After:  &parse.Benchmark{Name: "BenchmarkB", AllocsPerOp: 2, Measured: parse.AllocsPerOp},
+	},
+}
+
+func TestWriteInflux(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeInflux(&buf, pushDiffs, time.Unix(1, 5)); err != nil {
+		t.Fatal(err)
+	}
+	want := `benchdiff,benchmark=BenchmarkA/k\=v\ 1,metric=ns old=100,new=150,delta_pct=50 1000000005
+benchdiff,benchmark=BenchmarkB,metric=allocs old=0,new=2 1000000005
+`
+	if have := buf.String(); have != want {
+		t.Errorf("writeInflux: want\n%s\nhave\n%s", want, have)
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writePrometheus(&buf, pushDiffs, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP benchdiff_old Old value of the metric of the benchmark.
+# TYPE benchdiff_old gauge
+benchdiff_old{benchmark="BenchmarkA/k=v 1",metric="ns"} 100
+benchdiff_old{benchmark="BenchmarkB",metric="allocs"} 0
+# HELP benchdiff_new New value of the metric of the benchmark.
+# TYPE benchdiff_new gauge
+benchdiff_new{benchmark="BenchmarkA/k=v 1",metric="ns"} 150
+benchdiff_new{benchmark="BenchmarkB",metric="allocs"} 2
+# HELP benchdiff_delta_pct Percent change of the metric of the benchmark.
+# TYPE benchdiff_delta_pct gauge
+benchdiff_delta_pct{benchmark="BenchmarkA/k=v 1",metric="ns"} 50
+`
+	if have := buf.String(); have != want {
+		t.Errorf("writePrometheus: want\n%s\nhave\n%s", want, have)
+	}
+}
+
+func TestPushResults(t *testing.T) {
+	var body []byte
+	var auth, contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Errorf("push: want POST have %s", r.Method)
+		}
+		auth, contentType = r.Header.Get("Authorization"), r.Header.Get("Content-Type")
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+	defer os.Setenv(pushTokenEnv, os.Getenv(pushTokenEnv))
+	os.Setenv(pushTokenEnv, "secret")
+
+	if err := pushResults(srv.URL, pushEncoders["influx"], pushDiffs); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("push: want the bearer token, have Authorization %q", auth)
+	}
+	if contentType != pushEncoders["influx"].contentType {
+		t.Errorf("push: want Content-Type %q have %q", pushEncoders["influx"].contentType, contentType)
+	}
+	if !bytes.HasPrefix(body, []byte("benchdiff,benchmark=")) {
+		t.Errorf("push: want line protocol, have\n%s", body)
+	}
+
+	fail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no", http.StatusUnauthorized)
+	}))
+	defer fail.Close()
+	if err := pushResults(fail.URL, pushEncoders["json"], pushDiffs); err == nil {
+		t.Error("push to a failing endpoint: want an error")
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// fetchTimeout bounds the download of an input URL.
+const fetchTimeout = 60 * time.Second
+
+// fetchTokenEnv is the environment variable holding the bearer token
+// sent with the requests of input https URLs.
+const fetchTokenEnv = "BENCHDIFF_FETCH_TOKEN"
+
+// maxFetchSize bounds the size of a downloaded input and of a file read
+// from an archive.
+var maxFetchSize int64 = 1 << 30
+
+// fetchTransport is the transport of the requests of input URLs, the
+// default one if nil.
+var fetchTransport http.RoundTripper
+
+// memberSep separates an archive from the path of the file to compare
+// in it, as in artifacts.zip#bench/old.txt.
+const memberSep = "#"
+
+// archiveExts are the suffixes of the archives inputs can be read from.
+var archiveExts = []string{".zip", ".tar.gz", ".tgz", ".tar"}
+
+// isURL reports whether the input path is an HTTP(S) URL.
+func isURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// isArchive reports whether name is that of an archive.
+func isArchive(name string) bool {
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitArchive splits the input path into the archive, a file or a URL,
+// and the path of the file to compare in it. The member is empty if
+// path does not name a file in an archive, or names an existing file.
+func splitArchive(p string) (archive, member string) {
+	if !isURL(p) {
+		if _, err := os.Stat(p); err == nil {
+			return p, ""
+		}
+	}
+	end := -1
+	for _, ext := range archiveExts {
+		if i := strings.Index(p, ext+memberSep); i >= 0 && (end < 0 || i+len(ext) < end) {
+			end = i + len(ext)
+		}
+	}
+	if end < 0 {
+		return p, ""
+	}
+	return p[:end], p[end+len(memberSep):]
+}
+
+// readSource returns the content of the input file or URL at path, or
+// of the file it names in an archive.
+func readSource(p string) ([]byte, error) {
+	archive, member := splitArchive(p)
+	var data []byte
+	var err error
+	if isURL(archive) {
+		data, err = fetchURL(archive)
+	} else {
+		data, err = ioutil.ReadFile(archive)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if member == "" {
+		if isArchive(archive) {
+			return nil, fmt.Errorf("%s is an archive, name the file to compare in it as %s%spath", archive, archive, memberSep)
+		}
+		return data, nil
+	}
+	return extractMember(archive, member, data)
+}
+
+// fetchURL downloads url, of at most maxFetchSize bytes, with the
+// bearer token of $BENCHDIFF_FETCH_TOKEN if set and url and the
+// redirects it leads to are https.
+func fetchURL(url string) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := os.Getenv(fetchTokenEnv); token != "" && req.URL.Scheme == "https" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	client := &http.Client{Transport: fetchTransport, Timeout: fetchTimeout, CheckRedirect: dropPlainAuth}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	data, err := readLimited(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %v", url, err)
+	}
+	logf("fetched %s", url)
+	return data, nil
+}
+
+// readLimited reads r to the end, failing if it holds more than
+// maxFetchSize bytes.
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, maxFetchSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxFetchSize {
+		return nil, fmt.Errorf("larger than %d bytes", maxFetchSize)
+	}
+	return data, nil
+}
+
+// dropPlainAuth is the redirect policy of fetchURL: the default one,
+// not sending the bearer token over plain http.
+func dropPlainAuth(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return fmt.Errorf("stopped after %d redirects", len(via))
+	}
+	if req.URL.Scheme != "https" {
+		req.Header.Del("Authorization")
+	}
+	return nil
+}
+
+// extractMember returns the content of the file member of the zip or
+// tar archive named archive, whose content is data.
+func extractMember(archive, member string, data []byte) ([]byte, error) {
+	member = path.Clean(member)
+	var content []byte
+	var err error
+	if strings.HasSuffix(archive, ".zip") {
+		content, err = zipMember(data, member)
+	} else {
+		var r io.Reader = bytes.NewReader(data)
+		if !strings.HasSuffix(archive, ".tar") {
+			zr, zerr := gzip.NewReader(r)
+			if zerr != nil {
+				return nil, fmt.Errorf("%s: gzip: %v", archive, zerr)
+			}
+			defer zr.Close()
+			r = zr
+		}
+		content, err = tarMember(r, member)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", archive, err)
+	}
+	if content == nil {
+		return nil, fmt.Errorf("%s: no file %s in the archive", archive, member)
+	}
+	return content, nil
+}
+
+// zipMember returns the content of the file member of the zip archive
+// data, nil if there is none. It fails if the member holds more than
+// maxFetchSize bytes once decompressed.
+func zipMember(data []byte, member string) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range zr.File {
+		if path.Clean(f.Name) != member || f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return readLimited(rc)
+	}
+	return nil, nil
+}
+
+// tarMember returns the content of the file member of the tar stream
+// r, nil if there is none. It fails if the member holds more than
+// maxFetchSize bytes.
+func tarMember(r io.Reader, member string) ([]byte, error) {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.FileInfo().Mode().IsRegular() && path.Clean(hdr.Name) == member {
+			return readLimited(tr)
+		}
+	}
+}
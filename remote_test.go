@@ -0,0 +1,134 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const remoteResult = "BenchmarkA\t10\t100 ns/op\n"
+
+func TestSplitArchive(t *testing.T) {
+	for _, tt := range []struct {
+		path, archive, member string
+	}{
+		{"old.txt", "old.txt", ""},
+		{"artifacts.zip#bench/old.txt", "artifacts.zip", "bench/old.txt"},
+		{"out.tar.gz#old.txt.gz", "out.tar.gz", "old.txt.gz"},
+		{"https://ci/a.tgz#x#y.txt", "https://ci/a.tgz", "x#y.txt"},
+		{"notes#old.txt", "notes#old.txt", ""},
+	} {
+		archive, member := splitArchive(tt.path)
+		if archive != tt.archive || member != tt.member {
+			t.Errorf("splitArchive(%q): want %q, %q have %q, %q", tt.path, tt.archive, tt.member, archive, member)
+		}
+	}
+}
+
+func TestReadArchives(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchdiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var zbuf bytes.Buffer
+	zw := zip.NewWriter(&zbuf)
+	f, _ := zw.Create("bench/old.txt")
+	f.Write([]byte(remoteResult))
+	zw.Close()
+	var tbuf bytes.Buffer
+	gw := gzip.NewWriter(&tbuf)
+	tw := tar.NewWriter(gw)
+	tw.WriteHeader(&tar.Header{Name: "./new.txt", Mode: 0644, Size: int64(len(remoteResult)), Typeflag: tar.TypeReg})
+	tw.Write([]byte(remoteResult))
+	tw.Close()
+	gw.Close()
+	zipPath, tarPath := filepath.Join(dir, "a.zip"), filepath.Join(dir, "a.tar.gz")
+	ioutil.WriteFile(zipPath, zbuf.Bytes(), 0644)
+	ioutil.WriteFile(tarPath, tbuf.Bytes(), 0644)
+
+	for _, path := range []string{zipPath + "#bench/old.txt", zipPath + "#./bench/old.txt", tarPath + "#new.txt"} {
+		data, err := readInput(path, nil)
+		if err != nil {
+			t.Errorf("readInput(%s): %v", path, err)
+		} else if string(data) != remoteResult {
+			t.Errorf("readInput(%s): want %q have %q", path, remoteResult, data)
+		}
+	}
+	if _, err := readInput(zipPath+"#new.txt", nil); err == nil || !strings.Contains(err.Error(), "no file new.txt") {
+		t.Errorf("readInput of a missing member: want an error have %v", err)
+	}
+	if _, err := readInput(tarPath, nil); err == nil || !strings.Contains(err.Error(), "is an archive") {
+		t.Errorf("readInput of a whole archive: want an error have %v", err)
+	}
+	defer func(n int64) { maxFetchSize = n }(maxFetchSize)
+	maxFetchSize = int64(len(remoteResult)) - 1
+	for _, path := range []string{zipPath + "#bench/old.txt", tarPath + "#new.txt"} {
+		if _, err := readInput(path, nil); err == nil || !strings.Contains(err.Error(), "larger than") {
+			t.Errorf("readInput(%s) of a member above maxFetchSize: want an error have %v", path, err)
+		}
+	}
+
+	if have := labelFromPath(zipPath + "#bench/old.txt"); have != "old" {
+		t.Errorf("labelFromPath: want old have %q", have)
+	}
+	if have := formatFor("a.zip#old.txt.gz", inputAuto); have != inputGzip {
+		t.Errorf("formatFor a gzip member: want gzip have %q", have)
+	}
+}
+
+func TestFetchURL(t *testing.T) {
+	defer os.Setenv(fetchTokenEnv, os.Getenv(fetchTokenEnv))
+	os.Setenv(fetchTokenEnv, "secret")
+	var auth string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if r.URL.Path != "/old.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(remoteResult))
+	})
+	srv := httptest.NewTLSServer(handler)
+	defer srv.Close()
+	defer func(t http.RoundTripper) { fetchTransport = t }(fetchTransport)
+	fetchTransport = srv.Client().Transport
+
+	data, err := readInput(srv.URL+"/old.txt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != remoteResult {
+		t.Errorf("readInput of a URL: want %q have %q", remoteResult, data)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("Authorization: want the bearer token have %q", auth)
+	}
+	if _, err := readInput(srv.URL+"/missing.txt", nil); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("readInput of a missing URL: want a 404 error have %v", err)
+	}
+
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	if _, err := readInput(plain.URL+"/old.txt", nil); err != nil || auth != "" {
+		t.Errorf("readInput of an http URL: want no token have %q (%v)", auth, err)
+	}
+	defer func(n int64) { maxFetchSize = n }(maxFetchSize)
+	maxFetchSize = int64(len(remoteResult)) - 1
+	if _, err := readInput(srv.URL+"/old.txt", nil); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("readInput of a URL above maxFetchSize: want an error have %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// renames is the flag.Value of -rename, which may be repeated: it maps
+// old benchmark names to new ones.
+type renames map[string]string
+
+// renameFlag holds the renames given by -rename.
+var renameFlag = make(renames)
+
+func (r renames) String() string {
+	var pairs []string
+	for from, to := range r {
+		pairs = append(pairs, from+"="+to)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (r renames) Set(s string) error {
+	eq := strings.Index(s, "=")
+	if eq <= 0 || eq == len(s)-1 {
+		return fmt.Errorf("%q is not old=new", s)
+	}
+	r[s[:eq]] = s[eq+1:]
+	return nil
+}
+
+// renamedName returns the name to which rename renames name, and
+// whether it does.
+func renamedName(name, from, to string) (string, bool) {
+	for _, n := range nameForms(name) {
+		pkg := name[:len(name)-len(n)] // the package and dot, if n is unqualified
+		if n == from {
+			return pkg + to, true
+		}
+		if base, suffix := splitProcs(n); base == from {
+			return pkg + to + suffix, true
+		}
+	}
+	return "", false
+}
+
+// rename renames in bs the benchmarks named from, or from followed by a
+// -GOMAXPROCS suffix, to the name to with the same suffix. A benchmark
+// qualified by its package also matches from without it, and keeps its
+// package. It reports about the names already used in bs, which are
+// left alone.
+func rename(bs parse.Set, from, to string) (warnings []string) {
+	var names []string
+	for name := range bs {
+		if _, ok := renamedName(name, from, to); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		newName, _ := renamedName(name, from, to)
+		if _, ok := bs[newName]; ok {
+			warnings = append(warnings, fmt.Sprintf("not renaming %s: %s is already in the old results", name, newName))
+			continue
+		}
+		for _, b := range bs[name] {
+			b.Name = newName
+		}
+		bs[newName] = bs[name]
+		delete(bs, name)
+	}
+	return warnings
+}
+
+// applyRenames renames the old benchmarks of before as given by
+// -rename, returning the warnings about the names already in use.
+func applyRenames(before parse.Set) []string {
+	var froms []string
+	for from := range renameFlag {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms)
+	var warnings []string
+	for _, from := range froms {
+		warnings = append(warnings, rename(before, from, renameFlag[from])...)
+	}
+	return warnings
+}
+
+// levenshtein returns the edit distance between a and b, in bytes.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if d := prev[j] + 1; d < cur[j] {
+				cur[j] = d
+			}
+			if d := cur[j-1] + 1; d < cur[j] {
+				cur[j] = d
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// similarity returns how similar the names a and b are, from 0 for
+// nothing in common to 1 for equal names: one minus their edit
+// distance over the length of the longest.
+func similarity(a, b string) float64 {
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+	if n == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(a, b))/float64(n)
+}
+
+// A fuzzyPair is an old benchmark name matched with a new one by
+// -fuzzy.
+type fuzzyPair struct {
+	from, to string
+	score    float64
+}
+
+// fuzzyMatch pairs the benchmarks of before missing from after with the
+// benchmarks of after missing from before, by correlation key, whose
+// name is the most similar, at min at least. The pairs of highest similarity are made
+// first and every name is paired once at most.
+func fuzzyMatch(before, after parse.Set, min float64) []fuzzyPair {
+	keys := func(bs parse.Set) map[string]bool {
+		m := make(map[string]bool, len(bs))
+		for name := range bs {
+			m[correlateKey(&parse.Benchmark{Name: name})] = true
+		}
+		return m
+	}
+	beforeKeys, afterKeys := keys(before), keys(after)
+	var pairs []fuzzyPair
+	for from := range before {
+		if afterKeys[correlateKey(&parse.Benchmark{Name: from})] {
+			continue
+		}
+		for to := range after {
+			if beforeKeys[correlateKey(&parse.Benchmark{Name: to})] {
+				continue
+			}
+			if s := similarity(from, to); s >= min {
+				pairs = append(pairs, fuzzyPair{from, to, s})
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		pi, pj := pairs[i], pairs[j]
+		if pi.score != pj.score {
+			return pi.score > pj.score
+		}
+		if pi.from != pj.from {
+			return pi.from < pj.from
+		}
+		return pi.to < pj.to
+	})
+	var matched []fuzzyPair
+	used := make(map[string]bool)
+	for _, p := range pairs {
+		if used["old "+p.from] || used["new "+p.to] {
+			continue
+		}
+		used["old "+p.from], used["new "+p.to] = true, true
+		matched = append(matched, p)
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].from < matched[j].from })
+	return matched
+}
+
+// applyFuzzy renames the old benchmarks of before matched by fuzzyMatch
+// to their new names, returning a message about each of them.
+func applyFuzzy(before, after parse.Set, min float64) []string {
+	var msgs []string
+	for _, p := range fuzzyMatch(before, after, min) {
+		for _, b := range before[p.from] {
+			b.Name = p.to
+		}
+		before[p.to] = before[p.from]
+		delete(before, p.from)
+		msgs = append(msgs, fmt.Sprintf("benchdiff: comparing %s with %s (similarity %.2f)", p.from, p.to, p.score))
+	}
+	return msgs
+}
@@ -0,0 +1,105 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func setNames(bs parse.Set) []string {
+	var names []string
+	for name, bb := range bs {
+		for _, b := range bb {
+			if b.Name != name {
+				names = append(names, "mismatch:"+name)
+			}
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRename(t *testing.T) {
+	r := make(renames)
+	for _, s := range []string{"BenchmarkOld=BenchmarkNew", "BenchmarkX/a=BenchmarkX/b"} {
+		if err := r.Set(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, bad := range []string{"BenchmarkOld", "=BenchmarkNew", "BenchmarkOld="} {
+		if err := r.Set(bad); err == nil {
+			t.Errorf("Set(%q): no error", bad)
+		}
+	}
+	if want := "BenchmarkOld=BenchmarkNew,BenchmarkX/a=BenchmarkX/b"; r.String() != want {
+		t.Errorf("String: want %q have %q", want, r.String())
+	}
+
+	bs := parse.Set{}
+	for _, name := range []string{"BenchmarkOld-4", "BenchmarkOld-8", "BenchmarkX/a", "BenchmarkX/b", "BenchmarkKeep"} {
+		bs[name] = []*parse.Benchmark{{Name: name}}
+	}
+	var warnings []string
+	for _, from := range []string{"BenchmarkOld", "BenchmarkX/a"} {
+		warnings = append(warnings, rename(bs, from, r[from])...)
+	}
+	want := []string{"BenchmarkKeep", "BenchmarkNew-4", "BenchmarkNew-8", "BenchmarkX/a", "BenchmarkX/b"}
+	if have := setNames(bs); !reflect.DeepEqual(want, have) {
+		t.Errorf("rename: want %v have %v", want, have)
+	}
+	if want := []string{"not renaming BenchmarkX/a: BenchmarkX/b is already in the old results"}; !reflect.DeepEqual(want, warnings) {
+		t.Errorf("rename warnings: want %v have %v", want, warnings)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want float64
+	}{
+		{"", "", 1},
+		{"abc", "abc", 1},
+		{"abc", "", 0},
+		{"kitten", "sitting", 1 - 3.0/7},
+		{"BenchmarkParse", "BenchmarkParser", 1 - 1.0/15},
+	} {
+		if have := similarity(tt.a, tt.b); have != tt.want {
+			t.Errorf("similarity(%q, %q) = %v, want %v", tt.a, tt.b, have, tt.want)
+		}
+	}
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	set := func(names ...string) parse.Set {
+		bs := parse.Set{}
+		for _, name := range names {
+			bs[name] = []*parse.Benchmark{{Name: name}}
+		}
+		return bs
+	}
+	before := set("BenchmarkParse", "BenchmarkParseJSON", "BenchmarkSame", "BenchmarkGone")
+	after := set("BenchmarkParser", "BenchmarkParserJSON", "BenchmarkSame", "BenchmarkUnrelatedNew")
+	have := fuzzyMatch(before, after, 0.8)
+	want := []fuzzyPair{
+		{"BenchmarkParse", "BenchmarkParser", 1 - 1.0/15},
+		{"BenchmarkParseJSON", "BenchmarkParserJSON", similarity("BenchmarkParseJSON", "BenchmarkParserJSON")},
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("fuzzyMatch: want %v have %v", want, have)
+	}
+
+	msgs := applyFuzzy(before, after, 0.8)
+	if len(msgs) != 2 {
+		t.Errorf("applyFuzzy: want 2 messages, have %v", msgs)
+	}
+	if want := []string{"BenchmarkGone", "BenchmarkParser", "BenchmarkParserJSON", "BenchmarkSame"}; !reflect.DeepEqual(want, setNames(before)) {
+		t.Errorf("applyFuzzy: want %v have %v", want, setNames(before))
+	}
+}
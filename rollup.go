@@ -0,0 +1,117 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// rollupMark is appended to the names of rolled up benchmarks.
+const rollupMark = " (rollup)"
+
+// rollupMismatch correlates a benchmark present on one side with its
+// sub-benchmarks on the other side, as when BenchmarkX got split into
+// BenchmarkX/a and BenchmarkX/b. The sub-benchmarks are aggregated into
+// a single benchmark (mean ns/op and MB/s, summed allocs/op and B/op)
+// and both sides are renamed with rollupMark. Sub-benchmarks must have
+// as many results as the benchmark they are compared with.
+// rollupMismatch returns the names of the rolled up benchmarks.
+func rollupMismatch(before, after parse.Set) []string {
+	rolled := append(rollupInto(before, after), rollupInto(after, before)...)
+	sort.Strings(rolled)
+	return rolled
+}
+
+// rollupInto rolls up the sub-benchmarks of split matching the
+// benchmarks of flat missing from split.
+func rollupInto(flat, split parse.Set) []string {
+	var names []string
+	for name := range flat {
+		if len(split[name]) == 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var rolled []string
+	for _, name := range names {
+		subs := subBenchmarks(split, name)
+		if len(subs) == 0 {
+			continue
+		}
+		agg, ok := aggregateSubs(split, subs, len(flat[name]), name+rollupMark)
+		if !ok {
+			continue
+		}
+		for _, sub := range subs {
+			delete(split, sub)
+		}
+		split[name+rollupMark] = agg
+		flat[name+rollupMark] = renamed(flat[name], name+rollupMark)
+		delete(flat, name)
+		rolled = append(rolled, name)
+	}
+	return rolled
+}
+
+// subBenchmarks returns the sorted names of the sub-benchmarks of name
+// in bs having the same -GOMAXPROCS suffix.
+func subBenchmarks(bs parse.Set, name string) []string {
+	base, procs := splitProcs(name)
+	var subs []string
+	for n := range bs {
+		if nbase, nprocs := splitProcs(n); nprocs == procs && strings.HasPrefix(nbase, base+"/") {
+			subs = append(subs, n)
+		}
+	}
+	sort.Strings(subs)
+	return subs
+}
+
+// aggregateSubs returns count benchmarks named name, the i-th one
+// aggregating the i-th results of the subs of bs. It reports false if
+// a sub-benchmark does not have count results.
+func aggregateSubs(bs parse.Set, subs []string, count int, name string) ([]*parse.Benchmark, bool) {
+	for _, sub := range subs {
+		if len(bs[sub]) != count {
+			return nil, false
+		}
+	}
+	agg := make([]*parse.Benchmark, count)
+	for i := range agg {
+		b := &parse.Benchmark{Name: name, Measured: -1, Ord: -1}
+		for _, sub := range subs {
+			s := bs[sub][i]
+			if b.Ord < 0 || s.Ord < b.Ord {
+				b.Ord = s.Ord
+			}
+			if b.N == 0 || s.N < b.N {
+				b.N = s.N
+			}
+			b.Measured &= s.Measured
+			b.NsPerOp += s.NsPerOp / float64(len(subs))
+			b.MBPerS += s.MBPerS / float64(len(subs))
+			b.AllocsPerOp += s.AllocsPerOp
+			b.AllocedBytesPerOp += s.AllocedBytesPerOp
+		}
+		agg[i] = b
+	}
+	return agg, true
+}
+
+// renamed returns copies of bb named name.
+func renamed(bb []*parse.Benchmark, name string) []*parse.Benchmark {
+	cp := make([]*parse.Benchmark, len(bb))
+	for i, b := range bb {
+		c := *b
+		c.Name = name
+		copyCustom(&c, b)
+		cp[i] = &c
+	}
+	return cp
+}
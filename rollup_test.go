@@ -0,0 +1,46 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestRollupMismatch(t *testing.T) {
+	all := parse.NsPerOp | parse.AllocsPerOp | parse.AllocedBytesPerOp
+	before := parse.Set{
+		"BenchmarkX-8": []*parse.Benchmark{{Name: "BenchmarkX-8", N: 10, NsPerOp: 100, AllocsPerOp: 1, AllocedBytesPerOp: 10, Measured: all, Ord: 0}},
+		"BenchmarkY-8": []*parse.Benchmark{{Name: "BenchmarkY-8", N: 10, NsPerOp: 5, Measured: parse.NsPerOp, Ord: 1}},
+		"BenchmarkZ-8": []*parse.Benchmark{{Name: "BenchmarkZ-8", N: 10, NsPerOp: 5, Measured: parse.NsPerOp, Ord: 2}},
+	}
+	after := parse.Set{
+		"BenchmarkX/a-8": []*parse.Benchmark{{Name: "BenchmarkX/a-8", N: 10, NsPerOp: 80, AllocsPerOp: 1, AllocedBytesPerOp: 8, Measured: all, Ord: 1}},
+		"BenchmarkX/b-8": []*parse.Benchmark{{Name: "BenchmarkX/b-8", N: 20, NsPerOp: 140, AllocsPerOp: 1, AllocedBytesPerOp: 8, Measured: all, Ord: 0}},
+		"BenchmarkY-8":   []*parse.Benchmark{{Name: "BenchmarkY-8", N: 10, NsPerOp: 5, Measured: parse.NsPerOp, Ord: 2}},
+		// Different GOMAXPROCS: not a sub-benchmark of BenchmarkZ-8.
+		"BenchmarkZ/a-4": []*parse.Benchmark{{Name: "BenchmarkZ/a-4", N: 10, NsPerOp: 5, Measured: parse.NsPerOp, Ord: 3}},
+	}
+
+	if want, have := []string{"BenchmarkX-8"}, rollupMismatch(before, after); !reflect.DeepEqual(want, have) {
+		t.Fatalf("rollupMismatch: want %v have %v", want, have)
+	}
+
+	name := "BenchmarkX-8" + rollupMark
+	wantBefore := []*parse.Benchmark{{Name: name, N: 10, NsPerOp: 100, AllocsPerOp: 1, AllocedBytesPerOp: 10, Measured: all, Ord: 0}}
+	wantAfter := []*parse.Benchmark{{Name: name, N: 10, NsPerOp: 110, AllocsPerOp: 2, AllocedBytesPerOp: 16, Measured: all, Ord: 0}}
+	if !reflect.DeepEqual(wantBefore, before[name]) || !reflect.DeepEqual(wantAfter, after[name]) {
+		t.Errorf("rollupMismatch: want %v, %v have %v, %v", wantBefore, wantAfter, before[name], after[name])
+	}
+	for _, gone := range []string{"BenchmarkX-8", "BenchmarkX/a-8", "BenchmarkX/b-8"} {
+		if _, ok := before[gone]; ok {
+			t.Errorf("rollupMismatch: %s still in before", gone)
+		}
+		if _, ok := after[gone]; ok {
+			t.Errorf("rollupMismatch: %s still in after", gone)
+		}
+	}
+	if _, ok := after["BenchmarkZ/a-4"]; !ok {
+		t.Error("rollupMismatch: BenchmarkZ/a-4 must not be rolled up")
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// runBench runs the benchmark command line in dir and returns its
+// standard output. The command line is split on white space; it is
+// not interpreted by a shell.
+func runBench(dir, cmdline string) ([]byte, error) {
+	args := strings.Fields(cmdline)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty benchmark command")
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", cmdline, err)
+	}
+	return out, nil
+}
+
+// git runs a git command in dir and returns its trimmed output.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// withWorktree checks out ref in a temporary git worktree and calls fn
+// with the directory matching the current one inside that worktree.
+// The working tree of the current checkout, including uncommitted
+// changes, is never modified. The worktree is removed when fn returns
+// or the process is interrupted.
+func withWorktree(ref string, fn func(dir string) error) error {
+	prefix, err := git(".", "rev-parse", "--show-prefix")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir("", "benchdiff-")
+	if err != nil {
+		return err
+	}
+	if _, err := git(".", "worktree", "add", "--detach", tmp, ref); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	cleanup := func() {
+		git(".", "worktree", "remove", "--force", tmp)
+		os.RemoveAll(tmp)
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-interrupted:
+			cleanup()
+			os.Exit(1)
+		case <-done:
+		}
+	}()
+	defer func() {
+		signal.Stop(interrupted)
+		close(done)
+		cleanup()
+	}()
+
+	return fn(filepath.Join(tmp, prefix))
+}
+
+// benchSince runs cmdline on ref and on until, or on the current
+// working tree if until is empty, returning the old and the new output.
+func benchSince(ref, until, cmdline string) (before, after []byte, err error) {
+	before, err = benchRef(ref, cmdline)
+	if err != nil {
+		return nil, nil, err
+	}
+	if until != "" {
+		after, err = benchRef(until, cmdline)
+	} else {
+		after, err = runBench(".", cmdline)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return before, after, nil
+}
+
+// benchRef runs cmdline on ref checked out in a temporary worktree and
+// returns its output.
+func benchRef(ref, cmdline string) (out []byte, err error) {
+	err = withWorktree(ref, func(dir string) error {
+		out, err = runBench(dir, cmdline)
+		return err
+	})
+	return out, err
+}
+
+// confirmRegression runs -benchcmd n times in the current directory and
+// reports whether the comparison of before, the unprocessed old results,
+// with the results of a majority of the runs fails -errdelta or
+// -exitcode, for the benchmarks -filter shows. The warnings of the
+// comparisons of the runs, already printed for the first one, are not.
+func confirmRegression(before parse.Set, oldHeader benchHeader, n int) bool {
+	// Keep for -strict the warnings of the comparison being confirmed.
+	defer func(w []benchdiff.Warning) { correlateWarnings = w }(correlateWarnings)
+	state := saveParseState()
+	defer state.restore()
+	failures := 0
+	for i := 1; i <= n; i++ {
+		state.restore()
+		out, err := runBench(".", *benchCmd)
+		if err != nil {
+			fatal(err)
+		}
+		after, newHeader := parseData("new", fmt.Sprintf("rerun %d", i), out)
+		if sectionPackages != nil {
+			after = qualifySet(after)
+		}
+		muted = true
+		diffs, _, _ := prepare(deepCopySet(before), after, oldHeader, newHeader)
+		muted = false
+		if nameFilter != nil {
+			diffs = filterDiffs(diffs, nameFilter)
+		}
+		if msg := gateFailure(collectViolations(diffs)); msg != "" {
+			failures++
+			logf("rerun %d: %s", i, msg)
+		} else if *exitCode && regressionCount(diffs) > 0 {
+			failures++
+			logf("rerun %d: %d regressions beyond tolerance", i, regressionCount(diffs))
+		}
+	}
+	fmt.Fprintf(os.Stderr, "benchdiff: %d of %d reruns failed\n", failures, n)
+	return failures > n/2
+}
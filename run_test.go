@@ -0,0 +1,187 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestRunBench(t *testing.T) {
+	if _, err := exec.LookPath("echo"); err != nil {
+		t.Skip("echo not available")
+	}
+	out, err := runBench(".", "echo BenchmarkA 10 100 ns/op")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, have := "BenchmarkA 10 100 ns/op", strings.TrimSpace(string(out)); want != have {
+		t.Errorf("runBench: want %q have %q", want, have)
+	}
+	if _, err := runBench(".", "  "); err == nil {
+		t.Error("runBench: expected an error for an empty command")
+	}
+}
+
+func TestConfirmRegression(t *testing.T) {
+	if _, err := exec.LookPath("echo"); err != nil {
+		t.Skip("echo not available")
+	}
+	defer func(f bool, tol float64, cmd string, in []inputRecord) {
+		*failOnDelta, *tNsPerOp, *benchCmd, inputs = f, tol, cmd, in
+	}(*failOnDelta, *tNsPerOp, *benchCmd, inputs)
+	*failOnDelta, *tNsPerOp = true, 10
+
+	before := parse.Set{"BenchmarkA": nsRuns("BenchmarkA", 100)}
+	*benchCmd = "echo BenchmarkA 10 200 ns/op"
+	n := len(inputs)
+	if !confirmRegression(before, nil, 3) {
+		t.Error("confirmRegression: want a regression confirmed by slower reruns")
+	}
+	if len(inputs) != n {
+		t.Errorf("confirmRegression: want the %d inputs kept, have %d", n, len(inputs))
+	}
+	*benchCmd = "echo BenchmarkA 10 101 ns/op"
+	if confirmRegression(before, nil, 3) {
+		t.Error("confirmRegression: want no regression confirmed by reruns within tolerance")
+	}
+	if before["BenchmarkA"][0].NsPerOp != 100 {
+		t.Error("confirmRegression modified the old results")
+	}
+
+	defer func(v bool) { *exitCode = v }(*exitCode)
+	*failOnDelta, *exitCode = false, true
+	*benchCmd = "echo BenchmarkA 10 200 ns/op"
+	if !confirmRegression(before, nil, 3) {
+		t.Error("confirmRegression with -exitcode: want a regression confirmed by slower reruns")
+	}
+	*benchCmd = "echo BenchmarkA 10 101 ns/op"
+	if confirmRegression(before, nil, 3) {
+		t.Error("confirmRegression with -exitcode: want no regression confirmed by reruns within tolerance")
+	}
+}
+
+func TestConfirmRegressionFilter(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat not available")
+	}
+	defer func(f bool, tol float64, cmd string, re *regexp.Regexp) {
+		*failOnDelta, *tNsPerOp, *benchCmd, nameFilter = f, tol, cmd, re
+	}(*failOnDelta, *tNsPerOp, *benchCmd, nameFilter)
+	*failOnDelta, *tNsPerOp = true, 10
+	f, err := ioutil.TempFile("", "benchdiff-rerun-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("BenchmarkA 10 100 ns/op\nBenchmarkB 10 200 ns/op\n")
+	f.Close()
+
+	// Only BenchmarkB, left out by -filter, regresses on the reruns.
+	before := parse.Set{"BenchmarkA": nsRuns("BenchmarkA", 100), "BenchmarkB": nsRuns("BenchmarkB", 100)}
+	*benchCmd = "cat " + f.Name()
+	nameFilter = nil
+	if !confirmRegression(before, nil, 1) {
+		t.Error("confirmRegression: want the regression of BenchmarkB confirmed")
+	}
+	nameFilter = regexp.MustCompile("A")
+	if confirmRegression(before, nil, 1) {
+		t.Error("confirmRegression with -filter=A: want the regression of BenchmarkB ignored")
+	}
+}
+
+func TestConfirmRegressionMuted(t *testing.T) {
+	if _, err := exec.LookPath("echo"); err != nil {
+		t.Skip("echo not available")
+	}
+	defer func(f bool, tol float64, cmd string) {
+		*failOnDelta, *tNsPerOp, *benchCmd = f, tol, cmd
+	}(*failOnDelta, *tNsPerOp, *benchCmd)
+	*failOnDelta, *tNsPerOp = true, 10
+	stderr, err := ioutil.TempFile("", "benchdiff-stderr-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(stderr.Name())
+	defer func(f *os.File) { os.Stderr = f }(os.Stderr)
+	os.Stderr = stderr
+
+	// BenchmarkGone, missing from the reruns, is warned about once, by
+	// the comparison being confirmed.
+	before := parse.Set{"BenchmarkA": nsRuns("BenchmarkA", 100), "BenchmarkGone": nsRuns("BenchmarkGone", 100)}
+	*benchCmd = "echo BenchmarkA 10 200 ns/op"
+	confirmRegression(before, nil, 2)
+	stderr.Close()
+	data, _ := ioutil.ReadFile(stderr.Name())
+	if strings.Contains(string(data), "BenchmarkGone") || !strings.Contains(string(data), "2 of 2 reruns failed") {
+		t.Errorf("confirmRegression: want only the rerun count on stderr, have\n%s", data)
+	}
+	if muted {
+		t.Error("confirmRegression: want the warnings unmuted after the reruns")
+	}
+}
+
+func TestBenchSinceUntil(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat not available")
+	}
+	dir, err := ioutil.TempDir("", "benchdiff-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	commit := func(content, tag string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, "bench.txt"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{
+			{"add", "bench.txt"},
+			{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", tag},
+			{"tag", tag},
+		} {
+			if _, err := git(dir, args...); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if _, err := git(dir, "init", "-q"); err != nil {
+		t.Fatal(err)
+	}
+	commit("BenchmarkA 10 100 ns/op\n", "v1")
+	commit("BenchmarkA 10 80 ns/op\n", "v2")
+	if err := ioutil.WriteFile(filepath.Join(dir, "bench.txt"), []byte("BenchmarkA 10 60 ns/op\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		until, before, after string
+	}{
+		{"", "BenchmarkA 10 100 ns/op", "BenchmarkA 10 60 ns/op"}, // uncommitted working tree
+		{"v2", "BenchmarkA 10 100 ns/op", "BenchmarkA 10 80 ns/op"},
+	}
+	for _, tt := range cases {
+		before, after, err := benchSince("v1", tt.until, "cat bench.txt")
+		if err != nil {
+			t.Fatalf("benchSince(v1, %q): %v", tt.until, err)
+		}
+		if strings.TrimSpace(string(before)) != tt.before || strings.TrimSpace(string(after)) != tt.after {
+			t.Errorf("benchSince(v1, %q): want %q, %q have %q, %q", tt.until, tt.before, tt.after, before, after)
+		}
+	}
+}
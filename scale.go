@@ -0,0 +1,58 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// timeUnit is a unit ns/op values can be scaled to with -scale.
+type timeUnit struct {
+	name string  // replaces "ns" in the column name
+	ns   float64 // nanoseconds per unit
+}
+
+// timeUnits are the units of -scale, largest first.
+var timeUnits = []timeUnit{
+	{"s", 1e9},
+	{"ms", 1e6},
+	{"µs", 1e3},
+	{"ns", 1},
+}
+
+// scaledFor returns m displaying its values in the largest time unit
+// in which the smallest positive value of m in diffs is at least 1, if
+// -scale is set and m is ns/op. The unit is the same for the whole
+// column so that the values line up; the changes are still computed on
+// the raw values.
+func (m metric) scaledFor(diffs []benchdiff.BenchDiff) metric {
+	if !*scaleNs || m.name != "ns" {
+		return m
+	}
+	least := 0.0
+	for _, diff := range diffs {
+		if !m.measuredIn(diff) {
+			continue
+		}
+		for _, b := range [...]float64{diff.Before.NsPerOp, diff.After.NsPerOp} {
+			if b > 0 && (least == 0 || b < least) {
+				least = b
+			}
+		}
+	}
+	u := timeUnits[len(timeUnits)-1]
+	for _, tu := range timeUnits {
+		if least >= tu.ns {
+			u = tu
+			break
+		}
+	}
+	format := m.format
+	m.format = func(ns float64) string { return format(ns / u.ns) }
+	m.column = strings.Replace(m.column, "ns", u.name, 1)
+	return m
+}
@@ -0,0 +1,44 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+func TestScaledFor(t *testing.T) {
+	defer func(s bool, p int) { *scaleNs, *nsPrec = s, p }(*scaleNs, *nsPrec)
+	ns, _ := lookupMetric("ns")
+	cases := []struct {
+		diffs  []benchdiff.BenchDiff
+		scale  bool
+		prec   int
+		column string
+		cells  [2]string
+	}{
+		{[]benchdiff.BenchDiff{nsDiff("BenchmarkA", 0, 1234567890, 1334567890)}, false, -1, "ns/op", [2]string{"1234567890", "1334567890"}},
+		{[]benchdiff.BenchDiff{nsDiff("BenchmarkA", 0, 1234567890, 1334567890)}, true, -1, "s/op", [2]string{"1.23", "1.33"}},
+		{[]benchdiff.BenchDiff{nsDiff("BenchmarkA", 0, 1234567890, 1334567890)}, true, 3, "s/op", [2]string{"1.235", "1.335"}},
+		// The smallest value of the column picks the unit.
+		{[]benchdiff.BenchDiff{nsDiff("BenchmarkA", 0, 1234567890, 1334567890), nsDiff("BenchmarkB", 1, 25000, 2500000)}, true, -1, "µs/op", [2]string{"1234568", "1334568"}},
+		{[]benchdiff.BenchDiff{nsDiff("BenchmarkA", 0, 45600, 45700)}, true, -1, "µs/op", [2]string{"45.6", "45.7"}},
+		{[]benchdiff.BenchDiff{nsDiff("BenchmarkA", 0, 2.82, 2.81)}, true, -1, "ns/op", [2]string{"2.82", "2.81"}},
+		{[]benchdiff.BenchDiff{nsDiff("BenchmarkA", 0, 148, 143)}, false, 1, "ns/op", [2]string{"148.0", "143.0"}},
+	}
+	for _, tt := range cases {
+		*scaleNs, *nsPrec = tt.scale, tt.prec
+		m := ns.scaledFor(tt.diffs)
+		before, after := m.cells(tt.diffs[0])
+		if m.column != tt.column || before != tt.cells[0] || after != tt.cells[1] {
+			t.Errorf("-scale=%t -prec=%d: want %s %v have %s [%s %s]", tt.scale, tt.prec, tt.column, tt.cells, m.column, before, after)
+		}
+		// The change is computed on the raw values.
+		if want, have := ns.changeCell(tt.diffs[0]), m.changeCell(tt.diffs[0]); want != have {
+			t.Errorf("-scale=%t -prec=%d: want change %s have %s", tt.scale, tt.prec, want, have)
+		}
+	}
+}
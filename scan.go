@@ -0,0 +1,158 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// scanChunk is the least size of the pieces of an input scanned
+// concurrently: smaller inputs are scanned by a single goroutine.
+const scanChunk = 1 << 20
+
+// scanned is what one pass over the output of go test -bench reads: its
+// results in parse order, with the custom metrics and the package of
+// each, and its header.
+type scanned struct {
+	results []*parse.Benchmark
+	custom  []map[string]float64 // by result, nil if it has none
+	pkgs    []string             // by result, "" before any "pkg:" line
+	header  benchHeader
+}
+
+// chunkScan is the scan of a piece of an input. The package of its
+// first lead results is that of the last "pkg:" line of the previous
+// pieces, known once all are scanned.
+type chunkScan struct {
+	scanned
+	lead    int
+	lastPkg string // package of the last "pkg:" line, if sawPkg
+	sawPkg  bool
+}
+
+// scanResults reads the results of data as parse.ParseSet would, with
+// their custom metrics and packages and the header of data, parsing
+// each line once. Large inputs are cut at line boundaries into pieces
+// scanned concurrently, one per CPU, and merged in order.
+func scanResults(data []byte) scanned {
+	pieces := splitLines(data, runtime.GOMAXPROCS(0))
+	chunks := make([]chunkScan, len(pieces))
+	var wg sync.WaitGroup
+	for i, piece := range pieces {
+		wg.Add(1)
+		go func(i int, piece []byte) {
+			defer wg.Done()
+			chunks[i] = scanChunkLines(piece)
+		}(i, piece)
+	}
+	wg.Wait()
+
+	s := scanned{header: make(benchHeader)}
+	var pkg string
+	for _, c := range chunks {
+		for i, b := range c.results {
+			b.Ord = len(s.results)
+			s.results = append(s.results, b)
+			s.custom = append(s.custom, c.custom[i])
+			if i < c.lead {
+				s.pkgs = append(s.pkgs, pkg)
+			} else {
+				s.pkgs = append(s.pkgs, c.pkgs[i])
+			}
+		}
+		if c.sawPkg {
+			pkg = c.lastPkg
+		}
+		for k, v := range c.header {
+			if _, ok := s.header[k]; !ok {
+				s.header[k] = v
+			}
+		}
+	}
+	return s
+}
+
+// splitLines cuts data into at most n pieces of scanChunk bytes or
+// more, each ending at the end of a line.
+func splitLines(data []byte, n int) [][]byte {
+	size := len(data) / n
+	if size < scanChunk {
+		size = scanChunk
+	}
+	var pieces [][]byte
+	for len(data) > size {
+		end := bytes.IndexByte(data[size:], '\n')
+		if end < 0 {
+			break
+		}
+		end += size + 1
+		pieces = append(pieces, data[:end])
+		data = data[end:]
+	}
+	return append(pieces, data)
+}
+
+// scanChunkLines scans the lines of a piece of an input.
+func scanChunkLines(data []byte) chunkScan {
+	c := chunkScan{scanned: scanned{header: make(benchHeader)}}
+	var pkg string
+	for len(data) > 0 {
+		var text []byte
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			text, data = data[:i], data[i+1:]
+		} else {
+			text, data = data, nil
+		}
+		line := strings.TrimRight(string(text), " \r")
+		if m := headerLine.FindStringSubmatch(line); m != nil {
+			if _, ok := c.header[m[1]]; !ok {
+				c.header[m[1]] = m[2]
+			}
+		}
+		if p, ok := linePackage(line); ok {
+			if !c.sawPkg {
+				c.lead = len(c.results)
+			}
+			pkg, c.lastPkg, c.sawPkg = p, p, true
+			continue
+		}
+		b, err := parse.ParseLine(line)
+		if err != nil {
+			continue
+		}
+		var custom map[string]float64
+		if values := lineCustom(line); len(values) > 0 {
+			custom = values
+		}
+		c.results = append(c.results, b)
+		c.custom = append(c.custom, custom)
+		c.pkgs = append(c.pkgs, pkg)
+	}
+	if !c.sawPkg {
+		c.lead = len(c.results)
+	}
+	return c
+}
+
+// set returns the results of s by name, and records their custom
+// metrics and packages.
+func (s scanned) set() parse.Set {
+	bs := make(parse.Set)
+	for i, b := range s.results {
+		bs[b.Name] = append(bs[b.Name], b)
+		if s.custom[i] != nil {
+			customValues[b] = s.custom[i]
+		}
+		if s.pkgs[i] != "" {
+			resultPackages[b] = s.pkgs[i]
+		}
+	}
+	return bs
+}
@@ -0,0 +1,81 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// largeInput returns the output of several packages of benchmarks run
+// many times, spanning several scan pieces.
+func largeInput() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("goos: linux\ngoarch: amd64\nBenchmarkNoPkg-4 1000 50 ns/op\n")
+	for i := 0; buf.Len() < 3*scanChunk; i++ {
+		if i%5000 == 0 {
+			fmt.Fprintf(&buf, "pkg: example.com/p%d\ncpu: cpu%d\n", i/5000, i)
+		}
+		fmt.Fprintf(&buf, "BenchmarkB%d-4 \t 1000\t %d ns/op\t %d items/op\r\n", i%7, 100+i%13, i%3)
+	}
+	buf.WriteString("PASS\nok  \texample.com/p 1.0s")
+	return buf.Bytes()
+}
+
+func TestSplitLines(t *testing.T) {
+	data := largeInput()
+	pieces := splitLines(data, 4)
+	if len(pieces) < 2 || len(pieces) > 4 {
+		t.Fatalf("splitLines: want 2 to 4 pieces, have %d", len(pieces))
+	}
+	for _, p := range pieces[:len(pieces)-1] {
+		if len(p) < scanChunk || p[len(p)-1] != '\n' {
+			t.Errorf("splitLines: piece of %d bytes ending with %q", len(p), p[len(p)-1])
+		}
+	}
+	if joined := bytes.Join(pieces, nil); !bytes.Equal(joined, data) {
+		t.Error("splitLines: the pieces do not join to the input")
+	}
+	if pieces := splitLines([]byte("BenchmarkA 1 1 ns/op\n"), 4); len(pieces) != 1 {
+		t.Errorf("splitLines of a small input: want 1 piece, have %d", len(pieces))
+	}
+}
+
+func TestScanResults(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	data := largeInput()
+	want, err := parse.ParseSet(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := scanResults(data)
+	have := make(parse.Set)
+	for _, b := range s.results {
+		have[b.Name] = append(have[b.Name], b)
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Error("scanResults: results differ from parse.ParseSet")
+	}
+	// A single piece is scanned in order, as the pieces must be merged.
+	single := scanChunkLines(data)
+	if !reflect.DeepEqual(single.custom, s.custom) {
+		t.Error("scanResults: custom metrics differ from a single piece")
+	}
+	if !reflect.DeepEqual(single.pkgs, s.pkgs) {
+		t.Error("scanResults: packages differ from a single piece")
+	}
+	if !reflect.DeepEqual(single.header, s.header) {
+		t.Errorf("scanResults: want header %v have %v", single.header, s.header)
+	}
+	if s.pkgs[0] != "" || s.pkgs[1] != "example.com/p0" || s.pkgs[len(s.pkgs)-1] == "example.com/p0" {
+		t.Errorf("scanResults: want no package, then example.com/p0 up to a later one, have %s, %s... %s", s.pkgs[0], s.pkgs[1], s.pkgs[len(s.pkgs)-1])
+	}
+}
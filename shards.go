@@ -0,0 +1,69 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"strings"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// shardSep separates the files of one side given as a single argument,
+// as in old1.txt,old2.txt.
+const shardSep = ","
+
+// splitShards returns the files of the argument arg: arg itself if it
+// names an existing file or has no shardSep, or else its shardSep
+// separated paths.
+func splitShards(arg string) []string {
+	if !strings.Contains(arg, shardSep) {
+		return []string{arg}
+	}
+	if _, err := os.Stat(arg); err == nil {
+		return []string{arg}
+	}
+	return strings.Split(arg, shardSep)
+}
+
+// parseShards parses the files of arg, the results of side, and merges their results, as
+// repeated results of the benchmarks found in several of them. The
+// header is the one of the first file; the keys it does not have are
+// taken from the next files.
+func parseShards(side, arg string) (parse.Set, benchHeader) {
+	paths := splitShards(arg)
+	bs, h := parseFile(side, paths[0])
+	for _, path := range paths[1:] {
+		more, mh := parseFile(side, path)
+		mergeSets(bs, more)
+		for k, v := range mh {
+			if hv, ok := h[k]; !ok {
+				h[k] = v
+			} else if hv != v {
+				logf("%s: %s is %q, keeping %q of %s", path, k, v, hv, paths[0])
+			}
+		}
+	}
+	return bs, h
+}
+
+// mergeSets appends the results of src to those of dst, after them in
+// parse order.
+func mergeSets(dst, src parse.Set) {
+	next := 0
+	for _, bb := range dst {
+		for _, b := range bb {
+			if b.Ord >= next {
+				next = b.Ord + 1
+			}
+		}
+	}
+	for name, bb := range src {
+		for _, b := range bb {
+			b.Ord += next
+		}
+		dst[name] = append(dst[name], bb...)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseShards(t *testing.T) {
+	defer func(v []inputRecord) { inputs = v }(inputs)
+	dir, err := ioutil.TempDir("", "benchdiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	a := write("a.txt", "goos: linux\ncpu: A\nBenchmarkX\t10\t100 ns/op\nBenchmarkY\t10\t5 ns/op\n")
+	b := write("b.txt", "goos: linux\ncpu: B\ngoarch: arm64\nBenchmarkX\t10\t110 ns/op\n")
+	comma := write("c,d.txt", "BenchmarkZ\t10\t1 ns/op\n")
+
+	if have := splitShards(a + "," + b); !reflect.DeepEqual(have, []string{a, b}) {
+		t.Errorf("splitShards: want [%s %s] have %v", a, b, have)
+	}
+	if have := splitShards(comma); !reflect.DeepEqual(have, []string{comma}) {
+		t.Errorf("splitShards of an existing file: want [%s] have %v", comma, have)
+	}
+
+	inputs = nil
+	bs, h := parseShards("old", a+","+b)
+	var ns []float64
+	var ords []int
+	for _, b := range bs["BenchmarkX"] {
+		ns = append(ns, b.NsPerOp)
+		ords = append(ords, b.Ord)
+	}
+	if !reflect.DeepEqual(ns, []float64{100, 110}) || ords[1] <= bs["BenchmarkY"][0].Ord {
+		t.Errorf("parseShards BenchmarkX: ns/op %v, ords %v after BenchmarkY at %d", ns, ords, bs["BenchmarkY"][0].Ord)
+	}
+	if want := (benchHeader{"goos": "linux", "cpu": "A", "goarch": "arm64"}); !reflect.DeepEqual(h, want) {
+		t.Errorf("parseShards header: want %v have %v", want, h)
+	}
+
+	parseShards("new", write("z.txt", "BenchmarkZ\t10\t1 ns/op")+","+b)
+	var sides []string
+	for _, in := range inputs {
+		sides = append(sides, in.Side)
+	}
+	if want := []string{"old", "old", "new", "new"}; !reflect.DeepEqual(sides, want) {
+		t.Errorf("parseShards input sides: want %v have %v", want, sides)
+	}
+	if have, want := string(sideText(inputs, "new")), "BenchmarkZ\t10\t1 ns/op\ngoos: linux\ncpu: B\ngoarch: arm64\nBenchmarkX\t10\t110 ns/op\n"; have != want {
+		t.Errorf("sideText of the new shards: want %q have %q", want, have)
+	}
+}
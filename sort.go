@@ -0,0 +1,133 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// Sort keys accepted by -sort.
+const (
+	sortParse = "" // parse order
+	sortName  = "name"
+	sortDelta = "delta"
+	sortOld   = "old"
+	sortNew   = "new"
+)
+
+// validSortKey reports whether key is accepted by -sort.
+func validSortKey(key string) bool {
+	switch key {
+	case sortParse, sortName, sortDelta, sortOld, sortNew:
+		return true
+	}
+	return false
+}
+
+// sortOrder is a -sort value: a sort key or the name of a metric, whose
+// change then sorts every block, optionally followed by ":asc" or
+// ":desc".
+type sortOrder struct {
+	key     string
+	metric  string // canonical name of the metric sorting every block, "" for the metric of each block
+	flipped bool   // the order is the opposite of the default one of key
+}
+
+// parseSortOrder parses a -sort value. Sorting by delta, old or new is
+// descending by default, by name or in parse order ascending.
+func parseSortOrder(s string) (sortOrder, error) {
+	var o sortOrder
+	key, dir := s, ""
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		key, dir = s[:i], s[i+1:]
+	}
+	switch {
+	case validSortKey(key):
+		o.key = key
+	case metricAliases[key] != "":
+		o.key, o.metric = sortDelta, metricAliases[key]
+	default:
+		return o, fmt.Errorf("unknown sort key %q", key)
+	}
+	desc := o.key == sortDelta || o.key == sortOld || o.key == sortNew
+	switch dir {
+	case "":
+	case "asc":
+		o.flipped = desc
+	case "desc":
+		o.flipped = !desc
+	default:
+		return o, fmt.Errorf("unknown sort direction %q, want asc or desc", dir)
+	}
+	return o, nil
+}
+
+// order returns the order given by -sort, -mag standing for
+// -sort=delta.
+func order() sortOrder {
+	o, _ := parseSortOrder(*sortKey)
+	if *magSort {
+		o.key = sortDelta
+	}
+	return o
+}
+
+// orderKey returns the sort key of order.
+func orderKey() string {
+	return order().key
+}
+
+// reversed reports whether the order of -sort is reversed, by -reverse
+// or by its direction.
+func reversed() bool {
+	return *reverse != order().flipped
+}
+
+// sorter returns the sort of diffs for the block of m by key.
+func sorter(diffs []benchdiff.BenchDiff, m metric, key string) sort.Interface {
+	switch key {
+	case sortName:
+		return benchdiff.ByName(diffs)
+	case sortDelta:
+		return magSorter(diffs, m)
+	case sortOld, sortNew:
+		return byMetricValue{diffs, m, key == sortNew}
+	}
+	return benchdiff.ByParseOrder(diffs)
+}
+
+// byMetricValue sorts diffs by decreasing old value of m, or new value
+// if after is set, then by benchmark name. Diffs not measuring m come
+// last.
+type byMetricValue struct {
+	diffs []benchdiff.BenchDiff
+	m     metric
+	after bool
+}
+
+func (x byMetricValue) Len() int      { return len(x.diffs) }
+func (x byMetricValue) Swap(i, j int) { x.diffs[i], x.diffs[j] = x.diffs[j], x.diffs[i] }
+func (x byMetricValue) Less(i, j int) bool {
+	vi, iok := x.val(x.diffs[i])
+	vj, jok := x.val(x.diffs[j])
+	if iok != jok {
+		return iok
+	}
+	if vi != vj {
+		return vi > vj
+	}
+	return x.diffs[i].Name() < x.diffs[j].Name()
+}
+
+func (x byMetricValue) val(diff benchdiff.BenchDiff) (float64, bool) {
+	if x.after {
+		return x.m.value(diff.After)
+	}
+	return x.m.value(diff.Before)
+}
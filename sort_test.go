@@ -0,0 +1,121 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestSortKeys(t *testing.T) {
+	defer func(m, r bool, k string) { *magSort, *reverse, *sortKey = m, r, k }(*magSort, *reverse, *sortKey)
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("BenchmarkB", 0, 300, 301),
+		nsDiff("BenchmarkC", 1, 100, 400),
+		nsDiff("BenchmarkA", 2, 200, 150),
+	}
+	ns, _ := lookupMetric("ns")
+	tests := []struct {
+		mag     bool
+		key     string
+		reverse bool
+		want    []string
+	}{
+		{false, sortParse, false, []string{"BenchmarkB", "BenchmarkC", "BenchmarkA"}},
+		{false, sortName, false, []string{"BenchmarkA", "BenchmarkB", "BenchmarkC"}},
+		{false, sortName, true, []string{"BenchmarkC", "BenchmarkB", "BenchmarkA"}},
+		{false, sortDelta, false, []string{"BenchmarkC", "BenchmarkA", "BenchmarkB"}},
+		{true, sortParse, false, []string{"BenchmarkC", "BenchmarkA", "BenchmarkB"}},
+		{false, sortOld, false, []string{"BenchmarkB", "BenchmarkA", "BenchmarkC"}},
+		{false, sortNew, false, []string{"BenchmarkC", "BenchmarkB", "BenchmarkA"}},
+		{false, sortNew, true, []string{"BenchmarkA", "BenchmarkB", "BenchmarkC"}},
+	}
+	for _, tt := range tests {
+		*magSort, *sortKey, *reverse = tt.mag, tt.key, tt.reverse
+		sortDiffs(diffs, ns)
+		var have []string
+		for _, diff := range diffs {
+			have = append(have, diff.Name())
+		}
+		if !reflect.DeepEqual(tt.want, have) {
+			t.Errorf("sortDiffs -mag=%t -sort=%q -reverse=%t: want %v have %v", tt.mag, tt.key, tt.reverse, tt.want, have)
+		}
+	}
+}
+
+func TestValidSortKey(t *testing.T) {
+	for _, key := range []string{"", "name", "delta", "old", "new"} {
+		if !validSortKey(key) {
+			t.Errorf("validSortKey(%q): want true", key)
+		}
+	}
+	if validSortKey("mag") {
+		t.Error(`validSortKey("mag"): want false`)
+	}
+}
+
+func TestParseSortOrder(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want sortOrder
+	}{
+		{"", sortOrder{}},
+		{"name:desc", sortOrder{key: sortName, flipped: true}},
+		{"name:asc", sortOrder{key: sortName}},
+		{"delta:asc", sortOrder{key: sortDelta, flipped: true}},
+		{"new:desc", sortOrder{key: sortNew}},
+		{"allocs", sortOrder{key: sortDelta, metric: "allocs"}},
+		{"B/op:asc", sortOrder{key: sortDelta, metric: "bytes", flipped: true}},
+	} {
+		have, err := parseSortOrder(tt.in)
+		if err != nil || have != tt.want {
+			t.Errorf("parseSortOrder(%q) = %+v, %v; want %+v", tt.in, have, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"mag", "name:up", "speed:asc"} {
+		if _, err := parseSortOrder(bad); err == nil {
+			t.Errorf("parseSortOrder(%q): no error", bad)
+		}
+	}
+}
+
+func TestSortByMetric(t *testing.T) {
+	defer func(r bool, k string) { *reverse, *sortKey = r, k }(*reverse, *sortKey)
+	*reverse = false
+	memDiff := func(name string, ord int, nsBefore, nsAfter float64, allocsBefore, allocsAfter uint64) benchdiff.BenchDiff {
+		mem := parse.NsPerOp | parse.AllocsPerOp
+		return benchdiff.BenchDiff{
+			Before: &parse.Benchmark{Name: name, NsPerOp: nsBefore, AllocsPerOp: allocsBefore, Measured: mem, Ord: ord},
+			After:  &parse.Benchmark{Name: name, NsPerOp: nsAfter, AllocsPerOp: allocsAfter, Measured: mem, Ord: ord},
+		}
+	}
+	diffs := []benchdiff.BenchDiff{
+		memDiff("BenchmarkA", 0, 100, 200, 1, 1),
+		memDiff("BenchmarkB", 1, 100, 100, 1, 10),
+		memDiff("BenchmarkC", 2, 100, 110, 1, 2),
+	}
+	ns, _ := lookupMetric("ns")
+	for _, tt := range []struct {
+		key  string
+		want []string
+	}{
+		{"ns", []string{"BenchmarkA", "BenchmarkC", "BenchmarkB"}},
+		{"allocs", []string{"BenchmarkB", "BenchmarkC", "BenchmarkA"}},
+		{"allocs:asc", []string{"BenchmarkA", "BenchmarkC", "BenchmarkB"}},
+	} {
+		*sortKey = tt.key
+		sortDiffs(diffs, ns)
+		var have []string
+		for _, diff := range diffs {
+			have = append(have, diff.Name())
+		}
+		if !reflect.DeepEqual(tt.want, have) {
+			t.Errorf("sortDiffs -sort=%s: want %v have %v", tt.key, tt.want, have)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"text/tabwriter"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// writeSplit writes to path the metric tables of diffs restricted to
+// the rows for which keep is true, sorted like the main output.
+func writeSplit(path string, diffs []benchdiff.BenchDiff, keep func(metric, benchdiff.BenchDiff) bool) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(f, 0, 0, defaultPadding, ' ', 0)
+	printFiltered(w, diffs, keep)
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// printFiltered writes one block per metric with the rows of diffs for
+// which keep is true. Blocks without rows are omitted.
+func printFiltered(w *tabwriter.Writer, diffs []benchdiff.BenchDiff, keep func(metric, benchdiff.BenchDiff) bool) {
+	printBlocks(w, append([]benchdiff.BenchDiff(nil), diffs...), plainStyle, keep, nil)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestDirection(t *testing.T) {
+	ns, _ := lookupMetric("ns")
+	mbs, _ := lookupMetric("mbs")
+	b := func(ns, mbs float64) *parse.Benchmark {
+		return &parse.Benchmark{NsPerOp: ns, MBPerS: mbs, Measured: parse.NsPerOp | parse.MBPerS}
+	}
+	tests := []struct {
+		m    metric
+		diff benchdiff.BenchDiff
+		want int
+	}{
+		{ns, benchdiff.BenchDiff{Before: b(10, 0), After: b(8, 0)}, 1},
+		{ns, benchdiff.BenchDiff{Before: b(10, 0), After: b(12, 0)}, -1},
+		{ns, benchdiff.BenchDiff{Before: b(10, 0), After: b(10, 0)}, 0},
+		{mbs, benchdiff.BenchDiff{Before: b(0, 10), After: b(0, 12)}, 1},
+		{mbs, benchdiff.BenchDiff{Before: b(0, 10), After: b(0, 8)}, -1},
+	}
+	for i, tt := range tests {
+		if have := tt.m.direction(tt.diff); have != tt.want {
+			t.Errorf("#%d: %s direction: want %d have %d", i, tt.m.name, tt.want, have)
+		}
+	}
+}
+
+func TestPrintFiltered(t *testing.T) {
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("BenchmarkFaster", 0, 10, 8),
+		nsDiff("BenchmarkSlower", 1, 10, 12),
+		nsDiff("BenchmarkSame", 2, 10, 10),
+	}
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 1, ' ', 0)
+	printFiltered(w, diffs, metric.regressed)
+	w.Flush()
+	want := "benchmark       old ns/op new ns/op delta\n" +
+		"BenchmarkSlower 10.0      12.0      +20.00%\n"
+	if have := buf.String(); have != want {
+		t.Errorf("printFiltered:\nwant:\n%s\nhave:\n%s", want, have)
+	}
+
+	buf.Reset()
+	printFiltered(w, diffs, func(metric, benchdiff.BenchDiff) bool { return false })
+	w.Flush()
+	if buf.Len() != 0 {
+		t.Errorf("printFiltered without rows: want no output have %q", buf.String())
+	}
+
+	// Without an ns/op regression, the allocs/op block comes first.
+	allocs := benchdiff.BenchDiff{
+		Before: &parse.Benchmark{Name: "BenchmarkAllocs", NsPerOp: 10, AllocsPerOp: 1, Measured: parse.NsPerOp | parse.AllocsPerOp},
+		After:  &parse.Benchmark{Name: "BenchmarkAllocs", NsPerOp: 9, AllocsPerOp: 2, Measured: parse.NsPerOp | parse.AllocsPerOp},
+	}
+	buf.Reset()
+	printFiltered(w, []benchdiff.BenchDiff{allocs}, metric.regressed)
+	w.Flush()
+	if have := buf.String(); !strings.HasPrefix(have, "benchmark ") {
+		t.Errorf("printFiltered with an empty first block: want the allocs block first have %q", have)
+	}
+}
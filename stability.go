@@ -0,0 +1,133 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// stabilityRow compares the ns/op coefficients of variation of one
+// benchmark, in percent.
+type stabilityRow struct {
+	name            string
+	ord             int // parse order of the first old sample
+	before, after   float64
+	unstable        bool // after rose by more than the -stability points
+	beforeN, afterN int
+}
+
+// stabilityShown reports whether -stability is given, even as 0 to mark
+// any rise of the variation.
+func stabilityShown() bool {
+	return flagGiven("stability")
+}
+
+// cv returns the coefficient of variation of xs, its standard
+// deviation over its mean, in percent. It reports false if xs has less
+// than two values or a zero mean.
+func cv(xs []float64) (float64, bool) {
+	if len(xs) < 2 {
+		return 0, false
+	}
+	m := mean(xs)
+	if m == 0 {
+		return 0, false
+	}
+	return 100 * stddev(xs) / m, true
+}
+
+// stabilityRows compares the ns/op coefficients of variation of the
+// benchmarks repeated on both sides of before and after, marking as
+// unstable those rising by more than rise percentage points. The rows
+// are sorted by key as the blocks: by decreasing rise for delta, by
+// decreasing old or new variation for old and new, and in parse order
+// otherwise. The order is reversed if reverse is set.
+func stabilityRows(before, after parse.Set, rise float64, key string, reverse bool) []stabilityRow {
+	var rows []stabilityRow
+	for name, beforebb := range before {
+		bx, ax := nsSamples(beforebb), nsSamples(after[name])
+		bcv, bok := cv(bx)
+		acv, aok := cv(ax)
+		if !bok || !aok {
+			continue
+		}
+		rows = append(rows, stabilityRow{
+			name:     name,
+			ord:      beforebb[0].Ord,
+			before:   bcv,
+			after:    acv,
+			unstable: acv-bcv > rise,
+			beforeN:  len(bx),
+			afterN:   len(ax),
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if reverse {
+			i, j = j, i
+		}
+		ri, rj := rows[i], rows[j]
+		switch key {
+		case sortName:
+		case sortDelta:
+			if di, dj := ri.after-ri.before, rj.after-rj.before; di != dj {
+				return di > dj
+			}
+		case sortOld:
+			if ri.before != rj.before {
+				return ri.before > rj.before
+			}
+		case sortNew:
+			if ri.after != rj.after {
+				return ri.after > rj.after
+			}
+		default:
+			return ri.ord < rj.ord
+		}
+		return ri.name < rj.name
+	})
+	return rows
+}
+
+// printStability writes the block comparing the coefficients of
+// variation of rows, with -changed only the unstable ones.
+func printStability(w io.Writer, rows []stabilityRow) {
+	var header bool
+	for _, r := range rows {
+		if *changedOnly && !r.unstable {
+			continue
+		}
+		if !header {
+			fmt.Fprintf(w, "\nbenchmark\t%s cv\t%s cv\tdelta\n", *oldLabel, *newLabel)
+			header = true
+		}
+		delta := fmt.Sprintf("%+.2f pts", r.after-r.before)
+		if r.unstable {
+			delta += " (unstable)"
+		}
+		fmt.Fprintf(w, "%s\t%.2f%% (n=%d)\t%.2f%% (n=%d)\t%s\n", truncateName(r.name, nameWidth),
+			r.before, r.beforeN, r.after, r.afterN, delta)
+	}
+}
+
+// unstableWarning returns the warning about the unstable benchmarks of
+// rows, "" if there is none.
+func unstableWarning(rows []stabilityRow, rise float64) string {
+	var names []string
+	for _, r := range rows {
+		if r.unstable {
+			names = append(names, r.name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return fmt.Sprintf("benchdiff: warning: the ns/op variation of %d benchmarks rose by more than %g points: %s", len(names), rise, strings.Join(names, ", "))
+}
@@ -0,0 +1,62 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"text/tabwriter"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestCV(t *testing.T) {
+	if have, ok := cv([]float64{90, 100, 110}); !ok || math.Abs(have-10) > 1e-9 {
+		t.Errorf("cv: want 10, true have %g, %v", have, ok)
+	}
+	if _, ok := cv([]float64{100}); ok {
+		t.Error("cv of a single value: want false")
+	}
+	if _, ok := cv([]float64{0, 0}); ok {
+		t.Error("cv of a zero mean: want false")
+	}
+}
+
+func TestStability(t *testing.T) {
+	before := parse.Set{
+		"BenchmarkA":    nsRuns("BenchmarkA", 99, 100, 101),
+		"BenchmarkB":    nsRuns("BenchmarkB", 90, 110),
+		"BenchmarkOnce": nsRuns("BenchmarkOnce", 10),
+	}
+	after := parse.Set{
+		"BenchmarkA":    nsRuns("BenchmarkA", 80, 100, 120),
+		"BenchmarkB":    nsRuns("BenchmarkB", 90, 110),
+		"BenchmarkOnce": nsRuns("BenchmarkOnce", 10, 20),
+	}
+	rows := stabilityRows(before, after, 5, sortDelta, false)
+	if len(rows) != 2 || rows[0].name != "BenchmarkA" || !rows[0].unstable || rows[1].unstable {
+		t.Fatalf("stabilityRows: want an unstable BenchmarkA then BenchmarkB have %+v", rows)
+	}
+
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 5, ' ', 0)
+	printStability(w, rows)
+	w.Flush()
+	want := `
+benchmark      old cv           new cv           delta
+BenchmarkA     1.00% (n=3)      20.00% (n=3)     +19.00 pts (unstable)
+BenchmarkB     14.14% (n=2)     14.14% (n=2)     +0.00 pts
+`
+	if have := buf.String(); have != want {
+		t.Errorf("printStability: want\n%s\nhave\n%s", want, have)
+	}
+	if have, want := unstableWarning(rows, 5), "benchdiff: warning: the ns/op variation of 1 benchmarks rose by more than 5 points: BenchmarkA"; have != want {
+		t.Errorf("unstableWarning: want %q have %q", want, have)
+	}
+	if have := unstableWarning(rows[1:], 5); have != "" {
+		t.Errorf("unstableWarning without unstable benchmarks: want none have %q", have)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"sort"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// weightIters is the -geomean-weight value weighting benchmarks by
+// their iteration count.
+const weightIters = "iters"
+
+// mean returns the arithmetic mean of xs, which must not be empty.
+func mean(xs []float64) float64 {
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+// minimum returns the smallest value of xs, which must not be empty.
+func minimum(xs []float64) float64 {
+	m := xs[0]
+	for _, x := range xs[1:] {
+		if x < m {
+			m = x
+		}
+	}
+	return m
+}
+
+// maximum returns the largest value of xs, which must not be empty.
+func maximum(xs []float64) float64 {
+	m := xs[0]
+	for _, x := range xs[1:] {
+		if x > m {
+			m = x
+		}
+	}
+	return m
+}
+
+// percentile returns the p-th percentile (0 <= p <= 100) of sorted,
+// which must be in ascending order and not empty, using the
+// nearest-rank method.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// geomean returns the geometric mean of xs, which must be positive.
+// It returns 1 for an empty slice.
+func geomean(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 1
+	}
+	var sum float64
+	for _, x := range xs {
+		sum += math.Log(x)
+	}
+	return math.Exp(sum / float64(len(xs)))
+}
+
+// weightedGeomean returns the geometric mean of xs, which must be
+// positive, each x counting as much as its weight in ws. It returns 1
+// if no weight is positive.
+func weightedGeomean(xs, ws []float64) float64 {
+	var sum, total float64
+	for i, x := range xs {
+		sum += ws[i] * math.Log(x)
+		total += ws[i]
+	}
+	if total <= 0 {
+		return 1
+	}
+	return math.Exp(sum / total)
+}
+
+// ratioGeomean returns the geometric mean of the after/before ratios
+// of m over the diffs measuring it. Ratios involving a zero value are
+// skipped. With -geomean-weight=iters each ratio is weighted by the
+// smaller iteration count of its two sides. It reports false if no
+// ratio was available.
+func ratioGeomean(diffs []benchdiff.BenchDiff, m metric) (float64, bool) {
+	_, _, ratio, ok := summaryGeomeans(diffs, m)
+	return ratio, ok
+}
+
+// summaryGeomeans returns the geometric means of the before values, the
+// after values and the after/before ratios of m over the diffs measuring
+// it, weighted as in ratioGeomean. Diffs with a zero value are skipped.
+// It reports false if no diff was left.
+func summaryGeomeans(diffs []benchdiff.BenchDiff, m metric) (before, after, ratio float64, ok bool) {
+	var befores, afters, ratios, weights []float64
+	for _, diff := range diffs {
+		if !m.measuredIn(diff) {
+			continue
+		}
+		d := m.delta(diff)
+		if d.Before > 0 && d.After > 0 {
+			befores = append(befores, d.Before)
+			afters = append(afters, d.After)
+			ratios = append(ratios, d.After/d.Before)
+			weights = append(weights, geomeanWeight(diff))
+		}
+	}
+	if len(ratios) == 0 {
+		return 0, 0, 0, false
+	}
+	if *weightBy == "" {
+		return geomean(befores), geomean(afters), geomean(ratios), true
+	}
+	return weightedGeomean(befores, weights), weightedGeomean(afters, weights), weightedGeomean(ratios, weights), true
+}
+
+// geomeanWeight returns the weight of diff with -geomean-weight=iters.
+func geomeanWeight(diff benchdiff.BenchDiff) float64 {
+	n := diff.Before.N
+	if diff.After.N < n {
+		n = diff.After.N
+	}
+	return float64(n)
+}
+
+// median returns the median of xs, which must not be empty.
+func median(xs []float64) float64 {
+	sorted := append([]float64(nil), xs...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// variance returns the sample variance of xs, which must have at least
+// two values.
+func variance(xs []float64) float64 {
+	m := mean(xs)
+	var sum float64
+	for _, x := range xs {
+		sum += (x - m) * (x - m)
+	}
+	return sum / float64(len(xs)-1)
+}
+
+// cohensD returns the effect size of the change from before to after:
+// the difference of their means divided by their pooled standard
+// deviation. Without deviation, any difference is infinite. It reports
+// false if a side has less than two values.
+func cohensD(before, after []float64) (float64, bool) {
+	nb, na := len(before), len(after)
+	if nb < 2 || na < 2 {
+		return 0, false
+	}
+	diff := mean(after) - mean(before)
+	pooled := math.Sqrt((float64(nb-1)*variance(before) + float64(na-1)*variance(after)) / float64(nb+na-2))
+	switch {
+	case pooled > 0:
+		return diff / pooled, true
+	case diff == 0:
+		return 0, true
+	}
+	return math.Inf(int(math.Copysign(1, diff))), true
+}
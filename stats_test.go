@@ -0,0 +1,139 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestMeanMinimum(t *testing.T) {
+	xs := []float64{3, 1, 2, 6}
+	if want, have := 3.0, mean(xs); want != have {
+		t.Errorf("mean(%v): want %f have %f", xs, want, have)
+	}
+	if want, have := 1.0, minimum(xs); want != have {
+		t.Errorf("minimum(%v): want %f have %f", xs, want, have)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	cases := []struct {
+		p    float64
+		want float64
+	}{
+		{p: 0, want: 1},
+		{p: 50, want: 5},
+		{p: 90, want: 9},
+		{p: 95, want: 10},
+		{p: 100, want: 10},
+	}
+	for _, tt := range cases {
+		if have := percentile(sorted, tt.p); have != tt.want {
+			t.Errorf("percentile(%v): want %f have %f", tt.p, tt.want, have)
+		}
+	}
+}
+
+func TestGeomean(t *testing.T) {
+	cases := []struct {
+		xs   []float64
+		want float64
+	}{
+		{xs: nil, want: 1},
+		{xs: []float64{4}, want: 4},
+		{xs: []float64{1, 4}, want: 2},
+		{xs: []float64{0.5, 2}, want: 1},
+	}
+	for _, tt := range cases {
+		if have := geomean(tt.xs); math.Abs(have-tt.want) > 1e-9 {
+			t.Errorf("geomean(%v): want %f have %f", tt.xs, tt.want, have)
+		}
+	}
+}
+
+func TestRatioGeomean(t *testing.T) {
+	ns, _ := lookupMetric("ns")
+	diffs := []benchdiff.BenchDiff{
+		{Before: &parse.Benchmark{NsPerOp: 10, Measured: parse.NsPerOp}, After: &parse.Benchmark{NsPerOp: 20, Measured: parse.NsPerOp}},
+		{Before: &parse.Benchmark{NsPerOp: 10, Measured: parse.NsPerOp}, After: &parse.Benchmark{NsPerOp: 5, Measured: parse.NsPerOp}},
+		{Before: &parse.Benchmark{NsPerOp: 0, Measured: parse.NsPerOp}, After: &parse.Benchmark{NsPerOp: 5, Measured: parse.NsPerOp}},
+		{Before: &parse.Benchmark{AllocsPerOp: 1, Measured: parse.AllocsPerOp}, After: &parse.Benchmark{AllocsPerOp: 9, Measured: parse.AllocsPerOp}},
+	}
+	if have, ok := ratioGeomean(diffs, ns); !ok || math.Abs(have-1) > 1e-9 {
+		t.Errorf("ratioGeomean: want 1, true have %f, %t", have, ok)
+	}
+	if _, ok := ratioGeomean(diffs[2:], ns); ok {
+		t.Error("ratioGeomean: want no ratio when no benchmark qualifies")
+	}
+}
+
+func TestSummaryGeomeans(t *testing.T) {
+	ns, _ := lookupMetric("ns")
+	allocs, _ := lookupMetric("allocs")
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("BenchmarkA", 0, 2, 4),
+		nsDiff("BenchmarkB", 1, 8, 4),
+		nsDiff("BenchmarkC", 2, 0, 4),
+	}
+	before, after, ratio, ok := summaryGeomeans(diffs, ns)
+	if !ok || math.Abs(before-4) > 1e-9 || math.Abs(after-4) > 1e-9 || math.Abs(ratio-1) > 1e-9 {
+		t.Errorf("summaryGeomeans: want 4, 4, 1, true have %f, %f, %f, %t", before, after, ratio, ok)
+	}
+	if _, _, _, ok := summaryGeomeans(diffs, allocs); ok {
+		t.Error("summaryGeomeans: want false for a metric not measured")
+	}
+}
+
+func TestMedian(t *testing.T) {
+	xs := []float64{5, 1, 3}
+	if have := median(xs); have != 3 {
+		t.Errorf("median(%v): want 3 have %v", xs, have)
+	}
+	if xs[0] != 5 {
+		t.Errorf("median sorted its argument: %v", xs)
+	}
+	if have := median([]float64{4, 1, 2, 3}); have != 2.5 {
+		t.Errorf("median of even count: want 2.5 have %v", have)
+	}
+}
+
+func TestWeightedRatioGeomean(t *testing.T) {
+	defer func(v string) { *weightBy = v }(*weightBy)
+	*weightBy = weightIters
+
+	ns, _ := lookupMetric("ns")
+	diffs := []benchdiff.BenchDiff{
+		{Before: &parse.Benchmark{N: 300, NsPerOp: 10, Measured: parse.NsPerOp}, After: &parse.Benchmark{N: 500, NsPerOp: 20, Measured: parse.NsPerOp}},
+		{Before: &parse.Benchmark{N: 100, NsPerOp: 10, Measured: parse.NsPerOp}, After: &parse.Benchmark{N: 200, NsPerOp: 5, Measured: parse.NsPerOp}},
+	}
+	// 2 weighted by 300 and 0.5 by 100: 2^(3/4) * 0.5^(1/4) = sqrt(2).
+	if have, ok := ratioGeomean(diffs, ns); !ok || math.Abs(have-math.Sqrt2) > 1e-9 {
+		t.Errorf("ratioGeomean: want %f, true have %f, %t", math.Sqrt2, have, ok)
+	}
+	if have := weightedGeomean([]float64{2}, []float64{0}); have != 1 {
+		t.Errorf("weightedGeomean without weight: want 1 have %f", have)
+	}
+}
+
+func TestCohensD(t *testing.T) {
+	cases := []struct {
+		before, after []float64
+		want          float64
+		ok            bool
+	}{
+		{before: []float64{1}, after: []float64{1, 2}, ok: false},
+		{before: []float64{9, 10, 11}, after: []float64{11, 12, 13}, want: 2, ok: true},
+		{before: []float64{11, 12, 13}, after: []float64{9, 10, 11}, want: -2, ok: true},
+		{before: []float64{5, 5}, after: []float64{5, 5}, want: 0, ok: true},
+		{before: []float64{5, 5}, after: []float64{6, 6}, want: math.Inf(1), ok: true},
+	}
+	for _, tt := range cases {
+		have, ok := cohensD(tt.before, tt.after)
+		if ok != tt.ok || have != tt.want {
+			t.Errorf("cohensD(%v, %v): want %v, %t have %v, %t", tt.before, tt.after, tt.want, tt.ok, have, ok)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// stepSummaryTop is the number of regressions and of improvements
+// listed by -step-summary.
+const stepSummaryTop = 5
+
+// change is the change of one metric of one benchmark.
+type change struct {
+	m    metric
+	diff benchdiff.BenchDiff
+}
+
+// topChanges returns up to n changes of diffs for which keep is true,
+// largest first.
+func topChanges(diffs []benchdiff.BenchDiff, n int, keep func(metric, benchdiff.BenchDiff) bool) []change {
+	var cc []change
+	for _, m := range activeMetrics() {
+		for _, diff := range diffs {
+			if m.measuredIn(diff) && keep(m, diff) {
+				cc = append(cc, change{m, diff})
+			}
+		}
+	}
+	sort.SliceStable(cc, func(i, j int) bool {
+		if mi, mj := cc[i].m.delta(cc[i].diff).Mag(), cc[j].m.delta(cc[j].diff).Mag(); mi != mj {
+			return mi < mj
+		}
+		return cc[i].diff.Name() < cc[j].diff.Name()
+	})
+	if len(cc) > n {
+		cc = cc[:n]
+	}
+	return cc
+}
+
+// appendStepSummary appends the markdown summary of diffs to the file
+// named by $GITHUB_STEP_SUMMARY. It does nothing if that variable is
+// not set.
+func appendStepSummary(diffs []benchdiff.BenchDiff) error {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		logf("GITHUB_STEP_SUMMARY is not set, skipping -step-summary")
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	writeStepSummary(f, diffs)
+	return f.Close()
+}
+
+// writeStepSummary writes the geomean of the ns/op changes of diffs and
+// tables of their top regressions and improvements in markdown.
+func writeStepSummary(w io.Writer, diffs []benchdiff.BenchDiff) {
+	fmt.Fprintf(w, "### benchdiff: %s vs %s\n\n", *oldLabel, *newLabel)
+	ns, _ := lookupMetric("ns")
+	if g, ok := ratioGeomean(diffs, ns); ok {
+		fmt.Fprintf(w, "ns/op geomean: %+.2f%%\n\n", 100*g-100)
+	}
+	for _, section := range []struct {
+		title string
+		keep  func(metric, benchdiff.BenchDiff) bool
+	}{{"regressions", metric.regressed}, {"improvements", metric.improved}} {
+		fmt.Fprintf(w, "#### Top %s\n\n", section.title)
+		cc := topChanges(diffs, stepSummaryTop, section.keep)
+		if len(cc) == 0 {
+			fmt.Fprintf(w, "No %s.\n\n", section.title)
+			continue
+		}
+		fmt.Fprintf(w, "| benchmark | metric | %s | %s | delta |\n", *oldLabel, *newLabel)
+		fmt.Fprint(w, "| --- | --- | ---: | ---: | ---: |\n")
+		for _, c := range cc {
+			before, after := c.m.cells(c.diff)
+			fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n", markdownEscape(c.diff.Name()), c.m.unit, before, after, c.m.changeCell(c.diff))
+		}
+		fmt.Fprint(w, "\n")
+	}
+}
+
+// markdownEscape escapes the characters of s breaking a table cell.
+func markdownEscape(s string) string {
+	return strings.Replace(s, "|", `\|`, -1)
+}
+
+// metricSummary counts how the benchmarks measuring a metric changed,
+// for -summary.
+type metricSummary struct {
+	m                            metric
+	regressed, improved, unmoved int
+	changed                      int // changes of custom metrics, which have no direction
+	worst, best                  *benchdiff.BenchDiff
+}
+
+// summarize counts the changes of m in diffs. Changes within -epsilon
+// are unchanged; the worst regression and the best improvement are the
+// ones of largest magnitude.
+func summarize(diffs []benchdiff.BenchDiff, m metric) metricSummary {
+	s := metricSummary{m: m}
+	for i, diff := range diffs {
+		if !m.measuredIn(diff) {
+			continue
+		}
+		switch {
+		case !hasChanged(m.delta(diff)):
+			s.unmoved++
+		case m.improved(diff):
+			s.improved++
+			if s.best == nil || m.delta(diff).Mag() < m.delta(*s.best).Mag() {
+				s.best = &diffs[i]
+			}
+		case m.regressed(diff):
+			s.regressed++
+			if s.worst == nil || m.delta(diff).Mag() < m.delta(*s.worst).Mag() {
+				s.worst = &diffs[i]
+			}
+		default:
+			s.changed++
+		}
+	}
+	return s
+}
+
+// printSummary writes for each metric measured in diffs one line with
+// the number of benchmarks that regressed, improved or did not change,
+// the worst regression, the best improvement and the geomean of the
+// changes.
+func printSummary(w io.Writer, diffs []benchdiff.BenchDiff) {
+	for _, m := range activeMetrics() {
+		s := summarize(diffs, m)
+		if s.regressed+s.improved+s.unmoved+s.changed == 0 {
+			continue
+		}
+		cells := []string{m.unit, fmt.Sprintf("%d regressed", s.regressed), fmt.Sprintf("%d improved", s.improved), fmt.Sprintf("%d unchanged", s.unmoved)}
+		if m.custom {
+			cells = []string{m.unit, fmt.Sprintf("%d changed", s.changed), "", fmt.Sprintf("%d unchanged", s.unmoved)}
+		}
+		worst, best, geo := "", "", ""
+		if s.worst != nil {
+			worst = fmt.Sprintf("worst: %s %s", s.worst.Name(), m.changeCell(*s.worst))
+		}
+		if s.best != nil {
+			best = fmt.Sprintf("best: %s %s", s.best.Name(), m.changeCell(*s.best))
+		}
+		if ratio, ok := ratioGeomean(diffs, m); ok {
+			geo = "geomean: " + m.change(benchdiff.Delta{Before: 1, After: ratio})
+		}
+		cells = append(cells, worst, best, geo)
+		for len(cells) > 0 && cells[len(cells)-1] == "" {
+			cells = cells[:len(cells)-1]
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+}
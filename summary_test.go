@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestWriteStepSummary(t *testing.T) {
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("BenchmarkA", 0, 10, 12),
+		nsDiff("BenchmarkB|x", 1, 10, 15),
+		nsDiff("BenchmarkC", 2, 10, 10),
+	}
+	var buf bytes.Buffer
+	writeStepSummary(&buf, diffs)
+	want := `### benchdiff: old vs new
+
+ns/op geomean: +21.64%
+
+#### Top regressions
+
+| benchmark | metric | old | new | delta |
+| --- | --- | ---: | ---: | ---: |
+| BenchmarkB\|x | ns/op | 10.0 | 15.0 | +50.00% |
+| BenchmarkA | ns/op | 10.0 | 12.0 | +20.00% |
+
+#### Top improvements
+
+No improvements.
+
+`
+	if have := buf.String(); have != want {
+		t.Errorf("writeStepSummary:\nwant:\n%s\nhave:\n%s", want, have)
+	}
+}
+
+func TestTopChanges(t *testing.T) {
+	var diffs []benchdiff.BenchDiff
+	for i := 0; i < 8; i++ {
+		diffs = append(diffs, nsDiff("BenchmarkX", i, 10, float64(11+i)))
+	}
+	cc := topChanges(diffs, 3, metric.regressed)
+	if len(cc) != 3 {
+		t.Fatalf("topChanges: want 3 changes have %d", len(cc))
+	}
+	if after := cc[0].diff.After.NsPerOp; after != 18 {
+		t.Errorf("topChanges: want the largest change first, have %v ns/op", after)
+	}
+}
+
+func TestPrintSummary(t *testing.T) {
+	mbDiff := func(name string, ord int, before, after float64) benchdiff.BenchDiff {
+		return benchdiff.BenchDiff{
+			Before: &parse.Benchmark{Name: name, MBPerS: before, Measured: parse.MBPerS, Ord: ord},
+			After:  &parse.Benchmark{Name: name, MBPerS: after, Measured: parse.MBPerS, Ord: ord},
+		}
+	}
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("BenchmarkA", 0, 10, 12),
+		nsDiff("BenchmarkB", 1, 10, 15),
+		nsDiff("BenchmarkC", 2, 10, 8),
+		nsDiff("BenchmarkD", 3, 10, 10),
+		// Higher MB/s is better.
+		mbDiff("BenchmarkE", 4, 100, 200),
+		mbDiff("BenchmarkF", 5, 100, 90),
+	}
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 1, ' ', 0)
+	printSummary(w, diffs)
+	w.Flush()
+	want := `ns/op 2 regressed 1 improved 1 unchanged worst: BenchmarkB +50.00% best: BenchmarkC -20.00% geomean: +9.54%
+Mb/s  1 regressed 1 improved 0 unchanged worst: BenchmarkF 0.90x   best: BenchmarkE 2.00x   geomean: 1.34x
+`
+	if have := buf.String(); have != want {
+		t.Errorf("printSummary: want\n%s\nhave\n%s", want, have)
+	}
+}
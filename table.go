@@ -0,0 +1,146 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// formatMarkdown is the -format value writing markdown tables.
+const formatMarkdown = "markdown"
+
+// tableStyle renders the lines of the metric tables.
+type tableStyle struct {
+	header  func(m metric) string                           // header line of the block of m
+	row     func(m metric, diff benchdiff.BenchDiff) string // line of diff in the block of m
+	summary func(m metric, cells []string) string           // geomean line of the block of m, nil for none
+}
+
+// plainStyle writes tab separated cells, aligned by tabwriter.
+var plainStyle = tableStyle{
+	header: metric.header,
+	row: func(m metric, diff benchdiff.BenchDiff) string {
+		return strings.Join(m.row(diff), "\t") + "\n"
+	},
+}
+
+// terminalStyle is plainStyle with the colors of -budget-color and
+// -color, -color also dimming the unchanged rows. The header and
+// summary cells of a colored column get the default color, and those
+// of the other lines the normal intensity, so that tabwriter counts the
+// same escape sequences in every line.
+var terminalStyle = tableStyle{
+	header: func(m metric) string {
+		line := m.header()
+		if m.colored() {
+			line = colorCell(line, 3, sgrDefault)
+		}
+		if colorDeltas {
+			line = dimLine(line, false)
+		}
+		return line
+	},
+	row: func(m metric, diff benchdiff.BenchDiff) string {
+		cells := m.row(diff)
+		if m.colored() {
+			cells[3] = colorize(cells[3], m.changeSGR(diff))
+		}
+		line := strings.Join(cells, "\t") + "\n"
+		if colorDeltas {
+			line = dimLine(line, !hasChanged(m.delta(diff)))
+		}
+		return line
+	},
+	summary: func(m metric, cells []string) string {
+		if m.colored() {
+			cells[3] = colorize(cells[3], sgrDefault)
+		}
+		line := strings.Join(cells, "\t") + "\n"
+		if colorDeltas {
+			line = dimLine(line, false)
+		}
+		return line
+	},
+}
+
+// markdownStyle writes GitHub flavored markdown tables, with the
+// regressions in bold and the improvements in italics.
+var markdownStyle = tableStyle{
+	header: func(m metric) string {
+		cells := strings.Split(strings.TrimSuffix(m.header(), "\n"), "\t")
+		sep := make([]string, len(cells))
+		sep[0] = "---"
+		for i := 1; i < len(sep); i++ {
+			sep[i] = "---:"
+		}
+		return markdownRow(cells) + markdownRow(sep)
+	},
+	row: func(m metric, diff benchdiff.BenchDiff) string {
+		cells := m.row(diff)
+		for i, c := range cells {
+			cells[i] = markdownEscape(c)
+		}
+		switch m.direction(diff) {
+		case -1:
+			cells[3] = "**" + cells[3] + "**"
+		case 1:
+			cells[3] = "_" + cells[3] + "_"
+		}
+		return markdownRow(cells)
+	},
+	summary: func(m metric, cells []string) string {
+		for i, c := range cells {
+			cells[i] = markdownEscape(c)
+		}
+		return markdownRow(cells)
+	},
+}
+
+// markdownRow formats cells as a row of a markdown table.
+func markdownRow(cells []string) string {
+	return "| " + strings.Join(cells, " | ") + " |\n"
+}
+
+// printBlocks writes in style one block per metric with the rows of
+// diffs measuring it for which keep is true, calling each, if not nil,
+// after every row. Blocks without rows are omitted, and the printed
+// blocks after the first one are preceded by an empty line. The blocks
+// end with the summary line of style, if any, computed over all the
+// diffs measuring the metric.
+func printBlocks(w io.Writer, diffs []benchdiff.BenchDiff, style tableStyle, keep func(metric, benchdiff.BenchDiff) bool, each func(metric, benchdiff.BenchDiff)) {
+	var printed int // blocks printed so far
+	for _, m := range activeMetrics() {
+		var header bool // Has the header has been displayed yet for a given block?
+		m = m.scaledFor(diffs)
+
+		sortDiffs(diffs, m)
+		for _, diff := range diffs {
+			if !m.measuredIn(diff) || !keep(m, diff) {
+				continue
+			}
+			if !header {
+				if printed > 0 {
+					fmt.Fprint(w, "\n")
+				}
+				fmt.Fprint(w, style.header(m))
+				header = true
+				printed++
+			}
+			fmt.Fprint(w, style.row(m, diff))
+			if each != nil {
+				each(m, diff)
+			}
+		}
+		if header && style.summary != nil {
+			if cells, ok := m.summaryRow(diffs); ok {
+				fmt.Fprint(w, style.summary(m, cells))
+			}
+		}
+	}
+}
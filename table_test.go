@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+func TestMarkdownStyle(t *testing.T) {
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("BenchmarkSlower", 0, 10, 12),
+		nsDiff("BenchmarkFaster|x", 1, 10, 8),
+		nsDiff("BenchmarkSame", 2, 10, 10),
+	}
+	var buf bytes.Buffer
+	all := func(metric, benchdiff.BenchDiff) bool { return true }
+	printBlocks(&buf, diffs, markdownStyle, all, nil)
+	want := `| benchmark | old ns/op | new ns/op | delta |
+| --- | ---: | ---: | ---: |
+| BenchmarkSlower | 10.0 | 12.0 | **+20.00%** |
+| BenchmarkFaster\|x | 10.0 | 8.00 | _-20.00%_ |
+| BenchmarkSame | 10.0 | 10.0 | +0.00% |
+| [geomean] | 10.0 | 9.86 | -1.35% |
+`
+	if have := buf.String(); have != want {
+		t.Errorf("markdown tables:\nwant:\n%s\nhave:\n%s", want, have)
+	}
+}
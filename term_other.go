@@ -0,0 +1,15 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !darwin && !freebsd && !linux
+// +build !darwin,!freebsd,!linux
+
+package main
+
+import "os"
+
+// terminalWidth always reports false on this platform.
+func terminalWidth(f *os.File) (int, bool) {
+	return 0, false
+}
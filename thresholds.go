@@ -0,0 +1,195 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// thresholds maps benchmark names to the tolerances of their metrics,
+// keyed by canonical metric name.
+type thresholds map[string]map[string]float64
+
+// tolerances holds the thresholds read from -thresholds.
+var tolerances thresholds
+
+// readThresholds reads the thresholds file at path.
+func readThresholds(path string) (thresholds, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t, err := parseThresholds(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%v", path, err)
+	}
+	return t, nil
+}
+
+// parseThresholds parses lines such as "BenchmarkHot: ns=3%, allocs=0".
+// Blank lines and lines starting with '#' are ignored. The percent sign
+// is optional and metrics accept the spellings of -ungated.
+func parseThresholds(r io.Reader) (thresholds, error) {
+	t := make(thresholds)
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		colon := strings.LastIndex(text, ":")
+		if colon < 0 {
+			return nil, fmt.Errorf("%d: missing ':' after the benchmark name", line)
+		}
+		name := strings.TrimSpace(text[:colon])
+		if name == "" {
+			return nil, fmt.Errorf("%d: missing benchmark name", line)
+		}
+		if t[name] == nil {
+			t[name] = make(map[string]float64)
+		}
+		for _, field := range strings.Split(text[colon+1:], ",") {
+			eq := strings.Index(field, "=")
+			if eq < 0 {
+				return nil, fmt.Errorf("%d: %q is not metric=tolerance", line, strings.TrimSpace(field))
+			}
+			m := strings.TrimSpace(field[:eq])
+			canonical, ok := metricAliases[m]
+			if !ok {
+				return nil, fmt.Errorf("%d: unknown metric %q", line, m)
+			}
+			v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(field[eq+1:]), "%"), 64)
+			if err != nil || v < 0 {
+				return nil, fmt.Errorf("%d: invalid tolerance for %s: %q", line, m, strings.TrimSpace(field[eq+1:]))
+			}
+			t[name][canonical] = v
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// lookup returns the tolerance of metric for the benchmark name. A line
+// naming the benchmark with its -GOMAXPROCS suffix takes precedence
+// over a line naming it without, and a line naming it with its package
+// over a line naming it without.
+func (t thresholds) lookup(name, metric string) (float64, bool) {
+	for _, n := range nameForms(name) {
+		if v, ok := t[n][metric]; ok {
+			return v, true
+		}
+		base, _ := splitProcs(n)
+		if v, ok := t[base][metric]; ok {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// mentions reports whether t sets a tolerance for metric.
+func (t thresholds) mentions(metric string) bool {
+	for _, tols := range t {
+		if _, ok := tols[metric]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// A toleranceRule sets the tolerance of a metric for the benchmarks
+// whose name matches a regexp, for -tolerance.
+type toleranceRule struct {
+	expr    string // the regexp as given
+	pattern *regexp.Regexp
+	metric  string // canonical metric name
+	tol     float64
+}
+
+// toleranceRules is the flag.Value of -tolerance, which may be repeated.
+type toleranceRules []toleranceRule
+
+// ruleTolerances holds the rules given by -tolerance, in order.
+var ruleTolerances toleranceRules
+
+func (r *toleranceRules) String() string {
+	var rules []string
+	for _, rule := range *r {
+		rules = append(rules, fmt.Sprintf("%s:%s=%g%%", rule.expr, rule.metric, rule.tol))
+	}
+	return strings.Join(rules, " ")
+}
+
+// Set parses a rule such as "BenchmarkBatch.*:ns/op=15%". The regexp
+// must match the whole name, with or without its -GOMAXPROCS suffix,
+// and the metric accepts the spellings of -ungated.
+func (r *toleranceRules) Set(s string) error {
+	colon := strings.LastIndex(s, ":")
+	if colon < 0 {
+		return fmt.Errorf("missing ':' after the benchmark regexp")
+	}
+	re, err := regexp.Compile("^(?:" + s[:colon] + ")$")
+	if err != nil {
+		return err
+	}
+	field := s[colon+1:]
+	eq := strings.Index(field, "=")
+	if eq < 0 {
+		return fmt.Errorf("%q is not metric=tolerance", field)
+	}
+	m := strings.TrimSpace(field[:eq])
+	canonical, ok := metricAliases[m]
+	if !ok {
+		return fmt.Errorf("unknown metric %q", m)
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(field[eq+1:]), "%"), 64)
+	if err != nil || v < 0 {
+		return fmt.Errorf("invalid tolerance for %s: %q", m, strings.TrimSpace(field[eq+1:]))
+	}
+	*r = append(*r, toleranceRule{s[:colon], re, canonical, v})
+	return nil
+}
+
+// lookup returns the tolerance of metric for the benchmark name set by
+// the last rule matching it, with or without its package.
+func (r toleranceRules) lookup(name, metric string) (float64, bool) {
+	for i := len(r) - 1; i >= 0; i-- {
+		rule := r[i]
+		if rule.metric == metric && rule.matches(name) {
+			return rule.tol, true
+		}
+	}
+	return 0, false
+}
+
+// matches reports whether the regexp of rule matches the benchmark
+// name, with or without its -GOMAXPROCS suffix and its package.
+func (rule toleranceRule) matches(name string) bool {
+	for _, n := range nameForms(name) {
+		base, _ := splitProcs(n)
+		if rule.pattern.MatchString(n) || rule.pattern.MatchString(base) {
+			return true
+		}
+	}
+	return false
+}
+
+// mentions reports whether r sets a tolerance for metric.
+func (r toleranceRules) mentions(metric string) bool {
+	for _, rule := range r {
+		if rule.metric == metric {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestParseThresholds(t *testing.T) {
+	in := `# hot paths
+BenchmarkHot: ns=3%, allocs=0
+
+BenchmarkHot-8: ns=5
+BenchmarkIO/read-4: MB/s=10, B/op=2.5%
+`
+	want := thresholds{
+		"BenchmarkHot":       {"ns": 3, "allocs": 0},
+		"BenchmarkHot-8":     {"ns": 5},
+		"BenchmarkIO/read-4": {"mbs": 10, "bytes": 2.5},
+	}
+	have, err := parseThresholds(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("parseThresholds: want %v have %v", want, have)
+	}
+
+	for _, bad := range []string{
+		"BenchmarkHot ns=3",
+		": ns=3",
+		"BenchmarkHot: ns",
+		"BenchmarkHot: cpu=3",
+		"BenchmarkHot: ns=-1",
+		"BenchmarkHot: ns=fast",
+	} {
+		if _, err := parseThresholds(strings.NewReader(bad)); err == nil {
+			t.Errorf("parseThresholds(%q): want error", bad)
+		}
+	}
+}
+
+func TestToleranceFor(t *testing.T) {
+	defer func(v thresholds, f bool) { tolerances, *failOnDelta = v, f }(tolerances, *failOnDelta)
+	tolerances = thresholds{
+		"BenchmarkHot":   {"ns": 3, "allocs": 0},
+		"BenchmarkHot-8": {"ns": 5},
+	}
+	*failOnDelta = true
+	ns, _ := lookupMetric("ns")
+	allocs, _ := lookupMetric("allocs")
+	tests := []struct {
+		m    metric
+		name string
+		want float64
+	}{
+		{ns, "BenchmarkHot-8", 5},
+		{ns, "BenchmarkHot-4", 3},
+		{allocs, "BenchmarkHot-8", 0},
+		{ns, "BenchmarkCold-8", *tNsPerOp},
+	}
+	for _, tt := range tests {
+		tol := tt.m.toleranceFor(tt.name)
+		if tol == nil || *tol != tt.want {
+			t.Errorf("%s tolerance of %s: want %v have %v", tt.m.name, tt.name, tt.want, tol)
+		}
+	}
+
+	b := func(allocs uint64) *parse.Benchmark {
+		return &parse.Benchmark{Name: "BenchmarkHot-8", AllocsPerOp: allocs, Measured: parse.AllocsPerOp}
+	}
+	if !allocs.exceeded(benchdiff.BenchDiff{Before: b(2), After: b(3)}) {
+		t.Error("allocs.exceeded: want a violation of the zero tolerance")
+	}
+}
+
+func TestToleranceRules(t *testing.T) {
+	defer func(r toleranceRules, v thresholds) { ruleTolerances, tolerances = r, v }(ruleTolerances, tolerances)
+	ruleTolerances = nil
+	tolerances = thresholds{"BenchmarkHotPath": {"ns": 4}}
+	for _, rule := range []string{"BenchmarkHotPath:ns/op=2%", "BenchmarkBatch.*:ns/op=15%", "BenchmarkBatch/big:ns=30", "BenchmarkBatch.*:allocs=1"} {
+		if err := ruleTolerances.Set(rule); err != nil {
+			t.Fatalf("Set(%q): %v", rule, err)
+		}
+	}
+	if want := "BenchmarkHotPath:ns=2% BenchmarkBatch.*:ns=15% BenchmarkBatch/big:ns=30% BenchmarkBatch.*:allocs=1%"; ruleTolerances.String() != want {
+		t.Errorf("String: want %q have %q", want, ruleTolerances.String())
+	}
+	for _, bad := range []string{"BenchmarkX", "BenchmarkX:ns", "Benchmark(:ns=1", "BenchmarkX:speed=1", "BenchmarkX:ns=-1"} {
+		var r toleranceRules
+		if err := r.Set(bad); err == nil {
+			t.Errorf("Set(%q): no error", bad)
+		}
+	}
+
+	ns, _ := lookupMetric("ns")
+	allocs, _ := lookupMetric("allocs")
+	tests := []struct {
+		m    metric
+		name string
+		want float64
+	}{
+		{ns, "BenchmarkHotPath-8", 2}, // the rule wins over -thresholds
+		{ns, "BenchmarkBatch/small-8", 15},
+		{ns, "BenchmarkBatch/big-8", 30}, // the last matching rule wins
+		{allocs, "BenchmarkBatch/big", 1},
+		{ns, "BenchmarkHotPathX", *tNsPerOp}, // the whole name must match
+	}
+	for _, tt := range tests {
+		tol := tt.m.toleranceFor(tt.name)
+		if tol == nil || *tol != tt.want {
+			t.Errorf("%s tolerance of %s: want %v have %v", tt.m.name, tt.name, tt.want, tol)
+		}
+	}
+}
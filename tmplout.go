@@ -0,0 +1,121 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"text/template"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// outTemplate is the template read from the -template file, nil for
+// none.
+var outTemplate *template.Template
+
+// readTemplate parses the text/template in the file at path.
+func readTemplate(path string) (*template.Template, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(filepath.Base(path)).Parse(string(data))
+}
+
+// templateData is the data -template renders.
+type templateData struct {
+	Old, New   string           // labels of the sides
+	Metrics    []templateMetric // one per metric measured by some benchmark
+	Violations []string         // tolerance violations, as -errdelta reports them
+	Pass       bool             // -errdelta passes
+}
+
+// templateMetric is the comparison of one metric for -template.
+type templateMetric struct {
+	Name, Unit                     string
+	Rows                           []templateRow // in the order of the tables, as filtered by -changed
+	Geomean                        *templateRow  // nil if there is none
+	Regressed, Improved, Unchanged int
+}
+
+// templateRow is the change of a metric for one benchmark, or their
+// geomean, for -template. Percent is +Inf for changes from zero.
+type templateRow struct {
+	Name                          string
+	Before, After, Percent        float64
+	BeforeText, AfterText, Change string // as in the tables
+	Regressed, Improved, Exceeded bool
+}
+
+// templateMetrics builds the templateData metrics of diffs.
+func templateMetrics(diffs []benchdiff.BenchDiff) []templateMetric {
+	var metrics []templateMetric
+	for _, m := range activeMetrics() {
+		m = m.scaledFor(diffs)
+		sortDiffs(diffs, m)
+		s := summarize(diffs, m)
+		tm := templateMetric{Name: m.name, Unit: m.unit, Regressed: s.regressed, Improved: s.improved, Unchanged: s.unmoved}
+		measured := false
+		for _, diff := range diffs {
+			if !m.measuredIn(diff) {
+				continue
+			}
+			measured = true
+			if !shown(m, diff) {
+				continue
+			}
+			d := m.delta(diff)
+			before, after := m.cells(diff)
+			tm.Rows = append(tm.Rows, templateRow{
+				Name:       diff.Name(),
+				Before:     d.Before,
+				After:      d.After,
+				Percent:    d.Percent(),
+				BeforeText: before,
+				AfterText:  after,
+				Change:     m.changeCell(diff),
+				Regressed:  m.regressed(diff),
+				Improved:   m.improved(diff),
+				Exceeded:   m.exceeded(diff),
+			})
+		}
+		if !measured {
+			continue
+		}
+		if before, after, ratio, ok := summaryGeomeans(diffs, m); ok {
+			cells, _ := m.summaryRow(diffs)
+			tm.Geomean = &templateRow{
+				Name:       summaryName,
+				Before:     before,
+				After:      after,
+				Percent:    100*ratio - 100,
+				BeforeText: cells[1],
+				AfterText:  cells[2],
+				Change:     cells[3],
+				Regressed:  ratio > 1 != m.higher && ratio != 1,
+				Improved:   ratio > 1 == m.higher && ratio != 1,
+			}
+		}
+		metrics = append(metrics, tm)
+	}
+	return metrics
+}
+
+// writeTemplate renders diffs through t to w.
+func writeTemplate(w io.Writer, t *template.Template, diffs []benchdiff.BenchDiff) ([]violation, error) {
+	violations := collectViolations(diffs)
+	data := templateData{
+		Old:     *oldLabel,
+		New:     *newLabel,
+		Metrics: templateMetrics(diffs),
+		Pass:    gateFailure(violations) == "",
+	}
+	for _, v := range violations {
+		data.Violations = append(data.Violations, v.message())
+	}
+	return violations, t.Execute(w, data)
+}
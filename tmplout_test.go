@@ -0,0 +1,64 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+func TestWriteTemplate(t *testing.T) {
+	defer func(f bool, tol float64) { *failOnDelta, *tNsPerOp = f, tol }(*failOnDelta, *tNsPerOp)
+	*failOnDelta, *tNsPerOp = true, 10
+	dir, err := ioutil.TempDir("", "benchdiff-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "slack.tmpl")
+	text := `{{.Old}} vs {{.New}}{{if not .Pass}} FAIL{{end}}
+{{range .Metrics}}{{.Unit}}: {{.Regressed}} regressed, {{.Improved}} improved, {{.Unchanged}} unchanged
+{{range .Rows}}{{.Name}} {{.BeforeText}} {{.AfterText}} {{.Change}} {{.Percent}}{{if .Exceeded}} !{{end}}
+{{end}}{{with .Geomean}}{{.Name}} {{.Change}}
+{{end}}{{end}}{{range .Violations}}{{.}}
+{{end}}`
+	if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := readTemplate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("BenchmarkA", 0, 100, 150),
+		nsDiff("BenchmarkB", 1, 100, 100),
+	}
+	var buf bytes.Buffer
+	violations, err := writeTemplate(&buf, tmpl, diffs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `old vs new FAIL
+ns/op: 1 regressed, 0 improved, 1 unchanged
+BenchmarkA 100 150 +50.00% 50 !
+BenchmarkB 100 100 +0.00% 0
+[geomean] +22.47%
+BenchmarkA: +50.00% ns/op delta between benchmarks
+`
+	if have := buf.String(); have != want {
+		t.Errorf("writeTemplate: want\n%s\nhave\n%s", want, have)
+	}
+	if len(violations) != 1 {
+		t.Errorf("writeTemplate: want 1 violation have %v", violations)
+	}
+	if _, err := readTemplate(filepath.Join(dir, "missing.tmpl")); err == nil {
+		t.Error("readTemplate of a missing file: want an error")
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// toleranceValue is the flag.Value of a tolerance flag. A plain number
+// or a percentage, as "5" or "5%", sets the percent tolerance; a value
+// with a unit of the metric, as "200ns", sets its absolute tolerance as
+// the matching -abs flag does.
+type toleranceValue struct {
+	pct   *float64
+	abs   *float64
+	units []string // units of absolute values; "ns" accepts any duration
+}
+
+// toleranceFlag defines a tolerance flag with the given name and usage,
+// in percent by default, whose absolute values with one of units set
+// abs. It returns the address of the percent tolerance.
+func toleranceFlag(name string, abs *float64, units []string, usage string) *float64 {
+	v := &toleranceValue{pct: new(float64), abs: abs, units: units}
+	flag.Var(v, name, usage)
+	return v.pct
+}
+
+func (v toleranceValue) String() string {
+	if v.pct == nil {
+		return "0"
+	}
+	return strconv.FormatFloat(*v.pct, 'g', -1, 64)
+}
+
+func (v toleranceValue) Set(s string) error {
+	if pct, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64); err == nil {
+		if pct < 0 {
+			return errors.New("negative tolerance")
+		}
+		*v.pct = pct
+		return nil
+	}
+	abs, ok := v.absolute(s)
+	if !ok && len(v.units) == 0 {
+		return errors.New("want a percentage")
+	}
+	if !ok {
+		return errors.New("want a percentage or a value in " + strings.Join(v.units, ", "))
+	}
+	if abs < 0 {
+		return errors.New("negative tolerance")
+	}
+	*v.abs = abs
+	return nil
+}
+
+// absolute parses s as a value with one of the units of v.
+func (v toleranceValue) absolute(s string) (float64, bool) {
+	for _, unit := range v.units {
+		if unit == "ns" {
+			if d, err := time.ParseDuration(s); err == nil {
+				return float64(d), true
+			}
+			continue
+		}
+		if !strings.HasSuffix(s, unit) {
+			continue
+		}
+		if f, err := strconv.ParseFloat(strings.TrimSuffix(s, unit), 64); err == nil {
+			return f, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,54 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestToleranceValue(t *testing.T) {
+	cases := []struct {
+		units    []string
+		s        string
+		pct, abs float64
+		err      bool
+	}{
+		{[]string{"ns"}, "5", 5, 0, false},
+		{[]string{"ns"}, "2.5%", 2.5, 0, false},
+		{[]string{"ns"}, "200ns", 0, 200, false},
+		{[]string{"ns"}, "1.5µs", 0, 1500, false},
+		{[]string{"ns"}, "2ms", 0, 2e6, false},
+		{[]string{"MB/s"}, "5MB/s", 0, 5, false},
+		{[]string{"allocs/op", "allocs"}, "2allocs", 0, 2, false},
+		{[]string{"allocs/op", "allocs"}, "2allocs/op", 0, 2, false},
+		{[]string{"B/op", "B"}, "64B", 0, 64, false},
+		{[]string{"B/op", "B"}, "64ns", 0, 0, true},
+		{[]string{"ns"}, "-3%", 0, 0, true},
+		{[]string{"ns"}, "fast", 0, 0, true},
+	}
+	for _, tt := range cases {
+		v := toleranceValue{pct: new(float64), abs: new(float64), units: tt.units}
+		err := v.Set(tt.s)
+		if (err != nil) != tt.err {
+			t.Errorf("Set(%q): want error %t have %v", tt.s, tt.err, err)
+			continue
+		}
+		if *v.pct != tt.pct || *v.abs != tt.abs {
+			t.Errorf("Set(%q): want %v%%, %v absolute have %v%%, %v", tt.s, tt.pct, tt.abs, *v.pct, *v.abs)
+		}
+	}
+}
+
+func TestToleranceFlagAbsolute(t *testing.T) {
+	defer func(v float64) { *absNsPerOp = v }(*absNsPerOp)
+	defer func(v float64) { *tNsPerOp = v }(*tNsPerOp)
+	if err := flag.Set("tnsop", "200ns"); err != nil {
+		t.Fatal(err)
+	}
+	if *absNsPerOp != 200 || *tNsPerOp != 0 {
+		t.Errorf("-tnsop=200ns: want -absnsop 200 and -tnsop 0 have %v and %v", *absNsPerOp, *tNsPerOp)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// treeNode groups the benchmarks sharing a '/' separated name prefix.
+type treeNode struct {
+	name     string                // last name segment
+	diffs    []benchdiff.BenchDiff // benchmarks named exactly after this node
+	children []*treeNode           // in parse order
+	index    map[string]*treeNode
+}
+
+// buildTree arranges diffs by their '/' separated name segments.
+// Children are kept in parse order.
+func buildTree(diffs []benchdiff.BenchDiff) *treeNode {
+	sorted := make([]benchdiff.BenchDiff, len(diffs))
+	copy(sorted, diffs)
+	sort.Sort(benchdiff.ByParseOrder(sorted))
+
+	root := &treeNode{}
+	for _, diff := range sorted {
+		n := root
+		for _, seg := range strings.Split(diff.Name(), "/") {
+			n = n.child(seg)
+		}
+		n.diffs = append(n.diffs, diff)
+	}
+	return root
+}
+
+// child returns the child of n with the given name, creating it if needed.
+func (n *treeNode) child(name string) *treeNode {
+	if c, ok := n.index[name]; ok {
+		return c
+	}
+	if n.index == nil {
+		n.index = make(map[string]*treeNode)
+	}
+	c := &treeNode{name: name}
+	n.index[name] = c
+	n.children = append(n.children, c)
+	return c
+}
+
+// subtree returns the diffs of n and of all its descendants.
+func (n *treeNode) subtree() []benchdiff.BenchDiff {
+	all := append([]benchdiff.BenchDiff(nil), n.diffs...)
+	for _, c := range n.children {
+		all = append(all, c.subtree()...)
+	}
+	return all
+}
+
+// visible returns the diffs of n that measured m and pass -changed or
+// -changed-metric.
+func (n *treeNode) visible(m metric) []benchdiff.BenchDiff {
+	var vv []benchdiff.BenchDiff
+	for _, diff := range n.diffs {
+		if m.measuredIn(diff) && (!m.hidesUnchanged() || hasChanged(m.delta(diff))) {
+			vv = append(vv, diff)
+		}
+	}
+	return vv
+}
+
+// printTree writes the tree rooted at root for metric m.
+func printTree(w io.Writer, root *treeNode, m metric) {
+	fmt.Fprint(w, m.header())
+	for _, c := range root.children {
+		printNode(w, c, m, "")
+	}
+}
+
+// printNode writes n and its descendants, indented by indent.
+// It reports whether anything was written.
+func printNode(w io.Writer, n *treeNode, m metric, indent string) bool {
+	printed := false
+	for _, diff := range n.visible(m) {
+		before, after := m.cells(diff)
+		fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\n", indent, n.name, before, after, m.changeCell(diff))
+		printed = true
+	}
+	if len(n.children) == 0 {
+		return printed
+	}
+
+	// The branch row is written once we know a descendant is visible.
+	branch := fmt.Sprintf("%s%s/\t\t\t%s\n", indent, n.name, branchChange(n, m))
+	var buf strings.Builder
+	for _, c := range n.children {
+		printNode(&buf, c, m, indent+"  ")
+	}
+	if buf.Len() == 0 {
+		return printed
+	}
+	io.WriteString(w, branch)
+	io.WriteString(w, buf.String())
+	return true
+}
+
+// branchChange formats the geometric mean of the changes below n.
+func branchChange(n *treeNode, m metric) string {
+	g, ok := ratioGeomean(n.subtree(), m)
+	if !ok {
+		return "n/a"
+	}
+	return formatPercent(benchdiff.Delta{Before: 1, After: g})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func nsDiff(name string, ord int, before, after float64) benchdiff.BenchDiff {
+	return benchdiff.BenchDiff{
+		Before: &parse.Benchmark{Name: name, NsPerOp: before, Measured: parse.NsPerOp, Ord: ord},
+		After:  &parse.Benchmark{Name: name, NsPerOp: after, Measured: parse.NsPerOp, Ord: ord},
+	}
+}
+
+func TestPrintTree(t *testing.T) {
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("BenchmarkFlat-8", 3, 10, 12),
+		nsDiff("BenchmarkDB/Query/WithIndex-8", 0, 100, 90),
+		nsDiff("BenchmarkDB/Query/NoIndex-8", 1, 100, 110),
+		nsDiff("BenchmarkDB/Insert-8", 2, 50, 50),
+	}
+	ns, _ := lookupMetric("ns")
+
+	var buf strings.Builder
+	printTree(&buf, buildTree(diffs), ns)
+	want := strings.Join([]string{
+		"benchmark\told ns/op\tnew ns/op\tdelta",
+		"BenchmarkDB/\t\t\t-0.33%",
+		"  Query/\t\t\t-0.50%",
+		"    WithIndex-8\t100\t90.0\t-10.00%",
+		"    NoIndex-8\t100\t110\t+10.00%",
+		"  Insert-8\t50.0\t50.0\t+0.00%",
+		"BenchmarkFlat-8\t10.0\t12.0\t+20.00%",
+		"",
+	}, "\n")
+	if have := buf.String(); have != want {
+		t.Errorf("printTree: want\n%s\nhave\n%s", want, have)
+	}
+
+	defer func(v bool) { *changedOnly = v }(*changedOnly)
+	*changedOnly = true
+	buf.Reset()
+	printTree(&buf, buildTree(diffs[2:]), ns)
+	if have := buf.String(); strings.Contains(have, "Insert") || !strings.Contains(have, "BenchmarkDB/") {
+		t.Errorf("printTree with -changed: unexpected output\n%s", have)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// sparkTicks are the characters of a sparkline, from the lowest value
+// to the highest.
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline returns one tick per value of xs, scaled between the lowest
+// and the highest of them. A flat series is drawn with the lowest tick.
+func sparkline(xs []float64) string {
+	if len(xs) == 0 {
+		return ""
+	}
+	lo, hi := minimum(xs), maximum(xs)
+	ticks := make([]rune, len(xs))
+	for i, x := range xs {
+		var n int
+		if hi > lo {
+			n = int((x - lo) / (hi - lo) * float64(len(sparkTicks)-1))
+		}
+		ticks[i] = sparkTicks[n]
+	}
+	return string(ticks)
+}
+
+// series returns the ns/op of r in the order of the files, the old
+// value first.
+func (r multiRow) series() []float64 {
+	xs := []float64{r[0].Before.NsPerOp}
+	for _, diff := range r {
+		xs = append(xs, diff.After.NsPerOp)
+	}
+	return xs
+}
+
+// printTrend writes one row per benchmark measuring ns/op in every
+// file: its ns/op in the first and the last file, the change between
+// them and a sparkline of its ns/op across all the files. With -changed
+// or -changed-metric=ns only the rows changing in some file are shown.
+func printTrend(w io.Writer, rows []multiRow) {
+	ns, _ := lookupMetric("ns")
+	var header bool
+	for _, row := range rows {
+		if !row.measures(ns) || ns.hidesUnchanged() && !row.changed(ns) {
+			continue
+		}
+		if !header {
+			fmt.Fprint(w, "benchmark\tfirst ns/op\tlast ns/op\tdelta\ttrend\n")
+			header = true
+		}
+		xs := row.series()
+		d := benchdiff.Delta{Before: xs[0], After: xs[len(xs)-1]}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", displayName(row[0]),
+			formatNs(d.Before), formatNs(d.After), formatPercent(d), sparkline(xs))
+	}
+}
+
+// compareTrend writes to out the trend of the ns/op of the benchmarks
+// across the files at paths, in order.
+func compareTrend(out io.Writer, paths []string) {
+	rows, unmatched := correlateFiles(paths)
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 0, defaultPadding, ' ', 0)
+	printTrend(w, rows)
+	w.Flush()
+	if *showUnmatch {
+		printNames(out, "unmatched (not in every file)", unmatched)
+	}
+}
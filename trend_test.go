@@ -0,0 +1,65 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"text/tabwriter"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestSparkline(t *testing.T) {
+	for _, tt := range []struct {
+		xs   []float64
+		want string
+	}{
+		{nil, ""},
+		{[]float64{5}, "▁"},
+		{[]float64{3, 3, 3}, "▁▁▁"},
+		{[]float64{0, 1, 2, 3, 4, 5, 6, 7}, "▁▂▃▄▅▆▇█"},
+		{[]float64{200, 260, 200}, "▁█▁"},
+	} {
+		if have := sparkline(tt.xs); have != tt.want {
+			t.Errorf("sparkline(%v) = %q, want %q", tt.xs, have, tt.want)
+		}
+	}
+}
+
+func TestPrintTrend(t *testing.T) {
+	defer func(c bool) { *changedOnly = c }(*changedOnly)
+	ns := func(name string, ord int, v float64) []*parse.Benchmark {
+		return []*parse.Benchmark{{Name: name, NsPerOp: v, Measured: parse.NsPerOp, Ord: ord}}
+	}
+	before := parse.Set{"BenchmarkA": ns("BenchmarkA", 0, 200), "BenchmarkB": ns("BenchmarkB", 1, 10)}
+	afters := []parse.Set{
+		{"BenchmarkA": ns("BenchmarkA", 0, 220), "BenchmarkB": ns("BenchmarkB", 1, 10)},
+		{"BenchmarkA": ns("BenchmarkA", 0, 260), "BenchmarkB": ns("BenchmarkB", 1, 10)},
+	}
+	rows, _ := correlateMany(before, afters)
+
+	for _, tt := range []struct {
+		changed bool
+		want    string
+	}{
+		{false, `benchmark      first ns/op     last ns/op     delta       trend
+BenchmarkA     200             260            +30.00%     ▁▃█
+BenchmarkB     10.0            10.0           +0.00%      ▁▁▁
+`},
+		{true, `benchmark      first ns/op     last ns/op     delta       trend
+BenchmarkA     200             260            +30.00%     ▁▃█
+`},
+	} {
+		*changedOnly = tt.changed
+		var buf bytes.Buffer
+		w := tabwriter.NewWriter(&buf, 0, 0, 5, ' ', 0)
+		printTrend(w, rows)
+		w.Flush()
+		if have := buf.String(); have != tt.want {
+			t.Errorf("printTrend -changed=%t: want\n%s\nhave\n%s", tt.changed, tt.want, have)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// insignificantMark follows the changes that -alpha found not
+// significant.
+const insignificantMark = " ~"
+
+// Significance tests selected with -stats.
+const (
+	statsWelch = "welch"
+	statsUTest = "utest"
+)
+
+// pValues holds, by metric name then benchmark name, the p-value of the
+// -stats test on the benchmarks with repeated results, for -alpha.
+var pValues map[string]map[string]float64
+
+// metricPValues returns, by metric name then benchmark name, the p-value
+// of the -stats test on the results of each active metric of the
+// benchmarks having at least two of them on both sides.
+func metricPValues(before, after parse.Set) map[string]map[string]float64 {
+	ps := make(map[string]map[string]float64)
+	for _, m := range activeMetrics() {
+		ps[m.name] = make(map[string]float64)
+		for name, bb := range before {
+			if p, ok := pValue(m.samples(bb), m.samples(after[name])); ok {
+				ps[m.name][name] = p
+			}
+		}
+	}
+	return ps
+}
+
+// pValue returns the two-tailed p-value of the -stats test on before
+// and after. It reports false if a side has less than two values.
+func pValue(before, after []float64) (float64, bool) {
+	if *statsTest == statsUTest {
+		if len(before) < 2 || len(after) < 2 {
+			return 0, false
+		}
+		return mannWhitneyU(before, after), true
+	}
+	t, df, ok := welchT(before, after)
+	if !ok {
+		return 0, false
+	}
+	return tPValue(t, df), true
+}
+
+// insignificant reports whether -alpha is set and the change of m in
+// diff is not significant at that level.
+func (m metric) insignificant(diff benchdiff.BenchDiff) bool {
+	if *alpha <= 0 {
+		return false
+	}
+	p, ok := pValues[m.name][diff.Name()]
+	return ok && p > *alpha
+}
+
+// stddev returns the sample standard deviation of xs, which must have
+// at least two values.
+func stddev(xs []float64) float64 {
+	return math.Sqrt(variance(xs))
+}
+
+// welchT returns Welch's t statistic of the difference of the means of
+// before and after, and its degrees of freedom. Without deviation, any
+// difference is infinite. It reports false if a side has less than two
+// values.
+func welchT(before, after []float64) (t, df float64, ok bool) {
+	nb, na := float64(len(before)), float64(len(after))
+	if nb < 2 || na < 2 {
+		return 0, 0, false
+	}
+	vb, va := variance(before)/nb, variance(after)/na
+	diff := mean(after) - mean(before)
+	if vb+va == 0 {
+		if diff == 0 {
+			return 0, nb + na - 2, true
+		}
+		return math.Inf(int(math.Copysign(1, diff))), nb + na - 2, true
+	}
+	t = diff / math.Sqrt(vb+va)
+	df = (vb + va) * (vb + va) / (vb*vb/(nb-1) + va*va/(na-1))
+	return t, df, true
+}
+
+// tPValue returns the two-tailed p-value of t in a Student's t
+// distribution with df degrees of freedom.
+func tPValue(t, df float64) float64 {
+	if math.IsInf(t, 0) {
+		return 0
+	}
+	return betaInc(df/(df+t*t), df/2, 0.5)
+}
+
+// betaInc returns the regularized incomplete beta function I_x(a, b),
+// evaluated with its continued fraction.
+func betaInc(x, a, b float64) float64 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 1:
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+	// The continued fraction converges quickly only below this point;
+	// use the symmetry I_x(a, b) = 1 - I_1-x(b, a) above it.
+	if x > (a+1)/(a+b+2) {
+		return 1 - front*betaFraction(1-x, b, a)/b
+	}
+	return front * betaFraction(x, a, b) / a
+}
+
+// betaFraction evaluates the continued fraction of betaInc with the
+// modified Lentz's method.
+func betaFraction(x, a, b float64) float64 {
+	const (
+		tiny = 1e-300
+		eps  = 1e-14
+	)
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	f := d
+	for m := 1; m <= 300; m++ {
+		fm := float64(m)
+		for _, num := range []float64{
+			fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm)),
+			-(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1)),
+		} {
+			d = 1 + num*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + num/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			f *= c * d
+		}
+		if math.Abs(c*d-1) < eps {
+			break
+		}
+	}
+	return f
+}
@@ -0,0 +1,94 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"testing"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestStddev(t *testing.T) {
+	if have := stddev([]float64{2, 4, 4, 4, 5, 5, 7, 9}); math.Abs(have-math.Sqrt(32.0/7)) > 1e-9 {
+		t.Errorf("stddev: want %f have %f", math.Sqrt(32.0/7), have)
+	}
+}
+
+func TestWelchT(t *testing.T) {
+	tt, df, ok := welchT([]float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10})
+	if !ok || math.Abs(tt-5) > 1e-9 || math.Abs(df-8) > 1e-9 {
+		t.Errorf("welchT: want 5, 8, true have %f, %f, %t", tt, df, ok)
+	}
+	if tt, _, _ := welchT([]float64{3, 3}, []float64{4, 4}); !math.IsInf(tt, 1) {
+		t.Errorf("welchT without deviation: want +Inf have %f", tt)
+	}
+	if _, _, ok := welchT([]float64{1}, []float64{1, 2}); ok {
+		t.Error("welchT: want false with a single value")
+	}
+}
+
+func TestTPValue(t *testing.T) {
+	cases := []struct {
+		t, df, want float64
+	}{
+		{0, 5, 1},
+		{1, 1, 0.5},                        // Cauchy distribution
+		{math.Sqrt2, 2, 1 - math.Sqrt2/2},  // 1 - t/sqrt(t²+2)
+		{-math.Sqrt2, 2, 1 - math.Sqrt2/2}, // two-tailed
+		{5, 8, 0.0010528},                  // t tables
+		{math.Inf(1), 3, 0},
+	}
+	for _, tt := range cases {
+		if have := tPValue(tt.t, tt.df); math.Abs(have-tt.want) > 1e-6 {
+			t.Errorf("tPValue(%f, %f): want %f have %f", tt.t, tt.df, tt.want, have)
+		}
+	}
+}
+
+func TestInsignificant(t *testing.T) {
+	defer func(v float64) { *alpha = v }(*alpha)
+	defer func(ps map[string]map[string]float64) { pValues = ps }(pValues)
+	*alpha = 0.05
+	pValues = metricPValues(parse.Set{
+		"BenchmarkNoisy": nsRuns("BenchmarkNoisy", 100, 120, 90, 110),
+		"BenchmarkFast":  nsRuns("BenchmarkFast", 100, 101, 99, 100),
+		"BenchmarkOnce":  nsRuns("BenchmarkOnce", 100),
+	}, parse.Set{
+		"BenchmarkNoisy": nsRuns("BenchmarkNoisy", 105, 95, 115, 100),
+		"BenchmarkFast":  nsRuns("BenchmarkFast", 80, 81, 79, 80),
+		"BenchmarkOnce":  nsRuns("BenchmarkOnce", 50),
+	})
+	ns, _ := lookupMetric("ns")
+	for name, want := range map[string]bool{"BenchmarkNoisy": true, "BenchmarkFast": false, "BenchmarkOnce": false} {
+		if have := ns.insignificant(nsDiff(name, 0, 100, 100)); have != want {
+			t.Errorf("insignificant(%s): want %t have %t", name, want, have)
+		}
+	}
+}
+
+func TestInsignificantUTest(t *testing.T) {
+	defer func(v float64) { *alpha = v }(*alpha)
+	defer func(v string) { *statsTest = v }(*statsTest)
+	defer func(ps map[string]map[string]float64) { pValues = ps }(pValues)
+	*alpha, *statsTest = 0.05, statsUTest
+	pValues = metricPValues(parse.Set{
+		"BenchmarkNoisy": nsRuns("BenchmarkNoisy", 100, 120, 90, 110),
+		"BenchmarkFast":  nsRuns("BenchmarkFast", 100, 101, 99, 100),
+	}, parse.Set{
+		"BenchmarkNoisy": nsRuns("BenchmarkNoisy", 105, 95, 115, 100),
+		"BenchmarkFast":  nsRuns("BenchmarkFast", 80, 81, 79, 80),
+	})
+	ns, _ := lookupMetric("ns")
+	for name, want := range map[string]bool{"BenchmarkNoisy": true, "BenchmarkFast": false} {
+		if have := ns.insignificant(nsDiff(name, 0, 100, 100)); have != want {
+			t.Errorf("insignificant(%s) with -stats=utest: want %t have %t", name, want, have)
+		}
+	}
+	// Metrics not measured by the results have no p-value.
+	if allocs, _ := lookupMetric("allocs"); allocs.insignificant(nsDiff("BenchmarkNoisy", 0, 100, 100)) {
+		t.Error("insignificant allocs/op: want false without allocs/op results")
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// tuiPrompt is the prompt of -tui.
+const tuiPrompt = "benchdiff> "
+
+// tuiHelp lists the commands of -tui.
+const tuiHelp = `commands:
+	sort key       sort the rows as -sort=key (name, delta, old, new, a metric, :asc or :desc)
+	filter regexp  show only the benchmarks matching regexp, every one without it
+	metric name    hide or show the block of the metric name (ns, mbs, allocs, bytes...)
+	show name      list the samples of the benchmark name
+	help           print this list
+	quit           leave
+`
+
+// explorer is the state of the tables of -tui.
+type explorer struct {
+	diffs                  []benchdiff.BenchDiff
+	oldSamples, newSamples parse.Set
+	filter                 *regexp.Regexp // nil for every benchmark
+	hidden                 map[string]bool
+	padding                int
+	clear                  bool // clear the screen before redrawing the tables
+}
+
+// explore reads the commands of -tui from in, one per line, redrawing
+// the tables of diffs on out after each of them until "quit" or the end
+// of in. The samples of both sides are listed by "show". The tables are
+// written with padding spaces between the columns and, if clear is set,
+// on a cleared screen.
+func explore(in io.Reader, out io.Writer, diffs []benchdiff.BenchDiff, oldSamples, newSamples parse.Set, padding int, clear bool) {
+	e := &explorer{
+		diffs:      diffs,
+		oldSamples: oldSamples,
+		newSamples: newSamples,
+		hidden:     make(map[string]bool),
+		padding:    padding,
+		clear:      clear,
+	}
+	e.draw(out, "")
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, tuiPrompt)
+		if !scanner.Scan() {
+			fmt.Fprint(out, "\n")
+			return
+		}
+		cmd, arg := strings.TrimSpace(scanner.Text()), ""
+		if i := strings.IndexAny(cmd, " \t"); i >= 0 {
+			cmd, arg = cmd[:i], strings.TrimSpace(cmd[i+1:])
+		}
+		switch cmd {
+		case "":
+			continue
+		case "quit", "q":
+			return
+		case "help":
+			fmt.Fprint(out, tuiHelp)
+			continue
+		case "show":
+			e.show(out, arg)
+			continue
+		}
+		e.draw(out, e.run(cmd, arg))
+	}
+}
+
+// run applies the table command cmd with its argument arg and returns
+// the message to print after the tables, "" for none.
+func (e *explorer) run(cmd, arg string) string {
+	switch cmd {
+	case "sort":
+		if _, err := parseSortOrder(arg); err != nil {
+			return err.Error()
+		}
+		*sortKey, *magSort = arg, false
+	case "filter":
+		if arg == "" {
+			e.filter = nil
+			break
+		}
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return err.Error()
+		}
+		e.filter = re
+	case "metric":
+		name, ok := metricAliases[arg]
+		if !ok {
+			if _, ok = lookupMetric(arg); !ok {
+				return fmt.Sprintf("unknown metric %q", arg)
+			}
+			name = arg
+		}
+		if selectedMetrics != nil && !selectedMetrics[name] {
+			return fmt.Sprintf("%s is not selected by -metrics", arg)
+		}
+		e.hidden[name] = !e.hidden[name]
+	default:
+		return fmt.Sprintf("unknown command %q, try help", cmd)
+	}
+	return ""
+}
+
+// draw writes the tables of the benchmarks kept by the filter, without
+// the hidden metrics, followed by msg.
+func (e *explorer) draw(out io.Writer, msg string) {
+	if e.clear {
+		fmt.Fprint(out, clearScreen)
+	}
+	diffs := e.diffs
+	if e.filter != nil {
+		diffs = nil
+		for _, diff := range e.diffs {
+			if e.filter.MatchString(diff.Name()) {
+				diffs = append(diffs, diff)
+			}
+		}
+	}
+	w := tabwriter.NewWriter(out, 0, 0, e.padding, ' ', 0)
+	printBlocks(w, diffs, terminalStyle, func(m metric, diff benchdiff.BenchDiff) bool {
+		return !e.hidden[m.name] && shown(m, diff)
+	}, nil)
+	w.Flush()
+	var status []string
+	if e.filter != nil {
+		status = append(status, fmt.Sprintf("filter %s: %d of %d benchmarks", e.filter, len(diffs), len(e.diffs)))
+	}
+	for _, m := range activeMetrics() {
+		if e.hidden[m.name] {
+			status = append(status, "hiding "+m.name)
+		}
+	}
+	if msg != "" {
+		status = append(status, msg)
+	}
+	if len(status) > 0 {
+		fmt.Fprintf(out, "\n%s\n", strings.Join(status, "; "))
+	}
+}
+
+// show writes the old and new samples of the benchmark name, as in the
+// input.
+func (e *explorer) show(out io.Writer, name string) {
+	if len(e.oldSamples[name]) == 0 && len(e.newSamples[name]) == 0 {
+		fmt.Fprintf(out, "no benchmark %q\n", name)
+		return
+	}
+	for _, b := range e.oldSamples[name] {
+		fmt.Fprintf(out, "%s: %v\n", *oldLabel, b)
+	}
+	for _, b := range e.newSamples[name] {
+		fmt.Fprintf(out, "%s: %v\n", *newLabel, b)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestExplore(t *testing.T) {
+	defer func(key string) { *sortKey = key }(*sortKey)
+	a := &parse.Benchmark{Name: "BenchmarkA", N: 10, NsPerOp: 100, Measured: parse.NsPerOp}
+	diffs := []benchdiff.BenchDiff{
+		{Before: a, After: &parse.Benchmark{Name: "BenchmarkA", N: 10, NsPerOp: 110, Measured: parse.NsPerOp, Ord: 0}},
+		nsDiff("BenchmarkB", 1, 100, 200),
+	}
+	samples := parse.Set{"BenchmarkA": {a}}
+	in := strings.NewReader("sort delta\nfilter B$\nshow BenchmarkA\nmetric ns\nmetric nope\nquit\nfilter A\n")
+	var buf bytes.Buffer
+	explore(in, &buf, diffs, samples, parse.Set{}, 5, false)
+	want := `benchmark      old ns/op     new ns/op     delta
+BenchmarkA     100           110           +10.00%
+BenchmarkB     100           200           +100.00%
+[geomean]      100           148           +48.32%
+benchdiff> benchmark      old ns/op     new ns/op     delta
+BenchmarkB     100           200           +100.00%
+BenchmarkA     100           110           +10.00%
+[geomean]      100           148           +48.32%
+benchdiff> benchmark      old ns/op     new ns/op     delta
+BenchmarkB     100           200           +100.00%
+[geomean]      100           200           +100.00%
+
+filter B$: 1 of 2 benchmarks
+benchdiff> old: BenchmarkA 10 100.00 ns/op
+benchdiff> 
+filter B$: 1 of 2 benchmarks; hiding ns
+benchdiff> 
+filter B$: 1 of 2 benchmarks; hiding ns; unknown metric "nope"
+benchdiff> `
+	if have := buf.String(); have != want {
+		t.Errorf("explore: want\n%s\nhave\n%s", want, have)
+	}
+
+	defer func(m map[string]bool) { selectedMetrics = m }(selectedMetrics)
+	selectedMetrics = map[string]bool{"ns": true}
+	buf.Reset()
+	explore(strings.NewReader("metric allocs\nquit\n"), &buf, diffs, samples, parse.Set{}, 5, false)
+	if !strings.Contains(buf.String(), "allocs is not selected by -metrics") {
+		t.Errorf("explore metric allocs with -metrics=ns: want an error, have\n%s", buf.String())
+	}
+}
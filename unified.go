@@ -0,0 +1,74 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// printUnified writes a single table with one row per benchmark of
+// diffs and, for each metric measured by some of them, the old value,
+// the new value and the change, n/a for the metrics it did not measure.
+// Benchmarks are sorted like the ns/op table and the table ends with the
+// geomean of each metric.
+func printUnified(w io.Writer, diffs []benchdiff.BenchDiff) {
+	var mm []metric
+	for _, m := range activeMetrics() {
+		m = m.scaledFor(diffs)
+		for _, diff := range diffs {
+			if m.measuredIn(diff) {
+				mm = append(mm, m)
+				break
+			}
+		}
+	}
+	if len(mm) == 0 {
+		return
+	}
+	sortDiffs(diffs, mm[0])
+	header := []string{"benchmark"}
+	for _, m := range mm {
+		header = append(header, *oldLabel+" "+m.column, *newLabel+" "+m.column, m.changeCol)
+	}
+	fmt.Fprint(w, strings.Join(header, "\t")+"\n")
+	for _, diff := range diffs {
+		cells := []string{displayName(diff)}
+		filtered, changed := false, false
+		for _, m := range mm {
+			if !m.measuredIn(diff) {
+				cells = append(cells, "n/a", "n/a", "n/a")
+				continue
+			}
+			before, after := m.cells(diff)
+			cells = append(cells, before, after, m.changeCell(diff))
+			if m.hidesUnchanged() {
+				filtered = true
+				changed = changed || hasChanged(m.delta(diff))
+			}
+		}
+		if filtered && !changed {
+			continue
+		}
+		fmt.Fprint(w, strings.Join(cells, "\t")+"\n")
+	}
+	cells := []string{summaryName}
+	var ok bool
+	for _, m := range mm {
+		s, sok := m.summaryRow(diffs)
+		if !sok {
+			cells = append(cells, "n/a", "n/a", "n/a")
+			continue
+		}
+		cells = append(cells, s[1:4]...)
+		ok = true
+	}
+	if ok {
+		fmt.Fprint(w, strings.Join(cells, "\t")+"\n")
+	}
+}
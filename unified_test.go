@@ -0,0 +1,52 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestPrintUnified(t *testing.T) {
+	defer func(c bool) { *changedOnly = c }(*changedOnly)
+	mem := parse.NsPerOp | parse.AllocsPerOp | parse.AllocedBytesPerOp
+	diffs := []benchdiff.BenchDiff{
+		{
+			Before: &parse.Benchmark{Name: "BenchmarkB", NsPerOp: 100, AllocsPerOp: 2, AllocedBytesPerOp: 64, Measured: mem, Ord: 1},
+			After:  &parse.Benchmark{Name: "BenchmarkB", NsPerOp: 90, AllocsPerOp: 1, AllocedBytesPerOp: 32, Measured: mem, Ord: 1},
+		},
+		nsDiff("BenchmarkA", 0, 10, 12),
+		nsDiff("BenchmarkC", 2, 50, 50),
+	}
+	for _, tt := range []struct {
+		changed bool
+		want    string
+	}{
+		{false, `benchmark  old ns/op new ns/op delta   old allocs new allocs delta   old bytes new bytes delta
+BenchmarkA 10.0      12.0      +20.00% n/a        n/a        n/a     n/a       n/a       n/a
+BenchmarkB 100       90.0      -10.00% 2          1          -50.00% 64        32        -50.00%
+BenchmarkC 50.0      50.0      +0.00%  n/a        n/a        n/a     n/a       n/a       n/a
+[geomean]  36.8      37.8      +2.60%  2          1          -50.00% 64        32        -50.00%
+`},
+		{true, `benchmark  old ns/op new ns/op delta   old allocs new allocs delta   old bytes new bytes delta
+BenchmarkA 10.0      12.0      +20.00% n/a        n/a        n/a     n/a       n/a       n/a
+BenchmarkB 100       90.0      -10.00% 2          1          -50.00% 64        32        -50.00%
+[geomean]  36.8      37.8      +2.60%  2          1          -50.00% 64        32        -50.00%
+`},
+	} {
+		*changedOnly = tt.changed
+		var buf bytes.Buffer
+		w := tabwriter.NewWriter(&buf, 0, 0, 1, ' ', 0)
+		printUnified(w, diffs)
+		w.Flush()
+		if have := buf.String(); have != tt.want {
+			t.Errorf("printUnified -changed=%t:\nwant:\n%s\nhave:\n%s", tt.changed, tt.want, have)
+		}
+	}
+}
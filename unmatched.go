@@ -0,0 +1,49 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// removed and added hold the names of the benchmarks found only in the
+// old or only in the new file, set by prepare for -showunmatched.
+var removed, added []string
+
+// correlateWarnings are the benchmarks prepare could not correlate,
+// reported in the JSON and CSV output.
+var correlateWarnings []benchdiff.Warning
+
+// strictFailure returns the message -strict fails with if some
+// benchmarks of warnings have results only in the old file, "" if none
+// does.
+func strictFailure(warnings []benchdiff.Warning) string {
+	missing, _ := benchdiff.Unmatched(warnings)
+	if len(missing) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("benchdiff: -strict: %d benchmarks of %s are missing from %s: %s", len(missing), *oldLabel, *newLabel, strings.Join(missing, ", "))
+}
+
+// printUnmatched writes the sections listing the removed and the added
+// benchmarks, omitting the empty ones.
+func printUnmatched(w io.Writer, removed, added []string) {
+	printNames(w, fmt.Sprintf("removed (only in %s)", *oldLabel), removed)
+	printNames(w, fmt.Sprintf("added (only in %s)", *newLabel), added)
+}
+
+func printNames(w io.Writer, title string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s:\n", title)
+	for _, name := range names {
+		fmt.Fprintf(w, "\t%s\n", name)
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+func TestPrintUnmatched(t *testing.T) {
+	var buf bytes.Buffer
+	printUnmatched(&buf, []string{"BenchmarkOld"}, []string{"BenchmarkNew1", "BenchmarkNew2"})
+	want := "\nremoved (only in old):\n\tBenchmarkOld\n\nadded (only in new):\n\tBenchmarkNew1\n\tBenchmarkNew2\n"
+	if have := buf.String(); have != want {
+		t.Errorf("printUnmatched: want %q have %q", want, have)
+	}
+
+	buf.Reset()
+	printUnmatched(&buf, nil, []string{"BenchmarkNew"})
+	if want, have := "\nadded (only in new):\n\tBenchmarkNew\n", buf.String(); have != want {
+		t.Errorf("printUnmatched without removed: want %q have %q", want, have)
+	}
+}
+
+func TestStrictFailure(t *testing.T) {
+	warnings := []benchdiff.Warning{{Name: "BenchmarkNew", After: 1}, {Name: "BenchmarkDiff", Before: 2, After: 3}}
+	if msg := strictFailure(warnings); msg != "" {
+		t.Errorf("strictFailure without removed benchmarks: want none have %q", msg)
+	}
+	warnings = append(warnings, benchdiff.Warning{Name: "BenchmarkGone", Before: 1}, benchdiff.Warning{Name: "BenchmarkLost", Before: 2})
+	want := "benchdiff: -strict: 2 benchmarks of old are missing from new: BenchmarkGone, BenchmarkLost"
+	if have := strictFailure(warnings); have != want {
+		t.Errorf("strictFailure: want %q have %q", want, have)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// maxExactU is the largest total number of values for which
+// mannWhitneyU computes the exact distribution of U.
+const maxExactU = 100
+
+// mannWhitneyU returns the two-tailed p-value of the Mann-Whitney U-test
+// of before and after, which must not be empty. Without ties and with
+// at most maxExactU values the p-value is exact; otherwise it uses the
+// normal approximation of U with the tie and continuity corrections.
+func mannWhitneyU(before, after []float64) float64 {
+	n1, n2 := len(before), len(after)
+	u, ties := uStatistic(before, after)
+	if ties == 0 && n1+n2 <= maxExactU {
+		dist := uDistribution(n1, n2)
+		var below, total float64
+		for i, c := range dist {
+			if float64(i) <= u {
+				below += c
+			}
+			total += c
+		}
+		above := total - below
+		if i := int(u); float64(i) == u {
+			above += dist[i]
+		}
+		return math.Min(1, 2*math.Min(below, above)/total)
+	}
+	n, prod := float64(n1+n2), float64(n1*n2)
+	sigma := math.Sqrt(prod / 12 * (n + 1 - ties/(n*(n-1))))
+	if sigma == 0 {
+		return 1
+	}
+	z := math.Max(0, math.Abs(u-prod/2)-0.5) / sigma
+	return math.Erfc(z / math.Sqrt2)
+}
+
+// uStatistic returns the U statistic of before against after, the
+// number of pairs in which the before value is larger, ties counting
+// for one half, and the tie term: the sum of t³-t over the groups of t
+// equal values.
+func uStatistic(before, after []float64) (u, ties float64) {
+	type value struct {
+		x      float64
+		before bool
+	}
+	all := make([]value, 0, len(before)+len(after))
+	for _, x := range before {
+		all = append(all, value{x, true})
+	}
+	for _, x := range after {
+		all = append(all, value{x, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].x < all[j].x })
+	var ranks float64 // sum of the ranks of before
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].x == all[i].x {
+			j++
+		}
+		rank := float64(i+j+1) / 2 // mean of the ranks i+1 to j
+		for _, v := range all[i:j] {
+			if v.before {
+				ranks += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			ties += t*t*t - t
+		}
+		i = j
+	}
+	n1 := float64(len(before))
+	return ranks - n1*(n1+1)/2, ties
+}
+
+// uDistribution returns the number of orderings of n1 and n2 distinct
+// values giving each U statistic from 0 to n1*n2.
+func uDistribution(n1, n2 int) []float64 {
+	// prev[j] and cur[j] are the distributions for i-1 and i values
+	// against j values. Adding a largest value to the first sample
+	// raises U by j, adding it to the second leaves U unchanged.
+	prev := make([][]float64, n2+1)
+	for j := range prev {
+		prev[j] = []float64{1}
+	}
+	for i := 1; i <= n1; i++ {
+		cur := make([][]float64, n2+1)
+		cur[0] = []float64{1}
+		for j := 1; j <= n2; j++ {
+			d := make([]float64, i*j+1)
+			for u, c := range prev[j] {
+				d[u+j] += c
+			}
+			for u, c := range cur[j-1] {
+				d[u] += c
+			}
+			cur[j] = d
+		}
+		prev = cur
+	}
+	return prev[n2]
+}
@@ -0,0 +1,52 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUStatistic(t *testing.T) {
+	u, ties := uStatistic([]float64{3, 1, 5}, []float64{2, 4})
+	if u != 3 || ties != 0 {
+		t.Errorf("uStatistic: want 3, 0 have %f, %f", u, ties)
+	}
+	u, ties = uStatistic([]float64{1, 2, 2}, []float64{2, 3})
+	if u != 1 || ties != 24 {
+		t.Errorf("uStatistic with ties: want 1, 24 have %f, %f", u, ties)
+	}
+}
+
+func TestUDistribution(t *testing.T) {
+	want := []float64{1, 1, 2, 2, 2, 1, 1} // 3 and 2 values: 10 orderings
+	have := uDistribution(3, 2)
+	if len(have) != len(want) {
+		t.Fatalf("uDistribution(3, 2): want %v have %v", want, have)
+	}
+	for i := range want {
+		if have[i] != want[i] {
+			t.Fatalf("uDistribution(3, 2): want %v have %v", want, have)
+		}
+	}
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	cases := []struct {
+		before, after []float64
+		want          float64
+	}{
+		{[]float64{1, 2, 3}, []float64{4, 5, 6}, 0.1},                                                 // 2/20
+		{[]float64{6, 7, 8, 9, 10}, []float64{1, 2, 3, 4, 5}, 2.0 / 252},                              // either way
+		{[]float64{1, 3, 5}, []float64{2, 4, 6}, 0.7},                                                 // U = 3 of 9
+		{[]float64{4, 4, 4}, []float64{4, 4}, 1},                                                      // all tied
+		{[]float64{1, 2, 2, 3}, []float64{3, 4, 4, 5}, math.Erfc(7 / math.Sqrt(81.0/7) / math.Sqrt2)}, // normal approximation
+	}
+	for _, tt := range cases {
+		if have := mannWhitneyU(tt.before, tt.after); math.Abs(have-tt.want) > 1e-9 {
+			t.Errorf("mannWhitneyU(%v, %v): want %f have %f", tt.before, tt.after, tt.want, have)
+		}
+	}
+}
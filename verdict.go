@@ -0,0 +1,64 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// verdict is the content of the -out-summary file.
+type verdict struct {
+	Pass       bool               `json:"pass"`
+	Violations []verdictViolation `json:"violations"`
+}
+
+// verdictViolation is a metric of a benchmark beyond its tolerance.
+type verdictViolation struct {
+	Benchmark string   `json:"benchmark"`
+	Metric    string   `json:"metric"`
+	Before    float64  `json:"before"`
+	After     float64  `json:"after"`
+	DeltaPct  *float64 `json:"delta_pct"` // null if the change is infinite
+}
+
+// verdictViolations returns the violations of the verdict: those of
+// -errdelta, or with -exitcode alone the regressions beyond tolerance
+// of diffs.
+func verdictViolations(diffs []benchdiff.BenchDiff, violations []violation) []violation {
+	if *failOnDelta || !*exitCode {
+		return violations
+	}
+	var vv []violation
+	for _, m := range activeMetrics() {
+		for _, diff := range diffs {
+			if m.measuredIn(diff) && m.regressedBeyond(diff) {
+				vv = append(vv, violation{diff.Name(), m.unit, m.delta(diff), m.regressed(diff)})
+			}
+		}
+	}
+	return vv
+}
+
+// writeVerdict writes to path the verdict pass along with violations as
+// JSON.
+func writeVerdict(path string, pass bool, violations []violation) error {
+	v := verdict{Pass: pass, Violations: make([]verdictViolation, 0, len(violations))}
+	for _, vl := range violations {
+		vv := verdictViolation{Benchmark: vl.name, Metric: vl.unit, Before: vl.delta.Before, After: vl.delta.After}
+		if pct := vl.delta.Percent(); !math.IsInf(pct, 0) && !math.IsNaN(pct) {
+			vv.DeltaPct = &pct
+		}
+		v.Violations = append(v.Violations, vv)
+	}
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,106 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+func TestWriteVerdict(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchdiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "verdict.json")
+	violations := []violation{
+		{"BenchmarkA", "ns/op", benchdiff.Delta{Before: 100, After: 150}, true},
+		{"BenchmarkB", "allocs/op", benchdiff.Delta{Before: 0, After: 2}, true},
+	}
+	if err := writeVerdict(path, false, violations); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+	"pass": false,
+	"violations": [
+		{
+			"benchmark": "BenchmarkA",
+			"metric": "ns/op",
+			"before": 100,
+			"after": 150,
+			"delta_pct": 50
+		},
+		{
+			"benchmark": "BenchmarkB",
+			"metric": "allocs/op",
+			"before": 0,
+			"after": 2,
+			"delta_pct": null
+		}
+	]
+}
+`
+	if have := string(data); have != want {
+		t.Errorf("writeVerdict: want\n%s\nhave\n%s", want, have)
+	}
+
+	if err := writeVerdict(path, true, nil); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = ioutil.ReadFile(path)
+	if want := "{\n\t\"pass\": true,\n\t\"violations\": []\n}\n"; string(data) != want {
+		t.Errorf("writeVerdict passing: want\n%s\nhave\n%s", want, data)
+	}
+}
+
+func TestVerdictViolations(t *testing.T) {
+	defer func(f, e bool, tol float64) { *failOnDelta, *exitCode, *tNsPerOp = f, e, tol }(*failOnDelta, *exitCode, *tNsPerOp)
+	*failOnDelta, *exitCode, *tNsPerOp = false, true, 5
+	diffs := []benchdiff.BenchDiff{
+		nsDiff("BenchmarkA", 0, 100, 110),
+		nsDiff("BenchmarkB", 1, 100, 80),
+	}
+	vv := verdictViolations(diffs, nil)
+	if len(vv) != 1 || vv[0].name != "BenchmarkA" {
+		t.Errorf("verdictViolations with -exitcode: want the regression of BenchmarkA, have %v", vv)
+	}
+}
+
+func TestStrictVerdict(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchdiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldPath, newPath, path := filepath.Join(dir, "old.txt"), filepath.Join(dir, "new.txt"), filepath.Join(dir, "verdict.json")
+	if err := ioutil.WriteFile(oldPath, []byte("BenchmarkA 1 100 ns/op\nBenchmarkGone 1 100 ns/op\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(newPath, []byte("BenchmarkA 1 100 ns/op\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestExitCode$")
+	cmd.Env = append(os.Environ(), "BENCHDIFF_ARGS=-strict -out-summary "+path+" "+oldPath+" "+newPath)
+	if err := cmd.Run(); err == nil {
+		t.Error("benchdiff -strict: want a failure for the missing benchmark")
+	}
+	data, _ := ioutil.ReadFile(path)
+	if !strings.Contains(string(data), `"pass": false`) {
+		t.Errorf("benchdiff -strict -out-summary: want a failing verdict, have\n%s", data)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// watchPoll is the interval at which -watch looks for changed files.
+const watchPoll = time.Second
+
+// clearScreen moves the cursor home and clears the terminal.
+const clearScreen = "\x1b[H\x1b[2J"
+
+// snapshot returns the modification times of the files below dir, by
+// path. Hidden files and directories, as .git, are left out.
+func snapshot(dir string) (map[string]time.Time, error) {
+	files := make(map[string]time.Time)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			files[path] = info.ModTime()
+		}
+		return nil
+	})
+	return files, err
+}
+
+// sameSnapshot reports whether a and b have the same files with the
+// same modification times.
+func sameSnapshot(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, t := range a {
+		if u, ok := b[path]; !ok || !u.Equal(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// printWatch writes the tables comparing before, the results of the
+// first run, with after, those of run n, under a line telling the run.
+func printWatch(out io.Writer, before parse.Set, oldHeader benchHeader, after parse.Set, newHeader benchHeader, n int, limit int) {
+	fmt.Fprintf(out, "benchdiff -watch: run %d against the first run, at %s\n\n", n, time.Now().Format("15:04:05"))
+	diffs, _, _ := prepare(deepCopySet(before), after, oldHeader, newHeader)
+	if nameFilter != nil {
+		diffs = filterDiffs(diffs, nameFilter)
+	}
+	if len(diffs) == 0 {
+		fmt.Fprintln(out, "no benchmark in common with the first run")
+		return
+	}
+	padding, names := fitLayout(diffs, limit)
+	nameWidth = names
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 0, padding, ' ', 0)
+	printTables(w, diffs, terminalStyle)
+	w.Flush()
+}
+
+// parseState is the global state parsing the inputs adds to: the
+// inputs and the custom metrics and packages of their results.
+type parseState struct {
+	inputs []inputRecord
+	custom map[*parse.Benchmark]map[string]float64
+	pkgs   map[*parse.Benchmark]string
+}
+
+// saveParseState returns the current parse state.
+func saveParseState() parseState {
+	s := parseState{
+		inputs: append([]inputRecord(nil), inputs...),
+		custom: make(map[*parse.Benchmark]map[string]float64, len(customValues)),
+		pkgs:   make(map[*parse.Benchmark]string, len(resultPackages)),
+	}
+	for b, values := range customValues {
+		s.custom[b] = values
+	}
+	for b, pkg := range resultPackages {
+		s.pkgs[b] = pkg
+	}
+	return s
+}
+
+// restore sets the parse state back to s, forgetting what was parsed
+// since, so that the repeated runs of -watch and -confirm-runs do not
+// accumulate.
+func (s parseState) restore() {
+	inputs = append([]inputRecord(nil), s.inputs...)
+	customValues = make(map[*parse.Benchmark]map[string]float64, len(s.custom))
+	for b, values := range s.custom {
+		customValues[b] = values
+	}
+	resultPackages = make(map[*parse.Benchmark]string, len(s.pkgs))
+	for b, pkg := range s.pkgs {
+		resultPackages[b] = pkg
+	}
+}
+
+// watch runs -benchcmd in the current directory, keeps its results as
+// the old side, then runs it again whenever a file below the directory
+// changes, redrawing the comparison with the first run on out. A
+// failing run, as when the code does not build, is reported and the
+// files watched again. It never returns.
+func watch(out *os.File) {
+	limit := outputWidth(out)
+	_, term := terminalWidth(out)
+	if *colorMode == colorAuto {
+		colorDeltas = term
+	}
+	fmt.Fprintf(os.Stderr, "benchdiff: running %s for the first run\n", *benchCmd)
+	data, err := runBench(".", *benchCmd)
+	if err != nil {
+		fatal(err)
+	}
+	before, oldHeader := parseData("old", "first run", data)
+	first := saveParseState()
+	// Snapshot after each run, so that the files it writes, as profiles
+	// or test binaries, do not trigger the next one.
+	snap, err := snapshot(".")
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Fprintln(os.Stderr, "benchdiff: watching the files for changes")
+	for n := 2; ; {
+		time.Sleep(watchPoll)
+		s, err := snapshot(".")
+		if err != nil {
+			fatal(err)
+		}
+		if sameSnapshot(snap, s) {
+			continue
+		}
+		first.restore()
+		data, runErr := runBench(".", *benchCmd)
+		if snap, err = snapshot("."); err != nil {
+			fatal(err)
+		}
+		if runErr != nil {
+			fmt.Fprintln(os.Stderr, runErr)
+			continue
+		}
+		after, newHeader := parseData("new", fmt.Sprintf("run %d", n), data)
+		if term {
+			fmt.Fprint(out, clearScreen)
+		} else if n > 2 {
+			fmt.Fprint(out, "\n")
+		}
+		printWatch(out, before, oldHeader, after, newHeader, n, limit)
+		n++
+	}
+}
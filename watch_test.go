@@ -0,0 +1,109 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchdiff-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a.go", ".hidden", ".git/HEAD", "sub/b.go"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snap, err := snapshot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap) != 2 {
+		t.Errorf("snapshot: want a.go and sub/b.go, have %v", snap)
+	}
+	same, _ := snapshot(dir)
+	if !sameSnapshot(snap, same) {
+		t.Error("sameSnapshot: unchanged files reported changed")
+	}
+
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "sub/b.go"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	touched, _ := snapshot(dir)
+	if sameSnapshot(snap, touched) {
+		t.Error("sameSnapshot: modified file not reported")
+	}
+	os.Remove(filepath.Join(dir, "a.go"))
+	removed, _ := snapshot(dir)
+	if sameSnapshot(touched, removed) {
+		t.Error("sameSnapshot: removed file not reported")
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, ".git/index"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	hidden, _ := snapshot(dir)
+	if !sameSnapshot(removed, hidden) {
+		t.Error("sameSnapshot: change of a hidden directory reported")
+	}
+}
+
+func TestPrintWatch(t *testing.T) {
+	defer func(w []benchdiff.Warning) { correlateWarnings = w }(correlateWarnings)
+	bench := func(name string, ns float64) []*parse.Benchmark {
+		return []*parse.Benchmark{{Name: name, N: 1, NsPerOp: ns, Measured: parse.NsPerOp}}
+	}
+	before := parse.Set{"BenchmarkA": bench("BenchmarkA", 100)}
+	var buf bytes.Buffer
+	printWatch(&buf, before, benchHeader{}, parse.Set{"BenchmarkA": bench("BenchmarkA", 50)}, benchHeader{}, 2, 0)
+	out := buf.String()
+	if !strings.HasPrefix(out, "benchdiff -watch: run 2 against the first run, at ") {
+		t.Errorf("printWatch: missing the run line in\n%s", out)
+	}
+	if !strings.Contains(out, "BenchmarkA") || !strings.Contains(out, "-50.00%") {
+		t.Errorf("printWatch: missing the comparison in\n%s", out)
+	}
+	if len(before["BenchmarkA"]) != 1 || before["BenchmarkA"][0].NsPerOp != 100 {
+		t.Errorf("printWatch modified the first run: %v", before)
+	}
+
+	buf.Reset()
+	printWatch(&buf, before, benchHeader{}, parse.Set{"BenchmarkB": bench("BenchmarkB", 50)}, benchHeader{}, 3, 0)
+	if !strings.Contains(buf.String(), "no benchmark in common with the first run") {
+		t.Errorf("printWatch without common benchmark:\n%s", buf.String())
+	}
+}
+
+func TestParseState(t *testing.T) {
+	defer saveParseState().restore()
+	parseData("old", "first run", []byte("pkg: example.com/a\nBenchmarkA 1 100 ns/op 3 items/op\n"))
+	first := saveParseState()
+	n, custom, pkgs := len(inputs), len(customValues), len(resultPackages)
+	for i := 0; i < 3; i++ {
+		first.restore()
+		parseData("new", "run", []byte("pkg: example.com/a\nBenchmarkA 1 90 ns/op 2 items/op\n"))
+	}
+	first.restore()
+	if len(inputs) != n || len(customValues) != custom || len(resultPackages) != pkgs {
+		t.Errorf("restore: want %d inputs, %d custom and %d packages have %d, %d and %d",
+			n, custom, pkgs, len(inputs), len(customValues), len(resultPackages))
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+// Table layouts used to fit -width.
+const (
+	defaultPadding = 5
+	compactPadding = 1
+	minNameWidth   = 12 // names are never truncated below this width
+)
+
+// nameWidth is the maximum displayed length of benchmark names,
+// 0 for no limit.
+var nameWidth int
+
+// displayName returns the name of diff as shown in the tables, without
+// its package in the sections of printSections.
+func displayName(diff benchdiff.BenchDiff) string {
+	name := diff.Name()
+	if sectionNames {
+		_, name = splitPackage(name)
+	}
+	return truncateName(name, nameWidth)
+}
+
+// truncateName shortens name to n characters, keeping its end where
+// sub-benchmarks and the GOMAXPROCS suffix are. A limit of 0 keeps
+// the whole name.
+func truncateName(name string, n int) string {
+	runes := []rune(name)
+	switch {
+	case n <= 0 || len(runes) <= n:
+		return name
+	case n <= 3:
+		return string(runes[len(runes)-n:])
+	}
+	return "..." + string(runes[len(runes)-n+3:])
+}
+
+// outputWidth returns the width the tables written to f must fit in,
+// 0 for no limit. Unless set by -width, it is the size of the terminal
+// or the COLUMNS environment variable.
+func outputWidth(f *os.File) int {
+	if *width != 0 {
+		if *width < 0 {
+			return 0
+		}
+		return *width
+	}
+	if w, ok := terminalWidth(f); ok {
+		return w
+	}
+	if c, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && c > 0 {
+		return c
+	}
+	return 0
+}
+
+// tableWidth returns the length of the longest line of the metric
+// tables of diffs, using the given padding and name length limit.
+func tableWidth(diffs []benchdiff.BenchDiff, padding, names int) int {
+	saved := nameWidth
+	defer func() { nameWidth = saved }()
+	nameWidth = names
+	max := 0
+	for _, m := range activeMetrics() {
+		widths := cellWidths(nil, strings.Split(strings.TrimSuffix(m.header(), "\n"), "\t"))
+		for _, diff := range diffs {
+			if m.measuredIn(diff) {
+				widths = cellWidths(widths, m.row(diff))
+			}
+		}
+		line := 0
+		for i, w := range widths {
+			line += w
+			if i < len(widths)-1 {
+				line += padding
+			}
+		}
+		if line > max {
+			max = line
+		}
+	}
+	return max
+}
+
+// cellWidths widens widths to fit cells.
+func cellWidths(widths []int, cells []string) []int {
+	for i, c := range cells {
+		n := utf8.RuneCountInString(c)
+		if i == len(widths) {
+			widths = append(widths, n)
+		} else if n > widths[i] {
+			widths[i] = n
+		}
+	}
+	return widths
+}
+
+// fitLayout returns the padding and the name length limit making the
+// tables of diffs fit in limit columns: the default padding if
+// possible, else a compact padding, else compact padding and names
+// truncated as needed.
+func fitLayout(diffs []benchdiff.BenchDiff, limit int) (padding, names int) {
+	if limit <= 0 || tableWidth(diffs, defaultPadding, 0) <= limit {
+		return defaultPadding, 0
+	}
+	compact := tableWidth(diffs, compactPadding, 0)
+	if compact <= limit {
+		return compactPadding, 0
+	}
+	longest := 0
+	for _, diff := range diffs {
+		if n := utf8.RuneCountInString(diff.Name()); n > longest {
+			longest = n
+		}
+	}
+	names = longest - (compact - limit)
+	if names < minNameWidth {
+		names = minNameWidth
+	}
+	return compactPadding, names
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/chavacava/benchdiff/pkg/benchdiff"
+)
+
+func TestTruncateName(t *testing.T) {
+	cases := []struct {
+		name string
+		n    int
+		want string
+	}{
+		{name: "BenchmarkDB/Query/WithIndex-8", n: 0, want: "BenchmarkDB/Query/WithIndex-8"},
+		{name: "BenchmarkDB/Query/WithIndex-8", n: 40, want: "BenchmarkDB/Query/WithIndex-8"},
+		{name: "BenchmarkDB/Query/WithIndex-8", n: 14, want: "...WithIndex-8"},
+		{name: "BenchmarkDB", n: 2, want: "DB"},
+	}
+	for _, tt := range cases {
+		if have := truncateName(tt.name, tt.n); have != tt.want {
+			t.Errorf("truncateName(%q, %d): want %q have %q", tt.name, tt.n, tt.want, have)
+		}
+	}
+}
+
+func TestFitLayout(t *testing.T) {
+	diffs := []benchdiff.BenchDiff{nsDiff("BenchmarkDB/Query/WithIndex-8", 0, 100, 90)}
+	// Column widths: name 29, old ns/op 9, new ns/op 9, delta 7.
+	cases := []struct {
+		limit   int
+		padding int
+		names   int
+	}{
+		{limit: 0, padding: defaultPadding, names: 0},
+		{limit: 69, padding: defaultPadding, names: 0},
+		{limit: 68, padding: compactPadding, names: 0},
+		{limit: 57, padding: compactPadding, names: 0},
+		{limit: 50, padding: compactPadding, names: 22},
+		{limit: 10, padding: compactPadding, names: minNameWidth},
+	}
+	for _, tt := range cases {
+		padding, names := fitLayout(diffs, tt.limit)
+		if padding != tt.padding || names != tt.names {
+			t.Errorf("fitLayout(%d): want %d, %d have %d, %d", tt.limit, tt.padding, tt.names, padding, names)
+		}
+	}
+}